@@ -1,10 +1,15 @@
 -- Active: 1731493020520@@127.0.0.1@5432@xm
-CREATE TYPE ORG_TYPE AS ENUM (
-    'Corporations', 
-    'NonProfit', 
-    'Cooperative',
-    'Sole Proprietorship'
-);
+-- the script can be run again on an existing database: it creates what is missing and adds the columns added
+-- after the tables were created
+DO $$ BEGIN
+    CREATE TYPE ORG_TYPE AS ENUM (
+        'Corporations', 
+        'NonProfit', 
+        'Cooperative',
+        'Sole Proprietorship'
+    );
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
 
 
 CREATE TABLE IF NOT EXISTS "company" (
@@ -13,11 +18,14 @@ CREATE TABLE IF NOT EXISTS "company" (
     "description" VARCHAR(3000),
     "amount_employees" INT NOT NULL,
     "registered" BOOLEAN NOT NULL,
-    "type" ORG_TYPE NOT NULL
+    "type" ORG_TYPE NOT NULL,
+    "version" INT NOT NULL DEFAULT 1
 );
 
-CREATE UNIQUE INDEX company_id_idx ON "company"("id");
-CREATE UNIQUE INDEX name ON "company"("name");    
+ALTER TABLE "company" ADD COLUMN IF NOT EXISTS "version" INT NOT NULL DEFAULT 1;
+
+CREATE UNIQUE INDEX IF NOT EXISTS company_id_idx ON "company"("id");
+CREATE UNIQUE INDEX IF NOT EXISTS name ON "company"("name");    
 
 
 CREATE TABLE IF NOT EXISTS "users" (
@@ -26,22 +34,95 @@ CREATE TABLE IF NOT EXISTS "users" (
     "enc_password" VARCHAR(32) NOT NULL
 );
 
-CREATE UNIQUE INDEX users_id_idx ON "users"("id");
-CREATE UNIQUE INDEX email ON "users"("email");    
+CREATE UNIQUE INDEX IF NOT EXISTS users_id_idx ON "users"("id");
+CREATE UNIQUE INDEX IF NOT EXISTS email ON "users"("email");    
 
 
-CREATE TYPE EVENT_TYPE AS ENUM (
-    'create_company', 
-    'update_company', 
-    'delete_company'
-);
+DO $$ BEGIN
+    CREATE TYPE EVENT_TYPE AS ENUM (
+        'create_company', 
+        'update_company', 
+        'delete_company'
+    );
+EXCEPTION WHEN duplicate_object THEN NULL;
+END $$;
 
 CREATE TABLE IF NOT EXISTS "events" (
     "id" UUID PRIMARY KEY,
     "type" EVENT_TYPE NOT NULL,
+    "version" INT NOT NULL DEFAULT 1,
     "timestamp" TIMESTAMP NOT NULL,
-    "entity_id" UUID NOT NULL
+    "entity_id" UUID NOT NULL,
+    "payload" JSONB,
+    "trace_parent" VARCHAR(55) NOT NULL DEFAULT '',
+    "trace_state" TEXT NOT NULL DEFAULT ''
+);
+
+ALTER TABLE "events" ADD COLUMN IF NOT EXISTS "version" INT NOT NULL DEFAULT 1;
+ALTER TABLE "events" ADD COLUMN IF NOT EXISTS "payload" JSONB;
+ALTER TABLE "events" ADD COLUMN IF NOT EXISTS "trace_parent" VARCHAR(55) NOT NULL DEFAULT '';
+ALTER TABLE "events" ADD COLUMN IF NOT EXISTS "trace_state" TEXT NOT NULL DEFAULT '';
+
+CREATE UNIQUE INDEX IF NOT EXISTS events_id_idx ON "events"("id");
+CREATE INDEX IF NOT EXISTS entity_id ON "events"("entity_id");
+
+
+CREATE TABLE IF NOT EXISTS "dead_letter_events" (
+    "id" UUID PRIMARY KEY,
+    "type" VARCHAR(50) NOT NULL,
+    "version" INT NOT NULL,
+    "timestamp" TIMESTAMPTZ NOT NULL,
+    "entity_id" UUID NOT NULL,
+    "payload" JSONB,
+    "last_error" TEXT NOT NULL,
+    "attempts" INT NOT NULL,
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    "updated_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    "trace_parent" VARCHAR(55) NOT NULL DEFAULT '',
+    "trace_state" TEXT NOT NULL DEFAULT ''
 );
 
-CREATE UNIQUE INDEX events_id_idx ON "events"("id");
-CREATE INDEX entity_id ON "events"("entity_id");
\ No newline at end of file
+ALTER TABLE "dead_letter_events" ADD COLUMN IF NOT EXISTS "trace_parent" VARCHAR(55) NOT NULL DEFAULT '';
+ALTER TABLE "dead_letter_events" ADD COLUMN IF NOT EXISTS "trace_state" TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS dead_letter_events_created_at_idx ON "dead_letter_events"("created_at");
+
+
+CREATE TABLE IF NOT EXISTS "idempotency_keys" (
+    "user_id" UUID NOT NULL,
+    "key" VARCHAR(255) NOT NULL,
+    "request_hash" VARCHAR(64) NOT NULL,
+    "completed" BOOLEAN NOT NULL DEFAULT FALSE,
+    "status" INT NOT NULL DEFAULT 0,
+    "headers" JSONB NOT NULL DEFAULT '{}',
+    "body" BYTEA NOT NULL DEFAULT '',
+    "locked_until" TIMESTAMPTZ NOT NULL,
+    "expires_at" TIMESTAMPTZ NOT NULL,
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY ("user_id", "key")
+);
+
+CREATE TABLE IF NOT EXISTS "import_jobs" (
+    "id" UUID PRIMARY KEY,
+    "user_id" UUID NOT NULL,
+    "format" VARCHAR(16) NOT NULL,
+    "status" VARCHAR(16) NOT NULL,
+    "size" BIGINT NOT NULL DEFAULT 0,
+    "bytes_read" BIGINT NOT NULL DEFAULT 0,
+    "processed" INT NOT NULL DEFAULT 0,
+    "accepted" INT NOT NULL DEFAULT 0,
+    "rejected" INT NOT NULL DEFAULT 0,
+    "error" TEXT NOT NULL DEFAULT '',
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    "updated_at" TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS "import_rows" (
+    "job_id" UUID NOT NULL REFERENCES "import_jobs"("id") ON DELETE CASCADE,
+    "line" INT NOT NULL,
+    "accepted" BOOLEAN NOT NULL,
+    "company_id" UUID,
+    "error_code" VARCHAR(64) NOT NULL DEFAULT '',
+    "error_message" TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY ("job_id", "line")
+);
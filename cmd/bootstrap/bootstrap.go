@@ -1,11 +1,22 @@
 package bootstrap
 
 import (
-	"log"
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 	"xm_test/internal/conf"
 	"xm_test/internal/db"
+	"xm_test/internal/events"
+	"xm_test/internal/health"
 	"xm_test/internal/helpers"
+	"xm_test/internal/tracing"
 	"xm_test/internal/transport"
+
+	"go.uber.org/zap"
 )
 
 func Run() error {
@@ -15,31 +26,125 @@ func Run() error {
 	}
 
 	// Setup the logger
-	logger, err := NewZapLogger()
+	logger, logLevel, err := NewZapLogger()
 	if err != nil {
 		return err
 	}
+	defer logger.Sync()
 
 	logger.Info("Starting XM Test API")
 	logger.Debugf("starting with config: %s", helpers.PrettyPrintStructResponse(conf.GlobalConfig))
 
+	// Cancelled when the process receives SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Setup the traces exporter
+	tracer, err := tracing.Setup(ctx)
+	if err != nil {
+		return err
+	}
+	logger.Debugf("tracing exporter: %s", conf.GlobalConfig.Tracing.Exporter)
+
 	logger.Debugf("setting up database connection")
 	db := db.NewDatabaseAdapter(logger)
 	logger.Debugf("database connection established")
 
-	// Setup the transport layer and start the server
-	server := transport.NewTransporter(logger, db)
+	// Setup the events registry and check that it matches the event types supported by the database
+	registry, err := events.NewEventsRegistry()
+	if err != nil {
+		return err
+	}
+	if err := verifyEventTypes(db, registry); err != nil {
+		return err
+	}
 
+	// Setup the events hub and feed it with the events dispatched in any replica
+	hub := events.NewEventsHub(logger)
+	listener := events.NewEventsListener(logger, db, hub)
+	listenerCtx, stopListener := context.WithCancel(context.Background())
+	var listenerWg sync.WaitGroup
+	listenerWg.Add(1)
 	go func() {
-		if err := server.HealthCheck(); err != nil {
-			logger.Error(err)
-			log.Fatal(err)
+		defer listenerWg.Done()
+		if err := listener.Listen(listenerCtx); err != nil {
+			logger.Errorf("events listener stopped: %s", err)
 		}
 	}()
 
-	if err := server.Serve(); err != nil {
-		return err
+	// Setup the readiness checks
+	monitor := health.NewHealthMonitor(
+		logger,
+		conf.GlobalConfig.HealthCheckTimeout,
+		conf.GlobalConfig.HealthCheckCacheTTL,
+		health.NewChecker("database", db.Ping),
+		health.NewChecker("migrations", db.CheckSchema),
+		health.NewChecker("events_listener", func(ctx context.Context) error {
+			if !listener.Listening() {
+				return errors.New("events listener is not connected")
+			}
+			return nil
+		}),
+	)
+
+	// Setup the transport layer and start the server
+	server := transport.NewTransporter(logger, logLevel, db, hub, registry, monitor)
+
+	serverErrors := make(chan error, 2)
+	go func() {
+		serverErrors <- server.HealthCheck()
+	}()
+	go func() {
+		serverErrors <- server.Serve()
+	}()
+
+	// Wait until a shutdown signal is received or one of the servers fails
+	var runErr error
+	select {
+	case <-ctx.Done():
+		logger.Infof("shutdown signal received")
+	case runErr = <-serverErrors:
+		logger.Errorf("server stopped unexpectedly: %s", runErr)
+	}
+
+	return errors.Join(runErr, shutdown(logger, server, db, tracer, stopListener, &listenerWg))
+}
+
+// shutdown drains the transport layer, stops the events listener, closes the database connection and flushes the
+// pending spans, in that order, within the configured deadline
+func shutdown(logger *zap.SugaredLogger, server transport.Transporter, db db.DatabaseAdapter, tracer tracing.Provider, stopListener context.CancelFunc, listenerWg *sync.WaitGroup) error {
+	logger.Infof("shutting down with a deadline of %s", conf.GlobalConfig.ShutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), conf.GlobalConfig.ShutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	if err := server.Close(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	stopListener()
+	listenerWg.Wait()
+
+	if err := db.Close(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := tracer.Shutdown(ctx); err != nil {
+		errs = append(errs, err)
 	}
 
-	return nil
+	logger.Infof("shutdown completed")
+	return errors.Join(errs...)
+}
+
+// verifyEventTypes fails if the event types declared in the registry diverge from the ones supported by the database
+func verifyEventTypes(db db.DatabaseAdapter, registry events.Registry) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	types, err := db.GetEventTypes(ctx)
+	if err != nil {
+		return err
+	}
+	return registry.Verify(types)
 }
@@ -0,0 +1,120 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"xm_test/internal/conf"
+	"xm_test/internal/db"
+	"xm_test/internal/db/models"
+	"xm_test/internal/enum"
+	"xm_test/internal/service"
+	"xm_test/internal/service/export"
+	"xm_test/internal/service/inputs"
+)
+
+// Export exports the companies matching the filters from the command line, in the same formats as the
+// GET /company/export endpoint. The file is written to stdout unless an output file is set, and the amount of
+// companies exported is printed to stderr. The output file is removed when the export fails.
+func Export(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", "", "Format of the file: csv, ndjson, xlsx or parquet. Defaults to the extension of the output file, or csv")
+	columnList := flags.String("columns", "", "Comma-separated columns to export, in their order. Defaults to every column")
+	name := flags.String("name", "", "Case-insensitive substring of the name of the companies")
+	companyType := flags.String("type", "", "Type of the companies")
+	registered := flags.String("registered", "", "Whether the companies are registered: true or false")
+	output := flags.String("output", "", "File where the companies are written. Defaults to stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(*output), ".")
+	}
+	if *format == "" {
+		*format = enum.ExportCSV.String()
+	}
+	exportFormat := enum.ExportFormat(strings.ToLower(*format))
+	if !exportFormat.IsValid() {
+		return fmt.Errorf("export: unsupported format '%s', use csv, ndjson, xlsx or parquet", *format)
+	}
+	columns, err := export.ParseColumns(*columnList)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	input := &inputs.ExportCompaniesInput{Name: *name, Type: *companyType}
+	if *registered != "" {
+		value, err := strconv.ParseBool(*registered)
+		if err != nil {
+			return fmt.Errorf("export: invalid -registered '%s', use true or false", *registered)
+		}
+		input.Registered = &value
+	}
+
+	if err := conf.SetupConfig(); err != nil {
+		return err
+	}
+	// the logs are written to stderr, since the companies may be written to stdout
+	logger, _, err := newConsoleLogger(os.Stderr)
+	if err != nil {
+		return err
+	}
+	defer logger.Sync()
+
+	// Cancelled when the process receives SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db := db.NewDatabaseAdapter(logger)
+	defer db.Close(context.Background())
+	// the export only reads the companies, so it does not dispatch any event
+	cs := service.NewCompanyService(logger, db, nil, nil)
+
+	if *output == "" {
+		exported, err := exportCompanies(ctx, cs, input, os.Stdout, exportFormat, columns)
+		if err != nil {
+			return fmt.Errorf("export: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "%d companies exported\n", exported)
+		return nil
+	}
+
+	file, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	exported, err := exportCompanies(ctx, cs, input, file, exportFormat, columns)
+	err = errors.Join(err, file.Close())
+	if err != nil {
+		os.Remove(*output)
+		return fmt.Errorf("export: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "%d companies exported to %s\n", exported, *output)
+	return nil
+}
+
+// exportCompanies writes the companies matching the filters to w, and returns the amount of them
+func exportCompanies(ctx context.Context, cs service.CompanyService, input *inputs.ExportCompaniesInput, w io.Writer, format enum.ExportFormat, columns []string) (int, error) {
+	writer, err := export.NewWriter(w, format, columns)
+	if err != nil {
+		return 0, err
+	}
+
+	exported := 0
+	err = cs.ExportCompanies(ctx, input, func(company *models.CompanyModel) error {
+		exported++
+		return writer.Write(company)
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	return exported, err
+}
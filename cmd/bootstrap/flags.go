@@ -0,0 +1,15 @@
+package bootstrap
+
+import "strings"
+
+// stringsFlag is a command line flag that can be repeated, collecting every value
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
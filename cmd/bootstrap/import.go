@@ -0,0 +1,196 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+	"xm_test/internal/conf"
+	"xm_test/internal/db"
+	"xm_test/internal/db/models"
+	"xm_test/internal/enum"
+	"xm_test/internal/events"
+	"xm_test/internal/service"
+	"xm_test/internal/service/imports"
+	"xm_test/internal/service/inputs"
+	"xm_test/internal/transport/http/binding"
+
+	"github.com/google/uuid"
+)
+
+// importPollInterval is the interval at which the progress of the import is printed
+const importPollInterval = 500 * time.Millisecond
+
+// Import imports the companies of a CSV or NDJSON file from the command line, with the same validation and report
+// as the POST /company/imports endpoint. The progress is printed to stderr, and the report is written to a file
+// once the import ends. An interrupted import is marked as failed, keeping the rows imported so far.
+func Import(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	path := flags.String("file", "", "CSV or NDJSON file to import")
+	format := flags.String("format", "", "Format of the file: csv or ndjson. Defaults to the extension of the file")
+	reportPath := flags.String("report", "", "File where the report is written. Defaults to import-<id>-report.csv")
+	email := flags.String("user", "", "Email of the registered user the import is attributed to")
+	var mappings stringsFlag
+	flags.Var(&mappings, "map", "Column of the file holding a field, as field:column. Repeat it to map several fields")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *path == "" {
+		return errors.New("import: the -file flag is required")
+	}
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(*path), ".")
+	}
+	importFormat := enum.ImportFormat(strings.ToLower(*format))
+	if !importFormat.IsValid() {
+		return fmt.Errorf("import: unsupported format '%s', use csv or ndjson", *format)
+	}
+	columns, err := binding.ParseColumnMapping(mappings)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	if err := conf.SetupConfig(); err != nil {
+		return err
+	}
+	logger, _, err := NewZapLogger()
+	if err != nil {
+		return err
+	}
+	defer logger.Sync()
+
+	// Cancelled when the process receives SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db := db.NewDatabaseAdapter(logger)
+	defer db.Close(context.Background())
+
+	userID := uuid.Nil
+	if *email != "" {
+		user, err := db.GetUserByEmail(ctx, *email)
+		if err != nil {
+			return fmt.Errorf("import: failed to find user '%s': %w", *email, err)
+		}
+		userID = user.ID
+	}
+
+	file, err := os.Open(*path)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("import: %w", err)
+	}
+	rows, err := binding.NewCompanyRows(file, importFormat, columns)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("import: %w", err)
+	}
+
+	registry, err := events.NewEventsRegistry()
+	if err != nil {
+		rows.Close()
+		return err
+	}
+	cs := service.NewCompanyService(logger, db, registry, events.NewEventsDispatcher(logger, db, registry))
+	is := service.NewImportService(logger, db, cs, conf.GlobalConfig.Import.BatchSize)
+
+	// the import runs in the background, so its progress can be printed while the rows are imported
+	job, err := is.ImportCompanies(ctx, &inputs.ImportCompaniesInput{
+		UserID:     userID,
+		Format:     importFormat,
+		Size:       info.Size(),
+		Rows:       rows,
+		Background: true,
+	})
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	job, err = awaitImport(ctx, is, job)
+	if err != nil {
+		return errors.Join(fmt.Errorf("import: %w", err), awaitEvents(cs))
+	}
+	if err := awaitEvents(cs); err != nil {
+		return err
+	}
+
+	if *reportPath == "" {
+		*reportPath = fmt.Sprintf("import-%s-report.csv", job.ID)
+	}
+	if err := writeImportReport(context.Background(), is, job, *reportPath); err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "report written to %s\n", *reportPath)
+
+	if job.Status == enum.ImportFailed {
+		return fmt.Errorf("import: job '%s' failed: %s", job.ID, job.Error)
+	}
+	return nil
+}
+
+// awaitImport prints the progress of the job until it ends. When the context is done, the job is interrupted.
+func awaitImport(ctx context.Context, is service.ImportService, job *models.ImportJobModel) (*models.ImportJobModel, error) {
+	ticker := time.NewTicker(importPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(os.Stderr, "\ninterrupting the import")
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), conf.GlobalConfig.ShutdownTimeout)
+			defer cancel()
+			if err := is.Close(shutdownCtx); err != nil {
+				return nil, err
+			}
+			return is.GetImportJob(shutdownCtx, job.ID.String(), job.UserID)
+		case <-ticker.C:
+		}
+
+		current, err := is.GetImportJob(ctx, job.ID.String(), job.UserID)
+		if err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "\r%3d%% %d rows processed: %d accepted, %d rejected", current.Progress(), current.Processed, current.Accepted, current.Rejected)
+		if current.Status != enum.ImportRunning {
+			fmt.Fprintln(os.Stderr)
+			return current, nil
+		}
+	}
+}
+
+// writeImportReport writes the report of the job to the file at the path
+func writeImportReport(ctx context.Context, is service.ImportService, job *models.ImportJobModel, path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	report := imports.NewReportWriter(file)
+	err = is.StreamImportReport(ctx, job.ID.String(), job.UserID, report.Write)
+	if err == nil {
+		err = report.Flush()
+	}
+	return errors.Join(err, file.Close())
+}
+
+// awaitEvents waits for the events of the companies created by the import, which are dispatched in the background,
+// so they are not lost when the command ends
+func awaitEvents(cs service.CompanyService) error {
+	ctx, cancel := context.WithTimeout(context.Background(), conf.GlobalConfig.ShutdownTimeout)
+	defer cancel()
+	if err := cs.WaitForEvents(ctx); err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	return nil
+}
@@ -2,30 +2,66 @@ package bootstrap
 
 import (
 	"os"
+	"time"
 	"xm_test/internal/conf"
 	"xm_test/internal/enum"
+	"xm_test/internal/logging"
+	"xm_test/internal/redact"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-// NewZapLogger creates a new zap logger with the specified log level.
-func NewZapLogger() (*zap.SugaredLogger, error) {
-	pe := zap.NewProductionEncoderConfig()
+// NewZapLogger creates a new zap logger with the log level, format, output and sampling set in the configuration.
+// The returned level can be changed at runtime to adjust the verbosity of the logger. The emails and tokens are
+// masked in every entry.
+func NewZapLogger() (*zap.SugaredLogger, zap.AtomicLevel, error) {
+	return newConsoleLogger(os.Stdout)
+}
+
+// newConsoleLogger creates the logger of NewZapLogger writing to the console file instead of stdout, for the
+// commands writing their output to stdout
+func newConsoleLogger(console *os.File) (*zap.SugaredLogger, zap.AtomicLevel, error) {
+	// write to the console and, if set, to a file rotated by size
+	output := zapcore.AddSync(console)
+	if conf.GlobalConfig.Log.File != "" {
+		output = zapcore.NewMultiWriteSyncer(output, zapcore.AddSync(&lumberjack.Logger{
+			Filename:   conf.GlobalConfig.Log.File,
+			MaxSize:    conf.GlobalConfig.Log.FileMaxSizeMB,
+			MaxBackups: conf.GlobalConfig.Log.FileMaxBackups,
+			MaxAge:     conf.GlobalConfig.Log.FileMaxAgeDays,
+		}))
+	}
+
+	logger, level := newZapLogger(output)
+	return logger, level, nil
+}
 
+// newZapLogger creates the logger writing to the given output
+func newZapLogger(output zapcore.WriteSyncer) (*zap.SugaredLogger, zap.AtomicLevel) {
+	pe := zap.NewProductionEncoderConfig()
 	pe.EncodeTime = zapcore.ISO8601TimeEncoder
-	consoleEncoder := zapcore.NewConsoleEncoder(pe)
 
-	level := zap.InfoLevel
-	if conf.GlobalConfig.LogLevel == enum.Debug {
-		level = zap.DebugLevel
+	var encoder zapcore.Encoder
+	switch conf.GlobalConfig.Log.Format {
+	case enum.JSONFormat:
+		encoder = zapcore.NewJSONEncoder(pe)
+	default:
+		encoder = zapcore.NewConsoleEncoder(pe)
 	}
+	encoder = redact.NewEncoder(encoder)
+
+	level := zap.NewAtomicLevelAt(logging.ParseLevel(conf.GlobalConfig.LogLevel))
 
-	core := zapcore.NewTee(
-		zapcore.NewCore(consoleEncoder, zapcore.AddSync(os.Stdout), level),
-	)
+	core := zapcore.NewCore(encoder, output, level)
+
+	// drop the repeated entries of each second once the initial amount is logged
+	if conf.GlobalConfig.Log.SamplingInitial > 0 {
+		core = zapcore.NewSamplerWithOptions(core, time.Second, conf.GlobalConfig.Log.SamplingInitial, conf.GlobalConfig.Log.SamplingThereafter)
+	}
 
-	return zap.New(core, zap.AddCaller()).Sugar(), nil
+	return zap.New(core, zap.AddCaller()).Sugar(), level
 }
 
 var Logger *zap.SugaredLogger
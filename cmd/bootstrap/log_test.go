@@ -0,0 +1,74 @@
+package bootstrap
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"xm_test/internal/conf"
+	"xm_test/internal/enum"
+	"xm_test/internal/helpers"
+	"xm_test/internal/token"
+	"xm_test/internal/transport/http/schemas"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap/zapcore"
+)
+
+type logSuite struct {
+	suite.Suite
+}
+
+// TestSecretsAreRedacted fails if any secret value reaches the log output
+func (s *logSuite) TestSecretsAreRedacted() {
+	const (
+		jwtSecret  = "super-secret-jwt-key"
+		dbPassword = "super-secret-db-password"
+		email      = "john.doe@example.com"
+		adminEmail = "admin.user@example.com"
+		password   = "super-secret-user-password"
+	)
+
+	previous := conf.GlobalConfig
+	defer func() { conf.GlobalConfig = previous }()
+
+	accessToken, _, err := func() (string, *token.Claims, error) {
+		conf.GlobalConfig = &conf.Config{JwtSecret: jwtSecret}
+		return token.GenerateToken("a3f1c7a2-3c1e-4f0e-9a55-3d8f0b8d2c11", email)
+	}()
+	s.Require().NoError(err)
+
+	secrets := []string{jwtSecret, dbPassword, email, adminEmail, password, accessToken}
+
+	for _, format := range []enum.LogFormat{enum.ConsoleFormat, enum.JSONFormat} {
+		s.Run(format.String(), func() {
+			conf.GlobalConfig = &conf.Config{
+				LogLevel:    enum.Debug,
+				JwtSecret:   jwtSecret,
+				AdminEmails: []string{adminEmail},
+				Log:         conf.Log{Format: format},
+				Postgres:    conf.Postgres{User: "xm", Password: dbPassword},
+			}
+
+			var output bytes.Buffer
+			logger, _ := newZapLogger(zapcore.AddSync(&output))
+
+			logger.Debugf("starting with config: %s", helpers.PrettyPrintStructResponse(conf.GlobalConfig))
+			logger.Debugf("request body: %s", helpers.PrettyPrintStructResponse(schemas.RegisterRequest{Email: email, Password: password}))
+			logger.Debugf("response: %s", helpers.PrettyPrintStructResponse(schemas.LoginResponse{AccessToken: accessToken}))
+			logger.Infof("registering user with email '%s'", email)
+			logger.Infof("authorization header: Bearer %s", accessToken)
+			logger.Errorw("failed to create user", "error", errors.New(`duplicate key value violates unique constraint: Key (email)=(`+email+`) already exists`))
+			logger.With("email", email).Infow("token generated", "token", accessToken)
+			s.Require().NoError(logger.Sync())
+
+			s.Require().NotEmpty(output.String())
+			for _, secret := range secrets {
+				s.NotContains(output.String(), secret)
+			}
+		})
+	}
+}
+
+func TestLogSuite(t *testing.T) {
+	suite.Run(t, new(logSuite))
+}
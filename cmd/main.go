@@ -2,10 +2,28 @@ package main
 
 import (
 	"log"
+	"os"
 	"xm_test/cmd/bootstrap"
 )
 
 func main() {
+	// the import and export commands import and export files of companies instead of serving the API
+	if len(os.Args) > 1 {
+		var command func(args []string) error
+		switch os.Args[1] {
+		case "import":
+			command = bootstrap.Import
+		case "export":
+			command = bootstrap.Export
+		}
+		if command != nil {
+			if err := command(os.Args[2:]); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+			return
+		}
+	}
+
 	if err := bootstrap.Run(); err != nil {
 		log.Fatalf("Error: %v", err)
 	}
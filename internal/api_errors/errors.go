@@ -1,12 +1,53 @@
 package apierrors
 
-import "net/http"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"xm_test/internal/i18n"
 
-// APIError represents an error that is returned to the client.
+	"golang.org/x/text/language"
+)
+
+// StatusClientClosedRequest is the non-standard status used when the client closes the connection before the
+// response is sent
+const StatusClientClosedRequest = 499
+
+// APIError represents an error that is returned to the client. The errors defined by this package are shared by
+// every request, so they must never be modified: WithMessage, WithMessagef, WithMessageID, Wrap and WithErrors
+// return new errors that keep the code of the original one, so they can still be matched with errors.Is.
+//
+// The messages of the errors defined by this package and the ones set with WithMessageID are found in the catalogs
+// of the i18n package, so they can be translated with Localize. The messages set with WithMessage are only sent in
+// English.
 type APIError struct {
 	Code       string `json:"code"`    // error code that can be used to identify the error
 	Message    string `json:"message"` // detailed description of the error
 	HTTPStatus int    `json:"-"`       // http status code. It is not included in the response body
+
+	RequestID string `json:"request_id,omitempty"` // ID of the request that failed, set when the error is written to the response
+
+	Errors []FieldError `json:"errors,omitempty"` // every invalid field of the request, when the request is invalid
+
+	cause    error        // error that caused this one. It is logged, but never sent to the client
+	message  i18n.Message // message of the catalogs, if the message can be translated
+	language language.Tag // language the error was localized to
+}
+
+// FieldError describes why a field of the body or a parameter of the request is invalid
+type FieldError struct {
+	Pointer   string `json:"pointer,omitempty"`   // JSON pointer to the invalid field of the body
+	Parameter string `json:"parameter,omitempty"` // name of the invalid query parameter
+	Message   string `json:"message"`             // why the field is invalid
+
+	message i18n.Message // message of the catalogs, if the message can be translated
+}
+
+// NewFieldError creates a field error whose message is found in the catalogs, pointing to the field of the body
+func NewFieldError(pointer string, message i18n.Message) FieldError {
+	return FieldError{Pointer: pointer, Message: message.Translate(i18n.English), message: message}
 }
 
 // NewAPIError creates a new APIError.
@@ -18,49 +59,200 @@ func NewAPIError(code string, message string, httpStatus int) *APIError {
 	}
 }
 
-// Error returns the error message.
+// Error returns the error message, followed by its cause if any. The cause is only meant to be logged: the
+// responses are built from the Message field.
 func (e *APIError) Error() string {
+	if e.cause != nil {
+		return e.Message + ": " + e.cause.Error()
+	}
 	return e.Message
 }
 
+// WithMessage returns a copy of the error with the given message, which is not translated
+func (e *APIError) WithMessage(message string) *APIError {
+	c := *e
+	c.Message = message
+	c.message = i18n.Message{}
+	return &c
+}
+
+// WithMessagef returns a copy of the error with the message formatted according to the format specifier
+func (e *APIError) WithMessagef(format string, args ...any) *APIError {
+	return e.WithMessage(fmt.Sprintf(format, args...))
+}
+
+// WithMessageID returns a copy of the error with the message of the catalogs identified by the code of the error
+// and the given variant, e.g. "id" for COMPANY_NOT_FOUND.id, interpolating the given parameters
+func (e *APIError) WithMessageID(variant string, params i18n.Params) *APIError {
+	c := *e
+	c.message = i18n.Message{ID: e.Code + "." + variant, Params: params}
+	c.Message = c.message.Translate(i18n.English)
+	return &c
+}
+
+// Localize returns a copy of the error with its message and the messages of its invalid fields translated to the
+// given language. The messages that are not found in the catalogs are kept as they are.
+func (e *APIError) Localize(tag language.Tag) *APIError {
+	c := *e
+	c.language = tag
+	if c.message.ID != "" {
+		c.Message = c.message.Translate(tag)
+	}
+
+	c.Errors = slices.Clone(e.Errors)
+	for i, fieldErr := range c.Errors {
+		if fieldErr.message.ID != "" {
+			c.Errors[i].Message = fieldErr.message.Translate(tag)
+		}
+	}
+	return &c
+}
+
+// Translate returns the message of the error in the given language, so the error can be interpolated into the
+// message of another one
+func (e *APIError) Translate(tag language.Tag) string {
+	return e.Localize(tag).Message
+}
+
+// Wrap returns a copy of the error caused by the given one. The cause is kept out of the message, so the details of
+// the lower layers are logged but never sent to the client.
+func (e *APIError) Wrap(cause error) *APIError {
+	c := *e
+	c.cause = cause
+	return &c
+}
+
+// WithErrors returns a copy of the error with the given invalid fields
+func (e *APIError) WithErrors(errs []FieldError) *APIError {
+	c := *e
+	c.Errors = slices.Clone(errs)
+	return &c
+}
+
+// Unwrap returns the error that caused this one, if any
+func (e *APIError) Unwrap() error {
+	return e.cause
+}
+
+// Is reports whether the target is an APIError with the same code, so the copies returned by WithMessage and Wrap
+// match the error they were created from
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	return ok && t.Code == e.Code
+}
+
+// FromContext returns the error matching the reason why the context is done: ErrRequestTimeout when its deadline
+// is exceeded and ErrClientClosedRequest when it is cancelled. It returns nil if the context is not done.
+func FromContext(ctx context.Context) *APIError {
+	switch err := ctx.Err(); {
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrRequestTimeout
+	case errors.Is(err, context.Canceled):
+		return ErrClientClosedRequest
+	}
+	return nil
+}
+
 var (
 
 	// ErrInternalServer is returned when an internal server error occurs.
-	ErrInternalServer = NewAPIError("INTERNAL_SERVER_ERROR", "internal server error", http.StatusInternalServerError)
+	ErrInternalServer = define("INTERNAL_SERVER_ERROR", "internal server error", http.StatusInternalServerError)
 
 	// ErrInvalidBody is returned when the request body is invalid.
-	ErrInvalidBody = NewAPIError("INVALID_BODY", "invalid request body", http.StatusBadRequest)
+	ErrInvalidBody = define("INVALID_BODY", "invalid request body", http.StatusBadRequest)
 
 	// ErrUserNotFound is returned when a user is not found.
-	ErrUserNotFound = NewAPIError("USER_NOT_FOUND", "user not found", http.StatusBadRequest)
+	ErrUserNotFound = define("USER_NOT_FOUND", "user not found", http.StatusBadRequest)
 
 	// ErrUserAlreadyExists is returned when a user already exists.
-	ErrUserAlreadyExists = NewAPIError("USER_ALREADY_EXISTS", "user already exists", http.StatusBadRequest)
+	ErrUserAlreadyExists = define("USER_ALREADY_EXISTS", "user already exists", http.StatusBadRequest)
 
 	// ErrInvalidCredentials is returned when the credentials are invalid.
-	ErrInvalidCredentials = NewAPIError("INVALID_CREDENTIALS", "invalid credentials", http.StatusUnauthorized)
+	ErrInvalidCredentials = define("INVALID_CREDENTIALS", "invalid credentials", http.StatusUnauthorized)
 
 	// ErrCompanyNotFound is returned when a company is not found.
-	ErrCompanyNotFound = NewAPIError("COMPANY_NOT_FOUND", "company not found", http.StatusBadRequest)
+	ErrCompanyNotFound = define("COMPANY_NOT_FOUND", "company not found", http.StatusNotFound)
 
 	// ErrInvalidUUID is returned when the UUID is invalid.
-	ErrInvalidUUID = NewAPIError("INVALID_UUID", "invalid UUID", http.StatusBadRequest)
+	ErrInvalidUUID = define("INVALID_UUID", "invalid UUID", http.StatusBadRequest)
 
 	// ErrTokenNotFound is returned when a token is not found.
-	ErrTokenNotFound = NewAPIError("TOKEN_NOT_FOUND", "token not found", http.StatusBadRequest)
+	ErrTokenNotFound = define("TOKEN_NOT_FOUND", "token not found", http.StatusBadRequest)
 
 	// ErrInvalidToken is returned when the token is invalid.
-	ErrInvalidToken = NewAPIError("INVALID_TOKEN", "invalid token", http.StatusUnauthorized)
+	ErrInvalidToken = define("INVALID_TOKEN", "invalid token", http.StatusUnauthorized)
 
 	// ErrUnauthorized is returned when the user is not authorized.
-	ErrUnauthorized = NewAPIError("UNAUTHORIZED", "unauthorized", http.StatusUnauthorized)
+	ErrUnauthorized = define("UNAUTHORIZED", "unauthorized", http.StatusUnauthorized)
 
 	// ErrTokenExpired is returned when the token is expired.
-	ErrTokenExpired = NewAPIError("TOKEN_EXPIRED", "token expired", http.StatusUnauthorized)
+	ErrTokenExpired = define("TOKEN_EXPIRED", "token expired", http.StatusUnauthorized)
 
 	// ErrCompanyIDRequired is returned when the company ID is required.
-	ErrCompanyIDRequired = NewAPIError("COMPANY_ID_REQUIRED", "company ID is required", http.StatusBadRequest)
+	ErrCompanyIDRequired = define("COMPANY_ID_REQUIRED", "company ID is required", http.StatusBadRequest)
 
 	// ErrCreatingEvent is returned when an error occurs while creating an event.
-	ErrCreatingEvent = NewAPIError("CREATING_EVENT", "error creating event", http.StatusInternalServerError)
+	ErrCreatingEvent = define("CREATING_EVENT", "error creating event", http.StatusInternalServerError)
+
+	// ErrInvalidEvent is returned when an event does not match its registered definition.
+	ErrInvalidEvent = define("INVALID_EVENT", "invalid event", http.StatusInternalServerError)
+
+	// ErrDeadLetterNotFound is returned when a dead-lettered event is not found.
+	ErrDeadLetterNotFound = define("DEAD_LETTER_NOT_FOUND", "dead-lettered event not found", http.StatusNotFound)
+
+	// ErrForbidden is returned when the user is not allowed to perform the operation.
+	ErrForbidden = define("FORBIDDEN", "forbidden", http.StatusForbidden)
+
+	// ErrInvalidQuery is returned when the query parameters are invalid.
+	ErrInvalidQuery = define("INVALID_QUERY", "invalid query parameters", http.StatusBadRequest)
+
+	// ErrRequestTimeout is returned when the request is not completed within the server deadline.
+	ErrRequestTimeout = define("REQUEST_TIMEOUT", "request timeout", http.StatusGatewayTimeout)
+
+	// ErrClientClosedRequest is returned when the client closes the connection before the request is completed.
+	ErrClientClosedRequest = define("CLIENT_CLOSED_REQUEST", "client closed request", StatusClientClosedRequest)
+
+	// ErrInvalidRequest is returned when the request does not match the OpenAPI document and the requests are validated.
+	ErrInvalidRequest = define("INVALID_REQUEST", "the request does not match the API specification", http.StatusBadRequest)
+
+	// ErrInvalidResponse is returned when the response does not match the OpenAPI document and the responses are validated.
+	ErrInvalidResponse = define("INVALID_RESPONSE", "the response does not match the API specification", http.StatusInternalServerError)
+
+	// ErrProblemTypeNotFound is returned when a problem type is not documented.
+	ErrProblemTypeNotFound = define("PROBLEM_TYPE_NOT_FOUND", "problem type not found", http.StatusNotFound)
+
+	// ErrUnsupportedMediaType is returned when the request body is sent with a media type that is not supported.
+	ErrUnsupportedMediaType = define("UNSUPPORTED_MEDIA_TYPE", "unsupported media type", http.StatusUnsupportedMediaType)
+
+	// ErrNotAcceptable is returned when the response cannot be sent with any of the media types accepted by the client.
+	ErrNotAcceptable = define("NOT_ACCEPTABLE", "not acceptable", http.StatusNotAcceptable)
+
+	// ErrPatchConflict is returned when a patch cannot be applied to the current state of the resource.
+	ErrPatchConflict = define("PATCH_CONFLICT", "the patch cannot be applied", http.StatusConflict)
+
+	// ErrPreconditionFailed is returned when the resource does not match the conditional headers of the request,
+	// e.g. when it was modified since the client read it.
+	ErrPreconditionFailed = define("PRECONDITION_FAILED", "precondition failed", http.StatusPreconditionFailed)
+
+	// ErrPreconditionRequired is returned when a request that modifies a resource must be conditional but is not.
+	ErrPreconditionRequired = define("PRECONDITION_REQUIRED", "precondition required", http.StatusPreconditionRequired)
+
+	// ErrInvalidIdempotencyKey is returned when the Idempotency-Key header of a request is not valid.
+	ErrInvalidIdempotencyKey = define("INVALID_IDEMPOTENCY_KEY", "invalid idempotency key", http.StatusBadRequest)
+
+	// ErrIdempotencyKeyReused is returned when an idempotency key is sent again with a different request.
+	ErrIdempotencyKeyReused = define("IDEMPOTENCY_KEY_REUSED", "the idempotency key was used by another request", http.StatusConflict)
+
+	// ErrCompanyAlreadyExists is returned when the name of a company is taken by another company.
+	ErrCompanyAlreadyExists = define("COMPANY_ALREADY_EXISTS", "company already exists", http.StatusConflict)
+
+	// ErrBulkAborted is returned for the operations of a transactional bulk request that were not applied because
+	// another operation of the request failed.
+	ErrBulkAborted = define("BULK_ABORTED", "the operation was not applied because another operation failed", http.StatusFailedDependency)
+
+	// ErrImportJobNotFound is returned when an import job is not found.
+	ErrImportJobNotFound = define("IMPORT_JOB_NOT_FOUND", "import job not found", http.StatusNotFound)
+
+	// ErrPayloadTooLarge is returned when the request body exceeds the maximum size.
+	ErrPayloadTooLarge = define("PAYLOAD_TOO_LARGE", "payload too large", http.StatusRequestEntityTooLarge)
 )
@@ -0,0 +1,142 @@
+package apierrors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"xm_test/internal/i18n"
+
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/text/language"
+)
+
+type errorsSuite struct {
+	suite.Suite
+}
+
+func (s *errorsSuite) TestConstructors() {
+	s.Run("returns copies of the sentinel", func() {
+		e := ErrCompanyNotFound.WithMessagef("company '%s' not found", "x")
+		s.NotSame(ErrCompanyNotFound, e)
+		s.Equal("company 'x' not found", e.Message)
+		s.Equal(ErrCompanyNotFound.Code, e.Code)
+		s.Equal(ErrCompanyNotFound.HTTPStatus, e.HTTPStatus)
+		s.Equal("company not found", ErrCompanyNotFound.Message)
+	})
+
+	s.Run("keeps the cause out of the response", func() {
+		cause := errors.New("connection refused")
+		e := ErrInternalServer.WithMessage("failed to create company").Wrap(cause)
+		s.Equal("failed to create company", e.Message)
+		s.Equal("failed to create company: connection refused", e.Error())
+		s.Nil(ErrInternalServer.Unwrap())
+
+		data, err := json.Marshal(e)
+		s.Require().NoError(err)
+		s.NotContains(string(data), "connection refused")
+	})
+
+	s.Run("copies the invalid fields", func() {
+		fields := []FieldError{{Pointer: "/name", Message: "name is required"}}
+		e := ErrInvalidBody.WithErrors(fields)
+		fields[0].Message = "changed"
+		s.Equal("name is required", e.Errors[0].Message)
+		s.Empty(ErrInvalidBody.Errors)
+	})
+}
+
+func (s *errorsSuite) TestMatching() {
+	cause := errors.New("connection refused")
+	err := fmt.Errorf("service: %w", ErrCompanyNotFound.WithMessage("company 'x' not found").Wrap(cause))
+
+	s.Run("matches the sentinel", func() {
+		s.ErrorIs(err, ErrCompanyNotFound)
+		s.NotErrorIs(err, ErrUserNotFound)
+	})
+
+	s.Run("matches the cause", func() {
+		s.ErrorIs(err, cause)
+	})
+
+	s.Run("extracts the API error", func() {
+		var apiError *APIError
+		s.Require().ErrorAs(err, &apiError)
+		s.Equal("company 'x' not found", apiError.Message)
+	})
+}
+
+// TestConcurrentErrors must be run with the race detector: the errors built by concurrent requests must neither
+// interfere with each other nor modify the sentinel they were created from
+func (s *errorsSuite) TestConcurrentErrors() {
+	const requests = 100
+
+	var wg sync.WaitGroup
+	errs := make([]*APIError, requests)
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = ErrCompanyNotFound.WithMessagef("company '%d' not found", i).Wrap(fmt.Errorf("cause %d", i))
+		}()
+	}
+	wg.Wait()
+
+	for i, e := range errs {
+		s.Equal(fmt.Sprintf("company '%d' not found", i), e.Message)
+		s.Equal(fmt.Sprintf("company '%d' not found: cause %d", i, i), e.Error())
+	}
+	s.Equal("company not found", ErrCompanyNotFound.Message)
+	s.Nil(ErrCompanyNotFound.Unwrap())
+}
+
+func (s *errorsSuite) TestLocalize() {
+	s.Run("finds the messages of every error in the catalogs", func() {
+		for _, t := range ProblemTypes() {
+			message, ok := i18n.Lookup(i18n.English, t.Code)
+			s.True(ok, t.Code)
+			s.Equal(t.Title, title(message), t.Code)
+		}
+	})
+
+	s.Run("translates the message and its parameters", func() {
+		e := ErrUserAlreadyExists.WithMessageID("email", i18n.Params{"email": "jane@xm.com"})
+		s.Equal("user with email 'jane@xm.com' already exists", e.Message)
+		s.Equal("ya existe un usuario con email 'jane@xm.com'", e.Localize(language.Spanish).Message)
+		s.Equal("Benutzer mit der E-Mail 'jane@xm.com' existiert bereits", e.Localize(language.German).Message)
+		s.Equal("user with email 'jane@xm.com' already exists", e.Message)
+	})
+
+	s.Run("translates the errors defined by the package", func() {
+		s.Equal("entreprise introuvable", ErrCompanyNotFound.Localize(language.French).Message)
+		s.Equal("company not found", ErrCompanyNotFound.Message)
+	})
+
+	s.Run("translates the invalid fields", func() {
+		field := NewFieldError("/email", i18n.Message{ID: "validation.email", Params: i18n.Params{"field": "email"}})
+		s.Equal("email must be a valid email address", field.Message)
+
+		e := ErrInvalidBody.WithErrors([]FieldError{field, {Parameter: "limit", Message: "must be a number"}})
+		localized := e.Localize(language.Spanish)
+		s.Equal("email debe ser una dirección de email válida", localized.Errors[0].Message)
+		s.Equal("must be a number", localized.Errors[1].Message)
+		s.Equal("email must be a valid email address", e.Errors[0].Message)
+	})
+
+	s.Run("keeps the messages that are not in the catalogs", func() {
+		e := ErrCompanyNotFound.WithMessage("company 'x' not found")
+		s.Equal("company 'x' not found", e.Localize(language.Spanish).Message)
+	})
+
+	s.Run("keeps the cause", func() {
+		cause := errors.New("connection refused")
+		e := ErrCompanyNotFound.WithMessageID("id", i18n.Params{"id": "x"}).Wrap(cause).Localize(language.Spanish)
+		s.ErrorIs(e, cause)
+		s.ErrorIs(e, ErrCompanyNotFound)
+	})
+}
+
+func TestErrorsSuite(t *testing.T) {
+	suite.Run(t, new(errorsSuite))
+}
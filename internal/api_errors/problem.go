@@ -0,0 +1,108 @@
+package apierrors
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+	"xm_test/internal/i18n"
+)
+
+const (
+	// ProblemContentType is the media type of the errors following RFC 7807
+	ProblemContentType = "application/problem+json"
+
+	// problemTypePath is the path under which the problem types are documented
+	problemTypePath = "/problems/"
+)
+
+// Problem is the RFC 7807 representation of an APIError. The code, the ID of the request and the invalid fields
+// are included as extension members.
+type Problem struct {
+	Type     string `json:"type"`               // URI reference documenting the problem type
+	Title    string `json:"title"`              // summary of the problem type, which does not change between occurrences
+	Status   int    `json:"status"`             // http status code
+	Detail   string `json:"detail,omitempty"`   // description of this occurrence of the problem
+	Instance string `json:"instance,omitempty"` // URI reference of the request that failed
+
+	Code      string       `json:"code"`                 // code of the APIError
+	RequestID string       `json:"request_id,omitempty"` // ID of the request that failed
+	Errors    []FieldError `json:"errors,omitempty"`     // every invalid field of the request, when the request is invalid
+}
+
+// ProblemType documents a type of problem returned by the API
+type ProblemType struct {
+	Type   string `json:"type"`   // URI reference of the problem type
+	Code   string `json:"code"`   // code of the APIError
+	Title  string `json:"title"`  // summary of the problem type
+	Status int    `json:"status"` // http status code
+}
+
+// problemTypes holds the problem type of each error defined by the package, keyed by code
+var problemTypes = make(map[string]ProblemType)
+
+// define creates an error and registers its problem type. The title of the problem type is its default message.
+func define(code string, message string, httpStatus int) *APIError {
+	problemTypes[code] = ProblemType{
+		Type:   TypeURI(code),
+		Code:   code,
+		Title:  title(message),
+		Status: httpStatus,
+	}
+	e := NewAPIError(code, message, httpStatus)
+	e.message = i18n.Message{ID: code}
+	return e
+}
+
+// TypeURI returns the stable URI reference of the problem type of the code, e.g. COMPANY_NOT_FOUND is documented at
+// /problems/company-not-found
+func TypeURI(code string) string {
+	return problemTypePath + strings.ToLower(strings.ReplaceAll(code, "_", "-"))
+}
+
+// ProblemTypes returns the problem types of the errors defined by the package, sorted by code
+func ProblemTypes() []ProblemType {
+	types := make([]ProblemType, 0, len(problemTypes))
+	for _, t := range problemTypes {
+		types = append(types, t)
+	}
+	slices.SortFunc(types, func(a, b ProblemType) int { return strings.Compare(a.Code, b.Code) })
+	return types
+}
+
+// LookupProblemType returns the problem type documented at /problems/{name}
+func LookupProblemType(name string) (ProblemType, bool) {
+	code := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+	t, ok := problemTypes[code]
+	return t, ok
+}
+
+// Problem returns the RFC 7807 representation of the error. Errors whose code is not defined by the package are
+// returned with the about:blank type, whose title is the text of the status. The title is translated to the
+// language the error was localized to.
+func (e *APIError) Problem(instance string) *Problem {
+	problem := &Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(e.HTTPStatus),
+		Status:    e.HTTPStatus,
+		Detail:    e.Message,
+		Instance:  instance,
+		Code:      e.Code,
+		RequestID: e.RequestID,
+		Errors:    e.Errors,
+	}
+	if t, ok := problemTypes[e.Code]; ok {
+		problem.Type, problem.Title = t.Type, t.Title
+		if template, ok := i18n.Lookup(e.language, e.Code); ok {
+			problem.Title = title(template)
+		}
+	}
+	return problem
+}
+
+// title capitalizes the message to be used as the title of a problem type
+func title(message string) string {
+	r, size := utf8.DecodeRuneInString(message)
+	return string(unicode.ToUpper(r)) + message[size:]
+}
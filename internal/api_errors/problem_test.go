@@ -0,0 +1,74 @@
+package apierrors
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/text/language"
+)
+
+type problemSuite struct {
+	suite.Suite
+}
+
+func (s *problemSuite) TestProblem() {
+	s.Run("uses the problem type of the code", func() {
+		e := NewAPIError(ErrCompanyNotFound.Code, "company 'x' not found", ErrCompanyNotFound.HTTPStatus)
+		e.RequestID = "request"
+		problem := e.Problem("/company/x")
+		s.Equal(&Problem{
+			Type:      "/problems/company-not-found",
+			Title:     "Company not found",
+			Status:    http.StatusNotFound,
+			Detail:    "company 'x' not found",
+			Instance:  "/company/x",
+			Code:      "COMPANY_NOT_FOUND",
+			RequestID: "request",
+		}, problem)
+	})
+
+	s.Run("keeps the invalid fields", func() {
+		e := NewAPIError(ErrInvalidBody.Code, "invalid", ErrInvalidBody.HTTPStatus)
+		e.Errors = []FieldError{{Pointer: "/name", Message: "name is required"}}
+		s.Equal(e.Errors, e.Problem("/").Errors)
+	})
+
+	s.Run("translates the title", func() {
+		problem := ErrCompanyNotFound.Localize(language.German).Problem("/company/x")
+		s.Equal("Unternehmen nicht gefunden", problem.Title)
+		s.Equal("Unternehmen nicht gefunden", problem.Detail)
+	})
+
+	s.Run("uses about:blank for unknown codes", func() {
+		problem := NewAPIError("UNKNOWN", "unknown", http.StatusTeapot).Problem("/")
+		s.Equal("about:blank", problem.Type)
+		s.Equal(http.StatusText(http.StatusTeapot), problem.Title)
+	})
+}
+
+func (s *problemSuite) TestProblemTypes() {
+	s.Run("documents every error", func() {
+		types := ProblemTypes()
+		s.NotEmpty(types)
+		for _, t := range types {
+			s.Equal(TypeURI(t.Code), t.Type)
+			s.NotEmpty(t.Title)
+			s.NotZero(t.Status)
+		}
+	})
+
+	s.Run("looks up the problem types by name", func() {
+		t, ok := LookupProblemType("token-expired")
+		s.True(ok)
+		s.Equal(ErrTokenExpired.Code, t.Code)
+		s.Equal(http.StatusUnauthorized, t.Status)
+
+		_, ok = LookupProblemType("unknown")
+		s.False(ok)
+	})
+}
+
+func TestProblemSuite(t *testing.T) {
+	suite.Run(t, new(problemSuite))
+}
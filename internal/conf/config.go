@@ -2,6 +2,7 @@ package conf
 
 import (
 	"fmt"
+	"time"
 	"xm_test/internal/enum"
 
 	"github.com/go-playground/validator/v10"
@@ -14,17 +15,91 @@ type Postgres struct {
 	Host       string `mapstructure:"POSTGRES_HOST" validate:"required"`
 	Port       string `mapstructure:"POSTGRES_PORT" validate:"required"`
 	User       string `mapstructure:"POSTGRES_USER" validate:"required"`
-	Password   string `mapstructure:"POSTGRES_PASSWORD" validate:"required"`
+	Password   string `mapstructure:"POSTGRES_PASSWORD" validate:"required" redact:"true"`
 	Database   string `mapstructure:"POSTGRES_DATABASE" validate:"required"`
 	InitScript string `mapstructure:"POSTGRES_INIT_SCRIPT" validate:"required"`
+
+	NotifyChannel string `mapstructure:"POSTGRES_NOTIFY_CHANNEL" validate:"required"` // Channel used to fan out events across replicas
+}
+
+// EventsRetry holds the retry policy applied when an event fails to be dispatched
+type EventsRetry struct {
+	MaxAttempts    int           `mapstructure:"EVENTS_RETRY_MAX_ATTEMPTS" validate:"required,min=1"`                  // Attempts before sending the event to the dead-letter table
+	InitialBackoff time.Duration `mapstructure:"EVENTS_RETRY_INITIAL_BACKOFF" validate:"required"`                     // Delay before the first retry
+	MaxBackoff     time.Duration `mapstructure:"EVENTS_RETRY_MAX_BACKOFF" validate:"required,gtefield=InitialBackoff"` // Maximum delay between retries
+}
+
+// Tracing holds the configuration of the OpenTelemetry traces
+type Tracing struct {
+	Exporter     enum.TracingExporter `mapstructure:"TRACING_EXPORTER" validate:"required"`                       // Where the traces are exported: none, otlp, stdout
+	ServiceName  string               `mapstructure:"TRACING_SERVICE_NAME" validate:"required"`                   // Service name attached to every span
+	SampleRatio  float64              `mapstructure:"TRACING_SAMPLE_RATIO" validate:"min=0,max=1"`                // Ratio of the new traces that are sampled
+	OTLPEndpoint string               `mapstructure:"TRACING_OTLP_ENDPOINT" validate:"required_if=Exporter otlp"` // host:port of the OTLP gRPC collector
+	OTLPInsecure bool                 `mapstructure:"TRACING_OTLP_INSECURE"`                                      // Disables TLS when connecting to the collector
+	OutputFile   string               `mapstructure:"TRACING_OUTPUT_FILE"`                                        // File written by the stdout exporter. Empty to write to stdout
+}
+
+// Log holds the configuration of the logger
+type Log struct {
+	Format enum.LogFormat `mapstructure:"LOG_FORMAT" validate:"required"` // Encoding of the logs: console, json
+
+	File           string `mapstructure:"LOG_FILE"`                               // File where the logs are written besides stdout. Empty to only write to stdout
+	FileMaxSizeMB  int    `mapstructure:"LOG_FILE_MAX_SIZE_MB" validate:"min=1"`  // Size at which the log file is rotated
+	FileMaxBackups int    `mapstructure:"LOG_FILE_MAX_BACKUPS" validate:"min=0"`  // Rotated files that are kept. Zero keeps all of them
+	FileMaxAgeDays int    `mapstructure:"LOG_FILE_MAX_AGE_DAYS" validate:"min=0"` // Days the rotated files are kept. Zero keeps them forever
+
+	SamplingInitial    int `mapstructure:"LOG_SAMPLING_INITIAL" validate:"min=0"`                                      // Identical entries logged per second before sampling. Zero disables the sampling
+	SamplingThereafter int `mapstructure:"LOG_SAMPLING_THEREAFTER" validate:"required_unless=SamplingInitial 0,min=0"` // Once sampling, only one of every N identical entries is logged
+}
+
+// OpenAPIValidation holds which messages are validated against the OpenAPI document of the http transport
+type OpenAPIValidation struct {
+	Requests  bool `mapstructure:"OPENAPI_VALIDATE_REQUESTS"`  // Rejects the requests that do not match the document
+	Responses bool `mapstructure:"OPENAPI_VALIDATE_RESPONSES"` // Replaces the responses that do not match the document by an error. Meant for tests, since the responses are buffered
+}
+
+// Import holds the limits of the company imports
+type Import struct {
+	MaxSize     int64 `mapstructure:"IMPORT_MAX_SIZE" validate:"required,min=1"`              // Maximum size in bytes of an imported file
+	SyncMaxSize int64 `mapstructure:"IMPORT_SYNC_MAX_SIZE" validate:"min=0,ltefield=MaxSize"` // Files up to this size are imported within the request, and larger ones in the background
+	BatchSize   int   `mapstructure:"IMPORT_BATCH_SIZE" validate:"required,min=1,max=1000"`   // Rows written to the database at once
 }
 
 // Config holds the configuration values for the API
 type Config struct {
-	Port       string        `mapstructure:"PORT" validate:"required"`        // Port in which the API will listen
-	HealthPort string        `mapstructure:"HEALTH_PORT" validate:"required"` // Health port in which the API will listen
-	LogLevel   enum.LogLevel `mapstructure:"LOG_LEVEL" validate:"required"`   // Log level for the API: debug, info
-	JwtSecret  string        `mapstructure:"JWT_SECRET" validate:"required"`  // JWT secret key
+	Port       string        `mapstructure:"PORT" validate:"required"`                     // Port in which the API will listen
+	HealthPort string        `mapstructure:"HEALTH_PORT" validate:"required"`              // Health port in which the API will listen
+	GrpcPort   string        `mapstructure:"GRPC_PORT" validate:"required"`                // Port in which the gRPC server will listen
+	LogLevel   enum.LogLevel `mapstructure:"LOG_LEVEL" validate:"required"`                // Log level for the API: debug, info, warn, error
+	JwtSecret  string        `mapstructure:"JWT_SECRET" validate:"required" redact:"true"` // JWT secret key
+
+	Log Log `mapstructure:",squash"` // Logger configuration
+
+	Transports []enum.TransportType `mapstructure:"TRANSPORTS" validate:"required,min=1"` // Transports served by the API: http, grpc
+
+	AdminEmails []string `mapstructure:"ADMIN_EMAILS" redact:"email"` // Emails of the users allowed to call the admin endpoints
+
+	ShutdownTimeout time.Duration `mapstructure:"SHUTDOWN_TIMEOUT" validate:"required"` // Deadline to drain the requests and pending events on shutdown
+	RequestTimeout  time.Duration `mapstructure:"REQUEST_TIMEOUT"`                      // Deadline of each request. Zero disables it
+
+	MaxBodySize int64 `mapstructure:"MAX_BODY_SIZE" validate:"required,min=1"` // Maximum size in bytes of the JSON request bodies read in memory
+
+	BulkMaxOperations int `mapstructure:"BULK_MAX_OPERATIONS" validate:"required,min=1,max=1000"` // Maximum amount of operations of a bulk request, up to the limit of the company service
+
+	RequireIfMatch bool `mapstructure:"REQUIRE_IF_MATCH"` // Rejects the requests modifying a company without an If-Match header, or without its version in gRPC and GraphQL
+
+	IdempotencyKeyTTL time.Duration `mapstructure:"IDEMPOTENCY_KEY_TTL" validate:"required"` // Time during which the response to a request sent with an Idempotency-Key header is replayed
+
+	HealthCheckTimeout  time.Duration `mapstructure:"HEALTH_CHECK_TIMEOUT" validate:"required"`   // Maximum time spent by each readiness check
+	HealthCheckCacheTTL time.Duration `mapstructure:"HEALTH_CHECK_CACHE_TTL" validate:"required"` // Time during which the result of a readiness check is reused
+
+	EventsRetry EventsRetry `mapstructure:",squash"` // Retry policy applied when dispatching events
+
+	OpenAPIValidation OpenAPIValidation `mapstructure:",squash"` // Validation of the http messages against the OpenAPI document
+
+	Import Import `mapstructure:",squash"` // Limits of the company imports
+
+	Tracing Tracing `mapstructure:",squash"` // OpenTelemetry traces configuration
 
 	DatabaseType enum.DatabaseType `mapstructure:"DATABASE_TYPE" validate:"required"` // Database type. Default: postgres
 	Postgres     Postgres          // Database configuration
@@ -43,6 +118,23 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("invalid log level: %s", c.LogLevel)
 	}
 
+	// check transport enums
+	for _, transport := range c.Transports {
+		if !transport.IsValid() {
+			return fmt.Errorf("invalid transport: %s", transport)
+		}
+	}
+
+	// check log format enum
+	if !c.Log.Format.IsValid() {
+		return fmt.Errorf("invalid log format: %s", c.Log.Format)
+	}
+
+	// check tracing exporter enum
+	if !c.Tracing.Exporter.IsValid() {
+		return fmt.Errorf("invalid tracing exporter: %s", c.Tracing.Exporter)
+	}
+
 	// check database type enum
 	if !c.DatabaseType.IsValid() {
 		return fmt.Errorf("invalid database type: %s", c.DatabaseType)
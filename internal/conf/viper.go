@@ -2,6 +2,7 @@ package conf
 
 import (
 	"fmt"
+	"strings"
 	"xm_test/internal/enum"
 
 	"github.com/spf13/viper"
@@ -19,6 +20,7 @@ func SetupConfig() error {
 	if err := viper.Unmarshal(cfg); err != nil {
 		return fmt.Errorf("bootstrap: config: failed to unmarshal configuration: %v", err)
 	}
+	cfg.AdminEmails = trimList(cfg.AdminEmails)
 
 	// set database configuration
 	if err := setDatabaseConfig(cfg); err != nil {
@@ -44,13 +46,62 @@ func setDatabaseConfig(cfg *Config) error {
 
 }
 
+// trimList trims the entries of a comma-separated list, so that they can be separated by spaces as well, and drops
+// the empty ones
+func trimList(values []string) []string {
+	trimmed := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			trimmed = append(trimmed, value)
+		}
+	}
+	return trimmed
+}
+
 // setDefaults is a function that sets the default values for the API configuration.
 func setDefaults() {
 	viper.SetDefault("LOG_LEVEL", "info")
+	viper.SetDefault("LOG_FORMAT", "console")
+	viper.SetDefault("LOG_FILE", "")
+	viper.SetDefault("LOG_FILE_MAX_SIZE_MB", 100)
+	viper.SetDefault("LOG_FILE_MAX_BACKUPS", 5)
+	viper.SetDefault("LOG_FILE_MAX_AGE_DAYS", 30)
+	viper.SetDefault("LOG_SAMPLING_INITIAL", 0)
+	viper.SetDefault("LOG_SAMPLING_THEREAFTER", 100)
 	viper.SetDefault("HEALTH_PORT", "8081")
 	viper.SetDefault("PORT", "8080")
+	viper.SetDefault("GRPC_PORT", "9090")
+	viper.SetDefault("TRANSPORTS", "http")
 	viper.SetDefault("DATABASE_TYPE", "postgres")
 	viper.SetDefault("JWT_SECRET", "secret")
+	viper.SetDefault("ADMIN_EMAILS", "")
+	viper.SetDefault("SHUTDOWN_TIMEOUT", "30s")
+	viper.SetDefault("REQUEST_TIMEOUT", "15s")
+	viper.SetDefault("MAX_BODY_SIZE", 1048576)
+	viper.SetDefault("REQUIRE_IF_MATCH", false)
+	viper.SetDefault("IDEMPOTENCY_KEY_TTL", "24h")
+	viper.SetDefault("HEALTH_CHECK_TIMEOUT", "2s")
+	viper.SetDefault("HEALTH_CHECK_CACHE_TTL", "5s")
+
+	viper.SetDefault("EVENTS_RETRY_MAX_ATTEMPTS", 5)
+	viper.SetDefault("EVENTS_RETRY_INITIAL_BACKOFF", "200ms")
+	viper.SetDefault("EVENTS_RETRY_MAX_BACKOFF", "10s")
+
+	viper.SetDefault("OPENAPI_VALIDATE_REQUESTS", false)
+	viper.SetDefault("OPENAPI_VALIDATE_RESPONSES", false)
+
+	viper.SetDefault("BULK_MAX_OPERATIONS", 1000)
+
+	viper.SetDefault("IMPORT_MAX_SIZE", 104857600)
+	viper.SetDefault("IMPORT_SYNC_MAX_SIZE", 1048576)
+	viper.SetDefault("IMPORT_BATCH_SIZE", 500)
+
+	viper.SetDefault("TRACING_EXPORTER", "none")
+	viper.SetDefault("TRACING_SERVICE_NAME", "xm_test")
+	viper.SetDefault("TRACING_SAMPLE_RATIO", 1)
+	viper.SetDefault("TRACING_OTLP_ENDPOINT", "localhost:4317")
+	viper.SetDefault("TRACING_OTLP_INSECURE", false)
+	viper.SetDefault("TRACING_OUTPUT_FILE", "")
 
 	viper.SetDefault("POSTGRES_HOST", "localhost")
 	viper.SetDefault("POSTGRES_PORT", "5432")
@@ -58,4 +109,5 @@ func setDefaults() {
 	viper.SetDefault("POSTGRES_PASSWORD", "password")
 	viper.SetDefault("POSTGRES_DATABASE", "xm")
 	viper.SetDefault("POSTGRES_INIT_SCRIPT", "_db_schema/postgres/schema.sql")
+	viper.SetDefault("POSTGRES_NOTIFY_CHANNEL", "events")
 }
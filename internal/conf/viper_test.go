@@ -0,0 +1,23 @@
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type viperSuite struct {
+	suite.Suite
+}
+
+func (s *viperSuite) TestSetupConfig() {
+	s.Run("trims the admin emails", func() {
+		s.T().Setenv("ADMIN_EMAILS", "a@x.com, b@x.com ,,")
+		s.Require().NoError(SetupConfig())
+		s.Equal([]string{"a@x.com", "b@x.com"}, GlobalConfig.AdminEmails)
+	})
+}
+
+func TestViperSuite(t *testing.T) {
+	suite.Run(t, new(viperSuite))
+}
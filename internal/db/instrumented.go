@@ -0,0 +1,177 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/db/models"
+	"xm_test/internal/metrics"
+)
+
+// instrumentedAdapter wraps a DatabaseAdapter recording the latency and the errors of each operation
+type instrumentedAdapter struct {
+	DatabaseAdapter
+}
+
+// newInstrumentedAdapter returns the adapter wrapped with the database metrics
+func newInstrumentedAdapter(adapter DatabaseAdapter) *instrumentedAdapter {
+	return &instrumentedAdapter{DatabaseAdapter: adapter}
+}
+
+// notFoundErrors are the errors returned when the requested row does not exist. They are the expected outcome of
+// the lookups of missing entities, so they are not counted as failed operations.
+var notFoundErrors = []error{
+	apierrors.ErrCompanyNotFound,
+	apierrors.ErrUserNotFound,
+	apierrors.ErrDeadLetterNotFound,
+	apierrors.ErrImportJobNotFound,
+}
+
+// observe records the latency of the operation and whether it failed
+func observe(method string, start time.Time, err *error) {
+	metrics.DatabaseQueryDuration.WithLabelValues(method).Observe(time.Since(start).Seconds())
+	if *err != nil && !isNotFound(*err) {
+		metrics.DatabaseQueryErrors.WithLabelValues(method).Inc()
+	}
+}
+
+// isNotFound tells whether the error reports a missing row
+func isNotFound(err error) bool {
+	for _, notFound := range notFoundErrors {
+		if errors.Is(err, notFound) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *instrumentedAdapter) Ping(ctx context.Context) (err error) {
+	defer observe("Ping", time.Now(), &err)
+	return a.DatabaseAdapter.Ping(ctx)
+}
+
+func (a *instrumentedAdapter) CheckSchema(ctx context.Context) (err error) {
+	defer observe("CheckSchema", time.Now(), &err)
+	return a.DatabaseAdapter.CheckSchema(ctx)
+}
+
+func (a *instrumentedAdapter) CreateUser(ctx context.Context, user *models.UserModel) (err error) {
+	defer observe("CreateUser", time.Now(), &err)
+	return a.DatabaseAdapter.CreateUser(ctx, user)
+}
+
+func (a *instrumentedAdapter) GetUserByEmail(ctx context.Context, email string) (user *models.UserModel, err error) {
+	defer observe("GetUserByEmail", time.Now(), &err)
+	return a.DatabaseAdapter.GetUserByEmail(ctx, email)
+}
+
+func (a *instrumentedAdapter) CreateCompany(ctx context.Context, company *models.CompanyModel) (err error) {
+	defer observe("CreateCompany", time.Now(), &err)
+	return a.DatabaseAdapter.CreateCompany(ctx, company)
+}
+
+func (a *instrumentedAdapter) GetCompanyByID(ctx context.Context, id string) (company *models.CompanyModel, err error) {
+	defer observe("GetCompanyByID", time.Now(), &err)
+	return a.DatabaseAdapter.GetCompanyByID(ctx, id)
+}
+
+func (a *instrumentedAdapter) UpdateCompany(ctx context.Context, id string, updateCompany *models.CompanyModel, version int) (company *models.CompanyModel, err error) {
+	defer observe("UpdateCompany", time.Now(), &err)
+	return a.DatabaseAdapter.UpdateCompany(ctx, id, updateCompany, version)
+}
+
+func (a *instrumentedAdapter) PatchCompany(ctx context.Context, id string, patch *models.CompanyPatch, version int) (company *models.CompanyModel, err error) {
+	defer observe("PatchCompany", time.Now(), &err)
+	return a.DatabaseAdapter.PatchCompany(ctx, id, patch, version)
+}
+
+func (a *instrumentedAdapter) DeleteCompany(ctx context.Context, id string, version int) (err error) {
+	defer observe("DeleteCompany", time.Now(), &err)
+	return a.DatabaseAdapter.DeleteCompany(ctx, id, version)
+}
+
+func (a *instrumentedAdapter) ListCompanies(ctx context.Context, filter *models.CompanyFilter) (companies []*models.CompanyModel, err error) {
+	defer observe("ListCompanies", time.Now(), &err)
+	return a.DatabaseAdapter.ListCompanies(ctx, filter)
+}
+
+func (a *instrumentedAdapter) StreamCompanies(ctx context.Context, filter *models.CompanyFilter, handler func(company *models.CompanyModel) error) (err error) {
+	defer observe("StreamCompanies", time.Now(), &err)
+	return a.DatabaseAdapter.StreamCompanies(ctx, filter, handler)
+}
+
+func (a *instrumentedAdapter) GetCompaniesByIDs(ctx context.Context, ids []string) (companies []*models.CompanyModel, err error) {
+	defer observe("GetCompaniesByIDs", time.Now(), &err)
+	return a.DatabaseAdapter.GetCompaniesByIDs(ctx, ids)
+}
+
+func (a *instrumentedAdapter) BulkWriteCompanies(ctx context.Context, ops []*models.CompanyOperation, transactional bool) (err error) {
+	defer observe("BulkWriteCompanies", time.Now(), &err)
+	return a.DatabaseAdapter.BulkWriteCompanies(ctx, ops, transactional)
+}
+
+func (a *instrumentedAdapter) CreateEvent(ctx context.Context, event *models.EventModel) (err error) {
+	defer observe("CreateEvent", time.Now(), &err)
+	return a.DatabaseAdapter.CreateEvent(ctx, event)
+}
+
+func (a *instrumentedAdapter) GetEventTypes(ctx context.Context) (types []string, err error) {
+	defer observe("GetEventTypes", time.Now(), &err)
+	return a.DatabaseAdapter.GetEventTypes(ctx)
+}
+
+func (a *instrumentedAdapter) UpsertDeadLetterEvent(ctx context.Context, event *models.DeadLetterEventModel) (err error) {
+	defer observe("UpsertDeadLetterEvent", time.Now(), &err)
+	return a.DatabaseAdapter.UpsertDeadLetterEvent(ctx, event)
+}
+
+func (a *instrumentedAdapter) ListDeadLetterEvents(ctx context.Context, limit int, offset int) (events []*models.DeadLetterEventModel, err error) {
+	defer observe("ListDeadLetterEvents", time.Now(), &err)
+	return a.DatabaseAdapter.ListDeadLetterEvents(ctx, limit, offset)
+}
+
+func (a *instrumentedAdapter) GetDeadLetterEvent(ctx context.Context, id string) (event *models.DeadLetterEventModel, err error) {
+	defer observe("GetDeadLetterEvent", time.Now(), &err)
+	return a.DatabaseAdapter.GetDeadLetterEvent(ctx, id)
+}
+
+func (a *instrumentedAdapter) DeleteDeadLetterEvent(ctx context.Context, id string) (err error) {
+	defer observe("DeleteDeadLetterEvent", time.Now(), &err)
+	return a.DatabaseAdapter.DeleteDeadLetterEvent(ctx, id)
+}
+
+func (a *instrumentedAdapter) ReserveIdempotencyKey(ctx context.Context, key *models.IdempotencyKeyModel) (existing *models.IdempotencyKeyModel, err error) {
+	defer observe("ReserveIdempotencyKey", time.Now(), &err)
+	return a.DatabaseAdapter.ReserveIdempotencyKey(ctx, key)
+}
+
+func (a *instrumentedAdapter) CompleteIdempotencyKey(ctx context.Context, key *models.IdempotencyKeyModel) (err error) {
+	defer observe("CompleteIdempotencyKey", time.Now(), &err)
+	return a.DatabaseAdapter.CompleteIdempotencyKey(ctx, key)
+}
+
+func (a *instrumentedAdapter) ReleaseIdempotencyKey(ctx context.Context, userID string, key string) (err error) {
+	defer observe("ReleaseIdempotencyKey", time.Now(), &err)
+	return a.DatabaseAdapter.ReleaseIdempotencyKey(ctx, userID, key)
+}
+
+func (a *instrumentedAdapter) CreateImportJob(ctx context.Context, job *models.ImportJobModel) (err error) {
+	defer observe("CreateImportJob", time.Now(), &err)
+	return a.DatabaseAdapter.CreateImportJob(ctx, job)
+}
+
+func (a *instrumentedAdapter) GetImportJob(ctx context.Context, id string) (job *models.ImportJobModel, err error) {
+	defer observe("GetImportJob", time.Now(), &err)
+	return a.DatabaseAdapter.GetImportJob(ctx, id)
+}
+
+func (a *instrumentedAdapter) SaveImportProgress(ctx context.Context, job *models.ImportJobModel, rows []*models.ImportRowModel) (err error) {
+	defer observe("SaveImportProgress", time.Now(), &err)
+	return a.DatabaseAdapter.SaveImportProgress(ctx, job, rows)
+}
+
+func (a *instrumentedAdapter) StreamImportRows(ctx context.Context, jobID string, handler func(row *models.ImportRowModel) error) (err error) {
+	defer observe("StreamImportRows", time.Now(), &err)
+	return a.DatabaseAdapter.StreamImportRows(ctx, jobID, handler)
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/db/models"
+	"xm_test/internal/i18n"
+	"xm_test/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+// failingAdapter fails the company lookups with the given error
+type failingAdapter struct {
+	DatabaseAdapter
+	err error
+}
+
+func (a *failingAdapter) GetCompanyByID(ctx context.Context, id string) (*models.CompanyModel, error) {
+	return nil, a.err
+}
+
+type instrumentedSuite struct {
+	suite.Suite
+}
+
+func (s *instrumentedSuite) TestObserve() {
+	errorsOf := func(err error) float64 {
+		before := testutil.ToFloat64(metrics.DatabaseQueryErrors.WithLabelValues("GetCompanyByID"))
+		adapter := newInstrumentedAdapter(&failingAdapter{err: err})
+		_, _ = adapter.GetCompanyByID(context.Background(), "id")
+		return testutil.ToFloat64(metrics.DatabaseQueryErrors.WithLabelValues("GetCompanyByID")) - before
+	}
+
+	s.Run("counts the failures", func() {
+		s.Equal(1.0, errorsOf(errors.New("connection refused")))
+	})
+
+	s.Run("does not count the missing rows", func() {
+		s.Equal(0.0, errorsOf(apierrors.ErrCompanyNotFound.WithMessageID("id", i18n.Params{"id": "id"})))
+	})
+}
+
+func TestInstrumentedSuite(t *testing.T) {
+	suite.Run(t, new(instrumentedSuite))
+}
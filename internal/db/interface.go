@@ -19,6 +19,8 @@ type DatabaseAdapter interface {
 	// Connection operations
 	Connect(ctx context.Context, opts ...func(*options.DatabaseOptions)) error
 	Close(ctx context.Context) error
+	Ping(ctx context.Context) error
+	CheckSchema(ctx context.Context) error
 
 	// auth table operations
 	CreateUser(ctx context.Context, user *models.UserModel) error
@@ -27,14 +29,39 @@ type DatabaseAdapter interface {
 	// company table operations
 	CreateCompany(ctx context.Context, company *models.CompanyModel) error
 	GetCompanyByID(ctx context.Context, id string) (*models.CompanyModel, error)
-	UpdateCompany(ctx context.Context, id string, updateCompany *models.CompanyModel) error
-	DeleteCompany(ctx context.Context, id string) error
+	UpdateCompany(ctx context.Context, id string, updateCompany *models.CompanyModel, version int) (*models.CompanyModel, error)
+	PatchCompany(ctx context.Context, id string, patch *models.CompanyPatch, version int) (*models.CompanyModel, error)
+	DeleteCompany(ctx context.Context, id string, version int) error
+	ListCompanies(ctx context.Context, filter *models.CompanyFilter) ([]*models.CompanyModel, error)
+	StreamCompanies(ctx context.Context, filter *models.CompanyFilter, handler func(company *models.CompanyModel) error) error
+	GetCompaniesByIDs(ctx context.Context, ids []string) ([]*models.CompanyModel, error)
+	BulkWriteCompanies(ctx context.Context, ops []*models.CompanyOperation, transactional bool) error
 
 	// events table operations
 	CreateEvent(ctx context.Context, event *models.EventModel) error
+	ListenEvents(ctx context.Context, handler func(event *models.EventModel), onStatusChange func(listening bool)) error
+	GetEventTypes(ctx context.Context) ([]string, error)
+
+	// dead-letter events table operations
+	UpsertDeadLetterEvent(ctx context.Context, event *models.DeadLetterEventModel) error
+	ListDeadLetterEvents(ctx context.Context, limit int, offset int) ([]*models.DeadLetterEventModel, error)
+	GetDeadLetterEvent(ctx context.Context, id string) (*models.DeadLetterEventModel, error)
+	DeleteDeadLetterEvent(ctx context.Context, id string) error
+
+	// idempotency keys table operations
+	ReserveIdempotencyKey(ctx context.Context, key *models.IdempotencyKeyModel) (*models.IdempotencyKeyModel, error)
+	CompleteIdempotencyKey(ctx context.Context, key *models.IdempotencyKeyModel) error
+	ReleaseIdempotencyKey(ctx context.Context, userID string, key string) error
+
+	// import jobs table operations
+	CreateImportJob(ctx context.Context, job *models.ImportJobModel) error
+	GetImportJob(ctx context.Context, id string) (*models.ImportJobModel, error)
+	SaveImportProgress(ctx context.Context, job *models.ImportJobModel, rows []*models.ImportRowModel) error
+	StreamImportRows(ctx context.Context, jobID string, handler func(row *models.ImportRowModel) error) error
 }
 
-// NewDatabaseAdapter returns a new DatabaseAdapter instance.
+// NewDatabaseAdapter returns a new DatabaseAdapter instance. Every operation of the adapter is instrumented with
+// the database metrics.
 func NewDatabaseAdapter(logger *zap.SugaredLogger, opts ...func(*options.DatabaseOptions)) DatabaseAdapter {
 	switch conf.GlobalConfig.DatabaseType {
 	case enum.Postgres:
@@ -45,7 +72,7 @@ func NewDatabaseAdapter(logger *zap.SugaredLogger, opts ...func(*options.Databas
 			logger.Fatalf("failed to connect to database: %s", err)
 			return nil
 		}
-		return db
+		return newInstrumentedAdapter(db)
 	default:
 		logger.Fatalf("database type '%s' not supported", conf.GlobalConfig.DatabaseType)
 		return nil
@@ -1,6 +1,10 @@
 package models
 
 import (
+	"encoding/json"
+	"time"
+	"xm_test/internal/enum"
+
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 )
@@ -13,19 +17,135 @@ type CompanyModel struct {
 	AmountEmployees int       `json:"amount_employees" db:"amount_employees"`
 	Registered      bool      `json:"registered" db:"registered"`
 	Type            string    `json:"type" db:"type"`
+	Version         int       `json:"-" db:"version"` // Incremented on every update, the clients see it as the ETag
+}
+
+// CompanyPatch represents the columns of a company to update. The columns whose field is nil are kept.
+type CompanyPatch struct {
+	Name            *string
+	Description     *string
+	AmountEmployees *int
+	Registered      *bool
+	Type            *string
+}
+
+// IsEmpty tells whether the patch does not update any column
+func (p *CompanyPatch) IsEmpty() bool {
+	return p.Name == nil && p.Description == nil && p.AmountEmployees == nil && p.Registered == nil && p.Type == nil
+}
+
+// CompanyFilter represents the filters of the company listing and export. Companies are sorted by ID, and the listing
+// continues after the company with the After ID when it is set.
+type CompanyFilter struct {
+	Name       string     // Case-insensitive substring of the name
+	Type       string     // Company type
+	Registered *bool      // Whether the company is registered
+	After      *uuid.UUID // ID of the last company of the previous page
+	Limit      int        // Maximum amount of companies returned, or zero to return all of them
+}
+
+// CompanyPage represents a page of the company listing
+type CompanyPage struct {
+	Companies   []*CompanyModel // Companies of the page, sorted by ID
+	HasNextPage bool            // Whether there are more companies after the page
+}
+
+// CompanyOperation represents a write of a company in a bulk operation, along with its outcome. Exactly one of
+// Result and Err is set once the bulk operation completes.
+type CompanyOperation struct {
+	Type    enum.BulkOperation
+	Company *CompanyModel // Company to create or update. Only its ID is used by the deletes
+	Version int           // Version the company must be at to be updated or deleted, or zero for any version
+	Result  *CompanyModel // Company as written, or as it was before being deleted
+	Err     error         // Why the operation failed
 }
 
 // UserModel represents the user model
 type UserModel struct {
 	ID          uuid.UUID `json:"id" db:"id"`
-	Email       string    `json:"email" db:"email"`
-	EncPassword string    `json:"enc_password" db:"enc_password"`
+	Email       string    `json:"email" db:"email" redact:"email"`
+	EncPassword string    `json:"enc_password" db:"enc_password" redact:"true"`
 }
 
 // EventModel represents the event model
 type EventModel struct {
 	Type      string             `json:"type" db:"type"`
+	Version   int                `json:"version" db:"version"`
 	Timestamp pgtype.Timestamptz `json:"timestamp" db:"timestamp"`
 	ID        uuid.UUID          `json:"id" db:"id"`
 	EntityID  uuid.UUID          `json:"entity_id" db:"entity_id"`
+	Payload   json.RawMessage    `json:"payload" db:"payload"`
+
+	TraceParent string `json:"traceparent,omitempty" db:"trace_parent"`
+	TraceState  string `json:"tracestate,omitempty" db:"trace_state"`
+}
+
+// DeadLetterEventModel represents an event that could not be dispatched after exhausting its retries
+type DeadLetterEventModel struct {
+	ID        uuid.UUID       `json:"id" db:"id"`
+	Type      string          `json:"type" db:"type"`
+	Version   int             `json:"version" db:"version"`
+	Timestamp time.Time       `json:"timestamp" db:"timestamp"`
+	EntityID  uuid.UUID       `json:"entity_id" db:"entity_id"`
+	Payload   json.RawMessage `json:"payload" db:"payload"`
+	LastError string          `json:"last_error" db:"last_error"`
+	Attempts  int             `json:"attempts" db:"attempts"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at" db:"updated_at"`
+
+	TraceParent string `json:"traceparent,omitempty" db:"trace_parent"`
+	TraceState  string `json:"tracestate,omitempty" db:"trace_state"`
+}
+
+// IdempotencyKeyModel represents the response to the request sent by a user with an idempotency key. The key is
+// reserved while the request is processed, and the response is stored once it completes.
+type IdempotencyKeyModel struct {
+	UserID      uuid.UUID           `json:"user_id" db:"user_id"`
+	Key         string              `json:"key" db:"key"`
+	RequestHash string              `json:"request_hash" db:"request_hash"` // SHA-256 of the method, path and body of the request
+	Completed   bool                `json:"completed" db:"completed"`       // Whether the response is stored
+	Status      int                 `json:"status" db:"status"`
+	Headers     map[string][]string `json:"headers" db:"headers"`
+	Body        []byte              `json:"body" db:"body"`
+	LockedUntil time.Time           `json:"locked_until" db:"locked_until"` // Time after which a pending request is considered abandoned
+	ExpiresAt   time.Time           `json:"expires_at" db:"expires_at"`
+	CreatedAt   time.Time           `json:"created_at" db:"created_at"`
+}
+
+// ImportJobModel represents the import of the companies of a file. The counters are updated as the rows are
+// processed, so the progress of the imports running in the background can be polled.
+type ImportJobModel struct {
+	ID        uuid.UUID         `json:"id" db:"id"`
+	UserID    uuid.UUID         `json:"-" db:"user_id"` // User that imported the file, the only one allowed to see the job
+	Format    enum.ImportFormat `json:"format" db:"format"`
+	Status    enum.ImportStatus `json:"status" db:"status"`
+	Size      int64             `json:"size" db:"size"`             // Size of the file in bytes
+	BytesRead int64             `json:"bytes_read" db:"bytes_read"` // Bytes of the file processed so far
+	Processed int               `json:"processed" db:"processed"`   // Rows processed so far
+	Accepted  int               `json:"accepted" db:"accepted"`     // Rows whose company was created
+	Rejected  int               `json:"rejected" db:"rejected"`     // Rows that were invalid or whose company could not be created
+	Error     string            `json:"error,omitempty" db:"error"` // Why the job failed before the end of the file
+	CreatedAt time.Time         `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at" db:"updated_at"`
+}
+
+// Progress returns the percentage of the file processed by the job
+func (j *ImportJobModel) Progress() int {
+	switch {
+	case j.Status == enum.ImportCompleted:
+		return 100
+	case j.Size > 0:
+		return int(j.BytesRead * 100 / j.Size)
+	}
+	return 0
+}
+
+// ImportRowModel represents the outcome of a row of an imported file
+type ImportRowModel struct {
+	JobID        uuid.UUID  `json:"-" db:"job_id"`
+	Line         int        `json:"line" db:"line"` // Line of the row in the file
+	Accepted     bool       `json:"accepted" db:"accepted"`
+	CompanyID    *uuid.UUID `json:"company_id,omitempty" db:"company_id"`       // ID of the company created
+	ErrorCode    string     `json:"error_code,omitempty" db:"error_code"`       // Code of the error that rejected the row
+	ErrorMessage string     `json:"error_message,omitempty" db:"error_message"` // Message of the error that rejected the row, in English
 }
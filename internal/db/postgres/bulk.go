@@ -0,0 +1,240 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"slices"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/db/models"
+	"xm_test/internal/enum"
+	"xm_test/internal/i18n"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// companyColumns are the columns copied when the companies are created in bulk
+var companyColumns = []string{"id", "name", "description", "amount_employees", "registered", "type", "version"}
+
+// BulkWriteCompanies is a method that applies the operations to the company table, setting the result or the error
+// of each of them. When the write is transactional, the operations are applied all together or not at all: when
+// one of them fails, the others fail with ErrBulkAborted. Otherwise, the failing operations are skipped and the
+// others are applied. The returned error is only set when the database could not be reached.
+func (p *postgresDB) BulkWriteCompanies(ctx context.Context, ops []*models.CompanyOperation, transactional bool) error {
+	p.logger.Debugf("writing %d companies in bulk", len(ops))
+	if !transactional {
+		if err := p.writeEach(ctx, ops); err != nil {
+			return err
+		}
+		p.logger.Debugf("wrote %d companies in bulk", len(ops))
+		return nil
+	}
+
+	// the companies are copied when they are only created, which is much faster than inserting them one by one
+	if !slices.ContainsFunc(ops, func(op *models.CompanyOperation) bool { return op.Type != enum.BulkCreate }) {
+		copied, err := p.copyCompanies(ctx, ops)
+		if err != nil {
+			return err
+		}
+		if copied {
+			p.logger.Debugf("copied %d companies in bulk", len(ops))
+			return nil
+		}
+		p.logger.Debugf("failed to copy the companies, inserting them one by one to find the failing one")
+	}
+
+	failed, err := p.writeBatch(ctx, ops)
+	if err != nil {
+		return err
+	}
+	if failed >= 0 {
+		for i, op := range ops {
+			if i != failed {
+				op.Err = apierrors.ErrBulkAborted.WithMessageID("index", i18n.Params{"index": failed})
+			}
+		}
+		p.logger.Debugf("bulk write aborted by operation %d: %s", failed, ops[failed].Err)
+		return nil
+	}
+	p.logger.Debugf("wrote %d companies in bulk", len(ops))
+	return nil
+}
+
+// copyCompanies creates the companies of the operations with the copy protocol in a transaction, and tells whether
+// they were created. When a company cannot be created, e.g. because its name is taken, the copy does not tell which
+// one, so the transaction is rolled back and false is returned.
+func (p *postgresDB) copyCompanies(ctx context.Context, ops []*models.CompanyOperation) (bool, error) {
+	tx, err := p.client.Begin(ctx)
+	if err != nil {
+		return false, apierrors.ErrInternalServer.WithMessage("failed to copy companies").Wrap(err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows := pgx.CopyFromSlice(len(ops), func(i int) ([]any, error) {
+		c := ops[i].Company
+		return []any{c.ID, c.Name, c.Description, c.AmountEmployees, c.Registered, c.Type, 1}, nil
+	})
+	if _, err := tx.CopyFrom(ctx, pgx.Identifier{"company"}, companyColumns, rows); err != nil {
+		var e *pgconn.PgError
+		if errors.As(err, &e) {
+			return false, nil
+		}
+		return false, apierrors.ErrInternalServer.WithMessage("failed to copy companies").Wrap(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, apierrors.ErrInternalServer.WithMessage("failed to copy companies").Wrap(err)
+	}
+
+	for _, op := range ops {
+		company := *op.Company
+		company.Version = 1
+		op.Result = &company
+	}
+	return true, nil
+}
+
+// writeBatch sends the operations as a single batch in a transaction, which is committed when every operation
+// succeeds. Otherwise, the transaction is rolled back, and the index of the first failing operation is returned
+// once its error is set. It returns -1 when the operations are applied, and their results are set.
+func (p *postgresDB) writeBatch(ctx context.Context, ops []*models.CompanyOperation) (int, error) {
+	tx, err := p.client.Begin(ctx)
+	if err != nil {
+		return 0, apierrors.ErrInternalServer.WithMessage("failed to write companies in bulk").Wrap(err)
+	}
+	defer tx.Rollback(ctx)
+
+	batch := &pgx.Batch{}
+	for _, op := range ops {
+		cmd, args := operationCommand(op)
+		p.logger.Debugf("cmd: %s", cmd)
+		batch.Queue(cmd, args)
+	}
+
+	results := tx.SendBatch(ctx, batch)
+	written := make([]*models.CompanyModel, len(ops))
+	for i, op := range ops {
+		rows, err := results.Query()
+		companies := make([]models.CompanyModel, 0, 1)
+		if err == nil {
+			err = pgxscan.ScanAll(&companies, rows)
+		}
+
+		// the errors of the statements are caused by the operation, while the others mean the database is unreachable
+		var e *pgconn.PgError
+		switch {
+		case errors.As(err, &e):
+			results.Close()
+			op.Err = companyError(err, op.Company.Name, "failed to write company "+op.Company.ID.String())
+			return i, nil
+		case err != nil:
+			results.Close()
+			return 0, apierrors.ErrInternalServer.WithMessage("failed to write companies in bulk").Wrap(err)
+		case len(companies) == 0:
+			results.Close()
+			op.Err = p.notWritten(ctx, tx, op.Company.ID.String(), op.Version)
+			return i, nil
+		}
+		written[i] = &companies[0]
+	}
+	if err := results.Close(); err != nil {
+		return 0, apierrors.ErrInternalServer.WithMessage("failed to write companies in bulk").Wrap(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, apierrors.ErrInternalServer.WithMessage("failed to write companies in bulk").Wrap(err)
+	}
+
+	for i, op := range ops {
+		op.Result = written[i]
+	}
+	return -1, nil
+}
+
+// writeEach applies the operations one by one in a transaction. Each operation is applied within a savepoint, so a
+// failing operation is rolled back alone, without aborting the transaction, and the others are committed together.
+func (p *postgresDB) writeEach(ctx context.Context, ops []*models.CompanyOperation) error {
+	tx, err := p.client.Begin(ctx)
+	if err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to write companies in bulk").Wrap(err)
+	}
+	defer tx.Rollback(ctx)
+
+	written := make([]*models.CompanyModel, len(ops))
+	for i, op := range ops {
+		if written[i], err = p.writeSavepoint(ctx, tx, op); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to write companies in bulk").Wrap(err)
+	}
+
+	for i, op := range ops {
+		if op.Err == nil {
+			op.Result = written[i]
+		}
+	}
+	return nil
+}
+
+// writeSavepoint applies the operation within a savepoint of the transaction, and returns the company written. When
+// the operation fails, its error is set and the savepoint is rolled back. The returned error is only set when the
+// database could not be reached.
+func (p *postgresDB) writeSavepoint(ctx context.Context, tx pgx.Tx, op *models.CompanyOperation) (*models.CompanyModel, error) {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return nil, apierrors.ErrInternalServer.WithMessage("failed to write companies in bulk").Wrap(err)
+	}
+
+	cmd, args := operationCommand(op)
+	p.logger.Debugf("cmd: %s", cmd)
+	rows, err := savepoint.Query(ctx, cmd, args)
+	companies := make([]models.CompanyModel, 0, 1)
+	if err == nil {
+		err = pgxscan.ScanAll(&companies, rows)
+	}
+
+	// the errors of the statements are caused by the operation, while the others mean the database is unreachable
+	var e *pgconn.PgError
+	switch {
+	case errors.As(err, &e):
+		op.Err = companyError(err, op.Company.Name, "failed to write company "+op.Company.ID.String())
+	case err != nil:
+		return nil, apierrors.ErrInternalServer.WithMessage("failed to write companies in bulk").Wrap(err)
+	case len(companies) == 0:
+		op.Err = p.notWritten(ctx, savepoint, op.Company.ID.String(), op.Version)
+	}
+
+	if op.Err != nil {
+		if err := savepoint.Rollback(ctx); err != nil {
+			return nil, apierrors.ErrInternalServer.WithMessage("failed to write companies in bulk").Wrap(err)
+		}
+		return nil, nil
+	}
+	if err := savepoint.Commit(ctx); err != nil {
+		return nil, apierrors.ErrInternalServer.WithMessage("failed to write companies in bulk").Wrap(err)
+	}
+	return &companies[0], nil
+}
+
+// operationCommand returns the statement applying the operation, which returns the company written
+func operationCommand(op *models.CompanyOperation) (string, pgx.NamedArgs) {
+	c := op.Company
+	args := pgx.NamedArgs{
+		"id":               c.ID.String(),
+		"name":             c.Name,
+		"description":      c.Description,
+		"amount_employees": c.AmountEmployees,
+		"registered":       c.Registered,
+		"type":             c.Type,
+	}
+	switch op.Type {
+	case enum.BulkCreate:
+		return "INSERT INTO company (id, name, description, amount_employees, registered, type) VALUES (@id, @name, @description, @amount_employees, @registered, @type) RETURNING *", args
+	case enum.BulkUpdate:
+		return "UPDATE company SET name = @name, description = @description, amount_employees = @amount_employees, registered = @registered, type = @type, version = version + 1 WHERE " + versionCondition(args, c.ID.String(), op.Version) + " RETURNING *", args
+	default:
+		args = pgx.NamedArgs{}
+		return "DELETE FROM company WHERE " + versionCondition(args, c.ID.String(), op.Version) + " RETURNING *", args
+	}
+}
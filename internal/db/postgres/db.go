@@ -4,27 +4,51 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	apierrors "xm_test/internal/api_errors"
 	"xm_test/internal/conf"
 	"xm_test/internal/db/models"
 	"xm_test/internal/db/options"
+	"xm_test/internal/i18n"
 
 	"github.com/georgysavva/scany/v2/pgxscan"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 
 	pgxUUID "github.com/vgarvardt/pgx-google-uuid/v5"
 )
 
+// requiredTables are the tables that must exist in the database for the API to work
+var requiredTables = []string{"company", "users", "events", "dead_letter_events", "idempotency_keys", "import_jobs", "import_rows"}
+
+// requiredColumns are the columns of the required tables used by the API. The tables created before a column was
+// added keep missing it until the init script is run again.
+var requiredColumns = map[string][]string{
+	"company":            {"id", "name", "description", "amount_employees", "registered", "type", "version"},
+	"users":              {"id", "email", "enc_password"},
+	"events":             {"id", "type", "version", "timestamp", "entity_id", "payload", "trace_parent", "trace_state"},
+	"dead_letter_events": {"id", "type", "version", "timestamp", "entity_id", "payload", "last_error", "attempts", "created_at", "updated_at", "trace_parent", "trace_state"},
+	"idempotency_keys":   {"user_id", "key", "request_hash", "completed", "status", "headers", "body", "locked_until", "expires_at", "created_at"},
+	"import_jobs":        {"id", "user_id", "format", "status", "size", "bytes_read", "processed", "accepted", "rejected", "error", "created_at", "updated_at"},
+	"import_rows":        {"job_id", "line", "accepted", "company_id", "error_code", "error_message"},
+}
+
+// querier is implemented by the connection pool and by the transactions, so the same query can be run by both
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 // postgres is a struct that manages the postgres database connection.
 type postgresDB struct {
 	logger *zap.SugaredLogger
 
-	client *pgxpool.Pool
-	isConn bool
+	client    *pgxpool.Pool
+	isConn    bool
+	collector *poolCollector
 }
 
 // NewPostgresAdapter returns a new postgres instance.
@@ -51,19 +75,29 @@ func (p *postgresDB) Connect(ctx context.Context, opts ...func(*options.Database
 
 	p.logger.Debugf("connecting to postgres database")
 
-	pool, err := pgxpool.New(ctx, options.ConnString)
+	cfg, err := pgxpool.ParseConfig(options.ConnString)
 	if err != nil {
-		apiError := apierrors.ErrInternalServer
-		apiError.Message = fmt.Sprintf("failed to connect to postgres database: %s", err)
-		return apiError
+		return apierrors.ErrInternalServer.WithMessage("failed to parse postgres connection string").Wrap(err)
 	}
-	p.logger.Debugf("connected to postgres database: '%s'", pool.Config().ConnConfig.Database)
-
-	cfg := pool.Config()
 	cfg.AfterConnect = func(ctx context.Context, pgconn *pgx.Conn) error {
 		pgxUUID.Register(pgconn.TypeMap())
 		return nil
 	}
+	cfg.ConnConfig.Tracer = &queryTracer{database: cfg.ConnConfig.Database}
+
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to connect to postgres database").Wrap(err)
+	}
+	p.logger.Debugf("connected to postgres database: '%s'", pool.Config().ConnConfig.Database)
+
+	// expose the pool statistics. Registering may fail if another pool is already exposed in the same process
+	collector := newPoolCollector(pool)
+	if err := prometheus.Register(collector); err != nil {
+		p.logger.Warnf("failed to register postgres pool metrics: %s", err)
+	} else {
+		p.collector = collector
+	}
 
 	p.client = pool
 	p.isConn = true
@@ -77,12 +111,65 @@ func (p *postgresDB) Close(ctx context.Context) error {
 	}
 
 	p.logger.Debugf("closing postgres connection")
+	if p.collector != nil {
+		prometheus.Unregister(p.collector)
+		p.collector = nil
+	}
 	p.client.Close()
 	p.logger.Debugf("closed postgres connection")
 	p.isConn = false
 	return nil
 }
 
+// Ping is a method that checks that the postgres database is reachable.
+func (p *postgresDB) Ping(ctx context.Context) error {
+	if !p.isConn {
+		return apierrors.ErrInternalServer.WithMessage("postgres connection is closed")
+	}
+
+	if err := p.client.Ping(ctx); err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to ping postgres database").Wrap(err)
+	}
+	return nil
+}
+
+// CheckSchema is a method that checks that every table and column used by the API has been created in the database.
+func (p *postgresDB) CheckSchema(ctx context.Context) error {
+	missing := make([]string, 0)
+	cmd := "SELECT t FROM unnest($1::text[]) AS t WHERE to_regclass(t) IS NULL"
+	p.logger.Debugf("cmd: %s", cmd)
+
+	if err := pgxscan.Select(ctx, p.client, &missing, cmd, requiredTables); err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to check database schema").Wrap(err)
+	}
+	if len(missing) > 0 {
+		return apierrors.ErrInternalServer.WithMessagef("missing tables in database schema: %s", strings.Join(missing, ", "))
+	}
+
+	// the tables are listed in order, so the missing columns are reported in a stable order
+	var tables, columns []string
+	for _, table := range requiredTables {
+		for _, column := range requiredColumns[table] {
+			tables = append(tables, table)
+			columns = append(columns, column)
+		}
+	}
+	cmd = `SELECT c.t || '.' || c.c FROM unnest($1::text[], $2::text[]) WITH ORDINALITY AS c(t, c, n)
+		WHERE NOT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = c.t AND column_name = c.c
+		) ORDER BY c.n`
+	p.logger.Debugf("cmd: %s", cmd)
+
+	if err := pgxscan.Select(ctx, p.client, &missing, cmd, tables, columns); err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to check database schema").Wrap(err)
+	}
+	if len(missing) > 0 {
+		return apierrors.ErrInternalServer.WithMessagef("missing columns in database schema, run the init script again to add them: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
 // CreateUser is a method that creates a new user in the database.
 func (p *postgresDB) CreateUser(ctx context.Context, user *models.UserModel) error {
 	p.logger.Debugf("creating user: %s", user.Email)
@@ -97,13 +184,9 @@ func (p *postgresDB) CreateUser(ctx context.Context, user *models.UserModel) err
 	if _, err := p.client.Exec(ctx, cmd, args); err != nil {
 		var e *pgconn.PgError
 		if errors.As(err, &e) && e.Code == pgerrcode.UniqueViolation {
-			apiError := apierrors.ErrUserAlreadyExists
-			apiError.Message = fmt.Sprintf("user with email '%s' already exists", user.Email)
-			return apiError
+			return apierrors.ErrUserAlreadyExists.WithMessageID("email", i18n.Params{"email": user.Email})
 		}
-		apiError := apierrors.ErrInternalServer
-		apiError.Message = fmt.Sprintf("failed to create user: %s", err)
-		return apiError
+		return apierrors.ErrInternalServer.WithMessage("failed to create user").Wrap(err)
 	}
 	p.logger.Debugf("created user: %s", user.Email)
 	return nil
@@ -117,14 +200,10 @@ func (p *postgresDB) GetUserByEmail(ctx context.Context, email string) (*models.
 	p.logger.Debugf("cmd: %s", cmd)
 
 	if err := pgxscan.Select(ctx, p.client, &users, cmd, email); err != nil {
-		apiError := apierrors.ErrInternalServer
-		apiError.Message = fmt.Sprintf("failed to retrieve user by email: %s", err)
-		return nil, apiError
+		return nil, apierrors.ErrInternalServer.WithMessage("failed to retrieve user by email").Wrap(err)
 	}
 	if len(users) == 0 {
-		apiError := apierrors.ErrUserNotFound
-		apiError.Message = fmt.Sprintf("user with email '%s' not found", email)
-		return nil, apiError
+		return nil, apierrors.ErrUserNotFound.WithMessageID("email", i18n.Params{"email": email})
 	}
 
 	user := users[0]
@@ -133,7 +212,7 @@ func (p *postgresDB) GetUserByEmail(ctx context.Context, email string) (*models.
 	return &user, nil
 }
 
-// CreateCompany is a method that creates a new company in the database.
+// CreateCompany is a method that creates a new company in the database, and sets its initial version.
 func (p *postgresDB) CreateCompany(ctx context.Context, company *models.CompanyModel) error {
 	p.logger.Debugf("creating company: %s", company.Name)
 	args := pgx.NamedArgs{
@@ -144,13 +223,11 @@ func (p *postgresDB) CreateCompany(ctx context.Context, company *models.CompanyM
 		"registered":       company.Registered,
 		"type":             company.Type,
 	}
-	cmd := "INSERT INTO company (id, name, description, amount_employees, registered, type) VALUES (@id, @name, @description, @amount_employees, @registered, @type)"
+	cmd := "INSERT INTO company (id, name, description, amount_employees, registered, type) VALUES (@id, @name, @description, @amount_employees, @registered, @type) RETURNING version"
 	p.logger.Debugf("cmd: %s", cmd)
 
-	if _, err := p.client.Exec(ctx, cmd, args); err != nil {
-		apiError := apierrors.ErrInternalServer
-		apiError.Message = fmt.Sprintf("failed to create company: %s", err)
-		return apiError
+	if err := p.client.QueryRow(ctx, cmd, args).Scan(&company.Version); err != nil {
+		return companyError(err, company.Name, "failed to create company")
 	}
 	p.logger.Debugf("created company: %s", company.Name)
 	return nil
@@ -164,14 +241,10 @@ func (p *postgresDB) GetCompanyByID(ctx context.Context, id string) (*models.Com
 	p.logger.Debugf("cmd: %s", cmd)
 
 	if err := pgxscan.Select(ctx, p.client, &companies, cmd, id); err != nil {
-		apiError := apierrors.ErrInternalServer
-		apiError.Message = fmt.Sprintf("failed to retrieve company by id: %s", err)
-		return nil, apiError
+		return nil, apierrors.ErrInternalServer.WithMessage("failed to retrieve company by id").Wrap(err)
 	}
 	if len(companies) == 0 {
-		apiError := apierrors.ErrCompanyNotFound
-		apiError.Message = fmt.Sprintf("company with id '%s' not found", id)
-		return nil, apiError
+		return nil, apierrors.ErrCompanyNotFound.WithMessageID("id", i18n.Params{"id": id})
 	}
 
 	company := companies[0]
@@ -180,8 +253,10 @@ func (p *postgresDB) GetCompanyByID(ctx context.Context, id string) (*models.Com
 	return &company, nil
 }
 
-// UpdateCompany is a method that updates a company in the database.
-func (p *postgresDB) UpdateCompany(ctx context.Context, id string, updateCompany *models.CompanyModel) error {
+// UpdateCompany is a method that updates a company in the database, and returns the updated company. The version
+// of the company is incremented. When the version is not zero, the company is only updated if it is at that version,
+// otherwise it fails with ErrPreconditionFailed.
+func (p *postgresDB) UpdateCompany(ctx context.Context, id string, updateCompany *models.CompanyModel, version int) (*models.CompanyModel, error) {
 	p.logger.Debugf("updating company by id: %s", id)
 	args := pgx.NamedArgs{
 		"id":               id,
@@ -191,50 +266,273 @@ func (p *postgresDB) UpdateCompany(ctx context.Context, id string, updateCompany
 		"registered":       updateCompany.Registered,
 		"type":             updateCompany.Type,
 	}
-	cmd := "UPDATE company SET name = @name, description = @description, amount_employees = @amount_employees, registered = @registered, type = @type WHERE id = @id"
+	cmd := "UPDATE company SET name = @name, description = @description, amount_employees = @amount_employees, registered = @registered, type = @type, version = version + 1 WHERE " + versionCondition(args, id, version) + " RETURNING *"
 	p.logger.Debugf("cmd: %s", cmd)
 
-	if _, err := p.client.Exec(ctx, cmd, args); err != nil {
-		apiError := apierrors.ErrInternalServer
-		apiError.Message = fmt.Sprintf("failed to update company by id: %s", err)
-		return apiError
+	companies := make([]models.CompanyModel, 0, 1)
+	if err := pgxscan.Select(ctx, p.client, &companies, cmd, args); err != nil {
+		return nil, companyError(err, updateCompany.Name, "failed to update company by id")
+	}
+	if len(companies) == 0 {
+		return nil, p.notWritten(ctx, p.client, id, version)
 	}
 	p.logger.Debugf("updated company by id: %s", id)
-	return nil
+	return &companies[0], nil
+}
+
+// PatchCompany is a method that updates the columns of the patch of a company in the database, and returns the
+// updated company. Like UpdateCompany, the version of the company is incremented and checked when it is not zero.
+func (p *postgresDB) PatchCompany(ctx context.Context, id string, patch *models.CompanyPatch, version int) (*models.CompanyModel, error) {
+	p.logger.Debugf("patching company by id: %s", id)
+	var columns []string
+	args := pgx.NamedArgs{}
+	set := func(column string, value any) {
+		columns = append(columns, column+" = @"+column)
+		args[column] = value
+	}
+	if patch.Name != nil {
+		set("name", *patch.Name)
+	}
+	if patch.Description != nil {
+		set("description", *patch.Description)
+	}
+	if patch.AmountEmployees != nil {
+		set("amount_employees", *patch.AmountEmployees)
+	}
+	if patch.Registered != nil {
+		set("registered", *patch.Registered)
+	}
+	if patch.Type != nil {
+		set("type", *patch.Type)
+	}
+	if len(columns) == 0 {
+		company, err := p.GetCompanyByID(ctx, id)
+		if err == nil && version != 0 && company.Version != version {
+			return nil, apierrors.ErrPreconditionFailed.WithMessageID("version", i18n.Params{"id": id})
+		}
+		return company, err
+	}
+	columns = append(columns, "version = version + 1")
+
+	cmd := "UPDATE company SET " + strings.Join(columns, ", ") + " WHERE " + versionCondition(args, id, version) + " RETURNING *"
+	p.logger.Debugf("cmd: %s", cmd)
+
+	companies := make([]models.CompanyModel, 0, 1)
+	if err := pgxscan.Select(ctx, p.client, &companies, cmd, args); err != nil {
+		return nil, companyError(err, args["name"], "failed to patch company by id")
+	}
+	if len(companies) == 0 {
+		return nil, p.notWritten(ctx, p.client, id, version)
+	}
+	p.logger.Debugf("patched company by id: %s", id)
+	return &companies[0], nil
 }
 
-// DeleteCompany is a method that deletes a company by id from the database.
-func (p *postgresDB) DeleteCompany(ctx context.Context, id string) error {
+// DeleteCompany is a method that deletes a company by id from the database. When the version is not zero, the
+// company is only deleted if it is at that version, otherwise it fails with ErrPreconditionFailed.
+func (p *postgresDB) DeleteCompany(ctx context.Context, id string, version int) error {
 	p.logger.Debugf("deleting company by id: %s", id)
-	cmd := "DELETE FROM company WHERE id = $1"
+	args := pgx.NamedArgs{}
+	cmd := "DELETE FROM company WHERE " + versionCondition(args, id, version)
 	p.logger.Debugf("cmd: %s", cmd)
 
-	if _, err := p.client.Exec(ctx, cmd, id); err != nil {
-		apiError := apierrors.ErrInternalServer
-		apiError.Message = fmt.Sprintf("failed to delete company by id: %s", err)
-		return apiError
+	tag, err := p.client.Exec(ctx, cmd, args)
+	if err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to delete company by id").Wrap(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return p.notWritten(ctx, p.client, id, version)
 	}
 	p.logger.Debugf("deleted company by id: %s", id)
 	return nil
 }
 
+// versionCondition returns the WHERE condition matching the company with the id, and with the version when it is
+// not zero, and sets the arguments of the condition
+func versionCondition(args pgx.NamedArgs, id string, version int) string {
+	args["id"] = id
+	if version == 0 {
+		return "id = @id"
+	}
+	args["version"] = version
+	return "id = @id AND version = @version"
+}
+
+// notWritten returns the error of a write that did not match any company: either the company does not exist, or it
+// is not at the expected version. The company is looked up with the querier, so the writes of a pending transaction
+// are taken into account.
+func (p *postgresDB) notWritten(ctx context.Context, q querier, id string, version int) error {
+	if version != 0 {
+		var exists bool
+		cmd := "SELECT EXISTS (SELECT 1 FROM company WHERE id = $1)"
+		p.logger.Debugf("cmd: %s", cmd)
+		if err := q.QueryRow(ctx, cmd, id).Scan(&exists); err != nil {
+			return apierrors.ErrInternalServer.WithMessage("failed to check company by id").Wrap(err)
+		}
+		if exists {
+			return apierrors.ErrPreconditionFailed.WithMessageID("version", i18n.Params{"id": id})
+		}
+	}
+	return apierrors.ErrCompanyNotFound.WithMessageID("id", i18n.Params{"id": id})
+}
+
+// companyError returns the error of a failed write of a company: ErrCompanyAlreadyExists when its name is taken by
+// another company, or an internal error with the given message otherwise
+func companyError(err error, name any, message string) error {
+	var e *pgconn.PgError
+	if errors.As(err, &e) && e.Code == pgerrcode.UniqueViolation {
+		return apierrors.ErrCompanyAlreadyExists.WithMessageID("name", i18n.Params{"name": name})
+	}
+	return apierrors.ErrInternalServer.WithMessage(message).Wrap(err)
+}
+
+// ListCompanies is a method that retrieves the companies matching the filter, sorted by id.
+func (p *postgresDB) ListCompanies(ctx context.Context, filter *models.CompanyFilter) ([]*models.CompanyModel, error) {
+	p.logger.Debugf("listing companies")
+	cmd, args := companyQuery(filter)
+	p.logger.Debugf("cmd: %s", cmd)
+
+	companies := make([]*models.CompanyModel, 0)
+	if err := pgxscan.Select(ctx, p.client, &companies, cmd, args); err != nil {
+		return nil, apierrors.ErrInternalServer.WithMessage("failed to list companies").Wrap(err)
+	}
+	p.logger.Debugf("listed %d companies", len(companies))
+	return companies, nil
+}
+
+// StreamCompanies is a method that calls the handler with each company matching the filter, sorted by id. The
+// companies are read one by one from the cursor of the query, so the export of the whole table is never held in
+// memory.
+func (p *postgresDB) StreamCompanies(ctx context.Context, filter *models.CompanyFilter, handler func(company *models.CompanyModel) error) error {
+	p.logger.Debugf("streaming companies")
+	cmd, args := companyQuery(filter)
+	p.logger.Debugf("cmd: %s", cmd)
+
+	rows, err := p.client.Query(ctx, cmd, args)
+	if err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to stream companies").Wrap(err)
+	}
+	defer rows.Close()
+
+	count := 0
+	scanner := pgxscan.NewRowScanner(rows)
+	for rows.Next() {
+		var company models.CompanyModel
+		if err := scanner.Scan(&company); err != nil {
+			return apierrors.ErrInternalServer.WithMessage("failed to scan company").Wrap(err)
+		}
+		if err := handler(&company); err != nil {
+			return err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to stream companies").Wrap(err)
+	}
+	p.logger.Debugf("streamed %d companies", count)
+	return nil
+}
+
+// companyQuery returns the query selecting the companies matching the filter, sorted by id, along with its
+// arguments. The amount of companies is only limited when the limit of the filter is set.
+func companyQuery(filter *models.CompanyFilter) (string, pgx.NamedArgs) {
+	var conditions []string
+	args := pgx.NamedArgs{}
+	if filter.Name != "" {
+		conditions = append(conditions, "name ILIKE '%' || @name || '%'")
+		args["name"] = filter.Name
+	}
+	if filter.Type != "" {
+		conditions = append(conditions, "type = @type")
+		args["type"] = filter.Type
+	}
+	if filter.Registered != nil {
+		conditions = append(conditions, "registered = @registered")
+		args["registered"] = *filter.Registered
+	}
+	if filter.After != nil {
+		conditions = append(conditions, "id > @after")
+		args["after"] = filter.After.String()
+	}
+
+	cmd := "SELECT * FROM company"
+	if len(conditions) > 0 {
+		cmd += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	cmd += " ORDER BY id"
+	if filter.Limit > 0 {
+		cmd += " LIMIT @limit"
+		args["limit"] = filter.Limit
+	}
+	return cmd, args
+}
+
+// GetCompaniesByIDs is a method that retrieves the companies with the given ids in a single query. The ids that
+// do not exist are skipped.
+func (p *postgresDB) GetCompaniesByIDs(ctx context.Context, ids []string) ([]*models.CompanyModel, error) {
+	p.logger.Debugf("retrieving %d companies by id", len(ids))
+	companies := make([]*models.CompanyModel, 0, len(ids))
+	cmd := "SELECT * FROM company WHERE id = ANY($1::uuid[])"
+	p.logger.Debugf("cmd: %s", cmd)
+
+	if err := pgxscan.Select(ctx, p.client, &companies, cmd, ids); err != nil {
+		return nil, apierrors.ErrInternalServer.WithMessage("failed to retrieve companies by id").Wrap(err)
+	}
+	p.logger.Debugf("retrieved %d companies by id", len(companies))
+	return companies, nil
+}
+
 // CreateEvent is a method that creates a new event in the database.
 func (p *postgresDB) CreateEvent(ctx context.Context, event *models.EventModel) error {
 	p.logger.Debugf("creating event: %s", event.Type)
 	args := pgx.NamedArgs{
-		"id":        event.ID.String(),
-		"type":      event.Type,
-		"timestamp": event.Timestamp,
-		"entity_id": event.EntityID.String(),
+		"id":           event.ID.String(),
+		"type":         event.Type,
+		"version":      event.Version,
+		"timestamp":    event.Timestamp,
+		"entity_id":    event.EntityID.String(),
+		"payload":      event.Payload,
+		"trace_parent": event.TraceParent,
+		"trace_state":  event.TraceState,
 	}
-	cmd := "INSERT INTO events (id, type, timestamp, entity_id) VALUES (@id, @type, @timestamp, @entity_id)"
+	cmd := "INSERT INTO events (id, type, version, timestamp, entity_id, payload, trace_parent, trace_state) VALUES (@id, @type, @version, @timestamp, @entity_id, @payload, @trace_parent, @trace_state)"
 	p.logger.Debugf("cmd: %s", cmd)
 
-	if _, err := p.client.Exec(ctx, cmd, args); err != nil {
-		apiError := apierrors.ErrInternalServer
-		apiError.Message = fmt.Sprintf("failed to create event: %s", err)
-		return apiError
+	// the notification is sent in the same transaction, so listeners are only notified once the event is committed.
+	// It only carries the ID of the event, since the payloads of the notifications must be shorter than 8000 bytes.
+	tx, err := p.client.Begin(ctx)
+	if err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to create event").Wrap(err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, cmd, args); err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to create event").Wrap(err)
+	}
+
+	notifyCmd := "SELECT pg_notify($1, $2)"
+	p.logger.Debugf("cmd: %s", notifyCmd)
+	if _, err := tx.Exec(ctx, notifyCmd, conf.GlobalConfig.Postgres.NotifyChannel, event.ID.String()); err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to notify event").Wrap(err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to create event").Wrap(err)
 	}
 	p.logger.Debugf("created event: %s", event.Type)
 	return nil
 }
+
+// GetEventTypes is a method that retrieves the event types supported by the database.
+func (p *postgresDB) GetEventTypes(ctx context.Context) ([]string, error) {
+	p.logger.Debugf("retrieving event types")
+	types := make([]string, 0)
+	cmd := "SELECT unnest(enum_range(NULL::EVENT_TYPE))::text"
+	p.logger.Debugf("cmd: %s", cmd)
+
+	if err := pgxscan.Select(ctx, p.client, &types, cmd); err != nil {
+		return nil, apierrors.ErrInternalServer.WithMessage("failed to retrieve event types").Wrap(err)
+	}
+	p.logger.Debugf("retrieved event types: %v", types)
+	return types, nil
+}
@@ -2,16 +2,26 @@ package postgres
 
 import (
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+	apierrors "xm_test/internal/api_errors"
 	"xm_test/internal/conf"
 	"xm_test/internal/crypto"
 	"xm_test/internal/db/models"
 	"xm_test/internal/db/options"
 	"xm_test/internal/enum"
 	"xm_test/internal/mocks"
+	"xm_test/internal/projectpath"
 
 	"github.com/docker/go-connections/nat"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/stretchr/testify/suite"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"go.uber.org/zap"
@@ -60,6 +70,35 @@ func (s *PostgresSuite) TearDownSuite() {
 	s.Require().NoError(s.container.Terminate(ctx))
 }
 
+func (s *PostgresSuite) TestPing() {
+	s.Run("ok", func() {
+		s.NoError(s.db.Ping(context.Background()))
+	})
+}
+
+func (s *PostgresSuite) TestCheckSchema() {
+	ctx := context.Background()
+
+	s.Run("ok", func() {
+		s.NoError(s.db.CheckSchema(ctx))
+	})
+
+	s.Run("missing column added by the init script", func() {
+		_, err := s.db.client.Exec(ctx, `ALTER TABLE "events" DROP COLUMN "trace_state"`)
+		s.Require().NoError(err)
+		err = s.db.CheckSchema(ctx)
+		s.Require().ErrorIs(err, apierrors.ErrInternalServer)
+		s.Contains(err.Error(), "events.trace_state")
+
+		// the script is run again on the existing database, as it is when the API is upgraded
+		script, err := os.ReadFile(filepath.Join(projectpath.Root, conf.GlobalConfig.Postgres.InitScript))
+		s.Require().NoError(err)
+		_, err = s.db.client.Exec(ctx, string(script), pgx.QueryExecModeSimpleProtocol)
+		s.Require().NoError(err)
+		s.NoError(s.db.CheckSchema(ctx))
+	})
+}
+
 func (s *PostgresSuite) TestCreateUser() {
 	ctx := context.Background()
 
@@ -119,6 +158,7 @@ func (s *PostgresSuite) TestCreateCompany() {
 		}
 		err := s.db.CreateCompany(ctx, &company)
 		s.Require().NoError(err)
+		s.Equal(1, company.Version)
 
 		// check that the company was created
 		createdCompany, err := s.db.GetCompanyByID(ctx, company.ID.String())
@@ -131,6 +171,11 @@ func (s *PostgresSuite) TestCreateCompany() {
 		s.Equal(company.Registered, createdCompany.Registered)
 		s.Equal(company.Type, createdCompany.Type)
 	})
+
+	s.Run("fails when the name is taken", func() {
+		company := models.CompanyModel{ID: uuid.New(), Name: "createComp", AmountEmployees: 10, Type: enum.Cooperative.String()}
+		s.ErrorIs(s.db.CreateCompany(ctx, &company), apierrors.ErrCompanyAlreadyExists)
+	})
 }
 
 func (s *PostgresSuite) TestGetCompanyByID() {
@@ -184,8 +229,9 @@ func (s *PostgresSuite) TestUpdateCompany() {
 		company.Registered = false
 		company.Type = enum.NonProfit.String()
 
-		err := s.db.UpdateCompany(ctx, company.ID.String(), &company)
+		updated, err := s.db.UpdateCompany(ctx, company.ID.String(), &company, company.Version)
 		s.Require().NoError(err)
+		s.Equal(company.Version+1, updated.Version)
 
 		// check that the company was updated
 		updatedCompany, err := s.db.GetCompanyByID(ctx, company.ID.String())
@@ -197,6 +243,16 @@ func (s *PostgresSuite) TestUpdateCompany() {
 		s.Equal(company.AmountEmployees, updatedCompany.AmountEmployees)
 		s.Equal(company.Registered, updatedCompany.Registered)
 	})
+
+	s.Run("fails when the version does not match", func() {
+		_, err := s.db.UpdateCompany(ctx, company.ID.String(), &company, company.Version)
+		s.ErrorIs(err, apierrors.ErrPreconditionFailed)
+	})
+
+	s.Run("fails when the company does not exist", func() {
+		_, err := s.db.UpdateCompany(ctx, uuid.NewString(), &company, 0)
+		s.ErrorIs(err, apierrors.ErrCompanyNotFound)
+	})
 }
 
 func (s *PostgresSuite) TestDeleteCompany() {
@@ -214,8 +270,13 @@ func (s *PostgresSuite) TestDeleteCompany() {
 	err := s.db.CreateCompany(ctx, &company)
 	s.Require().NoError(err)
 
+	s.Run("fails when the version does not match", func() {
+		err := s.db.DeleteCompany(ctx, company.ID.String(), company.Version+1)
+		s.ErrorIs(err, apierrors.ErrPreconditionFailed)
+	})
+
 	s.Run("ok", func() {
-		err := s.db.DeleteCompany(ctx, company.ID.String())
+		err := s.db.DeleteCompany(ctx, company.ID.String(), company.Version)
 		s.Require().NoError(err)
 
 		// check that the company was deleted
@@ -224,6 +285,439 @@ func (s *PostgresSuite) TestDeleteCompany() {
 	})
 }
 
+func (s *PostgresSuite) TestListCompanies() {
+	ctx := context.Background()
+
+	// create the companies, sorted by id
+	companies := []*models.CompanyModel{
+		{ID: uuid.New(), Name: "lstCoop", AmountEmployees: 1, Registered: true, Type: enum.Cooperative.String()},
+		{ID: uuid.New(), Name: "lstCorp", AmountEmployees: 2, Registered: false, Type: enum.Corporation.String()},
+		{ID: uuid.New(), Name: "lstNonProfit", AmountEmployees: 3, Registered: true, Type: enum.NonProfit.String()},
+	}
+	slices.SortFunc(companies, func(a, b *models.CompanyModel) int { return strings.Compare(a.ID.String(), b.ID.String()) })
+	for _, company := range companies {
+		s.Require().NoError(s.db.CreateCompany(ctx, company))
+	}
+
+	s.Run("filters", func() {
+		registered := true
+		listed, err := s.db.ListCompanies(ctx, &models.CompanyFilter{Name: "LST", Registered: &registered, Limit: 10})
+		s.Require().NoError(err)
+		s.Len(listed, 2)
+		for _, company := range listed {
+			s.True(company.Registered)
+		}
+
+		listed, err = s.db.ListCompanies(ctx, &models.CompanyFilter{Name: "lst", Type: enum.Corporation.String(), Limit: 10})
+		s.Require().NoError(err)
+		s.Require().Len(listed, 1)
+		s.Equal("lstCorp", listed[0].Name)
+	})
+
+	s.Run("paginates", func() {
+		page, err := s.db.ListCompanies(ctx, &models.CompanyFilter{Name: "lst", Limit: 2})
+		s.Require().NoError(err)
+		s.Require().Len(page, 2)
+		s.Equal(companies[0].ID, page[0].ID)
+		s.Equal(companies[1].ID, page[1].ID)
+
+		page, err = s.db.ListCompanies(ctx, &models.CompanyFilter{Name: "lst", After: &page[1].ID, Limit: 2})
+		s.Require().NoError(err)
+		s.Require().Len(page, 1)
+		s.Equal(companies[2].ID, page[0].ID)
+	})
+}
+
+func (s *PostgresSuite) TestStreamCompanies() {
+	ctx := context.Background()
+
+	// create the companies, sorted by id
+	companies := []*models.CompanyModel{
+		{ID: uuid.New(), Name: "expCoop", AmountEmployees: 1, Registered: true, Type: enum.Cooperative.String()},
+		{ID: uuid.New(), Name: "expCorp", AmountEmployees: 2, Registered: false, Type: enum.Corporation.String()},
+		{ID: uuid.New(), Name: "expNonProfit", AmountEmployees: 3, Registered: true, Type: enum.NonProfit.String()},
+	}
+	slices.SortFunc(companies, func(a, b *models.CompanyModel) int { return strings.Compare(a.ID.String(), b.ID.String()) })
+	for _, company := range companies {
+		s.Require().NoError(s.db.CreateCompany(ctx, company))
+	}
+
+	s.Run("streams every company matching the filter", func() {
+		var streamed []uuid.UUID
+		err := s.db.StreamCompanies(ctx, &models.CompanyFilter{Name: "EXP"}, func(company *models.CompanyModel) error {
+			streamed = append(streamed, company.ID)
+			return nil
+		})
+		s.Require().NoError(err)
+		s.Equal([]uuid.UUID{companies[0].ID, companies[1].ID, companies[2].ID}, streamed)
+
+		registered := false
+		var names []string
+		err = s.db.StreamCompanies(ctx, &models.CompanyFilter{Name: "exp", Registered: &registered}, func(company *models.CompanyModel) error {
+			names = append(names, company.Name)
+			return nil
+		})
+		s.Require().NoError(err)
+		s.Equal([]string{"expCorp"}, names)
+	})
+
+	s.Run("stops when the handler fails", func() {
+		handlerErr := errors.New("client gone")
+		calls := 0
+		err := s.db.StreamCompanies(ctx, &models.CompanyFilter{Name: "exp"}, func(company *models.CompanyModel) error {
+			calls++
+			return handlerErr
+		})
+		s.ErrorIs(err, handlerErr)
+		s.Equal(1, calls)
+	})
+}
+
+func (s *PostgresSuite) TestGetCompaniesByIDs() {
+	ctx := context.Background()
+
+	company := models.CompanyModel{
+		ID:              uuid.New(),
+		Name:            "batchComp",
+		AmountEmployees: 10,
+		Registered:      true,
+		Type:            enum.Cooperative.String(),
+	}
+	s.Require().NoError(s.db.CreateCompany(ctx, &company))
+
+	s.Run("skips missing ids", func() {
+		companies, err := s.db.GetCompaniesByIDs(ctx, []string{company.ID.String(), uuid.NewString()})
+		s.Require().NoError(err)
+		s.Require().Len(companies, 1)
+		s.Equal(company.Name, companies[0].Name)
+	})
+}
+
+func (s *PostgresSuite) TestBulkWriteCompanies() {
+	ctx := context.Background()
+	create := func(name string) *models.CompanyOperation {
+		company := &models.CompanyModel{ID: uuid.New(), Name: name, AmountEmployees: 10, Type: enum.Cooperative.String()}
+		return &models.CompanyOperation{Type: enum.BulkCreate, Company: company}
+	}
+
+	s.Run("copies the companies", func() {
+		ops := []*models.CompanyOperation{create("bulkCopyA"), create("bulkCopyB")}
+		s.Require().NoError(s.db.BulkWriteCompanies(ctx, ops, true))
+
+		for _, op := range ops {
+			s.Require().NoError(op.Err)
+			s.Equal(1, op.Result.Version)
+
+			company, err := s.db.GetCompanyByID(ctx, op.Company.ID.String())
+			s.Require().NoError(err)
+			s.Equal(op.Company.Name, company.Name)
+		}
+	})
+
+	s.Run("aborts the transactional write when an operation fails", func() {
+		ops := []*models.CompanyOperation{create("bulkAborted"), create("bulkCopyA")}
+		s.Require().NoError(s.db.BulkWriteCompanies(ctx, ops, true))
+
+		s.ErrorIs(ops[0].Err, apierrors.ErrBulkAborted)
+		s.ErrorIs(ops[1].Err, apierrors.ErrCompanyAlreadyExists)
+		_, err := s.db.GetCompanyByID(ctx, ops[0].Company.ID.String())
+		s.ErrorIs(err, apierrors.ErrCompanyNotFound)
+	})
+
+	s.Run("skips the failing operations in best effort", func() {
+		existing := create("bulkExisting")
+		s.Require().NoError(s.db.CreateCompany(ctx, existing.Company))
+
+		updated := *existing.Company
+		updated.AmountEmployees = 20
+		ops := []*models.CompanyOperation{
+			create("bulkBestEffort"),
+			{Type: enum.BulkUpdate, Company: &updated, Version: 1},
+			{Type: enum.BulkDelete, Company: &models.CompanyModel{ID: uuid.New()}},
+			{Type: enum.BulkUpdate, Company: &updated, Version: 1},
+			create("bulkExisting"),
+			create("bulkAfterFailure"),
+		}
+		s.Require().NoError(s.db.BulkWriteCompanies(ctx, ops, false))
+
+		s.Require().NoError(ops[0].Err)
+		s.Require().NoError(ops[1].Err)
+		s.Equal(2, ops[1].Result.Version)
+		s.Equal(20, ops[1].Result.AmountEmployees)
+		s.ErrorIs(ops[2].Err, apierrors.ErrCompanyNotFound)
+		s.ErrorIs(ops[3].Err, apierrors.ErrPreconditionFailed)
+		s.Nil(ops[3].Result)
+
+		// the statement that fails is rolled back alone, so the following operations are applied
+		s.ErrorIs(ops[4].Err, apierrors.ErrCompanyAlreadyExists)
+		s.Require().NoError(ops[5].Err)
+
+		for _, op := range []*models.CompanyOperation{ops[0], ops[5]} {
+			_, err := s.db.GetCompanyByID(ctx, op.Company.ID.String())
+			s.Require().NoError(err)
+		}
+	})
+
+	s.Run("deletes the companies", func() {
+		existing := create("bulkDeleted")
+		s.Require().NoError(s.db.CreateCompany(ctx, existing.Company))
+
+		ops := []*models.CompanyOperation{{Type: enum.BulkDelete, Company: existing.Company, Version: existing.Company.Version}}
+		s.Require().NoError(s.db.BulkWriteCompanies(ctx, ops, true))
+		s.Require().NoError(ops[0].Err)
+		s.Equal(existing.Company.ID, ops[0].Result.ID)
+
+		_, err := s.db.GetCompanyByID(ctx, existing.Company.ID.String())
+		s.ErrorIs(err, apierrors.ErrCompanyNotFound)
+	})
+}
+
+func (s *PostgresSuite) TestListenEvents() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan *models.EventModel, 1)
+	go s.db.ListenEvents(ctx, func(event *models.EventModel) {
+		received <- event
+	}, func(listening bool) {})
+
+	// give the listener some time to issue the LISTEN command
+	time.Sleep(500 * time.Millisecond)
+
+	s.Run("ok", func() {
+		event := models.EventModel{
+			ID:        uuid.New(),
+			Type:      enum.EventCreateCompany.String(),
+			Timestamp: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+			EntityID:  uuid.New(),
+		}
+		err := s.db.CreateEvent(ctx, &event)
+		s.Require().NoError(err)
+
+		select {
+		case notified := <-received:
+			s.Equal(event.ID, notified.ID)
+			s.Equal(event.Type, notified.Type)
+			s.Equal(event.EntityID, notified.EntityID)
+		case <-time.After(5 * time.Second):
+			s.Fail("event notification not received")
+		}
+	})
+
+	s.Run("large payload", func() {
+		// the payloads of the notifications are limited to 8000 bytes, which the events may exceed
+		event := models.EventModel{
+			ID:        uuid.New(),
+			Type:      enum.EventCreateCompany.String(),
+			Timestamp: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+			EntityID:  uuid.New(),
+			Payload:   []byte(`{"description": "` + strings.Repeat("x", 10000) + `"}`),
+		}
+		err := s.db.CreateEvent(ctx, &event)
+		s.Require().NoError(err)
+
+		select {
+		case notified := <-received:
+			s.Equal(event.ID, notified.ID)
+			s.JSONEq(string(event.Payload), string(notified.Payload))
+			s.WithinDuration(event.Timestamp.Time, notified.Timestamp.Time, time.Millisecond)
+		case <-time.After(5 * time.Second):
+			s.Fail("event notification not received")
+		}
+	})
+}
+
+func (s *PostgresSuite) TestGetEventTypes() {
+	ctx := context.Background()
+
+	s.Run("ok", func() {
+		types, err := s.db.GetEventTypes(ctx)
+		s.Require().NoError(err)
+
+		s.ElementsMatch([]string{
+			enum.EventCreateCompany.String(),
+			enum.EventUpdateCompany.String(),
+			enum.EventDeleteCompany.String(),
+		}, types)
+	})
+}
+
+func (s *PostgresSuite) TestDeadLetterEvents() {
+	ctx := context.Background()
+
+	event := models.DeadLetterEventModel{
+		ID:        uuid.New(),
+		Type:      enum.EventCreateCompany.String(),
+		Version:   1,
+		Timestamp: time.Now(),
+		EntityID:  uuid.New(),
+		LastError: "first error",
+		Attempts:  3,
+	}
+
+	s.Run("upsert", func() {
+		err := s.db.UpsertDeadLetterEvent(ctx, &event)
+		s.Require().NoError(err)
+
+		event.LastError = "second error"
+		err = s.db.UpsertDeadLetterEvent(ctx, &event)
+		s.Require().NoError(err)
+
+		stored, err := s.db.GetDeadLetterEvent(ctx, event.ID.String())
+		s.Require().NoError(err)
+		s.Equal("second error", stored.LastError)
+		s.Equal(6, stored.Attempts)
+	})
+
+	s.Run("list", func() {
+		events, err := s.db.ListDeadLetterEvents(ctx, 10, 0)
+		s.Require().NoError(err)
+		s.Require().NotEmpty(events)
+		s.Equal(event.ID, events[0].ID)
+	})
+
+	s.Run("delete", func() {
+		err := s.db.DeleteDeadLetterEvent(ctx, event.ID.String())
+		s.Require().NoError(err)
+
+		_, err = s.db.GetDeadLetterEvent(ctx, event.ID.String())
+		s.Error(err)
+
+		err = s.db.DeleteDeadLetterEvent(ctx, event.ID.String())
+		s.Error(err)
+	})
+}
+
+func (s *PostgresSuite) TestIdempotencyKeys() {
+	ctx := context.Background()
+
+	now := time.Now()
+	key := models.IdempotencyKeyModel{
+		UserID:      uuid.New(),
+		Key:         "key",
+		RequestHash: "hash",
+		LockedUntil: now.Add(time.Minute),
+		ExpiresAt:   now.Add(time.Hour),
+	}
+
+	s.Run("reserve", func() {
+		stored, err := s.db.ReserveIdempotencyKey(ctx, &key)
+		s.Require().NoError(err)
+		s.Nil(stored)
+
+		// the key is held by the pending request
+		stored, err = s.db.ReserveIdempotencyKey(ctx, &key)
+		s.Require().NoError(err)
+		s.Require().NotNil(stored)
+		s.False(stored.Completed)
+	})
+
+	s.Run("complete", func() {
+		key.Status = 201
+		key.Headers = map[string][]string{"Location": {"/company/1"}}
+		key.Body = []byte(`{"id": 1}`)
+		err := s.db.CompleteIdempotencyKey(ctx, &key)
+		s.Require().NoError(err)
+
+		stored, err := s.db.ReserveIdempotencyKey(ctx, &key)
+		s.Require().NoError(err)
+		s.Require().NotNil(stored)
+		s.True(stored.Completed)
+		s.Equal(key.Status, stored.Status)
+		s.Equal(key.Headers, stored.Headers)
+		s.Equal(key.Body, stored.Body)
+
+		// the stored responses are kept
+		s.Require().NoError(s.db.ReleaseIdempotencyKey(ctx, key.UserID.String(), key.Key))
+		stored, err = s.db.ReserveIdempotencyKey(ctx, &key)
+		s.Require().NoError(err)
+		s.NotNil(stored)
+	})
+
+	s.Run("release", func() {
+		released := key
+		released.Key = "released"
+		stored, err := s.db.ReserveIdempotencyKey(ctx, &released)
+		s.Require().NoError(err)
+		s.Require().Nil(stored)
+
+		s.Require().NoError(s.db.ReleaseIdempotencyKey(ctx, released.UserID.String(), released.Key))
+		stored, err = s.db.ReserveIdempotencyKey(ctx, &released)
+		s.Require().NoError(err)
+		s.Nil(stored)
+	})
+
+	s.Run("takes over the abandoned and expired keys", func() {
+		abandoned := key
+		abandoned.Key = "abandoned"
+		abandoned.LockedUntil = now.Add(-time.Second)
+		stored, err := s.db.ReserveIdempotencyKey(ctx, &abandoned)
+		s.Require().NoError(err)
+		s.Require().Nil(stored)
+
+		stored, err = s.db.ReserveIdempotencyKey(ctx, &abandoned)
+		s.Require().NoError(err)
+		s.Nil(stored)
+
+		expired := key
+		expired.Key = "expired"
+		expired.ExpiresAt = now.Add(-time.Second)
+		stored, err = s.db.ReserveIdempotencyKey(ctx, &expired)
+		s.Require().NoError(err)
+		s.Require().Nil(stored)
+		s.Require().NoError(s.db.CompleteIdempotencyKey(ctx, &expired))
+
+		stored, err = s.db.ReserveIdempotencyKey(ctx, &expired)
+		s.Require().NoError(err)
+		s.Nil(stored)
+	})
+}
+
+func (s *PostgresSuite) TestImportJobs() {
+	ctx := context.Background()
+	job := &models.ImportJobModel{ID: uuid.New(), UserID: uuid.New(), Format: enum.ImportCSV, Status: enum.ImportRunning, Size: 100}
+	s.Require().NoError(s.db.CreateImportJob(ctx, job))
+	s.False(job.CreatedAt.IsZero())
+
+	s.Run("saves the progress", func() {
+		companyID := uuid.New()
+		rows := []*models.ImportRowModel{
+			{JobID: job.ID, Line: 3, ErrorCode: apierrors.ErrInvalidBody.Code, ErrorMessage: "name is required"},
+			{JobID: job.ID, Line: 2, Accepted: true, CompanyID: &companyID},
+		}
+		job.Status, job.BytesRead, job.Processed, job.Accepted, job.Rejected = enum.ImportCompleted, 100, 2, 1, 1
+		s.Require().NoError(s.db.SaveImportProgress(ctx, job, rows))
+
+		stored, err := s.db.GetImportJob(ctx, job.ID.String())
+		s.Require().NoError(err)
+		s.Equal(enum.ImportCompleted, stored.Status)
+		s.Equal(int64(100), stored.BytesRead)
+		s.Equal(1, stored.Rejected)
+
+		var streamed []*models.ImportRowModel
+		s.Require().NoError(s.db.StreamImportRows(ctx, job.ID.String(), func(row *models.ImportRowModel) error {
+			streamed = append(streamed, row)
+			return nil
+		}))
+		s.Require().Len(streamed, 2)
+		s.Equal(2, streamed[0].Line)
+		s.Equal(companyID, *streamed[0].CompanyID)
+		s.Nil(streamed[1].CompanyID)
+		s.Equal("name is required", streamed[1].ErrorMessage)
+	})
+
+	s.Run("stops streaming when the handler fails", func() {
+		errStop := errors.New("stop")
+		err := s.db.StreamImportRows(ctx, job.ID.String(), func(row *models.ImportRowModel) error { return errStop })
+		s.ErrorIs(err, errStop)
+	})
+
+	s.Run("not found", func() {
+		_, err := s.db.GetImportJob(ctx, uuid.NewString())
+		s.ErrorIs(err, apierrors.ErrImportJobNotFound)
+	})
+}
+
 func TestPostgresSuite(t *testing.T) {
 	suite.Run(t, new(PostgresSuite))
 }
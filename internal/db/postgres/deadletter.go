@@ -0,0 +1,92 @@
+package postgres
+
+import (
+	"context"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/db/models"
+	"xm_test/internal/i18n"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+// UpsertDeadLetterEvent is a method that stores an event in the dead-letter table. If the event was already
+// dead-lettered, its last error is replaced and its attempts are accumulated.
+func (p *postgresDB) UpsertDeadLetterEvent(ctx context.Context, event *models.DeadLetterEventModel) error {
+	p.logger.Debugf("storing dead-lettered event: %s", event.ID)
+	args := pgx.NamedArgs{
+		"id":           event.ID.String(),
+		"type":         event.Type,
+		"version":      event.Version,
+		"timestamp":    event.Timestamp,
+		"entity_id":    event.EntityID.String(),
+		"payload":      event.Payload,
+		"last_error":   event.LastError,
+		"attempts":     event.Attempts,
+		"trace_parent": event.TraceParent,
+		"trace_state":  event.TraceState,
+	}
+	cmd := `INSERT INTO dead_letter_events (id, type, version, timestamp, entity_id, payload, last_error, attempts, trace_parent, trace_state)
+		VALUES (@id, @type, @version, @timestamp, @entity_id, @payload, @last_error, @attempts, @trace_parent, @trace_state)
+		ON CONFLICT (id) DO UPDATE SET
+			last_error = EXCLUDED.last_error,
+			attempts = dead_letter_events.attempts + EXCLUDED.attempts,
+			updated_at = NOW()`
+	p.logger.Debugf("cmd: %s", cmd)
+
+	if _, err := p.client.Exec(ctx, cmd, args); err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to store dead-lettered event").Wrap(err)
+	}
+	p.logger.Debugf("stored dead-lettered event: %s", event.ID)
+	return nil
+}
+
+// ListDeadLetterEvents is a method that retrieves the dead-lettered events, most recent first.
+func (p *postgresDB) ListDeadLetterEvents(ctx context.Context, limit int, offset int) ([]*models.DeadLetterEventModel, error) {
+	p.logger.Debugf("listing dead-lettered events")
+	events := make([]*models.DeadLetterEventModel, 0)
+	cmd := "SELECT * FROM dead_letter_events ORDER BY created_at DESC LIMIT $1 OFFSET $2"
+	p.logger.Debugf("cmd: %s", cmd)
+
+	if err := pgxscan.Select(ctx, p.client, &events, cmd, limit, offset); err != nil {
+		return nil, apierrors.ErrInternalServer.WithMessage("failed to list dead-lettered events").Wrap(err)
+	}
+	p.logger.Debugf("listed %d dead-lettered events", len(events))
+	return events, nil
+}
+
+// GetDeadLetterEvent is a method that retrieves a dead-lettered event by id.
+func (p *postgresDB) GetDeadLetterEvent(ctx context.Context, id string) (*models.DeadLetterEventModel, error) {
+	p.logger.Debugf("retrieving dead-lettered event by id: %s", id)
+	events := make([]models.DeadLetterEventModel, 0)
+	cmd := "SELECT * FROM dead_letter_events WHERE id = $1 LIMIT 1"
+	p.logger.Debugf("cmd: %s", cmd)
+
+	if err := pgxscan.Select(ctx, p.client, &events, cmd, id); err != nil {
+		return nil, apierrors.ErrInternalServer.WithMessage("failed to retrieve dead-lettered event by id").Wrap(err)
+	}
+	if len(events) == 0 {
+		return nil, apierrors.ErrDeadLetterNotFound.WithMessageID("id", i18n.Params{"id": id})
+	}
+
+	event := events[0]
+	p.logger.Debugf("retrieved dead-lettered event by id: %s", id)
+	return &event, nil
+}
+
+// DeleteDeadLetterEvent is a method that removes a dead-lettered event by id.
+func (p *postgresDB) DeleteDeadLetterEvent(ctx context.Context, id string) error {
+	p.logger.Debugf("deleting dead-lettered event by id: %s", id)
+	cmd := "DELETE FROM dead_letter_events WHERE id = $1"
+	p.logger.Debugf("cmd: %s", cmd)
+
+	tag, err := p.client.Exec(ctx, cmd, id)
+	if err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to delete dead-lettered event by id").Wrap(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return apierrors.ErrDeadLetterNotFound.WithMessageID("id", i18n.Params{"id": id})
+	}
+	p.logger.Debugf("deleted dead-lettered event by id: %s", id)
+	return nil
+}
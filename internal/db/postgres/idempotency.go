@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"time"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/db/models"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+// ReserveIdempotencyKey is a method that reserves the idempotency key of a user, so that only one request is
+// processed with it. It returns nil when the key is reserved. When the key is already reserved or holds a response,
+// the stored key is returned instead. The keys that expired, and the reservations whose lock elapsed because their
+// request was abandoned, are taken over.
+func (p *postgresDB) ReserveIdempotencyKey(ctx context.Context, key *models.IdempotencyKeyModel) (*models.IdempotencyKeyModel, error) {
+	p.logger.Debugf("reserving idempotency key: %s", key.Key)
+	now := time.Now()
+	args := pgx.NamedArgs{
+		"user_id":      key.UserID.String(),
+		"key":          key.Key,
+		"request_hash": key.RequestHash,
+		"locked_until": key.LockedUntil,
+		"expires_at":   key.ExpiresAt,
+		"now":          now,
+	}
+
+	// the expired keys of the user are purged, so the table does not grow with the keys that are never reused
+	cmd := "DELETE FROM idempotency_keys WHERE user_id = @user_id AND expires_at <= @now AND key <> @key"
+	p.logger.Debugf("cmd: %s", cmd)
+	if _, err := p.client.Exec(ctx, cmd, args); err != nil {
+		return nil, apierrors.ErrInternalServer.WithMessage("failed to purge the expired idempotency keys").Wrap(err)
+	}
+
+	for {
+		cmd = `INSERT INTO idempotency_keys (user_id, key, request_hash, locked_until, expires_at)
+			VALUES (@user_id, @key, @request_hash, @locked_until, @expires_at)
+			ON CONFLICT (user_id, key) DO UPDATE SET
+				request_hash = EXCLUDED.request_hash,
+				completed = FALSE,
+				status = 0,
+				headers = '{}',
+				body = '',
+				locked_until = EXCLUDED.locked_until,
+				expires_at = EXCLUDED.expires_at,
+				created_at = NOW()
+			WHERE idempotency_keys.expires_at <= @now OR (NOT idempotency_keys.completed AND idempotency_keys.locked_until <= @now)
+			RETURNING key`
+		p.logger.Debugf("cmd: %s", cmd)
+
+		reserved := make([]string, 0, 1)
+		if err := pgxscan.Select(ctx, p.client, &reserved, cmd, args); err != nil {
+			return nil, apierrors.ErrInternalServer.WithMessage("failed to reserve idempotency key").Wrap(err)
+		}
+		if len(reserved) > 0 {
+			p.logger.Debugf("reserved idempotency key: %s", key.Key)
+			return nil, nil
+		}
+
+		cmd = "SELECT * FROM idempotency_keys WHERE user_id = @user_id AND key = @key"
+		p.logger.Debugf("cmd: %s", cmd)
+
+		keys := make([]models.IdempotencyKeyModel, 0, 1)
+		if err := pgxscan.Select(ctx, p.client, &keys, cmd, args); err != nil {
+			return nil, apierrors.ErrInternalServer.WithMessage("failed to retrieve idempotency key").Wrap(err)
+		}
+		// the key is reserved again when it was released in the meantime
+		if len(keys) > 0 {
+			p.logger.Debugf("idempotency key already reserved: %s", key.Key)
+			return &keys[0], nil
+		}
+	}
+}
+
+// CompleteIdempotencyKey is a method that stores the response of the request of a reserved idempotency key.
+func (p *postgresDB) CompleteIdempotencyKey(ctx context.Context, key *models.IdempotencyKeyModel) error {
+	p.logger.Debugf("storing the response of idempotency key: %s", key.Key)
+	args := pgx.NamedArgs{
+		"user_id": key.UserID.String(),
+		"key":     key.Key,
+		"status":  key.Status,
+		"headers": key.Headers,
+		"body":    key.Body,
+	}
+	cmd := "UPDATE idempotency_keys SET completed = TRUE, status = @status, headers = @headers, body = @body WHERE user_id = @user_id AND key = @key"
+	p.logger.Debugf("cmd: %s", cmd)
+
+	if _, err := p.client.Exec(ctx, cmd, args); err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to store the response of idempotency key").Wrap(err)
+	}
+	p.logger.Debugf("stored the response of idempotency key: %s", key.Key)
+	return nil
+}
+
+// ReleaseIdempotencyKey is a method that removes the reservation of an idempotency key whose response is not stored,
+// so that the request can be retried with it.
+func (p *postgresDB) ReleaseIdempotencyKey(ctx context.Context, userID string, key string) error {
+	p.logger.Debugf("releasing idempotency key: %s", key)
+	cmd := "DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2 AND NOT completed"
+	p.logger.Debugf("cmd: %s", cmd)
+
+	if _, err := p.client.Exec(ctx, cmd, userID, key); err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to release idempotency key").Wrap(err)
+	}
+	p.logger.Debugf("released idempotency key: %s", key)
+	return nil
+}
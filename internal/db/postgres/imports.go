@@ -0,0 +1,124 @@
+package postgres
+
+import (
+	"context"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/db/models"
+	"xm_test/internal/i18n"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+// importRowColumns are the columns copied when the rows of an import are stored
+var importRowColumns = []string{"job_id", "line", "accepted", "company_id", "error_code", "error_message"}
+
+// CreateImportJob is a method that creates a new import job in the database.
+func (p *postgresDB) CreateImportJob(ctx context.Context, job *models.ImportJobModel) error {
+	p.logger.Debugf("creating import job: %s", job.ID)
+	args := pgx.NamedArgs{
+		"id":      job.ID.String(),
+		"user_id": job.UserID.String(),
+		"format":  job.Format,
+		"status":  job.Status,
+		"size":    job.Size,
+	}
+	cmd := "INSERT INTO import_jobs (id, user_id, format, status, size) VALUES (@id, @user_id, @format, @status, @size) RETURNING created_at, updated_at"
+	p.logger.Debugf("cmd: %s", cmd)
+
+	if err := p.client.QueryRow(ctx, cmd, args).Scan(&job.CreatedAt, &job.UpdatedAt); err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to create import job").Wrap(err)
+	}
+	p.logger.Debugf("created import job: %s", job.ID)
+	return nil
+}
+
+// GetImportJob is a method that retrieves an import job by id from the database.
+func (p *postgresDB) GetImportJob(ctx context.Context, id string) (*models.ImportJobModel, error) {
+	p.logger.Debugf("retrieving import job by id: %s", id)
+	jobs := make([]models.ImportJobModel, 0, 1)
+	cmd := "SELECT * FROM import_jobs WHERE id = $1"
+	p.logger.Debugf("cmd: %s", cmd)
+
+	if err := pgxscan.Select(ctx, p.client, &jobs, cmd, id); err != nil {
+		return nil, apierrors.ErrInternalServer.WithMessage("failed to retrieve import job by id").Wrap(err)
+	}
+	if len(jobs) == 0 {
+		return nil, apierrors.ErrImportJobNotFound.WithMessageID("id", i18n.Params{"id": id})
+	}
+	p.logger.Debugf("retrieved import job by id: %s", id)
+	return &jobs[0], nil
+}
+
+// SaveImportProgress is a method that stores the outcome of the rows processed by an import job along with its
+// counters and status. Both are written in the same transaction, so the report always matches the counters.
+func (p *postgresDB) SaveImportProgress(ctx context.Context, job *models.ImportJobModel, rows []*models.ImportRowModel) error {
+	p.logger.Debugf("saving the progress of import job: %s", job.ID)
+	tx, err := p.client.Begin(ctx)
+	if err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to save import progress").Wrap(err)
+	}
+	defer tx.Rollback(ctx)
+
+	if len(rows) > 0 {
+		source := pgx.CopyFromSlice(len(rows), func(i int) ([]any, error) {
+			row := rows[i]
+			return []any{row.JobID, row.Line, row.Accepted, row.CompanyID, row.ErrorCode, row.ErrorMessage}, nil
+		})
+		if _, err := tx.CopyFrom(ctx, pgx.Identifier{"import_rows"}, importRowColumns, source); err != nil {
+			return apierrors.ErrInternalServer.WithMessage("failed to save import rows").Wrap(err)
+		}
+	}
+
+	args := pgx.NamedArgs{
+		"id":         job.ID.String(),
+		"status":     job.Status,
+		"bytes_read": job.BytesRead,
+		"processed":  job.Processed,
+		"accepted":   job.Accepted,
+		"rejected":   job.Rejected,
+		"error":      job.Error,
+	}
+	cmd := `UPDATE import_jobs SET status = @status, bytes_read = @bytes_read, processed = @processed, accepted = @accepted,
+		rejected = @rejected, error = @error, updated_at = NOW() WHERE id = @id RETURNING updated_at`
+	p.logger.Debugf("cmd: %s", cmd)
+
+	if err := tx.QueryRow(ctx, cmd, args).Scan(&job.UpdatedAt); err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to save import progress").Wrap(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to save import progress").Wrap(err)
+	}
+	p.logger.Debugf("saved the progress of import job: %s", job.ID)
+	return nil
+}
+
+// StreamImportRows is a method that calls the handler with each row of an import job, sorted by line. The rows
+// are read one by one from the database, so the report of a large file is never held in memory.
+func (p *postgresDB) StreamImportRows(ctx context.Context, jobID string, handler func(row *models.ImportRowModel) error) error {
+	p.logger.Debugf("streaming the rows of import job: %s", jobID)
+	cmd := "SELECT * FROM import_rows WHERE job_id = $1 ORDER BY line"
+	p.logger.Debugf("cmd: %s", cmd)
+
+	rows, err := p.client.Query(ctx, cmd, jobID)
+	if err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to stream import rows").Wrap(err)
+	}
+	defer rows.Close()
+
+	scanner := pgxscan.NewRowScanner(rows)
+	for rows.Next() {
+		var row models.ImportRowModel
+		if err := scanner.Scan(&row); err != nil {
+			return apierrors.ErrInternalServer.WithMessage("failed to scan import row").Wrap(err)
+		}
+		if err := handler(&row); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to stream import rows").Wrap(err)
+	}
+	p.logger.Debugf("streamed the rows of import job: %s", jobID)
+	return nil
+}
@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"xm_test/internal/conf"
+	"xm_test/internal/db/models"
+
+	"github.com/georgysavva/scany/v2/pgxscan"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	listenMinBackoff = 500 * time.Millisecond // first delay before reconnecting the listener
+	listenMaxBackoff = 30 * time.Second       // maximum delay between reconnection attempts
+)
+
+// ListenEvents is a method that listens for the events notified by any replica through the postgres LISTEN/NOTIFY
+// mechanism and calls the handler for each of them, once loaded from the events table. It blocks until the context is cancelled, and it reconnects
+// with an exponential backoff whenever the listening connection is lost. onStatusChange is called every time the
+// listener starts or stops listening.
+func (p *postgresDB) ListenEvents(ctx context.Context, handler func(event *models.EventModel), onStatusChange func(listening bool)) error {
+	backoff := listenMinBackoff
+	for {
+		listening, err := p.listenEvents(ctx, handler, onStatusChange)
+		if listening {
+			onStatusChange(false)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// the connection was healthy for a while, so start again with the shortest delay
+		if listening {
+			backoff = listenMinBackoff
+		}
+
+		p.logger.Errorf("events listener disconnected: %s. Reconnecting in %s", err, backoff)
+		if !waitBackoff(ctx, backoff) {
+			return nil
+		}
+		backoff = min(backoff*2, listenMaxBackoff)
+	}
+}
+
+// waitBackoff waits for the backoff, and reports whether it elapsed before the context was cancelled, so the
+// listener stops right away on shutdown
+func waitBackoff(ctx context.Context, backoff time.Duration) bool {
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// listenEvents runs a single LISTEN session. It reports whether the session managed to start listening.
+func (p *postgresDB) listenEvents(ctx context.Context, handler func(event *models.EventModel), onStatusChange func(listening bool)) (bool, error) {
+	channel := conf.GlobalConfig.Postgres.NotifyChannel
+
+	conn, err := p.client.Acquire(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire listening connection: %w", err)
+	}
+
+	// the connection is taken out of the pool, so it is never reused by another query while it is still listening
+	pgConn := conn.Hijack()
+	defer pgConn.Close(context.Background())
+
+	cmd := fmt.Sprintf("LISTEN %s", pgx.Identifier{channel}.Sanitize())
+	p.logger.Debugf("cmd: %s", cmd)
+	if _, err := pgConn.Exec(ctx, cmd); err != nil {
+		return false, fmt.Errorf("failed to listen on channel '%s': %w", channel, err)
+	}
+	p.logger.Infof("listening for events on channel '%s'", channel)
+	onStatusChange(true)
+
+	for {
+		notification, err := pgConn.WaitForNotification(ctx)
+		if err != nil {
+			return true, fmt.Errorf("failed to wait for notification: %w", err)
+		}
+
+		// the notifications only carry the ID of the event, which is committed before they are delivered
+		event, err := p.getEvent(ctx, notification.Payload)
+		if err != nil {
+			p.logger.Errorf("failed to load notified event '%s': %s", notification.Payload, err)
+			continue
+		}
+		handler(event)
+	}
+}
+
+// getEvent retrieves the event with the given ID
+func (p *postgresDB) getEvent(ctx context.Context, id string) (*models.EventModel, error) {
+	cmd := "SELECT id, type, version, timestamp::timestamptz AS timestamp, entity_id, payload, trace_parent, trace_state FROM events WHERE id = $1"
+	p.logger.Debugf("cmd: %s", cmd)
+
+	var event models.EventModel
+	if err := pgxscan.Get(ctx, p.client, &event, cmd, id); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
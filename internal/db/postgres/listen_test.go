@@ -0,0 +1,49 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+	"xm_test/internal/conf"
+	"xm_test/internal/db/models"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type listenSuite struct {
+	suite.Suite
+}
+
+func (s *listenSuite) TestListenEvents() {
+	s.Run("stops while waiting to reconnect", func() {
+		conf.NewConfig()
+
+		// nothing listens on the port, so the listener keeps failing to connect and waits to reconnect
+		pool, err := pgxpool.New(context.Background(), "postgres://xm:xm@127.0.0.1:1/xm?connect_timeout=1")
+		s.Require().NoError(err)
+		defer pool.Close()
+		p := &postgresDB{logger: zap.NewNop().Sugar(), client: pool}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- p.ListenEvents(ctx, func(*models.EventModel) {}, func(bool) {})
+		}()
+
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+
+		select {
+		case err := <-done:
+			s.NoError(err)
+		case <-time.After(listenMinBackoff / 2):
+			s.FailNow("the listener kept waiting after the context was cancelled")
+		}
+	})
+}
+
+func TestListenSuite(t *testing.T) {
+	suite.Run(t, new(listenSuite))
+}
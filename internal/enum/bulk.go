@@ -0,0 +1,39 @@
+package enum
+
+// BulkOperation represents the type of an operation of a bulk write of companies
+type BulkOperation string
+
+const (
+	BulkCreate BulkOperation = "create"
+	BulkUpdate BulkOperation = "update"
+	BulkDelete BulkOperation = "delete"
+)
+
+// String returns the string representation of the bulk operation
+func (o BulkOperation) String() string {
+	return string(o)
+}
+
+// IsValid checks if the bulk operation is valid
+func (o BulkOperation) IsValid() bool {
+	switch o {
+	case BulkCreate, BulkUpdate, BulkDelete:
+		return true
+	}
+	return false
+}
+
+// BulkMode represents how the operations of a bulk write of companies are applied
+type BulkMode string
+
+const (
+	// BulkTransactional applies every operation or none of them
+	BulkTransactional BulkMode = "transactional"
+	// BulkBestEffort applies the operations that succeed and skips the ones that fail
+	BulkBestEffort BulkMode = "best_effort"
+)
+
+// String returns the string representation of the bulk mode
+func (m BulkMode) String() string {
+	return string(m)
+}
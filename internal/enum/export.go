@@ -0,0 +1,25 @@
+package enum
+
+// ExportFormat represents the format of a file of exported companies
+type ExportFormat string
+
+const (
+	ExportCSV     ExportFormat = "csv"
+	ExportNDJSON  ExportFormat = "ndjson"
+	ExportXLSX    ExportFormat = "xlsx"
+	ExportParquet ExportFormat = "parquet"
+)
+
+// String returns the string representation of the export format
+func (f ExportFormat) String() string {
+	return string(f)
+}
+
+// IsValid checks if the export format is valid
+func (f ExportFormat) IsValid() bool {
+	switch f {
+	case ExportCSV, ExportNDJSON, ExportXLSX, ExportParquet:
+		return true
+	}
+	return false
+}
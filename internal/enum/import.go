@@ -0,0 +1,37 @@
+package enum
+
+// ImportFormat represents the format of a file of companies to import
+type ImportFormat string
+
+const (
+	ImportCSV    ImportFormat = "csv"
+	ImportNDJSON ImportFormat = "ndjson"
+)
+
+// String returns the string representation of the import format
+func (f ImportFormat) String() string {
+	return string(f)
+}
+
+// IsValid checks if the import format is valid
+func (f ImportFormat) IsValid() bool {
+	switch f {
+	case ImportCSV, ImportNDJSON:
+		return true
+	}
+	return false
+}
+
+// ImportStatus represents the status of an import job
+type ImportStatus string
+
+const (
+	ImportRunning   ImportStatus = "running"
+	ImportCompleted ImportStatus = "completed"
+	ImportFailed    ImportStatus = "failed"
+)
+
+// String returns the string representation of the import status
+func (s ImportStatus) String() string {
+	return string(s)
+}
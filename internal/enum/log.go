@@ -5,8 +5,10 @@ type LogLevel string
 
 // Log levels
 const (
-	Debug     LogLevel = "debug"
-	InfoLevel LogLevel = "info"
+	Debug      LogLevel = "debug"
+	InfoLevel  LogLevel = "info"
+	WarnLevel  LogLevel = "warn"
+	ErrorLevel LogLevel = "error"
 )
 
 // String returns the string representation of the log level
@@ -17,7 +19,31 @@ func (e LogLevel) String() string {
 // IsValid checks if the log level is valid
 func (e LogLevel) IsValid() bool {
 	switch e {
-	case Debug, InfoLevel:
+	case Debug, InfoLevel, WarnLevel, ErrorLevel:
+		return true
+	default:
+		return false
+	}
+}
+
+// LogFormat is a type for the encodings of the logs
+type LogFormat string
+
+// Log formats
+const (
+	ConsoleFormat LogFormat = "console"
+	JSONFormat    LogFormat = "json"
+)
+
+// String returns the string representation of the log format
+func (e LogFormat) String() string {
+	return string(e)
+}
+
+// IsValid checks if the log format is valid
+func (e LogFormat) IsValid() bool {
+	switch e {
+	case ConsoleFormat, JSONFormat:
 		return true
 	default:
 		return false
@@ -0,0 +1,24 @@
+package enum
+
+// TracingExporter is an enum to represent where the traces are exported
+type TracingExporter string
+
+const (
+	TracingNone   TracingExporter = "none"
+	TracingOTLP   TracingExporter = "otlp"
+	TracingStdout TracingExporter = "stdout"
+)
+
+// String returns the string value of the TracingExporter
+func (e TracingExporter) String() string {
+	return string(e)
+}
+
+// IsValid checks if the TracingExporter is valid
+func (e TracingExporter) IsValid() bool {
+	switch e {
+	case TracingNone, TracingOTLP, TracingStdout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,23 @@
+package enum
+
+// TransportType is an enum to represent the transports served by the API
+type TransportType string
+
+const (
+	HTTPTransport TransportType = "http"
+	GRPCTransport TransportType = "grpc"
+)
+
+// String returns the string value of the TransportType
+func (e TransportType) String() string {
+	return string(e)
+}
+
+// IsValid checks if the TransportType is valid
+func (e TransportType) IsValid() bool {
+	switch e {
+	case HTTPTransport, GRPCTransport:
+		return true
+	}
+	return false
+}
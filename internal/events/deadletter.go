@@ -0,0 +1,82 @@
+package events
+
+import (
+	"context"
+	"time"
+	"xm_test/internal/db"
+	"xm_test/internal/db/models"
+
+	"go.uber.org/zap"
+)
+
+// databaseTimeout is the maximum time spent by the events package in a single database operation
+const databaseTimeout = 10 * time.Second
+
+type deadLetterQueue struct {
+	logger     *zap.SugaredLogger
+	db         db.DatabaseAdapter
+	dispatcher Dispatcher
+}
+
+// newDeadLetterQueue returns a new dead-letter queue that re-dispatches the events with the given dispatcher
+func newDeadLetterQueue(logger *zap.SugaredLogger, db db.DatabaseAdapter, dispatcher Dispatcher) *deadLetterQueue {
+	return &deadLetterQueue{
+		logger:     logger,
+		db:         db,
+		dispatcher: dispatcher,
+	}
+}
+
+// List returns the dead-lettered events, most recent first
+func (q *deadLetterQueue) List(ctx context.Context, limit int, offset int) ([]*models.DeadLetterEventModel, error) {
+	q.logger.Debugf("listing dead-lettered events (limit %d, offset %d)", limit, offset)
+	return q.db.ListDeadLetterEvents(ctx, limit, offset)
+}
+
+// Get returns the dead-lettered event with the given ID
+func (q *deadLetterQueue) Get(ctx context.Context, id string) (*models.DeadLetterEventModel, error) {
+	q.logger.Debugf("retrieving dead-lettered event '%s'", id)
+	return q.db.GetDeadLetterEvent(ctx, id)
+}
+
+// Retry dispatches the dead-lettered event again. The event is removed from the dead-letter table when it succeeds,
+// otherwise its last error is updated.
+func (q *deadLetterQueue) Retry(ctx context.Context, id string) error {
+	q.logger.Infof("retrying dead-lettered event '%s'", id)
+	deadLetter, err := q.db.GetDeadLetterEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	event := &Event{
+		Type:      deadLetter.Type,
+		Version:   deadLetter.Version,
+		Timestamp: deadLetter.Timestamp,
+		ID:        deadLetter.ID,
+		EntityID:  deadLetter.EntityID,
+		Payload:   deadLetter.Payload,
+
+		TraceParent: deadLetter.TraceParent,
+		TraceState:  deadLetter.TraceState,
+	}
+	if err := q.dispatcher.Dispatch(event); err != nil {
+		return err
+	}
+
+	if err := q.db.DeleteDeadLetterEvent(ctx, id); err != nil {
+		return err
+	}
+	q.logger.Infof("dead-lettered event '%s' dispatched", id)
+	return nil
+}
+
+// Discard removes the dead-lettered event without dispatching it
+func (q *deadLetterQueue) Discard(ctx context.Context, id string) error {
+	q.logger.Infof("discarding dead-lettered event '%s'", id)
+	return q.db.DeleteDeadLetterEvent(ctx, id)
+}
+
+// newDatabaseContext returns the context used by the events package when no request context is available
+func newDatabaseContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), databaseTimeout)
+}
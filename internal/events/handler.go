@@ -2,31 +2,53 @@ package events
 
 import (
 	"context"
-	"fmt"
 	"time"
 	apierrors "xm_test/internal/api_errors"
 	"xm_test/internal/db"
 	"xm_test/internal/db/models"
+	"xm_test/internal/tracing"
 
 	"github.com/jackc/pgx/v5/pgtype"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
 type eventHandler struct {
-	logger *zap.SugaredLogger
-	db     db.DatabaseAdapter
+	logger   *zap.SugaredLogger
+	db       db.DatabaseAdapter
+	registry Registry
 }
 
 // NewEventHandler returns a new event handler instance
-func newEventHandler(logger *zap.SugaredLogger, db db.DatabaseAdapter) *eventHandler {
+func newEventHandler(logger *zap.SugaredLogger, db db.DatabaseAdapter, registry Registry) *eventHandler {
 	return &eventHandler{
-		logger: logger,
-		db:     db,
+		logger:   logger,
+		db:       db,
+		registry: registry,
 	}
 }
 
-// Dispatch dispatches an event to kafka, rabbitmq, or any other event bus, and it stores the event in the database
-func (e *eventHandler) Dispatch(event *Event) error {
+// Close does nothing, since the dispatches are not retried
+func (e *eventHandler) Close() {}
+
+// Dispatch dispatches an event to kafka, rabbitmq, or any other event bus, and it stores the event in the database.
+// The dispatch is traced as part of the trace carried by the event.
+func (e *eventHandler) Dispatch(event *Event) (err error) {
+	ctx := tracing.Extract(context.Background(), event.TraceParent, event.TraceState)
+	ctx, span := tracing.Start(ctx, "events.Dispatch", trace.WithSpanKind(trace.SpanKindProducer), trace.WithAttributes(
+		attribute.String("event.id", event.ID.String()),
+		attribute.String("event.type", event.Type),
+		attribute.String("event.entity_id", event.EntityID.String()),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	// validate event against the registry
+	e.logger.Debugf("validating event '%s' of type '%s'", event.ID, event.Type)
+	if err := e.registry.Validate(event); err != nil {
+		return err
+	}
+
 	// dispatch event to event bus
 	e.logger.Infof("dispatching event '%s' to event bus with ID '%s' at '%s'", event.Type, event.ID, event.Timestamp.Format(time.RFC3339))
 	// TODO: here you would dispatch the event to an event bus like kafka, rabbitmq, etc.
@@ -36,14 +58,19 @@ func (e *eventHandler) Dispatch(event *Event) error {
 	e.logger.Debugf("storing event '%s' in database", event.ID)
 	eventModel := &models.EventModel{
 		Type:      event.Type,
+		Version:   event.Version,
 		Timestamp: pgtype.Timestamptz{Time: event.Timestamp, Valid: true},
 		ID:        event.ID,
 		EntityID:  event.EntityID,
+		Payload:   event.Payload,
+
+		TraceParent: event.TraceParent,
+		TraceState:  event.TraceState,
 	}
-	if err := e.db.CreateEvent(context.Background(), eventModel); err != nil {
-		e := apierrors.ErrCreatingEvent
-		e.Message = fmt.Sprintf("failed to store event in database: %s", err)
-		return e
+	ctx, cancel := context.WithTimeout(ctx, databaseTimeout)
+	defer cancel()
+	if err := e.db.CreateEvent(ctx, eventModel); err != nil {
+		return apierrors.ErrCreatingEvent.WithMessage("failed to store event in database").Wrap(err)
 	}
 	e.logger.Debugf("event '%s' stored in database", event.ID)
 	return nil
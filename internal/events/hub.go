@@ -0,0 +1,66 @@
+package events
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// subscriberBufferSize is the amount of events buffered for each subscriber before new events are dropped
+const subscriberBufferSize = 64
+
+type hub struct {
+	logger *zap.SugaredLogger
+
+	mu          sync.RWMutex
+	nextID      int
+	subscribers map[int]chan *Event
+}
+
+// newHub returns a new in-process subscriber hub
+func newHub(logger *zap.SugaredLogger) *hub {
+	return &hub{
+		logger:      logger,
+		subscribers: make(map[int]chan *Event),
+	}
+}
+
+// Publish fans the event out to every local subscriber. Slow subscribers whose buffer is full miss the event
+// instead of blocking the rest of them.
+func (h *hub) Publish(event *Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	h.logger.Debugf("publishing event '%s' to %d local subscribers", event.ID, len(h.subscribers))
+	for id, ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+			h.logger.Warnf("subscriber %d is not keeping up, dropping event '%s'", id, event.ID)
+		}
+	}
+}
+
+// Subscribe registers a new local subscriber. The returned function must be called to release the subscription.
+func (h *hub) Subscribe() (<-chan *Event, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	id := h.nextID
+	h.nextID++
+	ch := make(chan *Event, subscriberBufferSize)
+	h.subscribers[id] = ch
+	h.logger.Debugf("subscriber %d registered", id)
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.subscribers, id)
+			close(ch)
+			h.logger.Debugf("subscriber %d unregistered", id)
+		})
+	}
+	return ch, unsubscribe
+}
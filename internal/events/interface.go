@@ -1,8 +1,12 @@
 package events
 
 import (
+	"context"
+	"encoding/json"
 	"time"
 	"xm_test/internal/db"
+	"xm_test/internal/db/models"
+	"xm_test/internal/enum"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -17,19 +21,96 @@ import (
 //
 // - Delete: When a company is deleted from the database
 type Event struct {
-	Type      string    `json:"type" db:"type"`           // create_company, update_company, delete_company
-	Timestamp time.Time `json:"timestamp" db:"timestamp"` // The time the event was created
-	ID        uuid.UUID `json:"id" db:"id"`               // The unique identifier of the event
-	EntityID  uuid.UUID `json:"entity_id" db:"entity_id"` // The unique identifier of the entity that the event is related to
+	Type      string          `json:"type" db:"type"`           // create_company, update_company, delete_company
+	Version   int             `json:"version" db:"version"`     // The version of the payload schema
+	Timestamp time.Time       `json:"timestamp" db:"timestamp"` // The time the event was created
+	ID        uuid.UUID       `json:"id" db:"id"`               // The unique identifier of the event
+	EntityID  uuid.UUID       `json:"entity_id" db:"entity_id"` // The unique identifier of the entity that the event is related to
+	Payload   json.RawMessage `json:"payload" db:"payload"`     // The data of the event, validated against the registered schema
+
+	TraceParent string `json:"traceparent,omitempty" db:"trace_parent"` // W3C traceparent of the request that triggered the event
+	TraceState  string `json:"tracestate,omitempty" db:"trace_state"`   // W3C tracestate of the request that triggered the event
+}
+
+// DeletedCompanyPayload is the payload of the delete_company events
+type DeletedCompanyPayload struct {
+	ID uuid.UUID `json:"id"` // The unique identifier of the deleted company
 }
 
 // Event is the interface that defines the methods that the dispatcher must implement
 type Dispatcher interface {
 	// Dispatch dispatches an event to kafka, rabbitmq, or any other event bus, and it stores the event in the database
 	Dispatch(event *Event) error
+	// Close stops waiting to retry the failed dispatches, so the shutdown does not wait for their backoff
+	Close()
+}
+
+// NewEventsDispatcher returns a new events dispatcher instance that validates the events against the registry. Failed
+// dispatches are retried with the policy defined in the configuration, and the events that keep failing are stored in
+// the dead-letter table.
+func NewEventsDispatcher(logger *zap.SugaredLogger, db db.DatabaseAdapter, registry Registry) Dispatcher {
+	return newRetryDispatcher(logger, db, newEventHandler(logger, db, registry), retryPolicyFromConfig())
+}
+
+// DeadLetterQueue is the interface that defines the methods to manage the events that could not be dispatched
+type DeadLetterQueue interface {
+	// List returns the dead-lettered events, most recent first
+	List(ctx context.Context, limit int, offset int) ([]*models.DeadLetterEventModel, error)
+	// Get returns the dead-lettered event with the given ID
+	Get(ctx context.Context, id string) (*models.DeadLetterEventModel, error)
+	// Retry dispatches the dead-lettered event again, removing it from the dead-letter table when it succeeds
+	Retry(ctx context.Context, id string) error
+	// Discard removes the dead-lettered event without dispatching it
+	Discard(ctx context.Context, id string) error
+}
+
+// NewDeadLetterQueue returns a new dead-letter queue instance that re-dispatches the events with the given dispatcher
+func NewDeadLetterQueue(logger *zap.SugaredLogger, db db.DatabaseAdapter, dispatcher Dispatcher) DeadLetterQueue {
+	return newDeadLetterQueue(logger, db, dispatcher)
+}
+
+// Registry is the interface that defines the methods of the events schema registry. It declares every event type
+// with the version and the JSON schema of its payload.
+type Registry interface {
+	// NewEvent creates a new event of the given type with the current version of its payload. The trace context of
+	// ctx is propagated into the event.
+	NewEvent(ctx context.Context, eventType enum.EventType, entityID uuid.UUID, payload any) (*Event, error)
+	// Validate checks that the event matches its registered definition
+	Validate(event *Event) error
+	// Definitions returns every registered event definition
+	Definitions() []*EventDefinition
+	// Verify checks that the event types supported by the database are exactly the registered ones
+	Verify(databaseTypes []string) error
+}
+
+// NewEventsRegistry returns a new events registry instance with the schemas of every declared event type
+func NewEventsRegistry() (Registry, error) {
+	return newRegistry()
+}
+
+// Hub is the interface that defines the methods of the in-process subscriber hub. It fans out the events
+// received from the database to every local consumer (SSE clients, etc.)
+type Hub interface {
+	// Publish sends the event to every local subscriber
+	Publish(event *Event)
+	// Subscribe registers a new local subscriber. The returned function releases the subscription
+	Subscribe() (<-chan *Event, func())
+}
+
+// Listener is the interface that defines the methods that the cross-replica events listener must implement
+type Listener interface {
+	// Listen blocks until the context is cancelled, feeding the local hub with the events dispatched in any replica
+	Listen(ctx context.Context) error
+	// Listening reports whether the listener is currently receiving the events of the other replicas
+	Listening() bool
+}
+
+// NewEventsHub returns a new in-process events hub instance
+func NewEventsHub(logger *zap.SugaredLogger) Hub {
+	return newHub(logger)
 }
 
-// NewEventsDispatcher returns a new events dispatcher instance
-func NewEventsDispatcher(logger *zap.SugaredLogger, db db.DatabaseAdapter) Dispatcher {
-	return newEventHandler(logger, db)
+// NewEventsListener returns a new events listener instance that publishes the events in the given hub
+func NewEventsListener(logger *zap.SugaredLogger, db db.DatabaseAdapter, hub Hub) Listener {
+	return newListener(logger, db, hub)
 }
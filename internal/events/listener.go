@@ -0,0 +1,53 @@
+package events
+
+import (
+	"context"
+	"sync/atomic"
+	"xm_test/internal/db"
+	"xm_test/internal/db/models"
+
+	"go.uber.org/zap"
+)
+
+type listener struct {
+	logger *zap.SugaredLogger
+	db     db.DatabaseAdapter
+	hub    Hub
+
+	listening atomic.Bool
+}
+
+// newListener returns a new listener that feeds the events stored by any replica into the local hub
+func newListener(logger *zap.SugaredLogger, db db.DatabaseAdapter, hub Hub) *listener {
+	return &listener{
+		logger: logger,
+		db:     db,
+		hub:    hub,
+	}
+}
+
+// Listen blocks until the context is cancelled, publishing in the local hub every event notified by the database
+func (l *listener) Listen(ctx context.Context) error {
+	l.logger.Infof("starting events listener")
+	err := l.db.ListenEvents(ctx, func(event *models.EventModel) {
+		l.logger.Debugf("received event '%s' from database", event.ID)
+		l.hub.Publish(&Event{
+			Type:      event.Type,
+			Version:   event.Version,
+			Timestamp: event.Timestamp.Time,
+			ID:        event.ID,
+			EntityID:  event.EntityID,
+			Payload:   event.Payload,
+
+			TraceParent: event.TraceParent,
+			TraceState:  event.TraceState,
+		})
+	}, l.listening.Store)
+	l.logger.Infof("events listener stopped")
+	return err
+}
+
+// Listening reports whether the listener is currently receiving the events of the other replicas
+func (l *listener) Listening() bool {
+	return l.listening.Load()
+}
@@ -0,0 +1,168 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/enum"
+	"xm_test/internal/tracing"
+
+	"github.com/google/uuid"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+//go:embed schemas/*.json
+var schemasFS embed.FS
+
+// definitions declares every event type handled by the API, together with the current version and the JSON schema
+// of its payload. Event types added here must also be added to the EVENT_TYPE enum of the database.
+var definitions = []struct {
+	eventType enum.EventType
+	version   int
+	schema    string
+}{
+	{eventType: enum.EventCreateCompany, version: 1, schema: "schemas/company.v1.json"},
+	{eventType: enum.EventUpdateCompany, version: 1, schema: "schemas/company.v1.json"},
+	{eventType: enum.EventDeleteCompany, version: 1, schema: "schemas/company_deleted.v1.json"},
+}
+
+// EventDefinition describes a registered event type
+type EventDefinition struct {
+	Type    string          `json:"type"`    // event type as stored in the database
+	Version int             `json:"version"` // current version of the payload
+	Schema  json.RawMessage `json:"schema"`  // JSON schema of the payload
+
+	compiled *jsonschema.Schema
+}
+
+type registry struct {
+	definitions []*EventDefinition
+	byType      map[string]*EventDefinition
+}
+
+// newRegistry compiles the schemas of every declared event type
+func newRegistry() (*registry, error) {
+	r := &registry{byType: make(map[string]*EventDefinition)}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.AssertFormat()
+	for _, d := range definitions {
+		raw, err := schemasFS.ReadFile(d.schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema of event '%s': %w", d.eventType, err)
+		}
+
+		doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode schema of event '%s': %w", d.eventType, err)
+		}
+
+		url := fmt.Sprintf("urn:xm:events:%s:v%d", d.eventType, d.version)
+		if err := compiler.AddResource(url, doc); err != nil {
+			return nil, fmt.Errorf("failed to load schema of event '%s': %w", d.eventType, err)
+		}
+		compiled, err := compiler.Compile(url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compile schema of event '%s': %w", d.eventType, err)
+		}
+
+		def := &EventDefinition{
+			Type:     d.eventType.String(),
+			Version:  d.version,
+			Schema:   raw,
+			compiled: compiled,
+		}
+		r.definitions = append(r.definitions, def)
+		r.byType[def.Type] = def
+	}
+
+	return r, nil
+}
+
+// NewEvent creates a new event of the given type with the current version of its payload. The trace context of
+// ctx is propagated into the event.
+func (r *registry) NewEvent(ctx context.Context, eventType enum.EventType, entityID uuid.UUID, payload any) (*Event, error) {
+	def, ok := r.byType[eventType.String()]
+	if !ok {
+		return nil, r.invalidEvent("event type '%s' is not registered", eventType)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, r.invalidEvent("failed to encode payload of event '%s': %s", eventType, err)
+	}
+
+	traceParent, traceState := tracing.Inject(ctx)
+	return &Event{
+		Type:        def.Type,
+		Version:     def.Version,
+		Timestamp:   time.Now(),
+		ID:          uuid.New(),
+		EntityID:    entityID,
+		Payload:     data,
+		TraceParent: traceParent,
+		TraceState:  traceState,
+	}, nil
+}
+
+// Validate checks that the event type is registered, that the version is the current one and that the payload
+// matches the schema
+func (r *registry) Validate(event *Event) error {
+	def, ok := r.byType[event.Type]
+	if !ok {
+		return r.invalidEvent("event type '%s' is not registered", event.Type)
+	}
+
+	if event.Version != def.Version {
+		return r.invalidEvent("event '%s' has version %d but the registered version is %d", event.Type, event.Version, def.Version)
+	}
+
+	payload, err := jsonschema.UnmarshalJSON(bytes.NewReader(event.Payload))
+	if err != nil {
+		return r.invalidEvent("payload of event '%s' is not valid JSON: %s", event.Type, err)
+	}
+	if err := def.compiled.Validate(payload); err != nil {
+		return r.invalidEvent("payload of event '%s' does not match its schema: %s", event.Type, err)
+	}
+
+	return nil
+}
+
+// Definitions returns every registered event definition
+func (r *registry) Definitions() []*EventDefinition {
+	return r.definitions
+}
+
+// Verify checks that the event types supported by the database are exactly the registered ones
+func (r *registry) Verify(databaseTypes []string) error {
+	var missingInDB, missingInRegistry []string
+	for _, def := range r.definitions {
+		if !slices.Contains(databaseTypes, def.Type) {
+			missingInDB = append(missingInDB, def.Type)
+		}
+	}
+	for _, t := range databaseTypes {
+		if _, ok := r.byType[t]; !ok {
+			missingInRegistry = append(missingInRegistry, t)
+		}
+	}
+
+	if len(missingInDB) == 0 && len(missingInRegistry) == 0 {
+		return nil
+	}
+	return fmt.Errorf(
+		"events registry and database diverge: missing in database [%s], missing in registry [%s]",
+		strings.Join(missingInDB, ", "),
+		strings.Join(missingInRegistry, ", "),
+	)
+}
+
+func (r *registry) invalidEvent(format string, args ...any) error {
+	return apierrors.ErrInvalidEvent.WithMessagef(format, args...)
+}
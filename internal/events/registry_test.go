@@ -0,0 +1,87 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"xm_test/internal/db/models"
+	"xm_test/internal/enum"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type registrySuite struct {
+	registry *registry
+	suite.Suite
+}
+
+func (s *registrySuite) SetupSuite() {
+	registry, err := newRegistry()
+	s.Require().NoError(err)
+	s.registry = registry
+}
+
+func (s *registrySuite) TestValidate() {
+	company := &models.CompanyModel{
+		ID:              uuid.New(),
+		Name:            "test",
+		Description:     "test",
+		AmountEmployees: 10,
+		Registered:      true,
+		Type:            enum.Corporation.String(),
+	}
+
+	s.Run("ok", func() {
+		event, err := s.registry.NewEvent(context.Background(), enum.EventCreateCompany, company.ID, company)
+		s.Require().NoError(err)
+		s.Equal(1, event.Version)
+		s.NoError(s.registry.Validate(event))
+
+		event, err = s.registry.NewEvent(context.Background(), enum.EventDeleteCompany, company.ID, &DeletedCompanyPayload{ID: company.ID})
+		s.Require().NoError(err)
+		s.NoError(s.registry.Validate(event))
+	})
+
+	s.Run("unknown type", func() {
+		event, err := s.registry.NewEvent(context.Background(), enum.EventCreateCompany, company.ID, company)
+		s.Require().NoError(err)
+		event.Type = "unknown"
+		s.Error(s.registry.Validate(event))
+	})
+
+	s.Run("invalid version", func() {
+		event, err := s.registry.NewEvent(context.Background(), enum.EventCreateCompany, company.ID, company)
+		s.Require().NoError(err)
+		event.Version = 2
+		s.Error(s.registry.Validate(event))
+	})
+
+	s.Run("invalid payload", func() {
+		event, err := s.registry.NewEvent(context.Background(), enum.EventUpdateCompany, company.ID, company)
+		s.Require().NoError(err)
+		event.Payload = json.RawMessage(`{"id":"not-a-uuid","name":"test"}`)
+		s.Error(s.registry.Validate(event))
+	})
+}
+
+func (s *registrySuite) TestVerify() {
+	s.Run("ok", func() {
+		err := s.registry.Verify([]string{"create_company", "update_company", "delete_company"})
+		s.NoError(err)
+	})
+
+	s.Run("missing in database", func() {
+		err := s.registry.Verify([]string{"create_company", "update_company"})
+		s.ErrorContains(err, "missing in database [delete_company]")
+	})
+
+	s.Run("missing in registry", func() {
+		err := s.registry.Verify([]string{"create_company", "update_company", "delete_company", "archive_company"})
+		s.ErrorContains(err, "missing in registry [archive_company]")
+	})
+}
+
+func TestRegistrySuite(t *testing.T) {
+	suite.Run(t, new(registrySuite))
+}
@@ -0,0 +1,153 @@
+package events
+
+import (
+	"errors"
+	"math/rand/v2"
+	"sync"
+	"time"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/conf"
+	"xm_test/internal/db"
+	"xm_test/internal/db/models"
+	"xm_test/internal/metrics"
+
+	"go.uber.org/zap"
+)
+
+// RetryPolicy defines how many times a failed dispatch is retried and how long to wait between attempts
+type RetryPolicy struct {
+	MaxAttempts    int           // attempts before sending the event to the dead-letter table
+	InitialBackoff time.Duration // delay before the first retry
+	MaxBackoff     time.Duration // maximum delay between retries
+}
+
+// retryPolicyFromConfig returns the retry policy defined in the configuration
+func retryPolicyFromConfig() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    conf.GlobalConfig.EventsRetry.MaxAttempts,
+		InitialBackoff: conf.GlobalConfig.EventsRetry.InitialBackoff,
+		MaxBackoff:     conf.GlobalConfig.EventsRetry.MaxBackoff,
+	}
+}
+
+// Backoff returns the delay to wait after the given failed attempt. The delay grows exponentially, and it is
+// randomized with full jitter so the replicas do not retry in lockstep.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	ceiling := p.InitialBackoff << (attempt - 1)
+	if ceiling <= 0 || ceiling > p.MaxBackoff {
+		ceiling = p.MaxBackoff
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(ceiling) + 1))
+}
+
+type retryDispatcher struct {
+	logger *zap.SugaredLogger
+	db     db.DatabaseAdapter
+	next   Dispatcher
+	policy RetryPolicy
+	wait   func(time.Duration) bool
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// newRetryDispatcher wraps the dispatcher with the retry policy. Events that keep failing are stored in the
+// dead-letter table.
+func newRetryDispatcher(logger *zap.SugaredLogger, db db.DatabaseAdapter, next Dispatcher, policy RetryPolicy) *retryDispatcher {
+	d := &retryDispatcher{
+		logger: logger,
+		db:     db,
+		next:   next,
+		policy: policy,
+		closed: make(chan struct{}),
+	}
+	d.wait = d.sleep
+	return d
+}
+
+// sleep waits for the backoff, and reports whether it elapsed before the dispatcher was closed
+func (d *retryDispatcher) sleep(backoff time.Duration) bool {
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-d.closed:
+		return false
+	}
+}
+
+// Close interrupts the dispatches waiting to be retried, which store their event in the dead-letter table right
+// away. It can be called several times.
+func (d *retryDispatcher) Close() {
+	d.closeOnce.Do(func() {
+		close(d.closed)
+	})
+	d.next.Close()
+}
+
+// Dispatch dispatches the event retrying it according to the policy
+func (d *retryDispatcher) Dispatch(event *Event) error {
+	var err error
+	attempt := 1
+	for ; ; attempt++ {
+		if err = d.next.Dispatch(event); err == nil {
+			metrics.EventsDispatched.WithLabelValues(event.Type, metrics.ResultSuccess).Inc()
+			return nil
+		}
+
+		// invalid events will never succeed, so there is no point in retrying them
+		if isInvalidEvent(err) || attempt >= d.policy.MaxAttempts {
+			break
+		}
+
+		backoff := d.policy.Backoff(attempt)
+		d.logger.Warnf("failed to dispatch event '%s' (attempt %d/%d): %s. Retrying in %s", event.ID, attempt, d.policy.MaxAttempts, err, backoff)
+		metrics.EventDispatchRetries.WithLabelValues(event.Type).Inc()
+		if !d.wait(backoff) {
+			d.logger.Warnf("dispatcher closed while waiting to retry event '%s'", event.ID)
+			break
+		}
+	}
+
+	metrics.EventsDispatched.WithLabelValues(event.Type, metrics.ResultFailure).Inc()
+	d.logger.Errorf("event '%s' failed after %d attempts, sending it to the dead-letter table: %s", event.ID, attempt, err)
+	if dlErr := d.deadLetter(event, attempt, err); dlErr != nil {
+		d.logger.Errorf("failed to store event '%s' in the dead-letter table: %s", event.ID, dlErr)
+	}
+	return err
+}
+
+// deadLetter stores the event and the last error in the dead-letter table
+func (d *retryDispatcher) deadLetter(event *Event, attempts int, lastErr error) error {
+	ctx, cancel := newDatabaseContext()
+	defer cancel()
+
+	deadLetter := &models.DeadLetterEventModel{
+		ID:        event.ID,
+		Type:      event.Type,
+		Version:   event.Version,
+		Timestamp: event.Timestamp,
+		EntityID:  event.EntityID,
+		Payload:   event.Payload,
+		LastError: lastErr.Error(),
+		Attempts:  attempts,
+
+		TraceParent: event.TraceParent,
+		TraceState:  event.TraceState,
+	}
+	if err := d.db.UpsertDeadLetterEvent(ctx, deadLetter); err != nil {
+		return err
+	}
+	metrics.EventsDeadLettered.WithLabelValues(event.Type).Inc()
+	return nil
+}
+
+// isInvalidEvent reports whether the error was caused by an event that does not match the registry
+func isInvalidEvent(err error) bool {
+	return errors.Is(err, apierrors.ErrInvalidEvent)
+}
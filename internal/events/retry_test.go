@@ -0,0 +1,119 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"xm_test/internal/db"
+	"xm_test/internal/db/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+// deadLetterDB stores the dead-lettered events in memory
+type deadLetterDB struct {
+	db.DatabaseAdapter
+	deadLetters []*models.DeadLetterEventModel
+}
+
+func (d *deadLetterDB) UpsertDeadLetterEvent(ctx context.Context, event *models.DeadLetterEventModel) error {
+	d.deadLetters = append(d.deadLetters, event)
+	return nil
+}
+
+// failingDispatcher fails the first `failures` dispatches
+type failingDispatcher struct {
+	failures int
+	calls    int
+}
+
+func (d *failingDispatcher) Dispatch(event *Event) error {
+	d.calls++
+	if d.calls <= d.failures {
+		return errors.New("event bus unavailable")
+	}
+	return nil
+}
+
+func (d *failingDispatcher) Close() {}
+
+type retrySuite struct {
+	suite.Suite
+}
+
+func (s *retrySuite) newDispatcher(next Dispatcher, db *deadLetterDB) *retryDispatcher {
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond}
+	d := newRetryDispatcher(zap.NewExample().Sugar(), db, next, policy)
+	d.wait = func(time.Duration) bool { return true }
+	return d
+}
+
+func (s *retrySuite) TestDispatch() {
+	event := &Event{Type: "create_company", Version: 1, ID: uuid.New(), EntityID: uuid.New(), Timestamp: time.Now()}
+
+	s.Run("succeeds after retrying", func() {
+		db := &deadLetterDB{}
+		next := &failingDispatcher{failures: 2}
+		err := s.newDispatcher(next, db).Dispatch(event)
+		s.Require().NoError(err)
+
+		s.Equal(3, next.calls)
+		s.Empty(db.deadLetters)
+	})
+
+	s.Run("dead-lettered after exhausting the attempts", func() {
+		db := &deadLetterDB{}
+		next := &failingDispatcher{failures: 5}
+		err := s.newDispatcher(next, db).Dispatch(event)
+		s.Require().Error(err)
+
+		s.Equal(3, next.calls)
+		s.Require().Len(db.deadLetters, 1)
+		s.Equal(event.ID, db.deadLetters[0].ID)
+		s.Equal(3, db.deadLetters[0].Attempts)
+		s.Equal("event bus unavailable", db.deadLetters[0].LastError)
+	})
+
+	s.Run("dead-lettered when closed while waiting to retry", func() {
+		db := &deadLetterDB{}
+		next := &failingDispatcher{failures: 5}
+		policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour, MaxBackoff: time.Hour}
+		d := newRetryDispatcher(zap.NewExample().Sugar(), db, next, policy)
+
+		done := make(chan error)
+		go func() { done <- d.Dispatch(event) }()
+
+		// the dispatch fails and waits for its backoff until the dispatcher is closed
+		time.Sleep(50 * time.Millisecond)
+		d.Close()
+		d.Close()
+
+		select {
+		case err := <-done:
+			s.Require().Error(err)
+		case <-time.After(time.Second):
+			s.FailNow("the dispatch kept waiting after the dispatcher was closed")
+		}
+		s.Equal(1, next.calls)
+		s.Require().Len(db.deadLetters, 1)
+		s.Equal(1, db.deadLetters[0].Attempts)
+	})
+}
+
+func (s *retrySuite) TestBackoff() {
+	policy := RetryPolicy{MaxAttempts: 10, InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	for attempt := 1; attempt <= 10; attempt++ {
+		backoff := policy.Backoff(attempt)
+		s.GreaterOrEqual(backoff, time.Duration(0))
+		s.LessOrEqual(backoff, policy.MaxBackoff)
+		s.LessOrEqual(backoff, policy.InitialBackoff<<(attempt-1))
+	}
+}
+
+func TestRetrySuite(t *testing.T) {
+	suite.Run(t, new(retrySuite))
+}
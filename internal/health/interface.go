@@ -0,0 +1,55 @@
+package health
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Status represents the status of a check or of the whole service
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+// Checker is the interface that every dependency check must implement
+type Checker interface {
+	Name() string                    // name of the check shown in the report
+	Check(ctx context.Context) error // returns an error if the dependency is not healthy
+}
+
+// CheckResult represents the result of a single check
+type CheckResult struct {
+	Name      string    `json:"name"`            // name of the check
+	Status    Status    `json:"status"`          // up or down
+	Latency   string    `json:"latency"`         // time spent running the check
+	Error     string    `json:"error,omitempty"` // reason why the check failed
+	CheckedAt time.Time `json:"checked_at"`      // time when the check was run
+	Cached    bool      `json:"cached"`          // whether the result was served from the cache
+}
+
+// Report represents the aggregated result of every check
+type Report struct {
+	Status Status        `json:"status"`           // up if every check is up
+	Checks []CheckResult `json:"checks,omitempty"` // result of each check, only included in verbose reports
+}
+
+// Monitor is the interface that runs the registered checks
+type Monitor interface {
+	// Check runs every registered check, or serves their cached result, and returns the aggregated report
+	Check(ctx context.Context) *Report
+}
+
+// NewChecker returns a new checker that runs the given function
+func NewChecker(name string, check func(ctx context.Context) error) Checker {
+	return &checkerFunc{name: name, check: check}
+}
+
+// NewHealthMonitor returns a new monitor that runs each check with the given timeout and caches its result for the
+// given ttl
+func NewHealthMonitor(logger *zap.SugaredLogger, timeout time.Duration, cacheTTL time.Duration, checkers ...Checker) Monitor {
+	return newMonitor(logger, timeout, cacheTTL, checkers...)
+}
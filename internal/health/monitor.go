@@ -0,0 +1,127 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type checkerFunc struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+// Name returns the name of the check
+func (c *checkerFunc) Name() string {
+	return c.name
+}
+
+// Check runs the check function
+func (c *checkerFunc) Check(ctx context.Context) error {
+	return c.check(ctx)
+}
+
+// cachedCheck holds a checker together with its last result
+type cachedCheck struct {
+	checker Checker
+
+	mu     sync.Mutex
+	result *CheckResult
+}
+
+type monitor struct {
+	logger   *zap.SugaredLogger
+	timeout  time.Duration
+	cacheTTL time.Duration
+	checks   []*cachedCheck
+	now      func() time.Time
+}
+
+// newMonitor returns a new monitor with the given checkers
+func newMonitor(logger *zap.SugaredLogger, timeout time.Duration, cacheTTL time.Duration, checkers ...Checker) *monitor {
+	m := &monitor{
+		logger:   logger,
+		timeout:  timeout,
+		cacheTTL: cacheTTL,
+		now:      time.Now,
+	}
+	for _, c := range checkers {
+		m.checks = append(m.checks, &cachedCheck{checker: c})
+	}
+	return m
+}
+
+// Check runs every check concurrently and returns the aggregated report
+func (m *monitor) Check(ctx context.Context) *Report {
+	results := make([]CheckResult, len(m.checks))
+
+	var wg sync.WaitGroup
+	for i, c := range m.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = m.run(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	report := &Report{Status: StatusUp, Checks: results}
+	for _, r := range results {
+		if r.Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// run returns the cached result of the check if it is still fresh, otherwise it runs the check. Concurrent callers
+// wait for the running check instead of running it again.
+func (m *monitor) run(ctx context.Context, c *cachedCheck) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.result != nil && m.now().Sub(c.result.CheckedAt) < m.cacheTTL {
+		result := *c.result
+		result.Cached = true
+		return result
+	}
+
+	// the result is shared with the other callers, so it must not depend on the caller cancelling its request
+	checkCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), m.timeout)
+	defer cancel()
+
+	start := m.now()
+	err := m.check(checkCtx, c.checker)
+	result := &CheckResult{
+		Name:      c.checker.Name(),
+		Status:    StatusUp,
+		Latency:   m.now().Sub(start).String(),
+		CheckedAt: start,
+	}
+	if err != nil {
+		m.logger.Warnf("health check '%s' failed: %s", c.checker.Name(), err)
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	c.result = result
+	return *result
+}
+
+// check runs the checker, giving up when the context is done even if the checker ignores it
+func (m *monitor) check(ctx context.Context, checker Checker) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- checker.Check(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("check timed out after %s", m.timeout)
+	}
+}
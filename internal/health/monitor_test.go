@@ -0,0 +1,88 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type monitorSuite struct {
+	suite.Suite
+}
+
+func (s *monitorSuite) TestCheck() {
+	logger := zap.NewExample().Sugar()
+
+	s.Run("up", func() {
+		m := newMonitor(logger, time.Second, 0, NewChecker("ok", func(ctx context.Context) error { return nil }))
+		report := m.Check(context.Background())
+
+		s.Equal(StatusUp, report.Status)
+		s.Require().Len(report.Checks, 1)
+		s.Equal("ok", report.Checks[0].Name)
+		s.Equal(StatusUp, report.Checks[0].Status)
+		s.Empty(report.Checks[0].Error)
+	})
+
+	s.Run("down", func() {
+		m := newMonitor(logger, time.Second, 0,
+			NewChecker("ok", func(ctx context.Context) error { return nil }),
+			NewChecker("failing", func(ctx context.Context) error { return errors.New("unreachable") }),
+		)
+		report := m.Check(context.Background())
+
+		s.Equal(StatusDown, report.Status)
+		s.Require().Len(report.Checks, 2)
+		s.Equal(StatusUp, report.Checks[0].Status)
+		s.Equal(StatusDown, report.Checks[1].Status)
+		s.Equal("unreachable", report.Checks[1].Error)
+	})
+
+	s.Run("timeout", func() {
+		block := make(chan struct{})
+		defer close(block)
+
+		m := newMonitor(logger, 10*time.Millisecond, 0, NewChecker("slow", func(ctx context.Context) error {
+			<-block
+			return nil
+		}))
+		report := m.Check(context.Background())
+
+		s.Equal(StatusDown, report.Status)
+		s.Contains(report.Checks[0].Error, "timed out")
+	})
+
+	s.Run("cached", func() {
+		calls := 0
+		m := newMonitor(logger, time.Second, time.Minute, NewChecker("counted", func(ctx context.Context) error {
+			calls++
+			return nil
+		}))
+
+		first := m.Check(context.Background())
+		second := m.Check(context.Background())
+
+		s.Equal(1, calls)
+		s.False(first.Checks[0].Cached)
+		s.True(second.Checks[0].Cached)
+	})
+
+	s.Run("ignores the cancellation of the caller", func() {
+		m := newMonitor(logger, time.Second, time.Minute, NewChecker("ok", func(ctx context.Context) error {
+			return ctx.Err()
+		}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		s.Equal(StatusUp, m.Check(ctx).Status)
+		s.Equal(StatusUp, m.Check(context.Background()).Status)
+	})
+}
+
+func TestMonitorSuite(t *testing.T) {
+	suite.Run(t, new(monitorSuite))
+}
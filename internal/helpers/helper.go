@@ -3,17 +3,19 @@ package helpers
 import (
 	"encoding/json"
 	"fmt"
+	"xm_test/internal/redact"
 )
 
-// PrettyPrintStruct prints prettily a struct
+// PrettyPrintStruct prints prettily a struct, masking the fields tagged with `redact`
 func PrettyPrintStruct(d any) {
-	s, _ := json.MarshalIndent(d, "", "\t")
+	s, _ := json.MarshalIndent(redact.Struct(d), "", "\t")
 	fmt.Print(string(s))
 }
 
-// PrettyPrintStructResponse prints prettily a struct and returns it as a string
+// PrettyPrintStructResponse prints prettily a struct and returns it as a string, masking the fields tagged with
+// `redact`
 func PrettyPrintStructResponse(d any) string {
-	s, _ := json.Marshal(d)
+	s, _ := json.Marshal(redact.Struct(d))
 	return string(s)
 }
 
@@ -0,0 +1,141 @@
+// Package i18n translates the messages sent to the clients. The messages are kept in one catalog per language,
+// keyed by the code of the API error, optionally followed by a dot and the name of the variant of the message, e.g.
+// COMPANY_NOT_FOUND.id. The catalogs can reference parameters by name, e.g. "company with id '{id}' not found".
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// English is the language of the messages when the client does not ask for a supported one
+var English = language.English
+
+//go:embed locales/*.json
+var locales embed.FS
+
+var (
+	// catalogs holds the messages of each supported language, keyed by ID
+	catalogs = make(map[language.Tag]map[string]string)
+
+	// supported lists the languages of the catalogs, English first so it is the fallback of the matcher
+	supported = []language.Tag{English}
+
+	matcher language.Matcher
+)
+
+func init() {
+	files, err := locales.ReadDir("locales")
+	if err != nil {
+		panic(fmt.Sprintf("failed to read the message catalogs: %v", err))
+	}
+
+	for _, file := range files {
+		tag := language.MustParse(strings.TrimSuffix(file.Name(), ".json"))
+
+		data, err := locales.ReadFile(path.Join("locales", file.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("failed to read the message catalog '%s': %v", file.Name(), err))
+		}
+
+		var catalog map[string]string
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			panic(fmt.Sprintf("failed to decode the message catalog '%s': %v", file.Name(), err))
+		}
+
+		catalogs[tag] = catalog
+		if tag != English {
+			supported = append(supported, tag)
+		}
+	}
+
+	matcher = language.NewMatcher(supported)
+}
+
+// Params holds the values interpolated into a message, keyed by name
+type Params map[string]any
+
+// Translator is implemented by the values that are translated before being interpolated into a message
+type Translator interface {
+	Translate(tag language.Tag) string
+}
+
+// Message identifies a message of the catalogs and the values of its parameters
+type Message struct {
+	ID     string
+	Params Params
+}
+
+// Translate returns the message in the given language, falling back to English when the catalog of the language
+// does not have it, and to the ID when no catalog has it
+func (m Message) Translate(tag language.Tag) string {
+	template, ok := Lookup(tag, m.ID)
+	if !ok {
+		if template, ok = Lookup(English, m.ID); !ok {
+			return m.ID
+		}
+	}
+	return interpolate(template, m.Params, tag)
+}
+
+// Messages is a list of messages that is translated as a single message separated by semicolons
+type Messages []Message
+
+// Translate returns the messages in the given language, separated by semicolons
+func (m Messages) Translate(tag language.Tag) string {
+	translated := make([]string, 0, len(m))
+	for _, message := range m {
+		translated = append(translated, message.Translate(tag))
+	}
+	return strings.Join(translated, "; ")
+}
+
+// Lookup returns the message of the catalog of the language, without interpolating its parameters
+func Lookup(tag language.Tag, id string) (string, bool) {
+	template, ok := catalogs[tag][id]
+	return template, ok
+}
+
+// Supported returns the languages of the catalogs, English first
+func Supported() []language.Tag {
+	return append([]language.Tag(nil), supported...)
+}
+
+// Match returns the supported language that best matches the Accept-Language header, or English when none does
+func Match(acceptLanguage string) language.Tag {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return English
+	}
+
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return English
+	}
+	return supported[index]
+}
+
+// interpolate replaces the {name} placeholders of the template by the values of the parameters
+func interpolate(template string, params Params, tag language.Tag) string {
+	if len(params) == 0 {
+		return template
+	}
+
+	replacements := make([]string, 0, 2*len(params))
+	for name, value := range params {
+		var s string
+		switch v := value.(type) {
+		case Translator:
+			s = v.Translate(tag)
+		default:
+			s = fmt.Sprint(v)
+		}
+		replacements = append(replacements, "{"+name+"}", s)
+	}
+	return strings.NewReplacer(replacements...).Replace(template)
+}
@@ -0,0 +1,89 @@
+package i18n
+
+import (
+	"maps"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/text/language"
+)
+
+type i18nSuite struct {
+	suite.Suite
+}
+
+var placeholder = regexp.MustCompile(`\{\w+\}`)
+
+func (s *i18nSuite) TestCatalogs() {
+	s.Run("loads the supported languages", func() {
+		s.Equal(English, Supported()[0])
+		s.ElementsMatch([]language.Tag{English, language.Spanish, language.French, language.German}, Supported())
+	})
+
+	s.Run("translates every message with the same parameters", func() {
+		english := catalogs[English]
+		for _, tag := range Supported() {
+			catalog := catalogs[tag]
+			s.ElementsMatch(slices.Collect(maps.Keys(english)), slices.Collect(maps.Keys(catalog)), tag.String())
+			for id, template := range catalog {
+				s.ElementsMatch(placeholder.FindAllString(english[id], -1), placeholder.FindAllString(template, -1), "%s: %s", tag, id)
+			}
+		}
+	})
+}
+
+func (s *i18nSuite) TestMatch() {
+	tests := []struct {
+		name           string
+		acceptLanguage string
+		expected       language.Tag
+	}{
+		{name: "defaults to English", acceptLanguage: "", expected: English},
+		{name: "matches the language", acceptLanguage: "de", expected: language.German},
+		{name: "matches a regional variant", acceptLanguage: "fr-BE", expected: language.French},
+		{name: "honours the weights", acceptLanguage: "fr;q=0.5, es;q=0.8", expected: language.Spanish},
+		{name: "skips the unsupported languages", acceptLanguage: "pl, es;q=0.5", expected: language.Spanish},
+		{name: "falls back to English", acceptLanguage: "pl, nl", expected: English},
+		{name: "ignores invalid headers", acceptLanguage: "@@@", expected: English},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			s.Equal(tt.expected, Match(tt.acceptLanguage))
+		})
+	}
+}
+
+func (s *i18nSuite) TestTranslate() {
+	s.Run("interpolates the parameters", func() {
+		message := Message{ID: "COMPANY_NOT_FOUND.id", Params: Params{"id": "x"}}
+		s.Equal("company with id 'x' not found", message.Translate(English))
+		s.Equal("no se encontró la empresa con id 'x'", message.Translate(language.Spanish))
+	})
+
+	s.Run("translates the nested messages", func() {
+		fields := Messages{
+			{ID: "validation.email", Params: Params{"field": "email"}},
+			{ID: "validation.required", Params: Params{"field": "name", "type": "string"}},
+		}
+		message := Message{ID: "INVALID_BODY.decode", Params: Params{"reason": fields}}
+		s.Equal(
+			"impossible de décoder le corps de la requête : email doit être une adresse email valide; name est obligatoire et doit être de type string",
+			message.Translate(language.French),
+		)
+	})
+
+	s.Run("falls back to English", func() {
+		s.Equal("company not found", Message{ID: "COMPANY_NOT_FOUND"}.Translate(language.Polish))
+	})
+
+	s.Run("falls back to the ID", func() {
+		s.Equal("UNKNOWN", Message{ID: "UNKNOWN"}.Translate(language.Spanish))
+	})
+}
+
+func TestI18nSuite(t *testing.T) {
+	suite.Run(t, new(i18nSuite))
+}
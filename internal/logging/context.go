@@ -0,0 +1,34 @@
+package logging
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+type contextKey string
+
+const loggerKey contextKey = "logger"
+
+// WithLogger returns a copy of the context carrying the request-scoped logger
+func WithLogger(ctx context.Context, logger *zap.SugaredLogger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// FromContext returns the request-scoped logger stored in the context, or the fallback logger if there is none
+func FromContext(ctx context.Context, fallback *zap.SugaredLogger) *zap.SugaredLogger {
+	if logger, ok := ctx.Value(loggerKey).(*zap.SugaredLogger); ok {
+		return logger
+	}
+	return fallback
+}
+
+// With returns a copy of the context whose request-scoped logger includes the given fields. The context is returned
+// unchanged if it does not carry a logger.
+func With(ctx context.Context, keysAndValues ...any) context.Context {
+	logger, ok := ctx.Value(loggerKey).(*zap.SugaredLogger)
+	if !ok {
+		return ctx
+	}
+	return WithLogger(ctx, logger.With(keysAndValues...))
+}
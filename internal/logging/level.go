@@ -0,0 +1,35 @@
+package logging
+
+import (
+	"xm_test/internal/enum"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ParseLevel returns the zap level matching the configured log level. Unknown levels default to info.
+func ParseLevel(level enum.LogLevel) zapcore.Level {
+	switch level {
+	case enum.Debug:
+		return zapcore.DebugLevel
+	case enum.WarnLevel:
+		return zapcore.WarnLevel
+	case enum.ErrorLevel:
+		return zapcore.ErrorLevel
+	default:
+		return zapcore.InfoLevel
+	}
+}
+
+// FormatLevel returns the configured log level matching the zap level
+func FormatLevel(level zapcore.Level) enum.LogLevel {
+	switch level {
+	case zapcore.DebugLevel:
+		return enum.Debug
+	case zapcore.WarnLevel:
+		return enum.WarnLevel
+	case zapcore.ErrorLevel:
+		return enum.ErrorLevel
+	default:
+		return enum.InfoLevel
+	}
+}
@@ -0,0 +1,33 @@
+package logging
+
+import (
+	"testing"
+	"xm_test/internal/enum"
+
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap/zapcore"
+)
+
+type levelSuite struct {
+	suite.Suite
+}
+
+func (s *levelSuite) TestParseLevel() {
+	cases := map[enum.LogLevel]zapcore.Level{
+		enum.Debug:      zapcore.DebugLevel,
+		enum.InfoLevel:  zapcore.InfoLevel,
+		enum.WarnLevel:  zapcore.WarnLevel,
+		enum.ErrorLevel: zapcore.ErrorLevel,
+	}
+
+	for level, expected := range cases {
+		s.Equal(expected, ParseLevel(level))
+		s.Equal(level, FormatLevel(expected))
+	}
+
+	s.Equal(zapcore.InfoLevel, ParseLevel("unknown"))
+}
+
+func TestLevelSuite(t *testing.T) {
+	suite.Run(t, new(levelSuite))
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Namespace is the prefix of every metric exposed by the API
+const Namespace = "xm"
+
+// http metrics
+var (
+	// HTTPRequests counts the handled requests, by method, chi route pattern and status code
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Number of handled HTTP requests.",
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestDuration measures the latency of the handled requests, by method, chi route pattern and status code
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of the handled HTTP requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// HTTPRequestsInFlight counts the requests being handled at the moment
+	HTTPRequestsInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Subsystem: "http",
+		Name:      "requests_in_flight",
+		Help:      "Number of HTTP requests being handled.",
+	})
+)
+
+// database metrics
+var (
+	// DatabaseQueryDuration measures the latency of the database operations, by DatabaseAdapter method
+	DatabaseQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Subsystem: "db",
+		Name:      "query_duration_seconds",
+		Help:      "Latency of the database operations.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"method"})
+
+	// DatabaseQueryErrors counts the failed database operations, by DatabaseAdapter method
+	DatabaseQueryErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "db",
+		Name:      "query_errors_total",
+		Help:      "Number of failed database operations.",
+	}, []string{"method"})
+)
+
+// events metrics
+var (
+	// EventsDispatched counts the dispatched events, by event type and result (success or failure)
+	EventsDispatched = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "events",
+		Name:      "dispatched_total",
+		Help:      "Number of dispatched events.",
+	}, []string{"type", "result"})
+
+	// EventDispatchRetries counts the times an event dispatch has been retried, by event type
+	EventDispatchRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "events",
+		Name:      "dispatch_retries_total",
+		Help:      "Number of event dispatch retries.",
+	}, []string{"type"})
+
+	// EventsDeadLettered counts the events stored in the dead-letter table after exhausting their retries, by event type
+	EventsDeadLettered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Subsystem: "events",
+		Name:      "dead_lettered_total",
+		Help:      "Number of events sent to the dead-letter table.",
+	}, []string{"type"})
+)
+
+// Dispatch results used in the EventsDispatched counter
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
@@ -0,0 +1,75 @@
+package redact
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// encoder wraps a zap encoder masking the emails and tokens found in the messages and in the string and error fields
+type encoder struct {
+	zapcore.Encoder
+}
+
+// NewEncoder returns a zap encoder that masks the emails and tokens before encoding the entries with the given one
+func NewEncoder(enc zapcore.Encoder) zapcore.Encoder {
+	return &encoder{Encoder: enc}
+}
+
+// Clone copies the encoder, keeping the redaction
+func (e *encoder) Clone() zapcore.Encoder {
+	return &encoder{Encoder: e.Encoder.Clone()}
+}
+
+// AddString masks the fields added with logger.With
+func (e *encoder) AddString(key string, value string) {
+	e.Encoder.AddString(key, String(value))
+}
+
+// AddByteString masks the byte fields added with logger.With
+func (e *encoder) AddByteString(key string, value []byte) {
+	e.Encoder.AddString(key, String(string(value)))
+}
+
+// EncodeEntry masks the message and the fields of the entry
+func (e *encoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	entry.Message = String(entry.Message)
+
+	masked := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		masked[i] = maskField(field)
+	}
+	return e.Encoder.EncodeEntry(entry, masked)
+}
+
+// maskField masks the string, stringer and error fields. The rest of the fields are kept as they are.
+func maskField(field zapcore.Field) zapcore.Field {
+	switch field.Type {
+	case zapcore.StringType:
+		field.String = String(field.String)
+	case zapcore.ByteStringType:
+		return zapcore.Field{Key: field.Key, Type: zapcore.StringType, String: String(string(field.Interface.([]byte)))}
+	case zapcore.StringerType:
+		if stringer, ok := field.Interface.(fmt.Stringer); ok {
+			if s, ok := safeString(stringer); ok {
+				return zapcore.Field{Key: field.Key, Type: zapcore.StringType, String: String(s)}
+			}
+		}
+	case zapcore.ErrorType:
+		if err, ok := field.Interface.(error); ok {
+			return zapcore.Field{Key: field.Key, Type: zapcore.StringType, String: String(err.Error())}
+		}
+	}
+	return field
+}
+
+// safeString calls the String method, which may panic on nil pointers. The field is left to zap in that case.
+func safeString(stringer fmt.Stringer) (s string, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	return stringer.String(), true
+}
@@ -0,0 +1,129 @@
+package redact
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Mask replaces the redacted values
+const Mask = "[REDACTED]"
+
+// tagName is the struct tag that marks the sensitive fields. The supported values are:
+//
+// - "true": the value is replaced by the mask.
+//
+// - "email": only the first character of the local part and the domain of the email are kept.
+const tagName = "redact"
+
+var (
+	emailPattern  = regexp.MustCompile(`([A-Za-z0-9._%+-])[A-Za-z0-9._%+-]*@([A-Za-z0-9.-]+\.[A-Za-z]{2,})`)
+	jwtPattern    = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]*\.[A-Za-z0-9_-]*`)
+	bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)\S+`)
+)
+
+// String masks the emails and the tokens (JWTs and bearer credentials) found in the string
+func String(s string) string {
+	if s == "" {
+		return s
+	}
+	s = bearerPattern.ReplaceAllString(s, "${1}"+Mask)
+	s = jwtPattern.ReplaceAllString(s, Mask)
+	return emailPattern.ReplaceAllString(s, "${1}***@${2}")
+}
+
+// Email masks the email, keeping the first character of the local part and the domain
+func Email(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return Mask
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+// Struct returns a copy of the value in which the fields tagged with `redact` are masked. Nested structs, pointers
+// and slices are copied as well, so the given value is never modified.
+func Struct[T any](v T) T {
+	copied := redactValue(reflect.ValueOf(&v).Elem())
+	return copied.Interface().(T)
+}
+
+// redactValue returns a copy of the value with the tagged fields masked
+func redactValue(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Type().Elem())
+		copied.Elem().Set(redactValue(v.Elem()))
+		return copied
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(redactValue(v.Elem()))
+		return copied
+	case reflect.Struct:
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if tag, ok := field.Tag.Lookup(tagName); ok {
+				copied.Field(i).Set(maskValue(v.Field(i), tag))
+				continue
+			}
+			copied.Field(i).Set(redactValue(v.Field(i)))
+		}
+		return copied
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(redactValue(v.Index(i)))
+		}
+		return copied
+	case reflect.Array:
+		copied := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(redactValue(v.Index(i)))
+		}
+		return copied
+	default:
+		return v
+	}
+}
+
+// maskValue returns the masked value of a tagged field. Empty values are kept, so it is still visible whether
+// they are set. Fields that are not strings are zeroed.
+func maskValue(v reflect.Value, tag string) reflect.Value {
+	if v.IsZero() {
+		return v
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		masked := Mask
+		if tag == "email" {
+			masked = Email(v.String())
+		}
+		return reflect.ValueOf(masked).Convert(v.Type())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return reflect.Zero(v.Type())
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(maskValue(v.Index(i), tag))
+		}
+		return copied
+	default:
+		return reflect.Zero(v.Type())
+	}
+}
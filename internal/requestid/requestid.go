@@ -0,0 +1,20 @@
+package requestid
+
+import "unicode"
+
+// MaxLength is the maximum length of the request IDs accepted from the clients
+const MaxLength = 128
+
+// IsValid checks that the request ID sent by the client is short and printable, so it can be safely logged and
+// echoed
+func IsValid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+	for _, c := range id {
+		if c > unicode.MaxASCII || !unicode.IsPrint(c) {
+			return false
+		}
+	}
+	return true
+}
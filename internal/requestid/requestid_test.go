@@ -0,0 +1,37 @@
+package requestid
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type requestIDSuite struct {
+	suite.Suite
+}
+
+func (s *requestIDSuite) TestIsValid() {
+	tests := []struct {
+		name  string
+		id    string
+		valid bool
+	}{
+		{name: "uuid", id: "0b5f1a4e-3c1d-4f7e-9a2b-6c8d0e1f2a3b", valid: true},
+		{name: "maximum length", id: strings.Repeat("a", MaxLength), valid: true},
+		{name: "empty", id: ""},
+		{name: "too long", id: strings.Repeat("a", MaxLength+1)},
+		{name: "control characters", id: "bad\nid"},
+		{name: "non ascii", id: "señal"},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			s.Equal(tt.valid, IsValid(tt.id))
+		})
+	}
+}
+
+func TestRequestIDSuite(t *testing.T) {
+	suite.Run(t, new(requestIDSuite))
+}
@@ -65,7 +65,7 @@ func (s *authSuite) TestRegisterUser() {
 		email := "testRegister@test.es"
 		password := "password"
 
-		err := s.as.Register(email, password)
+		err := s.as.Register(context.Background(), email, password)
 		s.Require().NoError(err)
 
 		user, err := s.db.GetUserByEmail(ctx, email)
@@ -84,11 +84,11 @@ func (s *authSuite) TestLoginUser() {
 	email := "testLogin@test.es"
 	password := "password"
 
-	err := s.as.Register(email, password)
+	err := s.as.Register(context.Background(), email, password)
 	s.Require().NoError(err)
 
 	s.Run("ok", func() {
-		accessToken, err := s.as.Login(email, password)
+		accessToken, err := s.as.Login(context.Background(), email, password)
 		s.Require().NoError(err)
 
 		s.NotEmpty(accessToken)
@@ -100,7 +100,7 @@ func (s *authSuite) TestLoginUser() {
 
 	s.Run("invalid password", func() {
 		invalidPassword := "invalid"
-		_, err := s.as.Login(email, invalidPassword)
+		_, err := s.as.Login(context.Background(), email, invalidPassword)
 		s.Error(err)
 	})
 }
@@ -2,32 +2,66 @@ package company
 
 import (
 	"context"
+	"errors"
+	"slices"
+	"sync"
 	apierrors "xm_test/internal/api_errors"
 	"xm_test/internal/db"
 	"xm_test/internal/db/models"
+	"xm_test/internal/enum"
+	"xm_test/internal/events"
+	"xm_test/internal/i18n"
+	"xm_test/internal/logging"
 	"xm_test/internal/service/inputs"
+	"xm_test/internal/tracing"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+// MaxPageSize is the maximum amount of companies of a page of the listing
+const MaxPageSize = 100
+
+// MaxBulkOperations is the maximum amount of operations of a bulk write, whatever the limit configured for the
+// bulk requests
+const MaxBulkOperations = 1000
+
+// patchAttempts is the maximum amount of times a patch is applied when other requests modify the company meanwhile
+const patchAttempts = 3
+
 type company struct {
 	logger *zap.SugaredLogger
 	db     db.DatabaseAdapter
+
+	// events schema registry and dispatcher of the events of the companies written
+	registry   events.Registry
+	dispatcher events.Dispatcher
+
+	// pending event dispatches, awaited on shutdown
+	pendingEvents sync.WaitGroup
 }
 
-// NewCompanyResolver returns a new company service instance
-func NewCompanyResolver(logger *zap.SugaredLogger, db db.DatabaseAdapter) *company {
+// NewCompanyResolver returns a new company service instance. The events of the companies created, updated and
+// deleted are created with the registry and dispatched in the background with the dispatcher, whatever the
+// transport writing them.
+func NewCompanyResolver(logger *zap.SugaredLogger, db db.DatabaseAdapter, registry events.Registry, dispatcher events.Dispatcher) *company {
 	return &company{
-		logger: logger,
-		db:     db,
+		logger:     logger,
+		db:         db,
+		registry:   registry,
+		dispatcher: dispatcher,
 	}
 }
 
 // CreateCompany creates a new company
-func (s *company) CreateCompany(company *inputs.CreateCompanyInput) (*models.CompanyModel, error) {
-	s.logger.Infof("creating company with name '%s'", company.Name)
-	ctx := context.Background()
+func (s *company) CreateCompany(ctx context.Context, company *inputs.CreateCompanyInput) (_ *models.CompanyModel, err error) {
+	ctx, span := tracing.Start(ctx, "CompanyService.CreateCompany")
+	defer func() { tracing.End(span, err) }()
+	logger := logging.FromContext(ctx, s.logger)
+
+	logger.Infof("creating company with name '%s'", company.Name)
 	id := uuid.New()
 	companyModel := models.CompanyModel{
 		ID:              id,
@@ -37,44 +71,52 @@ func (s *company) CreateCompany(company *inputs.CreateCompanyInput) (*models.Com
 		Registered:      *company.Registered,
 		Type:            company.Type,
 	}
-	if err := s.db.CreateCompany(ctx, &companyModel); err != nil {
+	if err = s.db.CreateCompany(ctx, &companyModel); err != nil {
 		return nil, err
 	}
-	s.logger.Infof("company with name '%s' registered", company.Name)
+	s.dispatchEvent(ctx, enum.EventCreateCompany, companyModel.ID, &companyModel)
+	logger.Infof("company with name '%s' registered", company.Name)
 	return &companyModel, nil
 }
 
 // GetCompanyByID retrieves a company by its ID
-func (s *company) GetCompanyByID(id string) (*models.CompanyModel, error) {
-	s.logger.Infof("retrieving company with id '%s'", id)
+func (s *company) GetCompanyByID(ctx context.Context, id string) (_ *models.CompanyModel, err error) {
+	ctx, span := tracing.Start(ctx, "CompanyService.GetCompanyByID", trace.WithAttributes(attribute.String("company.id", id)))
+	defer func() { tracing.End(span, err) }()
+	logger := logging.FromContext(ctx, s.logger)
 
-	s.logger.Debugf("checking uuid is valid")
+	logger.Infof("retrieving company with id '%s'", id)
+
+	logger.Debugf("checking uuid is valid")
 	if _, err := uuid.Parse(id); err != nil {
 		return nil, apierrors.ErrInvalidUUID
 	}
-	s.logger.Debugf("uuid is valid")
+	logger.Debugf("uuid is valid")
 
-	ctx := context.Background()
 	company, err := s.db.GetCompanyByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
-	s.logger.Infof("company with id '%s' retrieved", id)
+	logger.Infof("company with id '%s' retrieved", id)
 	return company, nil
 }
 
-// UpdateCompany updates a company
-func (s *company) UpdateCompany(id string, company *inputs.UpdateCompany) error {
-	s.logger.Infof("updating company with id '%s'", id)
+// UpdateCompany updates a company and returns it. When the version is not zero, the company is only updated if it is
+// at that version.
+func (s *company) UpdateCompany(ctx context.Context, id string, company *inputs.UpdateCompany, version int) (_ *models.CompanyModel, err error) {
+	ctx, span := tracing.Start(ctx, "CompanyService.UpdateCompany", trace.WithAttributes(attribute.String("company.id", id)))
+	defer func() { tracing.End(span, err) }()
+	logger := logging.FromContext(ctx, s.logger)
+
+	logger.Infof("updating company with id '%s'", id)
 
-	s.logger.Debugf("checking uuid is valid")
+	logger.Debugf("checking uuid is valid")
 	uuid, err := uuid.Parse(id)
 	if err != nil {
-		return apierrors.ErrInvalidUUID
+		return nil, apierrors.ErrInvalidUUID
 	}
-	s.logger.Debugf("uuid is valid")
+	logger.Debugf("uuid is valid")
 
-	ctx := context.Background()
 	companyModel := models.CompanyModel{
 		ID:              uuid,
 		Name:            company.Name,
@@ -83,27 +125,300 @@ func (s *company) UpdateCompany(id string, company *inputs.UpdateCompany) error
 		Registered:      *company.Registered,
 		Type:            company.Type,
 	}
-	if err := s.db.UpdateCompany(ctx, id, &companyModel); err != nil {
-		return err
+	updated, err := s.db.UpdateCompany(ctx, id, &companyModel, version)
+	if err != nil {
+		return nil, err
 	}
-	s.logger.Infof("company with id '%s' updated", id)
-	return nil
+	s.dispatchEvent(ctx, enum.EventUpdateCompany, updated.ID, updated)
+	logger.Infof("company with id '%s' updated", id)
+	return updated, nil
 }
 
-// DeleteCompany deletes a company
-func (s *company) DeleteCompany(id string) error {
-	s.logger.Infof("deleting company with id '%s'", id)
+// PatchCompany applies the patch to the company with the given ID and updates the fields that changed. It returns
+// the patched company, and whether any of its fields changed. When the version is not zero, the company is only
+// patched if it is at that version. Otherwise, the patch is applied again to the latest company when another request
+// modifies it in the meantime, so the changes of the other request are not lost, and it fails with ErrPatchConflict
+// when the company keeps being modified.
+func (s *company) PatchCompany(ctx context.Context, id string, version int, patch inputs.PatchCompany) (_ *models.CompanyModel, changed bool, err error) {
+	ctx, span := tracing.Start(ctx, "CompanyService.PatchCompany", trace.WithAttributes(attribute.String("company.id", id)))
+	defer func() { tracing.End(span, err) }()
+	logger := logging.FromContext(ctx, s.logger)
 
-	s.logger.Debugf("checking uuid is valid")
+	logger.Infof("patching company with id '%s'", id)
+
+	logger.Debugf("checking uuid is valid")
 	if _, err := uuid.Parse(id); err != nil {
+		return nil, false, apierrors.ErrInvalidUUID
+	}
+	logger.Debugf("uuid is valid")
+
+	for attempt := 1; ; attempt++ {
+		current, err := s.db.GetCompanyByID(ctx, id)
+		if err != nil {
+			return nil, false, err
+		}
+		if version != 0 && current.Version != version {
+			return nil, false, apierrors.ErrPreconditionFailed.WithMessageID("version", i18n.Params{"id": id})
+		}
+
+		// the input does not point to the current company, so the changes can be found by comparing them
+		amountEmployees, registered := current.AmountEmployees, current.Registered
+		input := &inputs.UpdateCompany{
+			Name:            current.Name,
+			Description:     current.Description,
+			AmountEmployees: &amountEmployees,
+			Registered:      &registered,
+			Type:            current.Type,
+		}
+		if err = patch(input); err != nil {
+			return nil, false, err
+		}
+
+		changes := companyChanges(current, input)
+		if changes.IsEmpty() {
+			logger.Infof("company with id '%s' did not change", id)
+			return current, false, nil
+		}
+
+		// the patch was applied to the current version, so the update fails if another request modified the company
+		company, err := s.db.PatchCompany(ctx, id, changes, current.Version)
+		if errors.Is(err, apierrors.ErrPreconditionFailed) && version == 0 {
+			if attempt < patchAttempts {
+				logger.Debugf("company with id '%s' was modified while patching it, retrying", id)
+				continue
+			}
+			// the client did not expect any version, so no precondition failed: the patch conflicts with the other requests
+			return nil, false, apierrors.ErrPatchConflict.WithMessageID("modified", i18n.Params{"id": id}).Wrap(err)
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		s.dispatchEvent(ctx, enum.EventUpdateCompany, company.ID, company)
+		logger.Infof("company with id '%s' patched", id)
+		return company, true, nil
+	}
+}
+
+// companyChanges returns the fields of the input that differ from the current company
+func companyChanges(current *models.CompanyModel, input *inputs.UpdateCompany) *models.CompanyPatch {
+	changes := &models.CompanyPatch{}
+	if input.Name != current.Name {
+		changes.Name = &input.Name
+	}
+	if input.Description != current.Description {
+		changes.Description = &input.Description
+	}
+	if input.AmountEmployees != nil && *input.AmountEmployees != current.AmountEmployees {
+		changes.AmountEmployees = input.AmountEmployees
+	}
+	if input.Registered != nil && *input.Registered != current.Registered {
+		changes.Registered = input.Registered
+	}
+	if input.Type != current.Type {
+		changes.Type = &input.Type
+	}
+	return changes
+}
+
+// DeleteCompany deletes a company. When the version is not zero, the company is only deleted if it is at that version.
+func (s *company) DeleteCompany(ctx context.Context, id string, version int) (err error) {
+	ctx, span := tracing.Start(ctx, "CompanyService.DeleteCompany", trace.WithAttributes(attribute.String("company.id", id)))
+	defer func() { tracing.End(span, err) }()
+	logger := logging.FromContext(ctx, s.logger)
+
+	logger.Infof("deleting company with id '%s'", id)
+
+	logger.Debugf("checking uuid is valid")
+	companyID, err := uuid.Parse(id)
+	if err != nil {
 		return apierrors.ErrInvalidUUID
 	}
-	s.logger.Debugf("uuid is valid")
+	logger.Debugf("uuid is valid")
 
-	ctx := context.Background()
-	if err := s.db.DeleteCompany(ctx, id); err != nil {
+	if err = s.db.DeleteCompany(ctx, id, version); err != nil {
 		return err
 	}
-	s.logger.Infof("company with id '%s' deleted", id)
+	s.dispatchEvent(ctx, enum.EventDeleteCompany, companyID, &events.DeletedCompanyPayload{ID: companyID})
+	logger.Infof("company with id '%s' deleted", id)
 	return nil
 }
+
+// ListCompanies retrieves a page of the companies matching the filters
+func (s *company) ListCompanies(ctx context.Context, input *inputs.ListCompaniesInput) (_ *models.CompanyPage, err error) {
+	ctx, span := tracing.Start(ctx, "CompanyService.ListCompanies", trace.WithAttributes(attribute.Int("page.size", input.First)))
+	defer func() { tracing.End(span, err) }()
+	logger := logging.FromContext(ctx, s.logger)
+
+	logger.Infof("listing companies")
+	if input.First < 1 || input.First > MaxPageSize {
+		return nil, apierrors.ErrInvalidQuery.WithMessageID("page_size", i18n.Params{"max": MaxPageSize})
+	}
+	if input.Type != "" && !enum.CompanyType(input.Type).IsValid() {
+		return nil, apierrors.ErrInvalidQuery.WithMessageID("company_type", i18n.Params{"type": input.Type})
+	}
+
+	// one more company is requested to know whether there is a next page
+	filter := &models.CompanyFilter{
+		Name:       input.Name,
+		Type:       input.Type,
+		Registered: input.Registered,
+		Limit:      input.First + 1,
+	}
+	if input.After != "" {
+		after, parseErr := uuid.Parse(input.After)
+		if parseErr != nil {
+			return nil, apierrors.ErrInvalidUUID
+		}
+		filter.After = &after
+	}
+
+	companies, err := s.db.ListCompanies(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.CompanyPage{Companies: companies}
+	if len(companies) > input.First {
+		page.Companies = companies[:input.First]
+		page.HasNextPage = true
+	}
+	logger.Infof("listed %d companies", len(page.Companies))
+	return page, nil
+}
+
+// ExportCompanies calls the handler with each company matching the filters, sorted by ID. The companies are
+// streamed from the database, so the export uses the same memory whatever the amount of companies.
+func (s *company) ExportCompanies(ctx context.Context, input *inputs.ExportCompaniesInput, handler func(company *models.CompanyModel) error) (err error) {
+	ctx, span := tracing.Start(ctx, "CompanyService.ExportCompanies")
+	defer func() { tracing.End(span, err) }()
+	logger := logging.FromContext(ctx, s.logger)
+
+	logger.Infof("exporting companies")
+	if input.Type != "" && !enum.CompanyType(input.Type).IsValid() {
+		return apierrors.ErrInvalidQuery.WithMessageID("company_type", i18n.Params{"type": input.Type})
+	}
+
+	exported := 0
+	err = s.db.StreamCompanies(ctx, &models.CompanyFilter{
+		Name:       input.Name,
+		Type:       input.Type,
+		Registered: input.Registered,
+	}, func(company *models.CompanyModel) error {
+		exported++
+		return handler(company)
+	})
+	if err != nil {
+		return err
+	}
+	logger.Infof("exported %d companies", exported)
+	return nil
+}
+
+// GetCompaniesByIDs retrieves the companies with the given IDs in a single query. The IDs that do not exist are
+// skipped, so the caller must match the companies with the requested IDs.
+func (s *company) GetCompaniesByIDs(ctx context.Context, ids []string) (_ []*models.CompanyModel, err error) {
+	ctx, span := tracing.Start(ctx, "CompanyService.GetCompaniesByIDs", trace.WithAttributes(attribute.Int("companies.requested", len(ids))))
+	defer func() { tracing.End(span, err) }()
+	logger := logging.FromContext(ctx, s.logger)
+
+	logger.Infof("retrieving %d companies", len(ids))
+	for _, id := range ids {
+		if _, parseErr := uuid.Parse(id); parseErr != nil {
+			return nil, apierrors.ErrInvalidUUID
+		}
+	}
+
+	companies, err := s.db.GetCompaniesByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("retrieved %d companies", len(companies))
+	return companies, nil
+}
+
+// BulkWriteCompanies applies the operations of the input to the companies, and returns the outcome of each of them
+// in the same order. The operations failing on their own, e.g. because the company does not exist, do not fail the
+// bulk write: their error is set instead. When the write is transactional, the operations are applied all together
+// or not at all. The event of every operation applied is dispatched.
+func (s *company) BulkWriteCompanies(ctx context.Context, input *inputs.BulkCompaniesInput) (_ []*models.CompanyOperation, err error) {
+	ctx, span := tracing.Start(ctx, "CompanyService.BulkWriteCompanies", trace.WithAttributes(
+		attribute.Int("bulk.operations", len(input.Operations)),
+		attribute.Bool("bulk.transactional", input.Transactional),
+	))
+	defer func() { tracing.End(span, err) }()
+	logger := logging.FromContext(ctx, s.logger)
+
+	logger.Infof("writing %d companies in bulk", len(input.Operations))
+	if len(input.Operations) < 1 || len(input.Operations) > MaxBulkOperations {
+		return nil, apierrors.ErrInvalidBody.WithMessageID("operations", i18n.Params{"max": MaxBulkOperations})
+	}
+
+	ops := make([]*models.CompanyOperation, len(input.Operations))
+	valid := make([]*models.CompanyOperation, 0, len(ops))
+	for i, operation := range input.Operations {
+		op, opErr := bulkOperation(operation)
+		ops[i] = op
+		if opErr != nil {
+			op.Err = opErr
+			continue
+		}
+		valid = append(valid, op)
+	}
+
+	// a transactional write is aborted as soon as one of its operations is invalid
+	if input.Transactional && len(valid) < len(ops) {
+		failed := slices.IndexFunc(ops, func(op *models.CompanyOperation) bool { return op.Err != nil })
+		for _, op := range ops {
+			if op.Err == nil {
+				op.Err = apierrors.ErrBulkAborted.WithMessageID("index", i18n.Params{"index": failed})
+			}
+		}
+		logger.Infof("bulk write aborted by invalid operation %d", failed)
+		return ops, nil
+	}
+
+	if len(valid) > 0 {
+		if err = s.db.BulkWriteCompanies(ctx, valid, input.Transactional); err != nil {
+			return nil, err
+		}
+	}
+	written := 0
+	for _, op := range ops {
+		if op.Err == nil {
+			written++
+			s.dispatchOperationEvent(ctx, op)
+		}
+	}
+	logger.Infof("wrote %d of %d companies in bulk", written, len(ops))
+	return ops, nil
+}
+
+// bulkOperation returns the operation of the database matching the input, or the error of the operation when the
+// input is invalid. The companies to create get a new ID.
+func bulkOperation(input inputs.BulkOperation) (*models.CompanyOperation, error) {
+	op := &models.CompanyOperation{Type: input.Type, Version: input.Version, Company: &models.CompanyModel{}}
+	if input.Invalid != nil {
+		return op, input.Invalid
+	}
+	if input.Type == enum.BulkCreate {
+		op.Company.ID = uuid.New()
+	} else {
+		id, err := uuid.Parse(input.ID)
+		if err != nil {
+			return op, apierrors.ErrInvalidUUID
+		}
+		op.Company.ID = id
+	}
+
+	if input.Type != enum.BulkDelete {
+		if input.Company == nil || input.Company.AmountEmployees == nil || input.Company.Registered == nil {
+			return op, apierrors.ErrInvalidBody
+		}
+		op.Company.Name = input.Company.Name
+		op.Company.Description = input.Company.Description
+		op.Company.AmountEmployees = *input.Company.AmountEmployees
+		op.Company.Registered = *input.Company.Registered
+		op.Company.Type = input.Company.Type
+	}
+	return op, nil
+}
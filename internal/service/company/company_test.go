@@ -2,16 +2,21 @@ package company
 
 import (
 	"context"
+	"fmt"
 	"testing"
+	apierrors "xm_test/internal/api_errors"
 	"xm_test/internal/conf"
 	"xm_test/internal/db"
+	"xm_test/internal/db/models"
 	"xm_test/internal/db/options"
 	"xm_test/internal/enum"
+	"xm_test/internal/events"
 	"xm_test/internal/helpers"
 	"xm_test/internal/mocks"
 	"xm_test/internal/service/inputs"
 
 	"github.com/docker/go-connections/nat"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/suite"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"go.uber.org/zap"
@@ -48,7 +53,9 @@ func (s *companySuite) SetupSuite() {
 	logger := zap.NewExample().Sugar()
 	db := db.NewDatabaseAdapter(logger, options.WithConnectionString(*connStr))
 	s.db = db
-	s.cs = NewCompanyResolver(logger, db)
+	registry, err := events.NewEventsRegistry()
+	s.Require().NoError(err)
+	s.cs = NewCompanyResolver(logger, db, registry, events.NewEventsDispatcher(logger, db, registry))
 }
 
 func (s *companySuite) TearDownSuite() {
@@ -66,7 +73,7 @@ func (s *companySuite) TestCreateCompany() {
 			Registered:      new(bool),
 			Type:            enum.Corporation.String(),
 		}
-		storedCompany, err := s.cs.CreateCompany(company)
+		storedCompany, err := s.cs.CreateCompany(context.Background(), company)
 		s.Require().NoError(err)
 
 		// check if company is created
@@ -91,11 +98,11 @@ func (s *companySuite) TestGetCompanyByID() {
 		Registered:      helpers.PointerValue(true),
 		Type:            enum.Corporation.String(),
 	}
-	storedCompany, err := s.cs.CreateCompany(company)
+	storedCompany, err := s.cs.CreateCompany(context.Background(), company)
 	s.Require().NoError(err)
 
 	s.Run("ok", func() {
-		companyModel, err := s.cs.GetCompanyByID(storedCompany.ID.String())
+		companyModel, err := s.cs.GetCompanyByID(context.Background(), storedCompany.ID.String())
 		s.Require().NoError(err)
 
 		s.Equal(storedCompany.ID, companyModel.ID)
@@ -116,7 +123,7 @@ func (s *companySuite) TestUpdateCompany() {
 		Registered:      helpers.PointerValue(true),
 		Type:            enum.Corporation.String(),
 	}
-	storedCompany, err := s.cs.CreateCompany(company)
+	storedCompany, err := s.cs.CreateCompany(context.Background(), company)
 	s.Require().NoError(err)
 
 	s.Run("ok", func() {
@@ -127,19 +134,145 @@ func (s *companySuite) TestUpdateCompany() {
 			Registered:      helpers.PointerValue(false),
 			Type:            enum.NonProfit.String(),
 		}
-		err := s.cs.UpdateCompany(storedCompany.ID.String(), updatedCompany)
+		updated, err := s.cs.UpdateCompany(context.Background(), storedCompany.ID.String(), updatedCompany, 0)
 		s.Require().NoError(err)
+		s.Equal(storedCompany.Version+1, updated.Version)
 
 		// check if company has been updated
 		companyModel, err := s.db.GetCompanyByID(context.Background(), storedCompany.ID.String())
 		s.Require().NoError(err)
 
+		s.Equal(updated, companyModel)
 		s.Equal(updatedCompany.Name, companyModel.Name)
 		s.Equal(updatedCompany.Description, companyModel.Description)
 		s.Equal(*updatedCompany.AmountEmployees, companyModel.AmountEmployees)
 		s.Equal(*updatedCompany.Registered, companyModel.Registered)
 		s.Equal(updatedCompany.Type, companyModel.Type)
 	})
+
+	s.Run("updates the company at the expected version", func() {
+		current, err := s.db.GetCompanyByID(context.Background(), storedCompany.ID.String())
+		s.Require().NoError(err)
+
+		updatedCompany := &inputs.UpdateCompany{
+			Name:            "versioned",
+			AmountEmployees: helpers.PointerValue(30),
+			Registered:      helpers.PointerValue(true),
+			Type:            enum.Cooperative.String(),
+		}
+		updated, err := s.cs.UpdateCompany(context.Background(), storedCompany.ID.String(), updatedCompany, current.Version)
+		s.Require().NoError(err)
+		s.Equal(current.Version+1, updated.Version)
+
+		// the version the client read is stale now
+		_, err = s.cs.UpdateCompany(context.Background(), storedCompany.ID.String(), updatedCompany, current.Version)
+		s.ErrorIs(err, apierrors.ErrPreconditionFailed)
+	})
+
+	s.Run("fails when the company does not exist", func() {
+		_, err := s.cs.UpdateCompany(context.Background(), uuid.NewString(), &inputs.UpdateCompany{
+			Name:            "missing",
+			AmountEmployees: helpers.PointerValue(1),
+			Registered:      helpers.PointerValue(true),
+			Type:            enum.Cooperative.String(),
+		}, 1)
+		s.ErrorIs(err, apierrors.ErrCompanyNotFound)
+	})
+}
+
+func (s *companySuite) TestPatchCompany() {
+	company := &inputs.CreateCompanyInput{
+		Name:            "patch",
+		Description:     "patch",
+		AmountEmployees: helpers.PointerValue(10),
+		Registered:      helpers.PointerValue(true),
+		Type:            enum.Corporation.String(),
+	}
+	storedCompany, err := s.cs.CreateCompany(context.Background(), company)
+	s.Require().NoError(err)
+
+	s.Run("updates the fields that changed", func() {
+		patched, changed, err := s.cs.PatchCompany(context.Background(), storedCompany.ID.String(), 0, func(input *inputs.UpdateCompany) error {
+			input.Name = "patched"
+			input.AmountEmployees = helpers.PointerValue(20)
+			return nil
+		})
+		s.Require().NoError(err)
+		s.True(changed)
+		s.Equal("patched", patched.Name)
+		s.Equal(20, patched.AmountEmployees)
+
+		companyModel, err := s.db.GetCompanyByID(context.Background(), storedCompany.ID.String())
+		s.Require().NoError(err)
+		s.Equal(patched, companyModel)
+		s.Equal(company.Description, companyModel.Description)
+		s.Equal(*company.Registered, companyModel.Registered)
+	})
+
+	s.Run("does not update the company when nothing changed", func() {
+		_, changed, err := s.cs.PatchCompany(context.Background(), storedCompany.ID.String(), 0, func(input *inputs.UpdateCompany) error {
+			return nil
+		})
+		s.Require().NoError(err)
+		s.False(changed)
+	})
+
+	s.Run("fails when the company does not exist", func() {
+		_, _, err := s.cs.PatchCompany(context.Background(), uuid.NewString(), 0, func(input *inputs.UpdateCompany) error {
+			return nil
+		})
+		s.ErrorIs(err, apierrors.ErrCompanyNotFound)
+	})
+
+	s.Run("fails when the patch fails", func() {
+		_, _, err := s.cs.PatchCompany(context.Background(), storedCompany.ID.String(), 0, func(input *inputs.UpdateCompany) error {
+			return apierrors.ErrPatchConflict
+		})
+		s.ErrorIs(err, apierrors.ErrPatchConflict)
+	})
+
+	s.Run("fails when the version does not match", func() {
+		current, err := s.db.GetCompanyByID(context.Background(), storedCompany.ID.String())
+		s.Require().NoError(err)
+
+		_, _, err = s.cs.PatchCompany(context.Background(), storedCompany.ID.String(), current.Version+1, func(input *inputs.UpdateCompany) error {
+			input.Name = "stale"
+			return nil
+		})
+		s.ErrorIs(err, apierrors.ErrPreconditionFailed)
+	})
+
+	s.Run("applies the patch again when the company is modified meanwhile", func() {
+		attempts := 0
+		patched, changed, err := s.cs.PatchCompany(context.Background(), storedCompany.ID.String(), 0, func(input *inputs.UpdateCompany) error {
+			attempts++
+			if attempts == 1 {
+				// another request modifies the company after it was read
+				_, err := s.db.PatchCompany(context.Background(), storedCompany.ID.String(), &models.CompanyPatch{Description: helpers.PointerValue("concurrent")}, 0)
+				s.Require().NoError(err)
+			}
+			input.Name = "retried"
+			return nil
+		})
+		s.Require().NoError(err)
+		s.True(changed)
+		s.Equal(2, attempts)
+		s.Equal("retried", patched.Name)
+		s.Equal("concurrent", patched.Description)
+	})
+
+	s.Run("fails with a conflict when the company keeps being modified", func() {
+		attempts := 0
+		_, _, err := s.cs.PatchCompany(context.Background(), storedCompany.ID.String(), 0, func(input *inputs.UpdateCompany) error {
+			attempts++
+			_, err := s.db.PatchCompany(context.Background(), storedCompany.ID.String(), &models.CompanyPatch{Description: helpers.PointerValue(fmt.Sprint("concurrent ", attempts))}, 0)
+			s.Require().NoError(err)
+			input.Name = "conflict"
+			return nil
+		})
+		s.ErrorIs(err, apierrors.ErrPatchConflict)
+		s.Equal(patchAttempts, attempts)
+	})
 }
 
 func (s *companySuite) TestDeleteCompany() {
@@ -151,17 +284,166 @@ func (s *companySuite) TestDeleteCompany() {
 		Registered:      helpers.PointerValue(true),
 		Type:            enum.Corporation.String(),
 	}
-	storedCompany, err := s.cs.CreateCompany(company)
+	storedCompany, err := s.cs.CreateCompany(context.Background(), company)
 	s.Require().NoError(err)
 
+	s.Run("fails when the version does not match", func() {
+		err := s.cs.DeleteCompany(context.Background(), storedCompany.ID.String(), storedCompany.Version+1)
+		s.ErrorIs(err, apierrors.ErrPreconditionFailed)
+	})
+
 	s.Run("ok", func() {
-		err := s.cs.DeleteCompany(storedCompany.ID.String())
+		err := s.cs.DeleteCompany(context.Background(), storedCompany.ID.String(), storedCompany.Version)
 		s.Require().NoError(err)
 
 		// check if company has been deleted
 		_, err = s.db.GetCompanyByID(context.Background(), storedCompany.ID.String())
 		s.Error(err)
 	})
+
+	s.Run("fails when the company does not exist", func() {
+		err := s.cs.DeleteCompany(context.Background(), storedCompany.ID.String(), 0)
+		s.ErrorIs(err, apierrors.ErrCompanyNotFound)
+	})
+}
+
+func (s *companySuite) TestListCompanies() {
+	// create the companies
+	for _, name := range []string{"pageA", "pageB", "pageC"} {
+		_, err := s.cs.CreateCompany(context.Background(), &inputs.CreateCompanyInput{
+			Name:            name,
+			AmountEmployees: helpers.PointerValue(10),
+			Registered:      helpers.PointerValue(true),
+			Type:            enum.Cooperative.String(),
+		})
+		s.Require().NoError(err)
+	}
+
+	s.Run("ok", func() {
+		page, err := s.cs.ListCompanies(context.Background(), &inputs.ListCompaniesInput{Name: "page", First: 2})
+		s.Require().NoError(err)
+		s.Len(page.Companies, 2)
+		s.True(page.HasNextPage)
+
+		page, err = s.cs.ListCompanies(context.Background(), &inputs.ListCompaniesInput{Name: "page", First: 2, After: page.Companies[1].ID.String()})
+		s.Require().NoError(err)
+		s.Len(page.Companies, 1)
+		s.False(page.HasNextPage)
+	})
+
+	s.Run("invalid page size", func() {
+		_, err := s.cs.ListCompanies(context.Background(), &inputs.ListCompaniesInput{First: MaxPageSize + 1})
+		s.Error(err)
+	})
+
+	s.Run("invalid type", func() {
+		_, err := s.cs.ListCompanies(context.Background(), &inputs.ListCompaniesInput{Type: "unknown", First: 1})
+		s.Error(err)
+	})
+}
+
+func (s *companySuite) TestExportCompanies() {
+	// create the companies
+	for _, name := range []string{"exportA", "exportB", "exportC"} {
+		_, err := s.cs.CreateCompany(context.Background(), &inputs.CreateCompanyInput{
+			Name:            name,
+			AmountEmployees: helpers.PointerValue(10),
+			Registered:      helpers.PointerValue(true),
+			Type:            enum.Cooperative.String(),
+		})
+		s.Require().NoError(err)
+	}
+
+	s.Run("ok", func() {
+		var names []string
+		err := s.cs.ExportCompanies(context.Background(), &inputs.ExportCompaniesInput{Name: "export", Type: enum.Cooperative.String()}, func(company *models.CompanyModel) error {
+			names = append(names, company.Name)
+			return nil
+		})
+		s.Require().NoError(err)
+		s.ElementsMatch([]string{"exportA", "exportB", "exportC"}, names)
+	})
+
+	s.Run("invalid type", func() {
+		err := s.cs.ExportCompanies(context.Background(), &inputs.ExportCompaniesInput{Type: "unknown"}, func(company *models.CompanyModel) error {
+			s.Fail("no company must be exported")
+			return nil
+		})
+		s.ErrorIs(err, apierrors.ErrInvalidQuery)
+	})
+}
+
+func (s *companySuite) TestGetCompaniesByIDs() {
+	storedCompany, err := s.cs.CreateCompany(context.Background(), &inputs.CreateCompanyInput{
+		Name:            "batch",
+		AmountEmployees: helpers.PointerValue(10),
+		Registered:      helpers.PointerValue(true),
+		Type:            enum.Cooperative.String(),
+	})
+	s.Require().NoError(err)
+
+	s.Run("ok", func() {
+		companies, err := s.cs.GetCompaniesByIDs(context.Background(), []string{storedCompany.ID.String()})
+		s.Require().NoError(err)
+		s.Require().Len(companies, 1)
+		s.Equal(storedCompany.ID, companies[0].ID)
+	})
+
+	s.Run("invalid id", func() {
+		_, err := s.cs.GetCompaniesByIDs(context.Background(), []string{"invalid"})
+		s.Error(err)
+	})
+}
+
+func (s *companySuite) TestBulkWriteCompanies() {
+	company := func(name string) *inputs.UpdateCompany {
+		return &inputs.UpdateCompany{
+			Name:            name,
+			AmountEmployees: helpers.PointerValue(10),
+			Registered:      helpers.PointerValue(true),
+			Type:            enum.Cooperative.String(),
+		}
+	}
+
+	s.Run("ok", func() {
+		ops, err := s.cs.BulkWriteCompanies(context.Background(), &inputs.BulkCompaniesInput{
+			Operations:    []inputs.BulkOperation{{Type: enum.BulkCreate, Company: company("bulkA")}, {Type: enum.BulkCreate, Company: company("bulkB")}},
+			Transactional: true,
+		})
+		s.Require().NoError(err)
+		s.Require().Len(ops, 2)
+		for _, op := range ops {
+			s.Require().NoError(op.Err)
+			s.Equal(op.Company.ID, op.Result.ID)
+		}
+	})
+
+	s.Run("aborts the transactional write when an operation is invalid", func() {
+		ops, err := s.cs.BulkWriteCompanies(context.Background(), &inputs.BulkCompaniesInput{
+			Operations:    []inputs.BulkOperation{{Type: enum.BulkCreate, Company: company("bulkC")}, {Type: enum.BulkDelete, ID: "invalid"}},
+			Transactional: true,
+		})
+		s.Require().NoError(err)
+		s.ErrorIs(ops[0].Err, apierrors.ErrBulkAborted)
+		s.ErrorIs(ops[1].Err, apierrors.ErrInvalidUUID)
+	})
+
+	s.Run("skips the invalid operations in best effort", func() {
+		ops, err := s.cs.BulkWriteCompanies(context.Background(), &inputs.BulkCompaniesInput{
+			Operations: []inputs.BulkOperation{{Type: enum.BulkCreate, Company: company("bulkD")}, {Type: enum.BulkDelete, ID: "invalid"}},
+		})
+		s.Require().NoError(err)
+		s.Require().NoError(ops[0].Err)
+		s.ErrorIs(ops[1].Err, apierrors.ErrInvalidUUID)
+	})
+
+	s.Run("invalid amount of operations", func() {
+		_, err := s.cs.BulkWriteCompanies(context.Background(), &inputs.BulkCompaniesInput{})
+		s.ErrorIs(err, apierrors.ErrInvalidBody)
+
+		_, err = s.cs.BulkWriteCompanies(context.Background(), &inputs.BulkCompaniesInput{Operations: make([]inputs.BulkOperation, MaxBulkOperations+1)})
+		s.ErrorIs(err, apierrors.ErrInvalidBody)
+	})
 }
 
 func TestCompanySuite(t *testing.T) {
@@ -0,0 +1,64 @@
+package company
+
+import (
+	"context"
+	"fmt"
+	"xm_test/internal/db/models"
+	"xm_test/internal/enum"
+	"xm_test/internal/events"
+	"xm_test/internal/logging"
+
+	"github.com/google/uuid"
+)
+
+// dispatchEvent creates and dispatches the event in the background. The dispatch is tracked, so it can be awaited
+// during the shutdown. The dispatch outlives the request, so only the values of its context, such as the trace,
+// are kept.
+func (s *company) dispatchEvent(ctx context.Context, eventType enum.EventType, entityID uuid.UUID, payload any) {
+	ctx = context.WithoutCancel(ctx)
+	s.pendingEvents.Add(1)
+	go func() {
+		defer s.pendingEvents.Done()
+
+		logger := logging.FromContext(ctx, s.logger)
+		logger.Debugf("creating event '%s' for entity '%s'", eventType, entityID)
+		evt, err := s.registry.NewEvent(ctx, eventType, entityID, payload)
+		if err != nil {
+			logger.Errorf("failed to create event: %v", err)
+			return
+		}
+		if err := s.dispatcher.Dispatch(evt); err != nil {
+			logger.Errorf("failed to dispatch event: %v", err)
+			return
+		}
+		logger.Debugf("event '%s' created for entity '%s'", eventType, entityID)
+	}()
+}
+
+// dispatchOperationEvent dispatches the event of an operation of a bulk write that was applied
+func (s *company) dispatchOperationEvent(ctx context.Context, op *models.CompanyOperation) {
+	switch op.Type {
+	case enum.BulkCreate:
+		s.dispatchEvent(ctx, enum.EventCreateCompany, op.Result.ID, op.Result)
+	case enum.BulkUpdate:
+		s.dispatchEvent(ctx, enum.EventUpdateCompany, op.Result.ID, op.Result)
+	case enum.BulkDelete:
+		s.dispatchEvent(ctx, enum.EventDeleteCompany, op.Result.ID, &events.DeletedCompanyPayload{ID: op.Result.ID})
+	}
+}
+
+// WaitForEvents blocks until the pending event dispatches finish or the context is done
+func (s *company) WaitForEvents(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.pendingEvents.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("pending events were not dispatched before the shutdown deadline: %w", ctx.Err())
+	}
+}
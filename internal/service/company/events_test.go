@@ -0,0 +1,183 @@
+package company
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+	"xm_test/internal/db"
+	"xm_test/internal/db/models"
+	"xm_test/internal/enum"
+	"xm_test/internal/events"
+	"xm_test/internal/helpers"
+	"xm_test/internal/service/inputs"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+// companyDB writes the companies without storing them, and returns the company it holds
+type companyDB struct {
+	db.DatabaseAdapter
+	company *models.CompanyModel
+}
+
+func (d *companyDB) CreateCompany(ctx context.Context, company *models.CompanyModel) error {
+	company.Version = 1
+	return nil
+}
+
+func (d *companyDB) GetCompanyByID(ctx context.Context, id string) (*models.CompanyModel, error) {
+	company := *d.company
+	return &company, nil
+}
+
+func (d *companyDB) UpdateCompany(ctx context.Context, id string, company *models.CompanyModel, version int) (*models.CompanyModel, error) {
+	return company, nil
+}
+
+func (d *companyDB) PatchCompany(ctx context.Context, id string, patch *models.CompanyPatch, version int) (*models.CompanyModel, error) {
+	company := *d.company
+	company.Name = *patch.Name
+	return &company, nil
+}
+
+func (d *companyDB) DeleteCompany(ctx context.Context, id string, version int) error {
+	return nil
+}
+
+func (d *companyDB) BulkWriteCompanies(ctx context.Context, ops []*models.CompanyOperation, transactional bool) error {
+	for _, op := range ops {
+		op.Result = op.Company
+	}
+	return nil
+}
+
+// recordingDispatcher records the events dispatched, blocking the dispatches while it is locked
+type recordingDispatcher struct {
+	mu     sync.Mutex
+	events []*events.Event
+}
+
+func (d *recordingDispatcher) Dispatch(event *events.Event) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.events = append(d.events, event)
+	return nil
+}
+
+func (d *recordingDispatcher) Close() {}
+
+type eventsSuite struct {
+	suite.Suite
+	db         *companyDB
+	dispatcher *recordingDispatcher
+	cs         *company
+}
+
+func (s *eventsSuite) SetupTest() {
+	registry, err := events.NewEventsRegistry()
+	s.Require().NoError(err)
+
+	s.db = &companyDB{company: &models.CompanyModel{ID: uuid.New(), Name: "xm", Type: enum.Cooperative.String(), Version: 1}}
+	s.dispatcher = &recordingDispatcher{}
+	s.cs = NewCompanyResolver(zap.NewNop().Sugar(), s.db, registry, s.dispatcher)
+}
+
+// dispatched waits for the pending dispatches and returns the type and the entity of the events dispatched
+func (s *eventsSuite) dispatched() map[enum.EventType]uuid.UUID {
+	s.Require().NoError(s.cs.WaitForEvents(context.Background()))
+	s.dispatcher.mu.Lock()
+	defer s.dispatcher.mu.Unlock()
+
+	dispatched := make(map[enum.EventType]uuid.UUID, len(s.dispatcher.events))
+	for _, evt := range s.dispatcher.events {
+		dispatched[enum.EventType(evt.Type)] = evt.EntityID
+	}
+	s.dispatcher.events = nil
+	return dispatched
+}
+
+func (s *eventsSuite) TestDispatchEvents() {
+	ctx := context.Background()
+	id := s.db.company.ID
+	company := inputs.UpdateCompany{Name: "xm", AmountEmployees: helpers.PointerValue(10), Registered: helpers.PointerValue(true), Type: enum.Cooperative.String()}
+
+	s.Run("create", func() {
+		created, err := s.cs.CreateCompany(ctx, &inputs.CreateCompanyInput{Name: "xm", AmountEmployees: company.AmountEmployees, Registered: company.Registered, Type: company.Type})
+		s.Require().NoError(err)
+		s.Equal(map[enum.EventType]uuid.UUID{enum.EventCreateCompany: created.ID}, s.dispatched())
+	})
+
+	s.Run("update", func() {
+		_, err := s.cs.UpdateCompany(ctx, id.String(), &company, 0)
+		s.Require().NoError(err)
+		s.Equal(map[enum.EventType]uuid.UUID{enum.EventUpdateCompany: id}, s.dispatched())
+	})
+
+	s.Run("patch", func() {
+		_, changed, err := s.cs.PatchCompany(ctx, id.String(), 0, func(input *inputs.UpdateCompany) error {
+			input.Name = "xm2"
+			return nil
+		})
+		s.Require().NoError(err)
+		s.True(changed)
+		s.Equal(map[enum.EventType]uuid.UUID{enum.EventUpdateCompany: id}, s.dispatched())
+	})
+
+	s.Run("patch without changes", func() {
+		_, changed, err := s.cs.PatchCompany(ctx, id.String(), 0, func(input *inputs.UpdateCompany) error { return nil })
+		s.Require().NoError(err)
+		s.False(changed)
+		s.Empty(s.dispatched())
+	})
+
+	s.Run("delete", func() {
+		s.Require().NoError(s.cs.DeleteCompany(ctx, id.String(), 0))
+		s.Equal(map[enum.EventType]uuid.UUID{enum.EventDeleteCompany: id}, s.dispatched())
+	})
+
+	s.Run("bulk", func() {
+		ops, err := s.cs.BulkWriteCompanies(ctx, &inputs.BulkCompaniesInput{Operations: []inputs.BulkOperation{
+			{Type: enum.BulkCreate, Company: &company},
+			{Type: enum.BulkDelete, ID: id.String()},
+			{Type: enum.BulkUpdate, ID: "invalid", Company: &company},
+		}})
+		s.Require().NoError(err)
+		s.Require().Error(ops[2].Err)
+		s.Equal(map[enum.EventType]uuid.UUID{enum.EventCreateCompany: ops[0].Result.ID, enum.EventDeleteCompany: id}, s.dispatched())
+	})
+}
+
+func (s *eventsSuite) TestWaitForEvents() {
+	s.Run("waits for the pending dispatches", func() {
+		s.dispatcher.mu.Lock()
+		s.Require().NoError(s.cs.DeleteCompany(context.Background(), s.db.company.ID.String(), 0))
+
+		done := make(chan error)
+		go func() { done <- s.cs.WaitForEvents(context.Background()) }()
+		select {
+		case <-done:
+			s.FailNow("returned before the dispatch finished")
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		s.dispatcher.mu.Unlock()
+		s.NoError(<-done)
+	})
+
+	s.Run("fails when the context is done", func() {
+		s.dispatcher.mu.Lock()
+		defer s.dispatcher.mu.Unlock()
+		s.Require().NoError(s.cs.DeleteCompany(context.Background(), s.db.company.ID.String(), 0))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		s.ErrorIs(s.cs.WaitForEvents(ctx), context.DeadlineExceeded)
+	})
+}
+
+func TestEventsSuite(t *testing.T) {
+	suite.Run(t, new(eventsSuite))
+}
@@ -0,0 +1,49 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"xm_test/internal/db/models"
+)
+
+// csvWriter writes the companies as CSV, with a header naming the columns
+type csvWriter struct {
+	w       *csv.Writer
+	columns []string
+	header  bool
+}
+
+func newCSVWriter(w io.Writer, columns []string) *csvWriter {
+	return &csvWriter{w: csv.NewWriter(w), columns: columns}
+}
+
+// Write writes the record of the company, after the header when it is the first one
+func (c *csvWriter) Write(company *models.CompanyModel) error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+
+	record := make([]string, len(c.columns))
+	for i, column := range c.columns {
+		record[i] = fmt.Sprint(value(company, column))
+	}
+	return c.w.Write(record)
+}
+
+// Close writes the records that are buffered, and the header when no company was written
+func (c *csvWriter) Close() error {
+	if err := c.writeHeader(); err != nil {
+		return err
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) writeHeader() error {
+	if c.header {
+		return nil
+	}
+	c.header = true
+	return c.w.Write(c.columns)
+}
@@ -0,0 +1,92 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/db/models"
+	"xm_test/internal/enum"
+	"xm_test/internal/i18n"
+)
+
+// Columns are the columns of the exported companies, in the order they are exported by default
+var Columns = []string{"id", "name", "description", "amount_employees", "registered", "type"}
+
+// mediaTypes maps the export formats to the media type of their files
+var mediaTypes = map[enum.ExportFormat]string{
+	enum.ExportCSV:     "text/csv",
+	enum.ExportNDJSON:  "application/x-ndjson",
+	enum.ExportXLSX:    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	enum.ExportParquet: "application/vnd.apache.parquet",
+}
+
+// Writer writes the exported companies to a file, one by one. Nothing is written until the first company or the
+// end of the export, so an export failing before it starts can still be reported.
+type Writer interface {
+	Write(company *models.CompanyModel) error // Write writes the columns of a company
+	Close() error                             // Close completes the file, which is not valid until then
+}
+
+// NewWriter returns a writer of the companies to w in the format, with the columns given in their order
+func NewWriter(w io.Writer, format enum.ExportFormat, columns []string) (Writer, error) {
+	switch format {
+	case enum.ExportCSV:
+		return newCSVWriter(w, columns), nil
+	case enum.ExportNDJSON:
+		return newNDJSONWriter(w, columns), nil
+	case enum.ExportXLSX:
+		return newXLSXWriter(w, columns)
+	case enum.ExportParquet:
+		return newParquetWriter(w, columns), nil
+	}
+	return nil, fmt.Errorf("unsupported export format '%s'", format)
+}
+
+// MediaType returns the media type of the files of the format
+func MediaType(format enum.ExportFormat) string {
+	return mediaTypes[format]
+}
+
+// ParseColumns returns the columns of the comma-separated list, in its order, or every column when it is empty.
+// The columns repeated are only exported once.
+func ParseColumns(list string) ([]string, error) {
+	if strings.TrimSpace(list) == "" {
+		return Columns, nil
+	}
+
+	var columns []string
+	for _, column := range strings.Split(list, ",") {
+		column = strings.ToLower(strings.TrimSpace(column))
+		if !slices.Contains(Columns, column) {
+			return nil, apierrors.ErrInvalidQuery.WithMessageID("columns", i18n.Params{
+				"column":  column,
+				"columns": strings.Join(Columns, ", "),
+			})
+		}
+		if !slices.Contains(columns, column) {
+			columns = append(columns, column)
+		}
+	}
+	return columns, nil
+}
+
+// value returns the value of the column of the company
+func value(company *models.CompanyModel, column string) any {
+	switch column {
+	case "id":
+		return company.ID.String()
+	case "name":
+		return company.Name
+	case "description":
+		return company.Description
+	case "amount_employees":
+		return company.AmountEmployees
+	case "registered":
+		return company.Registered
+	case "type":
+		return company.Type
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+	"testing"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/db/models"
+	"xm_test/internal/enum"
+
+	"github.com/google/uuid"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/suite"
+	"github.com/xuri/excelize/v2"
+)
+
+type exportSuite struct {
+	suite.Suite
+}
+
+// companies returns the amount of companies to export
+func companies(amount int) []*models.CompanyModel {
+	companies := make([]*models.CompanyModel, amount)
+	for i := range companies {
+		companies[i] = &models.CompanyModel{
+			ID:              uuid.New(),
+			Name:            "company" + strconv.Itoa(i),
+			Description:     "a, \"quoted\" description",
+			AmountEmployees: i,
+			Registered:      i%2 == 0,
+			Type:            enum.Cooperative.String(),
+		}
+	}
+	return companies
+}
+
+// export writes the companies in the format and returns the file
+func (s *exportSuite) export(format enum.ExportFormat, columns []string, companies []*models.CompanyModel) []byte {
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, format, columns)
+	s.Require().NoError(err)
+	for _, company := range companies {
+		s.Require().NoError(w.Write(company))
+	}
+	s.Require().NoError(w.Close())
+	return buf.Bytes()
+}
+
+func (s *exportSuite) TestParseColumns() {
+	columns, err := ParseColumns("")
+	s.Require().NoError(err)
+	s.Equal(Columns, columns)
+
+	columns, err = ParseColumns(" Type,name,type ")
+	s.Require().NoError(err)
+	s.Equal([]string{"type", "name"}, columns)
+
+	_, err = ParseColumns("name,version")
+	s.ErrorIs(err, apierrors.ErrInvalidQuery)
+}
+
+func (s *exportSuite) TestCSV() {
+	exported := companies(2)
+	data := s.export(enum.ExportCSV, []string{"name", "registered", "description"}, exported)
+
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	s.Require().NoError(err)
+	s.Equal([][]string{
+		{"name", "registered", "description"},
+		{"company0", "true", exported[0].Description},
+		{"company1", "false", exported[1].Description},
+	}, records)
+
+	s.Run("header without companies", func() {
+		s.Equal("id,name\n", string(s.export(enum.ExportCSV, []string{"id", "name"}, nil)))
+	})
+}
+
+func (s *exportSuite) TestNDJSON() {
+	exported := companies(2)
+	data := s.export(enum.ExportNDJSON, []string{"type", "amount_employees", "id"}, exported)
+
+	// the keys are kept in the order of the columns
+	s.Equal(
+		`{"type":"Cooperative","amount_employees":0,"id":"`+exported[0].ID.String()+"\"}\n"+
+			`{"type":"Cooperative","amount_employees":1,"id":"`+exported[1].ID.String()+"\"}\n",
+		string(data),
+	)
+}
+
+func (s *exportSuite) TestXLSX() {
+	exported := companies(3)
+	data := s.export(enum.ExportXLSX, []string{"name", "amount_employees"}, exported)
+
+	file, err := excelize.OpenReader(bytes.NewReader(data))
+	s.Require().NoError(err)
+	defer file.Close()
+	rows, err := file.GetRows(xlsxSheet)
+	s.Require().NoError(err)
+	s.Equal([][]string{
+		{"name", "amount_employees"},
+		{"company0", "0"},
+		{"company1", "1"},
+		{"company2", "2"},
+	}, rows)
+}
+
+func (s *exportSuite) TestParquet() {
+	type row struct {
+		ID              string `parquet:"id"`
+		Name            string `parquet:"name"`
+		AmountEmployees int64  `parquet:"amount_employees"`
+		Registered      bool   `parquet:"registered"`
+	}
+
+	exported := companies(parquetRowGroupSize + 1)
+	data := s.export(enum.ExportParquet, []string{"registered", "name", "id", "amount_employees"}, exported)
+
+	rows, err := parquet.Read[row](bytes.NewReader(data), int64(len(data)))
+	s.Require().NoError(err)
+	s.Require().Len(rows, len(exported))
+	for _, i := range []int{0, 1, parquetRowGroupSize} {
+		s.Equal(row{
+			ID:              exported[i].ID.String(),
+			Name:            exported[i].Name,
+			AmountEmployees: int64(exported[i].AmountEmployees),
+			Registered:      exported[i].Registered,
+		}, rows[i])
+	}
+}
+
+func TestExportSuite(t *testing.T) {
+	suite.Run(t, new(exportSuite))
+}
@@ -0,0 +1,49 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"xm_test/internal/db/models"
+)
+
+// ndjsonWriter writes the companies as newline-delimited JSON, with an object per company whose keys are the
+// columns in their order
+type ndjsonWriter struct {
+	w       *bufio.Writer
+	columns []string
+	line    bytes.Buffer
+}
+
+func newNDJSONWriter(w io.Writer, columns []string) *ndjsonWriter {
+	return &ndjsonWriter{w: bufio.NewWriter(w), columns: columns}
+}
+
+// Write writes the line of the company
+func (n *ndjsonWriter) Write(company *models.CompanyModel) error {
+	n.line.Reset()
+	n.line.WriteByte('{')
+	for i, column := range n.columns {
+		if i > 0 {
+			n.line.WriteByte(',')
+		}
+		key, _ := json.Marshal(column)
+		data, err := json.Marshal(value(company, column))
+		if err != nil {
+			return err
+		}
+		n.line.Write(key)
+		n.line.WriteByte(':')
+		n.line.Write(data)
+	}
+	n.line.WriteString("}\n")
+
+	_, err := n.w.Write(n.line.Bytes())
+	return err
+}
+
+// Close writes the lines that are buffered
+func (n *ndjsonWriter) Close() error {
+	return n.w.Flush()
+}
@@ -0,0 +1,72 @@
+package export
+
+import (
+	"io"
+	"xm_test/internal/db/models"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRowGroupSize is the amount of companies of each row group, which are held in memory until it is written
+const parquetRowGroupSize = 10000
+
+// parquetColumns are the nodes of the columns of the exported companies
+var parquetColumns = map[string]parquet.Node{
+	"id":               parquet.String(),
+	"name":             parquet.String(),
+	"description":      parquet.String(),
+	"amount_employees": parquet.Int(64),
+	"registered":       parquet.Leaf(parquet.BooleanType),
+	"type":             parquet.String(),
+}
+
+// parquetWriter writes the companies as a Parquet file compressed with Snappy, by row groups
+type parquetWriter struct {
+	w       *parquet.Writer
+	columns []string
+	indexes []int // index of each column in the schema, which sorts them by name
+	row     parquet.Row
+}
+
+func newParquetWriter(w io.Writer, columns []string) *parquetWriter {
+	group := parquet.Group{}
+	for _, column := range columns {
+		group[column] = parquetColumns[column]
+	}
+	schema := parquet.NewSchema("company", group)
+
+	p := &parquetWriter{
+		w:       parquet.NewWriter(w, schema, parquet.MaxRowsPerRowGroup(parquetRowGroupSize), parquet.Compression(&parquet.Snappy)),
+		columns: columns,
+		indexes: make([]int, len(columns)),
+		row:     make(parquet.Row, len(columns)),
+	}
+	for i, column := range columns {
+		leaf, _ := schema.Lookup(column)
+		p.indexes[i] = leaf.ColumnIndex
+	}
+	return p
+}
+
+// Write adds the company to the current row group
+func (p *parquetWriter) Write(company *models.CompanyModel) error {
+	for i, column := range p.columns {
+		var v parquet.Value
+		switch data := value(company, column).(type) {
+		case string:
+			v = parquet.ByteArrayValue([]byte(data))
+		case int:
+			v = parquet.Int64Value(int64(data))
+		case bool:
+			v = parquet.BooleanValue(data)
+		}
+		p.row[p.indexes[i]] = v.Level(0, 0, p.indexes[i])
+	}
+	_, err := p.w.WriteRows([]parquet.Row{p.row})
+	return err
+}
+
+// Close writes the last row group and the footer of the file
+func (p *parquetWriter) Close() error {
+	return p.w.Close()
+}
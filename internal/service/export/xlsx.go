@@ -0,0 +1,70 @@
+package export
+
+import (
+	"errors"
+	"io"
+	"xm_test/internal/db/models"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// xlsxSheet is the sheet of the workbook holding the companies
+const xlsxSheet = "Sheet1"
+
+// xlsxWriter writes the companies to a sheet of an Excel workbook, with a header row naming the columns. The rows
+// are streamed to a temporary file once they exceed the memory buffer of the workbook, and the workbook is written
+// when closed, since its archive cannot be written before every row is known.
+type xlsxWriter struct {
+	w       io.Writer
+	file    *excelize.File
+	sheet   *excelize.StreamWriter
+	columns []string
+	row     int
+}
+
+func newXLSXWriter(w io.Writer, columns []string) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+	sheet, err := file.NewStreamWriter(xlsxSheet)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	x := &xlsxWriter{w: w, file: file, sheet: sheet, columns: columns}
+	header := make([]any, len(columns))
+	for i, column := range columns {
+		header[i] = column
+	}
+	if err := x.writeRow(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return x, nil
+}
+
+// Write writes the row of the company
+func (x *xlsxWriter) Write(company *models.CompanyModel) error {
+	values := make([]any, len(x.columns))
+	for i, column := range x.columns {
+		values[i] = value(company, column)
+	}
+	return x.writeRow(values)
+}
+
+// Close writes the workbook and removes its temporary files
+func (x *xlsxWriter) Close() error {
+	err := x.sheet.Flush()
+	if err == nil {
+		err = x.file.Write(x.w)
+	}
+	return errors.Join(err, x.file.Close())
+}
+
+func (x *xlsxWriter) writeRow(values []any) error {
+	x.row++
+	cell, err := excelize.CoordinatesToCellName(1, x.row)
+	if err != nil {
+		return err
+	}
+	return x.sheet.SetRow(cell, values)
+}
@@ -0,0 +1,278 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/db"
+	"xm_test/internal/db/models"
+	"xm_test/internal/enum"
+	"xm_test/internal/i18n"
+	"xm_test/internal/logging"
+	"xm_test/internal/service/inputs"
+	"xm_test/internal/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// shutdownMessage is the error of the jobs interrupted by the shutdown
+const shutdownMessage = "interrupted by the shutdown"
+
+// CompanyWriter writes the companies in bulk and dispatches their events, as the company service does
+type CompanyWriter interface {
+	BulkWriteCompanies(ctx context.Context, input *inputs.BulkCompaniesInput) ([]*models.CompanyOperation, error)
+}
+
+type importer struct {
+	logger    *zap.SugaredLogger
+	db        db.DatabaseAdapter
+	companies CompanyWriter
+	batchSize int
+
+	// cancelled on shutdown to interrupt the jobs running in the background
+	ctx    context.Context
+	cancel context.CancelFunc
+	jobs   sync.WaitGroup
+}
+
+// NewImportResolver returns a new import service instance. The rows are written by the companies writer, by batches
+// of the given size.
+func NewImportResolver(logger *zap.SugaredLogger, db db.DatabaseAdapter, companies CompanyWriter, batchSize int) *importer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &importer{
+		logger:    logger,
+		db:        db,
+		companies: companies,
+		batchSize: batchSize,
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// ImportCompanies creates a job importing the companies of the rows of the input. The rows are imported before
+// returning the job, unless the input asks to import them in the background: the job is returned once created, and
+// its progress is polled with GetImportJob. Each row is validated and written on its own, so the invalid rows are
+// rejected without failing the job, and the outcome of every row is reported by StreamImportReport.
+func (s *importer) ImportCompanies(ctx context.Context, input *inputs.ImportCompaniesInput) (_ *models.ImportJobModel, err error) {
+	ctx, span := tracing.Start(ctx, "ImportService.ImportCompanies", trace.WithAttributes(
+		attribute.String("import.format", input.Format.String()),
+		attribute.Int64("import.size", input.Size),
+		attribute.Bool("import.background", input.Background),
+	))
+	defer func() { tracing.End(span, err) }()
+	logger := logging.FromContext(ctx, s.logger)
+
+	job := &models.ImportJobModel{
+		ID:     uuid.New(),
+		UserID: input.UserID,
+		Format: input.Format,
+		Status: enum.ImportRunning,
+		Size:   input.Size,
+	}
+	logger.Infof("creating import job '%s' for a %s file of %d bytes", job.ID, job.Format, job.Size)
+	if err = s.db.CreateImportJob(ctx, job); err != nil {
+		input.Rows.Close()
+		return nil, err
+	}
+
+	if !input.Background {
+		err = s.run(ctx, job, input.Rows)
+		return job, err
+	}
+
+	// the job outlives the request, so only the values of its context, such as the trace, are kept
+	created := *job
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(s.ctx, cancel)
+	s.jobs.Add(1)
+	go func() {
+		defer s.jobs.Done()
+		defer cancel()
+		defer stop()
+		s.run(jobCtx, job, input.Rows)
+	}()
+	logger.Infof("import job '%s' running in the background", job.ID)
+	return &created, nil
+}
+
+// run imports the rows by batches, saving the progress of the job after each of them. The job fails when the rows
+// cannot be read or written.
+func (s *importer) run(ctx context.Context, job *models.ImportJobModel, rows inputs.CompanyRows) error {
+	logger := logging.FromContext(ctx, s.logger)
+	defer rows.Close()
+
+	batch := make([]*inputs.ImportRow, 0, s.batchSize)
+	for {
+		row, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return s.fail(ctx, job, err)
+		}
+
+		batch = append(batch, row)
+		if len(batch) < s.batchSize {
+			continue
+		}
+		if err := s.importBatch(ctx, job, batch, rows.BytesRead(), enum.ImportRunning); err != nil {
+			return s.fail(ctx, job, err)
+		}
+		batch = batch[:0]
+	}
+
+	if err := s.importBatch(ctx, job, batch, rows.BytesRead(), enum.ImportCompleted); err != nil {
+		return s.fail(ctx, job, err)
+	}
+	logger.Infof("import job '%s' completed: %d rows accepted, %d rejected", job.ID, job.Accepted, job.Rejected)
+	return nil
+}
+
+// importBatch creates the companies of the valid rows of the batch, and saves the outcome of every row along with
+// the progress and the status of the job. The events of the companies are dispatched by the companies writer.
+func (s *importer) importBatch(ctx context.Context, job *models.ImportJobModel, batch []*inputs.ImportRow, bytesRead int64, status enum.ImportStatus) error {
+	logger := logging.FromContext(ctx, s.logger)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var ops []*models.CompanyOperation
+	if len(batch) > 0 {
+		input := &inputs.BulkCompaniesInput{Operations: make([]inputs.BulkOperation, len(batch))}
+		for i, row := range batch {
+			input.Operations[i] = inputs.BulkOperation{Type: enum.BulkCreate, Invalid: row.Invalid}
+			if row.Company != nil {
+				company := inputs.UpdateCompany(*row.Company)
+				input.Operations[i].Company = &company
+			}
+		}
+
+		var err error
+		if ops, err = s.companies.BulkWriteCompanies(ctx, input); err != nil {
+			return err
+		}
+	}
+
+	// the job is only updated once its progress is saved
+	progress := *job
+	progress.Status = status
+	progress.BytesRead = bytesRead
+	progress.Processed += len(ops)
+	rows := make([]*models.ImportRowModel, len(ops))
+	for i, op := range ops {
+		rows[i] = importRow(job.ID, batch[i].Line, op)
+		if rows[i].Accepted {
+			progress.Accepted++
+		} else {
+			progress.Rejected++
+		}
+	}
+	if err := s.db.SaveImportProgress(ctx, &progress, rows); err != nil {
+		return err
+	}
+	*job = progress
+	logger.Debugf("import job '%s' processed %d rows", job.ID, job.Processed)
+	return nil
+}
+
+// importRow returns the outcome of the operation creating the company of the row at the line
+func importRow(jobID uuid.UUID, line int, op *models.CompanyOperation) *models.ImportRowModel {
+	row := &models.ImportRowModel{JobID: jobID, Line: line, Accepted: op.Err == nil}
+	if row.Accepted {
+		row.CompanyID = &op.Result.ID
+		return row
+	}
+
+	var apiError *apierrors.APIError
+	if !errors.As(op.Err, &apiError) {
+		apiError = apierrors.ErrInternalServer.Wrap(op.Err)
+	}
+	row.ErrorCode = apiError.Code
+	row.ErrorMessage = apiError.Message
+	return row
+}
+
+// fail marks the job as failed and returns the error that caused it. The errors of the server are not reported,
+// since they may leak implementation details.
+func (s *importer) fail(ctx context.Context, job *models.ImportJobModel, err error) error {
+	logger := logging.FromContext(ctx, s.logger)
+	logger.Errorf("import job '%s' failed: %v", job.ID, err)
+
+	job.Status = enum.ImportFailed
+	job.Error = apierrors.ErrInternalServer.Message
+	var apiError *apierrors.APIError
+	switch {
+	case s.ctx.Err() != nil:
+		job.Error = shutdownMessage
+	case errors.As(err, &apiError) && apiError.HTTPStatus < http.StatusInternalServerError:
+		job.Error = apiError.Message
+	}
+
+	// the job is marked as failed even if it failed because its context is done
+	if saveErr := s.db.SaveImportProgress(context.WithoutCancel(ctx), job, nil); saveErr != nil {
+		logger.Errorf("failed to mark import job '%s' as failed: %v", job.ID, saveErr)
+	}
+	return err
+}
+
+// GetImportJob retrieves an import job of the user by its ID
+func (s *importer) GetImportJob(ctx context.Context, id string, userID uuid.UUID) (_ *models.ImportJobModel, err error) {
+	ctx, span := tracing.Start(ctx, "ImportService.GetImportJob")
+	defer func() { tracing.End(span, err) }()
+	logger := logging.FromContext(ctx, s.logger)
+
+	logger.Infof("retrieving import job with id '%s'", id)
+	if err = uuid.Validate(id); err != nil {
+		return nil, apierrors.ErrInvalidUUID
+	}
+	job, err := s.db.GetImportJob(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// the jobs of the other users are hidden, so their existence is not disclosed
+	if job.UserID != userID {
+		return nil, apierrors.ErrImportJobNotFound.WithMessageID("id", i18n.Params{"id": id})
+	}
+	logger.Infof("import job with id '%s' retrieved", id)
+	return job, nil
+}
+
+// StreamImportReport calls the handler with the outcome of each row of an import job of the user, sorted by line.
+// The rows of a running job are the ones processed so far.
+func (s *importer) StreamImportReport(ctx context.Context, id string, userID uuid.UUID, handler func(row *models.ImportRowModel) error) (err error) {
+	ctx, span := tracing.Start(ctx, "ImportService.StreamImportReport")
+	defer func() { tracing.End(span, err) }()
+	logger := logging.FromContext(ctx, s.logger)
+
+	if _, err = s.GetImportJob(ctx, id, userID); err != nil {
+		return err
+	}
+	logger.Infof("streaming the report of import job with id '%s'", id)
+	return s.db.StreamImportRows(ctx, id, handler)
+}
+
+// Close interrupts the jobs running in the background, which are marked as failed, and waits for them until the
+// context is done
+func (s *importer) Close(ctx context.Context) error {
+	s.cancel()
+	done := make(chan struct{})
+	go func() {
+		s.jobs.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("import jobs were not interrupted before the shutdown deadline: %w", ctx.Err())
+	}
+}
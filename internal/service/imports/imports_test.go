@@ -0,0 +1,241 @@
+package imports
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/conf"
+	"xm_test/internal/db"
+	"xm_test/internal/db/models"
+	"xm_test/internal/db/options"
+	"xm_test/internal/enum"
+	"xm_test/internal/events"
+	"xm_test/internal/helpers"
+	"xm_test/internal/mocks"
+	"xm_test/internal/service/company"
+	"xm_test/internal/service/inputs"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"go.uber.org/zap"
+)
+
+// sliceRows returns the rows of a slice, and blocks before the rows after the gate until it is closed
+type sliceRows struct {
+	rows   []*inputs.ImportRow
+	gate   int
+	open   chan struct{}
+	closed bool
+}
+
+func (r *sliceRows) Next() (*inputs.ImportRow, error) {
+	if len(r.rows) == 0 {
+		return nil, io.EOF
+	}
+	if r.open != nil && r.rows[0].Line > r.gate {
+		<-r.open
+	}
+	row := r.rows[0]
+	r.rows = r.rows[1:]
+	return row, nil
+}
+
+func (r *sliceRows) BytesRead() int64 { return 0 }
+
+func (r *sliceRows) Close() error {
+	r.closed = true
+	return nil
+}
+
+type importSuite struct {
+	db db.DatabaseAdapter
+	is *importer
+
+	// company service writing the companies and dispatching their events
+	cs interface {
+		CompanyWriter
+		WaitForEvents(ctx context.Context) error
+	}
+
+	eventsMu sync.Mutex
+	events   []uuid.UUID
+
+	container *postgres.PostgresContainer
+	suite.Suite
+}
+
+func (s *importSuite) SetupSuite() {
+	conf.SetupConfig()
+	conf.GlobalConfig.DatabaseType = enum.Postgres
+
+	// establish connection to the test database with testcontainer
+	user, database, password := "test", "test", "test"
+	container, connStr, err := mocks.RunPostgresTestDatabaseContainer(
+		user,
+		database,
+		password,
+		conf.GlobalConfig.Postgres.InitScript,
+	)
+	s.Require().NoError(err)
+	s.container = container
+
+	mappedPort, err := container.MappedPort(context.Background(), nat.Port("5432"))
+	s.Require().NoError(err)
+
+	conf.GlobalConfig.Postgres.Port = mappedPort.Port()
+
+	logger := zap.NewExample().Sugar()
+	db := db.NewDatabaseAdapter(logger, options.WithConnectionString(*connStr))
+	s.db = db
+	registry, err := events.NewEventsRegistry()
+	s.Require().NoError(err)
+	s.cs = company.NewCompanyResolver(logger, db, registry, s)
+	s.is = NewImportResolver(logger, db, s.cs, 2)
+}
+
+func (s *importSuite) TearDownSuite() {
+	ctx := context.Background()
+	s.Require().NoError(s.is.Close(ctx))
+	s.Require().NoError(s.db.Close(ctx))
+	s.Require().NoError(s.container.Terminate(ctx))
+}
+
+// Dispatch records the entity of the events dispatched by the company service
+func (s *importSuite) Dispatch(evt *events.Event) error {
+	s.eventsMu.Lock()
+	defer s.eventsMu.Unlock()
+	s.events = append(s.events, evt.EntityID)
+	return nil
+}
+
+func (s *importSuite) Close() {}
+
+// row returns a valid row creating the company with the name
+func row(line int, name string) *inputs.ImportRow {
+	return &inputs.ImportRow{Line: line, Company: &inputs.CreateCompanyInput{
+		Name:            name,
+		AmountEmployees: helpers.PointerValue(10),
+		Registered:      helpers.PointerValue(true),
+		Type:            enum.Cooperative.String(),
+	}}
+}
+
+// report returns the rows of the report of the job
+func (s *importSuite) report(id string, userID uuid.UUID) []*models.ImportRowModel {
+	var rows []*models.ImportRowModel
+	s.Require().NoError(s.is.StreamImportReport(context.Background(), id, userID, func(row *models.ImportRowModel) error {
+		rows = append(rows, row)
+		return nil
+	}))
+	return rows
+}
+
+func (s *importSuite) TestImportCompanies() {
+	userID := uuid.New()
+
+	s.Run("imports the valid rows", func() {
+		rows := &sliceRows{rows: []*inputs.ImportRow{
+			row(2, "importA"),
+			{Line: 3, Invalid: apierrors.ErrInvalidBody},
+			row(4, "importB"),
+			row(5, "importA"),
+			row(6, "importC"),
+		}}
+		job, err := s.is.ImportCompanies(context.Background(), &inputs.ImportCompaniesInput{UserID: userID, Format: enum.ImportCSV, Rows: rows})
+		s.Require().NoError(err)
+		s.True(rows.closed)
+		s.Equal(enum.ImportCompleted, job.Status)
+		s.Equal(5, job.Processed)
+		s.Equal(3, job.Accepted)
+		s.Equal(2, job.Rejected)
+
+		stored, err := s.is.GetImportJob(context.Background(), job.ID.String(), userID)
+		s.Require().NoError(err)
+		s.Equal(job.Accepted, stored.Accepted)
+
+		report := s.report(job.ID.String(), userID)
+		s.Require().Len(report, 5)
+		s.True(report[0].Accepted)
+		s.Equal(apierrors.ErrInvalidBody.Code, report[1].ErrorCode)
+		s.Equal(apierrors.ErrCompanyAlreadyExists.Code, report[3].ErrorCode)
+		s.True(report[4].Accepted)
+
+		company, err := s.db.GetCompanyByID(context.Background(), report[4].CompanyID.String())
+		s.Require().NoError(err)
+		s.Equal("importC", company.Name)
+
+		s.Require().NoError(s.cs.WaitForEvents(context.Background()))
+		s.eventsMu.Lock()
+		defer s.eventsMu.Unlock()
+		s.Contains(s.events, *report[4].CompanyID)
+	})
+
+	s.Run("imports in the background", func() {
+		rows := &sliceRows{rows: []*inputs.ImportRow{row(1, "importD"), row(2, "importE"), row(3, "importF")}, gate: 2, open: make(chan struct{})}
+		job, err := s.is.ImportCompanies(context.Background(), &inputs.ImportCompaniesInput{UserID: userID, Format: enum.ImportNDJSON, Rows: rows, Background: true})
+		s.Require().NoError(err)
+		s.Equal(enum.ImportRunning, job.Status)
+
+		// the first batch is saved while the job waits for the last row
+		s.Eventually(func() bool {
+			stored, err := s.is.GetImportJob(context.Background(), job.ID.String(), userID)
+			return err == nil && stored.Processed == 2
+		}, 10*time.Second, 50*time.Millisecond)
+		close(rows.open)
+
+		s.Eventually(func() bool {
+			stored, err := s.is.GetImportJob(context.Background(), job.ID.String(), userID)
+			return err == nil && stored.Status == enum.ImportCompleted && stored.Accepted == 3
+		}, 10*time.Second, 50*time.Millisecond)
+	})
+
+	s.Run("fails when the rows cannot be read", func() {
+		job, err := s.is.ImportCompanies(context.Background(), &inputs.ImportCompaniesInput{UserID: userID, Format: enum.ImportCSV, Rows: &failingRows{}})
+		s.Require().Error(err)
+		stored, err := s.is.GetImportJob(context.Background(), job.ID.String(), userID)
+		s.Require().NoError(err)
+		s.Equal(enum.ImportFailed, stored.Status)
+		s.Equal(apierrors.ErrInternalServer.Message, stored.Error)
+	})
+}
+
+func (s *importSuite) TestGetImportJob() {
+	userID := uuid.New()
+	job, err := s.is.ImportCompanies(context.Background(), &inputs.ImportCompaniesInput{UserID: userID, Format: enum.ImportCSV, Rows: &sliceRows{}})
+	s.Require().NoError(err)
+
+	s.Run("hides the jobs of the other users", func() {
+		_, err := s.is.GetImportJob(context.Background(), job.ID.String(), uuid.New())
+		s.ErrorIs(err, apierrors.ErrImportJobNotFound)
+
+		err = s.is.StreamImportReport(context.Background(), job.ID.String(), uuid.New(), func(row *models.ImportRowModel) error { return nil })
+		s.ErrorIs(err, apierrors.ErrImportJobNotFound)
+	})
+
+	s.Run("not found", func() {
+		_, err := s.is.GetImportJob(context.Background(), uuid.NewString(), userID)
+		s.ErrorIs(err, apierrors.ErrImportJobNotFound)
+	})
+
+	s.Run("invalid id", func() {
+		_, err := s.is.GetImportJob(context.Background(), "invalid", userID)
+		s.ErrorIs(err, apierrors.ErrInvalidUUID)
+	})
+}
+
+// failingRows fails to be read
+type failingRows struct{}
+
+func (failingRows) Next() (*inputs.ImportRow, error) { return nil, errors.New("connection reset") }
+func (failingRows) BytesRead() int64                 { return 0 }
+func (failingRows) Close() error                     { return nil }
+
+func TestImportSuite(t *testing.T) {
+	suite.Run(t, new(importSuite))
+}
@@ -0,0 +1,55 @@
+package imports
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"xm_test/internal/db/models"
+)
+
+// reportHeader is the header of the reports of the import jobs
+var reportHeader = []string{"line", "status", "company_id", "error_code", "error_message"}
+
+// ReportWriter writes the report of an import job as CSV, with a record for each row of the imported file
+type ReportWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+// NewReportWriter returns a report writer writing to w
+func NewReportWriter(w io.Writer) *ReportWriter {
+	return &ReportWriter{w: csv.NewWriter(w)}
+}
+
+// Write writes the record of the row, after the header when it is the first one
+func (r *ReportWriter) Write(row *models.ImportRowModel) error {
+	if err := r.writeHeader(); err != nil {
+		return err
+	}
+
+	record := []string{strconv.Itoa(row.Line), "rejected", "", row.ErrorCode, row.ErrorMessage}
+	if row.Accepted {
+		record[1] = "accepted"
+	}
+	if row.CompanyID != nil {
+		record[2] = row.CompanyID.String()
+	}
+	return r.w.Write(record)
+}
+
+// Flush writes the records that are buffered, and the header when no row was written
+func (r *ReportWriter) Flush() error {
+	if err := r.writeHeader(); err != nil {
+		return err
+	}
+	r.w.Flush()
+	return r.w.Error()
+}
+
+func (r *ReportWriter) writeHeader() error {
+	if r.header {
+		return nil
+	}
+	r.header = true
+	return r.w.Write(reportHeader)
+}
@@ -1,5 +1,11 @@
 package inputs
 
+import (
+	"xm_test/internal/enum"
+
+	"github.com/google/uuid"
+)
+
 // CreateCompany represents the input for creating a company
 type CreateCompanyInput struct {
 	Name            string `json:"name" validate:"required"`
@@ -11,3 +17,61 @@ type CreateCompanyInput struct {
 
 // UpdateCompany represents the input for updating a company
 type UpdateCompany CreateCompanyInput
+
+// PatchCompany modifies the current state of a company, which is given as an update input. It must validate the
+// result, since the columns that changed are stored as they are.
+type PatchCompany func(company *UpdateCompany) error
+
+// ListCompaniesInput represents the input for listing the companies
+type ListCompaniesInput struct {
+	Name       string // Case-insensitive substring of the name
+	Type       string // Company type
+	Registered *bool  // Whether the company is registered
+	First      int    // Amount of companies of the page
+	After      string // ID of the last company of the previous page
+}
+
+// ExportCompaniesInput represents the input for exporting the companies, with the same filters as the listing
+type ExportCompaniesInput struct {
+	Name       string // Case-insensitive substring of the name
+	Type       string // Company type
+	Registered *bool  // Whether the company is registered
+}
+
+// BulkOperation represents an operation of a bulk write of companies
+type BulkOperation struct {
+	Type    enum.BulkOperation
+	ID      string         // ID of the company to update or delete
+	Version int            // Version the company must be at to be updated or deleted, or zero for any version
+	Company *UpdateCompany // Company to create or update
+	Invalid error          // Why the transport rejected the operation, which is reported along with the others
+}
+
+// BulkCompaniesInput represents the input for writing companies in bulk
+type BulkCompaniesInput struct {
+	Operations    []BulkOperation
+	Transactional bool // Whether the operations are applied all together or not at all
+}
+
+// ImportRow represents a row of a file of companies to import
+type ImportRow struct {
+	Line    int                 // Line of the file where the row starts
+	Company *CreateCompanyInput // Company to create
+	Invalid error               // Why the transport rejected the row, which is reported along with the others
+}
+
+// CompanyRows reads the rows of a file of companies to import one by one
+type CompanyRows interface {
+	Next() (*ImportRow, error) // Next returns the next row, or io.EOF once every row is read
+	BytesRead() int64          // BytesRead returns the amount of bytes of the file read so far
+	Close() error              // Close releases the file
+}
+
+// ImportCompaniesInput represents the input for importing the companies of a file
+type ImportCompaniesInput struct {
+	UserID     uuid.UUID // User importing the file
+	Format     enum.ImportFormat
+	Size       int64       // Size of the file in bytes, used to report the progress
+	Rows       CompanyRows // Rows of the file, which are closed once the import ends
+	Background bool        // Whether the rows are imported in the background once the job is created
+}
@@ -1,27 +1,45 @@
 package service
 
 import (
+	"context"
 	"xm_test/internal/db"
 	"xm_test/internal/db/models"
+	"xm_test/internal/events"
 	"xm_test/internal/service/auth"
 	"xm_test/internal/service/company"
+	"xm_test/internal/service/imports"
 	"xm_test/internal/service/inputs"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
 // AuthService is an interface for the authentication service. It defines the Login, and Logout methods.
 type AuthService interface {
-	Register(email string, password string) error         // Register registers a new user
-	Login(email string, password string) (*string, error) // Login logs in a user
+	Register(ctx context.Context, email string, password string) error         // Register registers a new user
+	Login(ctx context.Context, email string, password string) (*string, error) // Login logs in a user
 }
 
 // CompanyService is an interface for the company service.
 type CompanyService interface {
-	CreateCompany(company *inputs.CreateCompanyInput) (*models.CompanyModel, error) // CreateCompany creates a new company
-	GetCompanyByID(id string) (*models.CompanyModel, error)                         // GetCompany retrieves a company by its ID
-	UpdateCompany(id string, updatedCompany *inputs.UpdateCompany) error            // UpdateCompany updates a company by its ID
-	DeleteCompany(id string) error                                                  // DeleteCompany deletes a company by its ID
+	CreateCompany(ctx context.Context, company *inputs.CreateCompanyInput) (*models.CompanyModel, error)                             // CreateCompany creates a new company
+	GetCompanyByID(ctx context.Context, id string) (*models.CompanyModel, error)                                                     // GetCompany retrieves a company by its ID
+	UpdateCompany(ctx context.Context, id string, updatedCompany *inputs.UpdateCompany, version int) (*models.CompanyModel, error)   // UpdateCompany updates a company by its ID, at the version when it is not zero
+	PatchCompany(ctx context.Context, id string, version int, patch inputs.PatchCompany) (*models.CompanyModel, bool, error)         // PatchCompany updates the fields of a company changed by the patch
+	DeleteCompany(ctx context.Context, id string, version int) error                                                                 // DeleteCompany deletes a company by its ID, at the version when it is not zero
+	ListCompanies(ctx context.Context, input *inputs.ListCompaniesInput) (*models.CompanyPage, error)                                // ListCompanies retrieves a page of the companies matching the filters
+	ExportCompanies(ctx context.Context, input *inputs.ExportCompaniesInput, handler func(company *models.CompanyModel) error) error // ExportCompanies calls the handler with each company matching the filters
+	GetCompaniesByIDs(ctx context.Context, ids []string) ([]*models.CompanyModel, error)                                             // GetCompaniesByIDs retrieves the companies with the given IDs in a single query
+	BulkWriteCompanies(ctx context.Context, input *inputs.BulkCompaniesInput) ([]*models.CompanyOperation, error)                    // BulkWriteCompanies creates, updates and deletes companies in bulk
+	WaitForEvents(ctx context.Context) error                                                                                         // WaitForEvents blocks until the events of the companies written are dispatched
+}
+
+// ImportService is an interface for the service importing companies from files.
+type ImportService interface {
+	ImportCompanies(ctx context.Context, input *inputs.ImportCompaniesInput) (*models.ImportJobModel, error)                   // ImportCompanies imports the companies of a file, in the background when requested
+	GetImportJob(ctx context.Context, id string, userID uuid.UUID) (*models.ImportJobModel, error)                             // GetImportJob retrieves an import job of the user by its ID
+	StreamImportReport(ctx context.Context, id string, userID uuid.UUID, handler func(row *models.ImportRowModel) error) error // StreamImportReport calls the handler with the outcome of each row of an import job
+	Close(ctx context.Context) error                                                                                           // Close interrupts the imports running in the background
 }
 
 // NewAuthService returns a new auth service instance
@@ -29,7 +47,14 @@ func NewAuthService(logger *zap.SugaredLogger, db db.DatabaseAdapter) AuthServic
 	return auth.NewAuthResolver(logger, db)
 }
 
-// NewCompanyService returns a new company service instance
-func NewCompanyService(logger *zap.SugaredLogger, db db.DatabaseAdapter) CompanyService {
-	return company.NewCompanyResolver(logger, db)
+// NewCompanyService returns a new company service instance. The events of the companies written are created with the
+// registry and dispatched with the dispatcher.
+func NewCompanyService(logger *zap.SugaredLogger, db db.DatabaseAdapter, registry events.Registry, dispatcher events.Dispatcher) CompanyService {
+	return company.NewCompanyResolver(logger, db, registry, dispatcher)
+}
+
+// NewImportService returns a new import service instance. The companies are written by the company service, by
+// batches of the given size, which dispatches their events.
+func NewImportService(logger *zap.SugaredLogger, db db.DatabaseAdapter, cs CompanyService, batchSize int) ImportService {
+	return imports.NewImportResolver(logger, db, cs, batchSize)
 }
@@ -7,6 +7,7 @@ import (
 	"time"
 	apierrors "xm_test/internal/api_errors"
 	"xm_test/internal/conf"
+	"xm_test/internal/i18n"
 
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -14,7 +15,7 @@ import (
 // Claims represents the claims of a JWT token
 type Claims struct {
 	Id    string `json:"id"`
-	Email string `json:"email"`
+	Email string `json:"email" redact:"email"`
 	jwt.RegisteredClaims
 }
 
@@ -54,23 +55,17 @@ func ValidateAndParseToken(token string) (*Claims, error) {
 func DecodeTokenFromRequest(r *http.Request) (*Claims, error) {
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		e := apierrors.ErrTokenNotFound
-		e.Message = "missing Authorization header"
-		return nil, e
+		return nil, apierrors.ErrTokenNotFound.WithMessageID("header", nil)
 	}
 
 	tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
 	if tokenStr == "" {
-		e := apierrors.ErrTokenNotFound
-		e.Message = "missing token in Authorization header"
-		return nil, e
+		return nil, apierrors.ErrTokenNotFound.WithMessageID("token", nil)
 	}
 
 	claims, err := ValidateAndParseToken(tokenStr)
 	if err != nil {
-		e := apierrors.ErrInvalidToken
-		e.Message = fmt.Sprintf("invalid token: %v", err)
-		return nil, e
+		return nil, apierrors.ErrInvalidToken.WithMessageID("reason", i18n.Params{"reason": err})
 	}
 	return claims, nil
 }
@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"xm_test/internal/conf"
+	"xm_test/internal/enum"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Provider is the tracer provider installed globally by Setup
+type Provider interface {
+	Shutdown(ctx context.Context) error // flushes the pending spans and stops the exporter
+}
+
+// noopProvider is returned when tracing is disabled
+type noopProvider struct{}
+
+func (noopProvider) Shutdown(ctx context.Context) error { return nil }
+
+// fileProvider closes the output file of the stdout exporter once the spans are flushed
+type fileProvider struct {
+	*sdktrace.TracerProvider
+	file io.Closer
+}
+
+func (p *fileProvider) Shutdown(ctx context.Context) error {
+	err := p.TracerProvider.Shutdown(ctx)
+	if closeErr := p.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Setup installs the global tracer provider and the W3C trace-context propagator, exporting the spans to the
+// exporter set in the configuration. The W3C propagator is installed even when tracing is disabled, so that
+// incoming trace contexts are still forwarded to the events.
+func Setup(ctx context.Context) (Provider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	cfg := conf.GlobalConfig.Tracing
+	var (
+		exporter sdktrace.SpanExporter
+		file     *os.File
+		err      error
+	)
+	switch cfg.Exporter {
+	case enum.TracingNone:
+		return noopProvider{}, nil
+	case enum.TracingOTLP:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint)}
+		if cfg.OTLPInsecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	case enum.TracingStdout:
+		var w io.Writer = os.Stdout
+		if cfg.OutputFile != "" {
+			file, err = os.OpenFile(cfg.OutputFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				return nil, fmt.Errorf("failed to open traces output file: %w", err)
+			}
+			w = file
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	default:
+		return nil, fmt.Errorf("unsupported tracing exporter: %s", cfg.Exporter)
+	}
+	if err != nil {
+		if file != nil {
+			file.Close()
+		}
+		return nil, fmt.Errorf("failed to create %s traces exporter: %w", cfg.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create traces resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	if file != nil {
+		return &fileProvider{TracerProvider: provider, file: file}, nil
+	}
+	return provider, nil
+}
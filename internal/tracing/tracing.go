@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	apierrors "xm_test/internal/api_errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans created by the API
+const instrumentationName = "xm_test"
+
+// Start creates a span as a child of the span stored in the context, if any
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// End records the error in the span, if any, and ends it. Client errors are recorded as events, so that only
+// the server errors mark the span as failed.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		var apiError *apierrors.APIError
+		if !errors.As(err, &apiError) || apiError.HTTPStatus >= 500 {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
+
+// Inject returns the W3C trace-context headers (traceparent and tracestate) of the span stored in the context.
+// Both values are empty if the context does not carry a span.
+func Inject(ctx context.Context) (traceParent string, traceState string) {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier.Get("traceparent"), carrier.Get("tracestate")
+}
+
+// Extract returns a copy of the context carrying the remote span described by the W3C trace-context headers
+func Extract(ctx context.Context, traceParent string, traceState string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+
+	carrier := propagation.MapCarrier{"traceparent": traceParent}
+	if traceState != "" {
+		carrier.Set("tracestate", traceState)
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	apierrors "xm_test/internal/api_errors"
+
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type tracingSuite struct {
+	recorder *tracetest.SpanRecorder
+	suite.Suite
+}
+
+func (s *tracingSuite) SetupSuite() {
+	s.recorder = tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(s.recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+}
+
+func (s *tracingSuite) TestPropagation() {
+	s.Run("round trip", func() {
+		ctx, span := Start(context.Background(), "parent")
+		defer span.End()
+
+		traceParent, _ := Inject(ctx)
+		s.Require().NotEmpty(traceParent)
+
+		_, child := Start(Extract(context.Background(), traceParent, ""), "child")
+		child.End()
+
+		s.Equal(span.SpanContext().TraceID(), child.SpanContext().TraceID())
+	})
+
+	s.Run("without span", func() {
+		traceParent, traceState := Inject(context.Background())
+		s.Empty(traceParent)
+		s.Empty(traceState)
+
+		ctx := context.Background()
+		s.Equal(ctx, Extract(ctx, "", ""))
+	})
+}
+
+func (s *tracingSuite) TestEnd() {
+	cases := []struct {
+		name   string
+		err    error
+		status codes.Code
+	}{
+		{name: "ok", err: nil, status: codes.Unset},
+		{name: "client error", err: apierrors.NewAPIError("NOT_FOUND", "not found", http.StatusNotFound), status: codes.Unset},
+		{name: "server error", err: apierrors.NewAPIError("INTERNAL", "boom", http.StatusInternalServerError), status: codes.Error},
+		{name: "unknown error", err: errors.New("boom"), status: codes.Error},
+	}
+
+	for _, c := range cases {
+		s.Run(c.name, func() {
+			_, span := Start(context.Background(), c.name)
+			End(span, c.err)
+
+			ended := s.recorder.Ended()
+			last := ended[len(ended)-1]
+			s.Equal(c.name, last.Name())
+			s.Equal(c.status, last.Status().Code)
+		})
+	}
+}
+
+func TestTracingSuite(t *testing.T) {
+	suite.Run(t, new(tracingSuite))
+}
@@ -0,0 +1,44 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	apierrors "xm_test/internal/api_errors"
+
+	qerrors "github.com/graph-gophers/graphql-go/errors"
+	"go.uber.org/zap"
+)
+
+// formatErrors adds the code and the status of the API errors returned by the resolvers to the extensions of the
+// GraphQL errors, so that clients can identify them as they do with the HTTP transport. The errors that are not
+// API errors are hidden behind the internal server error, since they may leak implementation details.
+func formatErrors(ctx context.Context, logger *zap.SugaredLogger, errs []*qerrors.QueryError, requestID string) {
+	for _, err := range errs {
+		if err.ResolverError == nil {
+			continue
+		}
+
+		resolverErr := err.ResolverError
+		// the request context explains the failure better than the error returned by the lower layers
+		if ctxErr := apierrors.FromContext(ctx); ctxErr != nil {
+			resolverErr = ctxErr
+		}
+
+		var apiError *apierrors.APIError
+		if !errors.As(resolverErr, &apiError) {
+			logger.Errorf("resolver failed: %s", resolverErr)
+			apiError = apierrors.ErrInternalServer
+		} else {
+			logger.Error(apiError.Error())
+		}
+
+		err.Message = apiError.Message
+		err.Extensions = map[string]interface{}{
+			"code":   apiError.Code,
+			"status": apiError.HTTPStatus,
+		}
+		if requestID != "" {
+			err.Extensions["request_id"] = requestID
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package graphql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/events"
+	"xm_test/internal/i18n"
+	"xm_test/internal/logging"
+	"xm_test/internal/service"
+
+	customMiddlewares "xm_test/internal/transport/http/middleware"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	"go.uber.org/zap"
+)
+
+// Request is the body of the GraphQL requests
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Handler serves the GraphQL queries and mutations as JSON, and the subscriptions as server-sent events following
+// the GraphQL over SSE protocol, when the client accepts text/event-stream.
+type Handler struct {
+	logger *zap.SugaredLogger
+	cs     service.CompanyService
+	schema *graphqlgo.Schema
+
+	// queries and mutations must be completed within the timeout, while subscriptions are long-lived
+	timeout time.Duration
+
+	// the endpoint is public, so the size of the request bodies is limited
+	maxBodySize int64
+
+	// closed on shutdown to end the subscriptions
+	streamsDone <-chan struct{}
+}
+
+// NewHandler returns a new GraphQL handler. The events of the mutations are dispatched by the company service, and
+// the subscriptions are fed by the local events hub until streamsDone is closed. The request bodies larger than
+// maxBodySize are rejected. When requireVersion is set, the updates and deletions must send the version of the
+// company.
+func NewHandler(logger *zap.SugaredLogger, cs service.CompanyService, hub events.Hub, timeout time.Duration, maxBodySize int64, streamsDone <-chan struct{}, requireVersion bool) *Handler {
+	return &Handler{
+		logger:      logger,
+		cs:          cs,
+		timeout:     timeout,
+		maxBodySize: maxBodySize,
+		streamsDone: streamsDone,
+		schema: newSchema(&resolver{
+			logger:         logger,
+			cs:             cs,
+			hub:            hub,
+			streamsDone:    streamsDone,
+			requireVersion: requireVersion,
+		}),
+	}
+}
+
+// ServeHTTP decodes the GraphQL request and executes it
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("graphql endpoint called")
+
+	var body Request
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, h.maxBodySize)).Decode(&body)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		e := apierrors.ErrPayloadTooLarge.WithMessageID("max", i18n.Params{"max": maxBytesErr.Limit})
+		logger.Error(e.Error())
+		customMiddlewares.RenderError(w, r, e)
+		return
+	}
+	if err != nil || body.Query == "" {
+		e := apierrors.ErrInvalidBody.WithMessageID("query", nil)
+		if err != nil {
+			e = apierrors.ErrInvalidBody.WithMessageID("decode", i18n.Params{"reason": err})
+		}
+		logger.Error(e.Error())
+		customMiddlewares.RenderError(w, r, e)
+		return
+	}
+
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		h.subscribe(w, r, &body)
+		return
+	}
+	customMiddlewares.Deadline(h.timeout)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.exec(w, r, &body)
+	})).ServeHTTP(w, r)
+}
+
+// exec executes the queries and mutations. The companies loaded within the request are cached, so every company
+// is queried at most once.
+func (h *Handler) exec(w http.ResponseWriter, r *http.Request, body *Request) {
+	logger := logging.FromContext(r.Context(), h.logger)
+	ctx := withLoader(r.Context(), newCompanyLoader(h.cs, true))
+
+	response := h.schema.Exec(ctx, body.Query, body.OperationName, body.Variables)
+	formatErrors(ctx, logger, response.Errors, customMiddlewares.RequestIDFromContext(ctx))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logger.Errorf("failed to encode graphql response: %v", err)
+	}
+}
+
+// subscribe executes the subscriptions and streams every result as a next event, followed by a complete event
+// once the subscription ends
+func (h *Handler) subscribe(w http.ResponseWriter, r *http.Request, body *Request) {
+	logger := logging.FromContext(r.Context(), h.logger)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		e := apierrors.ErrInternalServer.WithMessage("streaming is not supported")
+		logger.Error(e.Error())
+		customMiddlewares.RenderError(w, r, e)
+		return
+	}
+
+	// the subscriptions must see the current state of the companies, so the loaded companies are not cached
+	ctx := withLoader(r.Context(), newCompanyLoader(h.cs, false))
+	responses, err := h.schema.Subscribe(ctx, body.Query, body.OperationName, body.Variables)
+	if err != nil {
+		e := apierrors.ErrInternalServer.WithMessage("failed to subscribe").Wrap(err)
+		logger.Error(e.Error())
+		customMiddlewares.RenderError(w, r, e)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// the pending results are drained once the stream ends, so the subscription is never blocked sending them
+	defer func() {
+		go func() {
+			for range responses {
+			}
+		}()
+	}()
+
+	requestID := customMiddlewares.RequestIDFromContext(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Infof("graphql subscription closed by the client")
+			return
+		case <-h.streamsDone:
+			logger.Infof("graphql subscription closed by the server")
+			fmt.Fprint(w, "event: complete\ndata: \n\n")
+			flusher.Flush()
+			return
+		case resp, ok := <-responses:
+			if !ok {
+				fmt.Fprint(w, "event: complete\ndata: \n\n")
+				flusher.Flush()
+				return
+			}
+
+			response := resp.(*graphqlgo.Response)
+			formatErrors(ctx, logger, response.Errors, requestID)
+			data, err := json.Marshal(response)
+			if err != nil {
+				logger.Errorf("failed to encode graphql response: %v", err)
+				continue
+			}
+			fmt.Fprintf(w, "event: next\ndata: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package graphql
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/conf"
+	"xm_test/internal/db/models"
+	"xm_test/internal/enum"
+	"xm_test/internal/events"
+	"xm_test/internal/service"
+	"xm_test/internal/service/inputs"
+	"xm_test/internal/token"
+
+	customMiddlewares "xm_test/internal/transport/http/middleware"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+// companyService stores the companies in memory and records the batched lookups
+type companyService struct {
+	service.CompanyService
+
+	mu        sync.Mutex
+	companies []*models.CompanyModel
+	batches   [][]string
+	versions  []int // versions expected by the updates and deletions
+}
+
+func (s *companyService) GetCompaniesByIDs(ctx context.Context, ids []string) ([]*models.CompanyModel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, ids)
+
+	var found []*models.CompanyModel
+	for _, company := range s.companies {
+		for _, id := range ids {
+			if company.ID.String() == id {
+				found = append(found, company)
+			}
+		}
+	}
+	return found, nil
+}
+
+func (s *companyService) ListCompanies(ctx context.Context, input *inputs.ListCompaniesInput) (*models.CompanyPage, error) {
+	start := 0
+	for i, company := range s.companies {
+		if company.ID.String() == input.After {
+			start = i + 1
+		}
+	}
+	end := min(start+input.First, len(s.companies))
+	return &models.CompanyPage{Companies: s.companies[start:end], HasNextPage: end < len(s.companies)}, nil
+}
+
+func (s *companyService) CreateCompany(ctx context.Context, input *inputs.CreateCompanyInput) (*models.CompanyModel, error) {
+	company := &models.CompanyModel{ID: uuid.New(), Name: input.Name, AmountEmployees: *input.AmountEmployees, Registered: *input.Registered, Type: input.Type}
+	s.companies = append(s.companies, company)
+	return company, nil
+}
+
+func (s *companyService) UpdateCompany(ctx context.Context, id string, input *inputs.UpdateCompany, version int) (*models.CompanyModel, error) {
+	s.versions = append(s.versions, version)
+	return &models.CompanyModel{ID: uuid.MustParse(id), Name: input.Name, AmountEmployees: *input.AmountEmployees, Registered: *input.Registered, Type: input.Type, Version: version + 1}, nil
+}
+
+func (s *companyService) DeleteCompany(ctx context.Context, id string, version int) error {
+	s.versions = append(s.versions, version)
+	return nil
+}
+
+type response struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+type handlerSuite struct {
+	suite.Suite
+
+	cs     *companyService
+	hub    events.Hub
+	server *httptest.Server
+	done   chan struct{}
+}
+
+func (s *handlerSuite) SetupTest() {
+	conf.NewConfig()
+	conf.GlobalConfig.JwtSecret = "secret"
+
+	logger := zap.NewNop().Sugar()
+	s.cs = &companyService{companies: []*models.CompanyModel{
+		{ID: uuid.MustParse("00000000-0000-0000-0000-000000000001"), Name: "first", Type: enum.Cooperative.String()},
+		{ID: uuid.MustParse("00000000-0000-0000-0000-000000000002"), Name: "second", Type: enum.NonProfit.String()},
+		{ID: uuid.MustParse("00000000-0000-0000-0000-000000000003"), Name: "third", Type: enum.Corporation.String()},
+	}}
+	s.hub = events.NewEventsHub(logger)
+	s.done = make(chan struct{})
+	s.server = s.newServer(false)
+}
+
+// newServer returns a server of the GraphQL handler, which requires the version of the modified companies when
+// requireVersion is set
+func (s *handlerSuite) newServer(requireVersion bool) *httptest.Server {
+	handler := NewHandler(zap.NewNop().Sugar(), s.cs, s.hub, time.Second, 1024, s.done, requireVersion)
+	return httptest.NewServer(customMiddlewares.UserMayBeAuthenticated(handler))
+}
+
+func (s *handlerSuite) TearDownTest() {
+	close(s.done)
+	s.server.Close()
+}
+
+// post sends the query and decodes the response
+func (s *handlerSuite) post(query string, variables map[string]any, authenticated bool) *response {
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	s.Require().NoError(err)
+
+	req, err := http.NewRequest(http.MethodPost, s.server.URL, strings.NewReader(string(body)))
+	s.Require().NoError(err)
+	if authenticated {
+		req.Header.Set("Authorization", "Bearer "+s.token())
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	var decoded response
+	s.Require().NoError(json.NewDecoder(resp.Body).Decode(&decoded))
+	return &decoded
+}
+
+func (s *handlerSuite) token() string {
+	accessToken, _, err := token.GenerateToken(uuid.NewString(), "user@xm.com")
+	s.Require().NoError(err)
+	return accessToken
+}
+
+func (s *handlerSuite) TestBodySize() {
+	body := `{"query": "{ company(id: \"` + strings.Repeat("0", 1024) + `\") { name } }"}`
+	resp, err := http.Post(s.server.URL, "application/json", strings.NewReader(body))
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+	s.Equal(http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+	var decoded apierrors.APIError
+	s.Require().NoError(json.NewDecoder(resp.Body).Decode(&decoded))
+	s.Equal(apierrors.ErrPayloadTooLarge.Code, decoded.Code)
+}
+
+func (s *handlerSuite) TestCompany() {
+	s.Run("batches the lookups", func() {
+		resp := s.post(`{
+			a: company(id: "00000000-0000-0000-0000-000000000001") { name }
+			b: company(id: "00000000-0000-0000-0000-000000000002") { name }
+			c: company(id: "00000000-0000-0000-0000-000000000009") { name }
+		}`, nil, false)
+		s.Empty(resp.Errors)
+		s.JSONEq(`{"name":"first"}`, string(resp.Data["a"]))
+		s.JSONEq(`{"name":"second"}`, string(resp.Data["b"]))
+		s.JSONEq(`null`, string(resp.Data["c"]))
+
+		s.Require().Len(s.cs.batches, 1)
+		s.ElementsMatch([]string{
+			"00000000-0000-0000-0000-000000000001",
+			"00000000-0000-0000-0000-000000000002",
+			"00000000-0000-0000-0000-000000000009",
+		}, s.cs.batches[0])
+	})
+
+	s.Run("invalid id", func() {
+		resp := s.post(`{ company(id: "invalid") { name } }`, nil, false)
+		s.Require().Len(resp.Errors, 1)
+		s.Equal("INVALID_UUID", resp.Errors[0].Extensions["code"])
+	})
+}
+
+func (s *handlerSuite) TestCompanies() {
+	query := `query($after: String) {
+		companies(first: 2, after: $after) {
+			edges { node { name } }
+			pageInfo { endCursor hasNextPage }
+		}
+	}`
+
+	type page struct {
+		Edges []struct {
+			Node struct{ Name string }
+		}
+		PageInfo struct {
+			EndCursor   string
+			HasNextPage bool
+		}
+	}
+	decode := func(resp *response) page {
+		s.Require().Empty(resp.Errors)
+		var p page
+		s.Require().NoError(json.Unmarshal(resp.Data["companies"], &p))
+		return p
+	}
+
+	first := decode(s.post(query, nil, false))
+	s.Len(first.Edges, 2)
+	s.True(first.PageInfo.HasNextPage)
+
+	second := decode(s.post(query, map[string]any{"after": first.PageInfo.EndCursor}, false))
+	s.Require().Len(second.Edges, 1)
+	s.Equal("third", second.Edges[0].Node.Name)
+	s.False(second.PageInfo.HasNextPage)
+
+	s.Run("invalid cursor", func() {
+		resp := s.post(query, map[string]any{"after": "invalid"}, false)
+		s.Require().Len(resp.Errors, 1)
+		s.Equal("INVALID_QUERY", resp.Errors[0].Extensions["code"])
+	})
+}
+
+func (s *handlerSuite) TestCreateCompany() {
+	mutation := `mutation($input: CompanyInput!) { createCompany(input: $input) { id name } }`
+	input := map[string]any{"input": map[string]any{"name": "created", "amountEmployees": 5, "registered": true, "type": "Cooperative"}}
+
+	s.Run("requires a token", func() {
+		resp := s.post(mutation, input, false)
+		s.Require().Len(resp.Errors, 1)
+		s.Equal("TOKEN_NOT_FOUND", resp.Errors[0].Extensions["code"])
+	})
+
+	s.Run("validates the input", func() {
+		invalid := map[string]any{"input": map[string]any{"name": "created", "amountEmployees": 5, "registered": true, "type": "unknown"}}
+		resp := s.post(mutation, invalid, true)
+		s.Require().Len(resp.Errors, 1)
+		s.Equal("INVALID_BODY", resp.Errors[0].Extensions["code"])
+	})
+
+	s.Run("ok", func() {
+		resp := s.post(mutation, input, true)
+		s.Require().Empty(resp.Errors)
+		s.Contains(string(resp.Data["createCompany"]), `"name":"created"`)
+	})
+}
+
+func (s *handlerSuite) TestVersion() {
+	update := `mutation($version: Int) {
+		updateCompany(id: "00000000-0000-0000-0000-000000000001", input: {name: "updated", amountEmployees: 5, registered: true, type: "Cooperative"}, version: $version) { version }
+	}`
+	remove := `mutation($version: Int) { deleteCompany(id: "00000000-0000-0000-0000-000000000001", version: $version) }`
+
+	s.Run("modifies the version", func() {
+		resp := s.post(update, map[string]any{"version": 3}, true)
+		s.Require().Empty(resp.Errors)
+		s.JSONEq(`{"version":4}`, string(resp.Data["updateCompany"]))
+
+		resp = s.post(remove, map[string]any{"version": 4}, true)
+		s.Require().Empty(resp.Errors)
+		s.Equal([]int{3, 4}, s.cs.versions)
+	})
+
+	s.Run("modifies any version", func() {
+		s.cs.versions = nil
+		s.Require().Empty(s.post(update, nil, true).Errors)
+		s.Require().Empty(s.post(remove, nil, true).Errors)
+		s.Equal([]int{0, 0}, s.cs.versions)
+	})
+
+	s.Run("rejects the invalid versions", func() {
+		resp := s.post(remove, map[string]any{"version": 0}, true)
+		s.Require().Len(resp.Errors, 1)
+		s.Equal("PRECONDITION_FAILED", resp.Errors[0].Extensions["code"])
+	})
+
+	s.Run("requires the version", func() {
+		s.server.Close()
+		s.server = s.newServer(true)
+		s.cs.versions = nil
+
+		for _, mutation := range []string{update, remove} {
+			resp := s.post(mutation, nil, true)
+			s.Require().Len(resp.Errors, 1)
+			s.Equal("PRECONDITION_REQUIRED", resp.Errors[0].Extensions["code"])
+		}
+		s.Empty(s.cs.versions)
+
+		s.Require().Empty(s.post(remove, map[string]any{"version": 2}, true).Errors)
+		s.Equal([]int{2}, s.cs.versions)
+	})
+}
+
+func (s *handlerSuite) TestCompanyEvents() {
+	body := `{"query": "subscription { companyEvents(types: [\"update_company\"]) { type company { name } } }"}`
+	req, err := http.NewRequest(http.MethodPost, s.server.URL, strings.NewReader(body))
+	s.Require().NoError(err)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Authorization", "Bearer "+s.token())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+	s.Require().Equal("text/event-stream", resp.Header.Get("Content-Type"))
+
+	// the events are published until the subscription receives one, since it may not be registered yet
+	companyID := s.cs.companies[0].ID
+	go func() {
+		for ctx.Err() == nil {
+			s.hub.Publish(&events.Event{Type: "delete_company", ID: uuid.New(), EntityID: companyID, Timestamp: time.Now(), Payload: []byte(`{}`)})
+			s.hub.Publish(&events.Event{Type: "update_company", ID: uuid.New(), EntityID: companyID, Timestamp: time.Now(), Payload: []byte(`{}`)})
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	s.Require().NoError(err)
+	s.Equal("event: next\n", line)
+
+	line, err = reader.ReadString('\n')
+	s.Require().NoError(err)
+	s.JSONEq(`{"data":{"companyEvents":{"type":"update_company","company":{"name":"first"}}}}`, strings.TrimPrefix(strings.TrimSpace(line), "data: "))
+}
+
+func TestHandlerSuite(t *testing.T) {
+	suite.Run(t, new(handlerSuite))
+}
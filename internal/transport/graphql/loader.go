@@ -0,0 +1,59 @@
+package graphql
+
+import (
+	"context"
+	"time"
+	"xm_test/internal/db/models"
+	"xm_test/internal/service"
+
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+// loaderWait is the time the loader waits to collect the keys of a batch
+const loaderWait = 2 * time.Millisecond
+
+type loaderKey struct{}
+
+// companyLoader batches the company lookups of a request into a single query
+type companyLoader = dataloader.Loader[string, *models.CompanyModel]
+
+// newCompanyLoader returns a loader that resolves the companies with CompanyService.GetCompaniesByIDs. The missing
+// companies are resolved as nil. The results are cached for the lifetime of the loader unless cache is false,
+// which is used by the subscriptions, since they must always see the current state of the companies.
+func newCompanyLoader(cs service.CompanyService, cache bool) *companyLoader {
+	batch := func(ctx context.Context, ids []string) []*dataloader.Result[*models.CompanyModel] {
+		results := make([]*dataloader.Result[*models.CompanyModel], len(ids))
+		companies, err := cs.GetCompaniesByIDs(ctx, ids)
+		if err != nil {
+			for i := range results {
+				results[i] = &dataloader.Result[*models.CompanyModel]{Error: err}
+			}
+			return results
+		}
+
+		byID := make(map[string]*models.CompanyModel, len(companies))
+		for _, company := range companies {
+			byID[company.ID.String()] = company
+		}
+		for i, id := range ids {
+			results[i] = &dataloader.Result[*models.CompanyModel]{Data: byID[id]}
+		}
+		return results
+	}
+
+	opts := []dataloader.Option[string, *models.CompanyModel]{dataloader.WithWait[string, *models.CompanyModel](loaderWait)}
+	if !cache {
+		opts = append(opts, dataloader.WithCache[string, *models.CompanyModel](&dataloader.NoCache[string, *models.CompanyModel]{}))
+	}
+	return dataloader.NewBatchedLoader(batch, opts...)
+}
+
+// withLoader returns a copy of the context that carries the loader
+func withLoader(ctx context.Context, loader *companyLoader) context.Context {
+	return context.WithValue(ctx, loaderKey{}, loader)
+}
+
+// loaderFromContext returns the loader of the context
+func loaderFromContext(ctx context.Context) *companyLoader {
+	return ctx.Value(loaderKey{}).(*companyLoader)
+}
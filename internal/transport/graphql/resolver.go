@@ -0,0 +1,413 @@
+package graphql
+
+import (
+	"context"
+	"encoding/base64"
+	"slices"
+	"strings"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/db/models"
+	"xm_test/internal/events"
+	"xm_test/internal/logging"
+	"xm_test/internal/service"
+	"xm_test/internal/service/inputs"
+	"xm_test/internal/transport/http/binding"
+	"xm_test/internal/transport/http/schemas"
+
+	customMiddlewares "xm_test/internal/transport/http/middleware"
+
+	"github.com/google/uuid"
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	"go.uber.org/zap"
+)
+
+// cursorPrefix is prepended to the company ID before encoding the cursors, so they stay opaque to the clients
+const cursorPrefix = "company:"
+
+// resolver is the root resolver of the queries, mutations and subscriptions
+type resolver struct {
+	logger *zap.SugaredLogger
+	cs     service.CompanyService
+	hub    events.Hub
+
+	// closed on shutdown to end the subscriptions
+	streamsDone <-chan struct{}
+
+	// rejects the updates and deletions without the version of the company
+	requireVersion bool
+}
+
+type companyFilterInput struct {
+	Name       *string
+	Type       *string
+	Registered *bool
+}
+
+type companyInput struct {
+	Name            string
+	Description     *string
+	AmountEmployees int32
+	Registered      bool
+	Type            string
+}
+
+// Company retrieves a company by its ID
+func (r *resolver) Company(ctx context.Context, args struct{ ID graphqlgo.ID }) (*companyResolver, error) {
+	logger := logging.FromContext(ctx, r.logger)
+	logger.Infof("company query called")
+
+	id := string(args.ID)
+	if err := uuid.Validate(id); err != nil {
+		return nil, apierrors.ErrInvalidUUID
+	}
+	return loadCompany(ctx, id)
+}
+
+// Companies lists the companies matching the filter
+func (r *resolver) Companies(ctx context.Context, args struct {
+	Filter *companyFilterInput
+	First  int32
+	After  *string
+}) (*companyConnectionResolver, error) {
+	logger := logging.FromContext(ctx, r.logger)
+	logger.Infof("companies query called")
+
+	input := &inputs.ListCompaniesInput{First: int(args.First)}
+	if args.Filter != nil {
+		input.Name = deref(args.Filter.Name)
+		input.Type = deref(args.Filter.Type)
+		input.Registered = args.Filter.Registered
+	}
+	if args.After != nil {
+		after, err := decodeCursor(*args.After)
+		if err != nil {
+			return nil, err
+		}
+		input.After = after
+	}
+
+	page, err := r.cs.ListCompanies(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	// prime the loader, so the companies of the page are not queried again within the request
+	loader := loaderFromContext(ctx)
+	for _, company := range page.Companies {
+		loader.Prime(ctx, company.ID.String(), company)
+	}
+	return &companyConnectionResolver{page: page}, nil
+}
+
+// CreateCompany creates a new company
+func (r *resolver) CreateCompany(ctx context.Context, args struct{ Input companyInput }) (*companyResolver, error) {
+	logger := logging.FromContext(ctx, r.logger)
+	logger.Infof("create company mutation called")
+
+	if err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	body, err := validateCompanyInput(args.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("creating company with name '%s'", body.Name)
+	companyModel, err := r.cs.CreateCompany(ctx, &inputs.CreateCompanyInput{
+		Name:            body.Name,
+		Description:     body.Description,
+		AmountEmployees: body.AmountEmployees,
+		Registered:      body.Registered,
+		Type:            body.Type,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("company with name '%s' created", body.Name)
+	return &companyResolver{company: companyModel}, nil
+}
+
+// UpdateCompany updates a company
+func (r *resolver) UpdateCompany(ctx context.Context, args struct {
+	ID      graphqlgo.ID
+	Input   companyInput
+	Version *int32
+}) (*companyResolver, error) {
+	logger := logging.FromContext(ctx, r.logger)
+	logger.Infof("update company mutation called")
+
+	if err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	companyID := string(args.ID)
+	if err := uuid.Validate(companyID); err != nil {
+		return nil, apierrors.ErrInvalidUUID
+	}
+	version, err := binding.ExpectedVersion(companyID, args.Version, r.requireVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := validateCompanyInput(args.Input)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("updating company with id '%s'", companyID)
+	input := &inputs.UpdateCompany{
+		Name:            body.Name,
+		Description:     body.Description,
+		AmountEmployees: body.AmountEmployees,
+		Registered:      body.Registered,
+		Type:            body.Type,
+	}
+	companyModel, err := r.cs.UpdateCompany(ctx, companyID, input, version)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("company with id '%s' updated", companyID)
+	return &companyResolver{company: companyModel}, nil
+}
+
+// DeleteCompany deletes a company
+func (r *resolver) DeleteCompany(ctx context.Context, args struct {
+	ID      graphqlgo.ID
+	Version *int32
+}) (graphqlgo.ID, error) {
+	logger := logging.FromContext(ctx, r.logger)
+	logger.Infof("delete company mutation called")
+
+	if err := requireUser(ctx); err != nil {
+		return "", err
+	}
+
+	companyID := string(args.ID)
+	if err := uuid.Validate(companyID); err != nil {
+		return "", apierrors.ErrInvalidUUID
+	}
+	version, err := binding.ExpectedVersion(companyID, args.Version, r.requireVersion)
+	if err != nil {
+		return "", err
+	}
+
+	logger.Debugf("deleting company with id '%s'", companyID)
+	if err := r.cs.DeleteCompany(ctx, companyID, version); err != nil {
+		return "", err
+	}
+
+	logger.Infof("company with id '%s' deleted", companyID)
+	return args.ID, nil
+}
+
+// CompanyEvents streams the company events dispatched by any replica until the client disconnects or the server
+// shuts down
+func (r *resolver) CompanyEvents(ctx context.Context, args struct{ Types *[]string }) (<-chan *eventResolver, error) {
+	logger := logging.FromContext(ctx, r.logger)
+	logger.Infof("company events subscription called")
+
+	if err := requireUser(ctx); err != nil {
+		return nil, err
+	}
+
+	evts, unsubscribe := r.hub.Subscribe()
+	out := make(chan *eventResolver)
+	go func() {
+		defer close(out)
+		defer unsubscribe()
+
+		for {
+			select {
+			case <-ctx.Done():
+				logger.Infof("company events subscription closed by the client")
+				return
+			case <-r.streamsDone:
+				logger.Infof("company events subscription closed by the server")
+				return
+			case evt, ok := <-evts:
+				if !ok {
+					return
+				}
+				if args.Types != nil && !slices.Contains(*args.Types, evt.Type) {
+					continue
+				}
+
+				select {
+				case out <- &eventResolver{event: evt}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+// requireUser returns an error unless the request carries a valid access token
+func requireUser(ctx context.Context) error {
+	if _, ok := customMiddlewares.ClaimsFromContext(ctx); !ok {
+		return apierrors.ErrTokenNotFound.WithMessageID("required", nil)
+	}
+	return nil
+}
+
+// validateCompanyInput validates the input with the same rules as the HTTP bodies
+func validateCompanyInput(input companyInput) (*schemas.CreateCompanyRequest, error) {
+	amountEmployees := int(input.AmountEmployees)
+	body := &schemas.CreateCompanyRequest{
+		Name:            input.Name,
+		Description:     deref(input.Description),
+		AmountEmployees: &amountEmployees,
+		Registered:      &input.Registered,
+		Type:            input.Type,
+	}
+	if err := binding.Validate(body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// loadCompany loads the company with the loader of the context. It returns nil if the company does not exist.
+func loadCompany(ctx context.Context, id string) (*companyResolver, error) {
+	company, err := loaderFromContext(ctx).Load(ctx, id)()
+	if err != nil {
+		return nil, err
+	}
+	if company == nil {
+		return nil, nil
+	}
+	return &companyResolver{company: company}, nil
+}
+
+// encodeCursor returns the opaque cursor pointing to the company
+func encodeCursor(id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + id.String()))
+}
+
+// decodeCursor returns the company ID of the cursor
+func decodeCursor(cursor string) (string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(data), cursorPrefix) {
+		return "", apierrors.ErrInvalidQuery.WithMessageID("cursor", nil)
+	}
+	return strings.TrimPrefix(string(data), cursorPrefix), nil
+}
+
+func deref[T any](v *T) T {
+	if v == nil {
+		var zero T
+		return zero
+	}
+	return *v
+}
+
+type companyResolver struct {
+	company *models.CompanyModel
+}
+
+func (r *companyResolver) ID() graphqlgo.ID {
+	return graphqlgo.ID(r.company.ID.String())
+}
+
+func (r *companyResolver) Name() string {
+	return r.company.Name
+}
+
+func (r *companyResolver) Description() string {
+	return r.company.Description
+}
+
+func (r *companyResolver) AmountEmployees() int32 {
+	return int32(r.company.AmountEmployees)
+}
+
+func (r *companyResolver) Registered() bool {
+	return r.company.Registered
+}
+
+func (r *companyResolver) Type() string {
+	return r.company.Type
+}
+
+func (r *companyResolver) Version() int32 {
+	return int32(r.company.Version)
+}
+
+type companyConnectionResolver struct {
+	page *models.CompanyPage
+}
+
+func (r *companyConnectionResolver) Edges() []*companyEdgeResolver {
+	edges := make([]*companyEdgeResolver, len(r.page.Companies))
+	for i, company := range r.page.Companies {
+		edges[i] = &companyEdgeResolver{company: company}
+	}
+	return edges
+}
+
+func (r *companyConnectionResolver) PageInfo() *pageInfoResolver {
+	return &pageInfoResolver{page: r.page}
+}
+
+type companyEdgeResolver struct {
+	company *models.CompanyModel
+}
+
+func (r *companyEdgeResolver) Cursor() string {
+	return encodeCursor(r.company.ID)
+}
+
+func (r *companyEdgeResolver) Node() *companyResolver {
+	return &companyResolver{company: r.company}
+}
+
+type pageInfoResolver struct {
+	page *models.CompanyPage
+}
+
+func (r *pageInfoResolver) EndCursor() *string {
+	if len(r.page.Companies) == 0 {
+		return nil
+	}
+	cursor := encodeCursor(r.page.Companies[len(r.page.Companies)-1].ID)
+	return &cursor
+}
+
+func (r *pageInfoResolver) HasNextPage() bool {
+	return r.page.HasNextPage
+}
+
+type eventResolver struct {
+	event *events.Event
+}
+
+func (r *eventResolver) ID() graphqlgo.ID {
+	return graphqlgo.ID(r.event.ID.String())
+}
+
+func (r *eventResolver) Type() string {
+	return r.event.Type
+}
+
+func (r *eventResolver) Version() int32 {
+	return int32(r.event.Version)
+}
+
+func (r *eventResolver) Timestamp() graphqlgo.Time {
+	return graphqlgo.Time{Time: r.event.Timestamp}
+}
+
+func (r *eventResolver) EntityID() graphqlgo.ID {
+	return graphqlgo.ID(r.event.EntityID.String())
+}
+
+func (r *eventResolver) Payload() jsonScalar {
+	return jsonScalar(r.event.Payload)
+}
+
+func (r *eventResolver) Company(ctx context.Context) (*companyResolver, error) {
+	return loadCompany(ctx, r.event.EntityID.String())
+}
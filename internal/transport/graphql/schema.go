@@ -0,0 +1,41 @@
+package graphql
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+)
+
+// maxDepth is the maximum nesting of the queries, which bounds the work of a single request
+const maxDepth = 10
+
+//go:embed schema.graphql
+var schemaString string
+
+// newSchema parses the schema and binds it to the resolver. The schema is embedded, so it panics if the schema
+// and the resolvers diverge.
+func newSchema(resolver *resolver) *graphqlgo.Schema {
+	return graphqlgo.MustParseSchema(schemaString, resolver, graphqlgo.MaxDepth(maxDepth))
+}
+
+// jsonScalar is the JSON scalar, used to return the payload of the events as is
+type jsonScalar json.RawMessage
+
+func (jsonScalar) ImplementsGraphQLType(name string) bool {
+	return name == "JSON"
+}
+
+func (j *jsonScalar) UnmarshalGraphQL(input interface{}) error {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("invalid JSON value: %w", err)
+	}
+	*j = data
+	return nil
+}
+
+func (j jsonScalar) MarshalJSON() ([]byte, error) {
+	return json.RawMessage(j).MarshalJSON()
+}
@@ -0,0 +1,57 @@
+package grpc
+
+import (
+	"context"
+	"xm_test/internal/logging"
+	"xm_test/internal/service"
+	xmv1 "xm_test/internal/transport/grpc/pb/xm/v1"
+	"xm_test/internal/transport/http/binding"
+	"xm_test/internal/transport/http/schemas"
+
+	"go.uber.org/zap"
+)
+
+// authServer implements the AuthService on top of the auth service shared with the HTTP transport
+type authServer struct {
+	xmv1.UnimplementedAuthServiceServer
+
+	logger *zap.SugaredLogger
+	as     service.AuthService
+}
+
+// Register registers a new user
+func (s *authServer) Register(ctx context.Context, req *xmv1.RegisterRequest) (*xmv1.RegisterResponse, error) {
+	logger := logging.FromContext(ctx, s.logger)
+	logger.Infof("register method called")
+
+	body := schemas.RegisterRequest{Email: req.GetEmail(), Password: req.GetPassword()}
+	if err := binding.Validate(&body); err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("creating account for user with email '%s'", body.Email)
+	if err := s.as.Register(ctx, body.Email, body.Password); err != nil {
+		return nil, err
+	}
+	logger.Infof("user with email '%s' registered", body.Email)
+	return &xmv1.RegisterResponse{Message: "user registered"}, nil
+}
+
+// Login logs in a user
+func (s *authServer) Login(ctx context.Context, req *xmv1.LoginRequest) (*xmv1.LoginResponse, error) {
+	logger := logging.FromContext(ctx, s.logger)
+	logger.Infof("login method called")
+
+	body := schemas.LoginRequest{Email: req.GetEmail(), Password: req.GetPassword()}
+	if err := binding.Validate(&body); err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("logging in user with email '%s'", body.Email)
+	token, err := s.as.Login(ctx, body.Email, body.Password)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("user with email '%s' logged in", body.Email)
+	return &xmv1.LoginResponse{AccessToken: *token}, nil
+}
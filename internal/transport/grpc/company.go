@@ -0,0 +1,175 @@
+package grpc
+
+import (
+	"context"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/db/models"
+	"xm_test/internal/logging"
+	"xm_test/internal/service"
+	"xm_test/internal/service/inputs"
+	xmv1 "xm_test/internal/transport/grpc/pb/xm/v1"
+	"xm_test/internal/transport/http/binding"
+	"xm_test/internal/transport/http/schemas"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// companyServer implements the CompanyService on top of the company service shared with the HTTP transport
+type companyServer struct {
+	xmv1.UnimplementedCompanyServiceServer
+
+	logger *zap.SugaredLogger
+	cs     service.CompanyService
+
+	// rejects the updates and deletions without the version of the company
+	requireVersion bool
+}
+
+// CreateCompany creates a new company
+func (s *companyServer) CreateCompany(ctx context.Context, req *xmv1.CreateCompanyRequest) (*xmv1.CreateCompanyResponse, error) {
+	logger := logging.FromContext(ctx, s.logger)
+	logger.Infof("create company method called")
+
+	body := schemas.CreateCompanyRequest{
+		Name:            req.GetName(),
+		Description:     req.GetDescription(),
+		AmountEmployees: optionalInt(req.AmountEmployees),
+		Registered:      req.Registered,
+		Type:            req.GetType(),
+	}
+	if err := binding.Validate(&body); err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("creating company with name '%s'", body.Name)
+	companyModel, err := s.cs.CreateCompany(ctx, &inputs.CreateCompanyInput{
+		Name:            body.Name,
+		Description:     body.Description,
+		AmountEmployees: body.AmountEmployees,
+		Registered:      body.Registered,
+		Type:            body.Type,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("company with name '%s' created", body.Name)
+	return &xmv1.CreateCompanyResponse{Company: toCompany(companyModel)}, nil
+}
+
+// GetCompany retrieves a company by its ID
+func (s *companyServer) GetCompany(ctx context.Context, req *xmv1.GetCompanyRequest) (*xmv1.GetCompanyResponse, error) {
+	logger := logging.FromContext(ctx, s.logger)
+	logger.Infof("get company method called")
+
+	if err := validateCompanyID(req.GetId()); err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("retrieving company with id '%s'", req.GetId())
+	company, err := s.cs.GetCompanyByID(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("company with id '%s' retrieved", req.GetId())
+	return &xmv1.GetCompanyResponse{Company: toCompany(company)}, nil
+}
+
+// UpdateCompany updates a company
+func (s *companyServer) UpdateCompany(ctx context.Context, req *xmv1.UpdateCompanyRequest) (*xmv1.UpdateCompanyResponse, error) {
+	logger := logging.FromContext(ctx, s.logger)
+	logger.Infof("update company method called")
+
+	companyID := req.GetId()
+	if err := validateCompanyID(companyID); err != nil {
+		return nil, err
+	}
+	version, err := binding.ExpectedVersion(companyID, req.Version, s.requireVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	body := schemas.UpdateCompanyRequest{
+		Name:            req.GetName(),
+		Description:     req.GetDescription(),
+		AmountEmployees: optionalInt(req.AmountEmployees),
+		Registered:      req.Registered,
+		Type:            req.GetType(),
+	}
+	if err := binding.Validate(&body); err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("updating company with id '%s'", companyID)
+	input := &inputs.UpdateCompany{
+		Name:            body.Name,
+		Description:     body.Description,
+		AmountEmployees: body.AmountEmployees,
+		Registered:      body.Registered,
+		Type:            body.Type,
+	}
+	company, err := s.cs.UpdateCompany(ctx, companyID, input, version)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Infof("company with id '%s' updated", companyID)
+	return &xmv1.UpdateCompanyResponse{Message: "company updated", Version: int32(company.Version)}, nil
+}
+
+// DeleteCompany deletes a company
+func (s *companyServer) DeleteCompany(ctx context.Context, req *xmv1.DeleteCompanyRequest) (*xmv1.DeleteCompanyResponse, error) {
+	logger := logging.FromContext(ctx, s.logger)
+	logger.Infof("delete company method called")
+
+	companyID := req.GetId()
+	if err := validateCompanyID(companyID); err != nil {
+		return nil, err
+	}
+	version, err := binding.ExpectedVersion(companyID, req.Version, s.requireVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("deleting company with id '%s'", companyID)
+	if err := s.cs.DeleteCompany(ctx, companyID, version); err != nil {
+		return nil, err
+	}
+
+	logger.Infof("company with id '%s' deleted", companyID)
+	return &xmv1.DeleteCompanyResponse{Message: "company deleted"}, nil
+}
+
+// validateCompanyID checks that the company ID is set and is a valid uuid
+func validateCompanyID(id string) error {
+	if id == "" {
+		return apierrors.ErrCompanyIDRequired
+	}
+	if err := uuid.Validate(id); err != nil {
+		return apierrors.ErrInvalidUUID
+	}
+	return nil
+}
+
+// optionalInt converts an optional proto integer into the pointer used by the request schemas
+func optionalInt(v *int32) *int {
+	if v == nil {
+		return nil
+	}
+	i := int(*v)
+	return &i
+}
+
+// toCompany converts the company model into its proto message
+func toCompany(company *models.CompanyModel) *xmv1.Company {
+	return &xmv1.Company{
+		Id:              company.ID.String(),
+		Name:            company.Name,
+		Description:     company.Description,
+		AmountEmployees: int32(company.AmountEmployees),
+		Registered:      company.Registered,
+		Type:            company.Type,
+		Version:         int32(company.Version),
+	}
+}
@@ -0,0 +1,78 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	apierrors "xm_test/internal/api_errors"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain is the domain of the ErrorInfo details attached to the errors
+const errorDomain = "xm_test"
+
+// statusCodes maps the HTTP status of the API errors to the gRPC status codes
+var statusCodes = map[int]codes.Code{
+	http.StatusBadRequest:               codes.InvalidArgument,
+	http.StatusUnauthorized:             codes.Unauthenticated,
+	http.StatusForbidden:                codes.PermissionDenied,
+	http.StatusNotFound:                 codes.NotFound,
+	http.StatusConflict:                 codes.Aborted,
+	http.StatusPreconditionFailed:       codes.FailedPrecondition,
+	http.StatusPreconditionRequired:     codes.FailedPrecondition,
+	http.StatusTooManyRequests:          codes.ResourceExhausted,
+	apierrors.StatusClientClosedRequest: codes.Canceled,
+	http.StatusNotImplemented:           codes.Unimplemented,
+	http.StatusServiceUnavailable:       codes.Unavailable,
+	http.StatusGatewayTimeout:           codes.DeadlineExceeded,
+	http.StatusInternalServerError:      codes.Internal,
+	http.StatusUnsupportedMediaType:     codes.InvalidArgument,
+}
+
+// errorCodes maps the API errors whose gRPC status code does not follow from their HTTP status, keyed by code. The
+// conflicts are aborted, unless the name of the entity is taken.
+var errorCodes = map[string]codes.Code{
+	apierrors.ErrCompanyAlreadyExists.Code: codes.AlreadyExists,
+	apierrors.ErrUserAlreadyExists.Code:    codes.AlreadyExists,
+}
+
+// toStatus converts the error returned by the services into a gRPC status. The code of the API error is attached
+// as an ErrorInfo detail, so that clients can identify the error as they do with the HTTP transport.
+func toStatus(ctx context.Context, err error, requestID string) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	// the request context explains the failure better than the error returned by the lower layers
+	if ctxErr := apierrors.FromContext(ctx); ctxErr != nil {
+		err = ctxErr
+	}
+
+	var apiError *apierrors.APIError
+	if !errors.As(err, &apiError) {
+		apiError = apierrors.ErrInternalServer.Wrap(err)
+	}
+
+	code, ok := errorCodes[apiError.Code]
+	if !ok {
+		if code, ok = statusCodes[apiError.HTTPStatus]; !ok {
+			code = codes.Unknown
+		}
+	}
+
+	st := status.New(code, apiError.Message)
+	info := &errdetails.ErrorInfo{Reason: apiError.Code, Domain: errorDomain}
+	if requestID != "" {
+		info.Metadata = map[string]string{"request_id": requestID}
+	}
+	if detailed, err := st.WithDetails(info); err == nil {
+		st = detailed
+	}
+	return st.Err()
+}
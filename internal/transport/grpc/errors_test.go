@@ -0,0 +1,79 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	apierrors "xm_test/internal/api_errors"
+
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type errorsSuite struct {
+	suite.Suite
+}
+
+func (s *errorsSuite) TestToStatus() {
+	s.Run("maps the API errors", func() {
+		tests := []struct {
+			err  error
+			code codes.Code
+		}{
+			{apierrors.ErrInvalidBody, codes.InvalidArgument},
+			{apierrors.ErrInvalidToken, codes.Unauthenticated},
+			{apierrors.ErrForbidden, codes.PermissionDenied},
+			{apierrors.ErrDeadLetterNotFound, codes.NotFound},
+			{apierrors.ErrPatchConflict, codes.Aborted},
+			{apierrors.ErrIdempotencyKeyReused, codes.Aborted},
+			{apierrors.ErrCompanyAlreadyExists, codes.AlreadyExists},
+			{apierrors.ErrUserAlreadyExists, codes.AlreadyExists},
+			{apierrors.ErrRequestTimeout, codes.DeadlineExceeded},
+			{apierrors.ErrInternalServer, codes.Internal},
+			{apierrors.NewAPIError("TEAPOT", "teapot", http.StatusTeapot), codes.Unknown},
+		}
+
+		for _, tt := range tests {
+			st := status.Convert(toStatus(context.Background(), tt.err, "request-id"))
+			s.Equal(tt.code, st.Code(), tt.err.Error())
+
+			apiError := tt.err.(*apierrors.APIError)
+			s.Equal(apiError.Message, st.Message())
+			s.Require().Len(st.Details(), 1)
+			info := st.Details()[0].(*errdetails.ErrorInfo)
+			s.Equal(apiError.Code, info.Reason)
+			s.Equal(errorDomain, info.Domain)
+			s.Equal("request-id", info.Metadata["request_id"])
+		}
+	})
+
+	s.Run("hides unknown errors behind the internal code", func() {
+		st := status.Convert(toStatus(context.Background(), errors.New("boom"), ""))
+		s.Equal(codes.Internal, st.Code())
+		s.Equal(apierrors.ErrInternalServer.Message, st.Message())
+		s.Equal(apierrors.ErrInternalServer.Code, st.Details()[0].(*errdetails.ErrorInfo).Reason)
+	})
+
+	s.Run("reports the cancellation of the call", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		st := status.Convert(toStatus(ctx, errors.New("context canceled"), ""))
+		s.Equal(codes.Canceled, st.Code())
+	})
+
+	s.Run("keeps the status errors", func() {
+		err := status.Error(codes.Unavailable, "unavailable")
+		s.Equal(err, toStatus(context.Background(), err, ""))
+	})
+
+	s.Run("nil", func() {
+		s.NoError(toStatus(context.Background(), nil, ""))
+	})
+}
+
+func TestErrorsSuite(t *testing.T) {
+	suite.Run(t, new(errorsSuite))
+}
@@ -0,0 +1,84 @@
+package grpc
+
+import (
+	"encoding/json"
+	"xm_test/internal/events"
+	"xm_test/internal/logging"
+	xmv1 "xm_test/internal/transport/grpc/pb/xm/v1"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// eventsServer implements the EventsService on top of the local events hub
+type eventsServer struct {
+	xmv1.UnimplementedEventsServiceServer
+
+	logger *zap.SugaredLogger
+	hub    events.Hub
+
+	// closed on shutdown to end the long-lived streams
+	streamsDone <-chan struct{}
+}
+
+// StreamEvents streams the events dispatched by any replica to the client
+func (s *eventsServer) StreamEvents(_ *xmv1.StreamEventsRequest, stream xmv1.EventsService_StreamEventsServer) error {
+	logger := logging.FromContext(stream.Context(), s.logger)
+	logger.Infof("stream events method called")
+
+	evts, unsubscribe := s.hub.Subscribe()
+	defer unsubscribe()
+
+	// the header is sent right away, so the client knows the stream is set up before the first event
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			logger.Infof("events stream closed by the client")
+			return nil
+		case <-s.streamsDone:
+			logger.Infof("events stream closed by the server")
+			return nil
+		case evt, ok := <-evts:
+			if !ok {
+				return nil
+			}
+			msg, err := toEvent(evt)
+			if err != nil {
+				logger.Errorf("failed to encode event '%s': %v", evt.ID, err)
+				continue
+			}
+			if err := stream.Send(&xmv1.StreamEventsResponse{Event: msg}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// toEvent converts the event into its proto message
+func toEvent(evt *events.Event) (*xmv1.Event, error) {
+	var payload map[string]any
+	if err := json.Unmarshal(evt.Payload, &payload); err != nil {
+		return nil, err
+	}
+	pbPayload, err := structpb.NewStruct(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &xmv1.Event{
+		Id:          evt.ID.String(),
+		Type:        evt.Type,
+		Version:     int32(evt.Version),
+		Timestamp:   timestamppb.New(evt.Timestamp),
+		EntityId:    evt.EntityID.String(),
+		Payload:     pbPayload,
+		Traceparent: evt.TraceParent,
+		Tracestate:  evt.TraceState,
+	}, nil
+}
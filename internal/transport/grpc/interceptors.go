@@ -0,0 +1,218 @@
+package grpc
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/conf"
+	"xm_test/internal/i18n"
+	"xm_test/internal/logging"
+	"xm_test/internal/requestid"
+	"xm_test/internal/token"
+	"xm_test/internal/tracing"
+	xmv1 "xm_test/internal/transport/grpc/pb/xm/v1"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// requestIDHeader is the metadata key used to receive and return the ID of the call
+const requestIDHeader = "x-request-id"
+
+// bearerScheme is the authentication scheme that the authorization metadata must use, compared case-insensitively
+const bearerScheme = "Bearer"
+
+type contextKey string
+
+const (
+	claimsKey contextKey = "claims"
+	callKey   contextKey = "call"
+)
+
+// publicMethods are the methods of the API services that can be called without an access token
+var publicMethods = map[string]bool{
+	xmv1.AuthService_Register_FullMethodName:      true,
+	xmv1.AuthService_Login_FullMethodName:         true,
+	xmv1.CompanyService_GetCompany_FullMethodName: true,
+}
+
+// callInfo holds the data of the call that is only known by the inner layers, such as the authenticated user
+type callInfo struct {
+	requestID string
+	userID    string
+}
+
+// serverStream overrides the context of a server stream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// unaryInterceptor observes the unary calls, applies the request deadline and authenticates the caller
+func (t *grpcTransport) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, finish := t.startCall(ctx, info.FullMethod, func(md metadata.MD) error { return grpc.SetHeader(ctx, md) })
+
+	if timeout := conf.GlobalConfig.RequestTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	ctx, err := authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, finish(ctx, err)
+	}
+
+	resp, err := handler(ctx, req)
+	return resp, finish(ctx, err)
+}
+
+// streamInterceptor observes the streaming calls and authenticates the caller. The request deadline is not applied
+// to the streams, since they are long-lived.
+func (t *grpcTransport) streamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, finish := t.startCall(ss.Context(), info.FullMethod, ss.SetHeader)
+
+	ctx, err := authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return finish(ctx, err)
+	}
+
+	return finish(ctx, handler(srv, &serverStream{ServerStream: ss, ctx: ctx}))
+}
+
+// startCall assigns an ID to the call, honouring the x-request-id metadata sent by the client, starts its span and
+// stores a logger with the request and trace IDs in the context. The returned function converts the error of the
+// call into a gRPC status, ends the span and writes the access log.
+func (t *grpcTransport) startCall(ctx context.Context, method string, setHeader func(metadata.MD) error) (context.Context, func(context.Context, error) error) {
+	start := time.Now()
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	requestID := firstValue(md, requestIDHeader)
+	if !requestid.IsValid(requestID) {
+		requestID = uuid.NewString()
+	}
+	if err := setHeader(metadata.Pairs(requestIDHeader, requestID)); err != nil {
+		t.logger.Warnf("failed to set the request ID header: %s", err)
+	}
+
+	service, rpc := splitMethod(method)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+	ctx, span := tracing.Start(ctx, strings.TrimPrefix(method, "/"),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(semconv.RPCSystemGRPC, semconv.RPCService(service), semconv.RPCMethod(rpc)),
+	)
+
+	fields := []any{"request_id", requestID}
+	if span.SpanContext().IsValid() {
+		fields = append(fields, "trace_id", span.SpanContext().TraceID().String())
+	}
+	call := &callInfo{requestID: requestID}
+	ctx = context.WithValue(ctx, callKey, call)
+	ctx = logging.WithLogger(ctx, t.logger.With(fields...))
+
+	return ctx, func(ctx context.Context, err error) error {
+		// the status only carries the message sent to the client, so the original error is logged instead
+		cause := err
+		err = toStatus(ctx, err, requestID)
+		code := status.Code(err)
+
+		span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+
+		fields := []any{"method", method, "code", code.String(), "latency", time.Since(start), "request_id", requestID}
+		if call.userID != "" {
+			fields = append(fields, "user_id", call.userID)
+		}
+		if err != nil {
+			t.logger.Warnw("call completed", append(fields, "error", cause.Error())...)
+		} else {
+			t.logger.Infow("call completed", fields...)
+		}
+		return err
+	}
+}
+
+// authenticate validates the access token sent in the authorization metadata, unless the method is public, and
+// stores its claims in the context
+func authenticate(ctx context.Context, method string) (context.Context, error) {
+	if !strings.HasPrefix(method, "/"+string(xmv1.File_xm_v1_company_proto.Package())+".") || publicMethods[method] {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	authHeader := firstValue(md, "authorization")
+	if authHeader == "" {
+		// the call is rejected as unauthenticated, as gRPC clients expect, while keeping the code of the API error
+		e := apierrors.ErrTokenNotFound.WithMessageID("metadata", nil)
+		e.HTTPStatus = http.StatusUnauthorized
+		return ctx, e
+	}
+
+	scheme, tokenStr, ok := strings.Cut(authHeader, " ")
+	if !ok || !strings.EqualFold(scheme, bearerScheme) {
+		return ctx, apierrors.ErrInvalidToken.WithMessageID("scheme", nil)
+	}
+
+	claims, err := token.ValidateAndParseToken(tokenStr)
+	if err != nil {
+		return ctx, apierrors.ErrInvalidToken.WithMessageID("reason", i18n.Params{"reason": err})
+	}
+
+	if call, ok := ctx.Value(callKey).(*callInfo); ok {
+		call.userID = claims.ID
+	}
+	ctx = logging.With(ctx, "user_id", claims.ID)
+	return context.WithValue(ctx, claimsKey, claims), nil
+}
+
+// splitMethod splits the full method name (/package.Service/Method) into the service and the method
+func splitMethod(fullMethod string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !ok {
+		return "", service
+	}
+	return service, method
+}
+
+// firstValue returns the first value of the metadata key, or an empty string if it is not set
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// metadataCarrier adapts the gRPC metadata to the OpenTelemetry propagators
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	return firstValue(metadata.MD(c), key)
+}
+
+func (c metadataCarrier) Set(key string, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,170 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+	"xm_test/internal/conf"
+	"xm_test/internal/db"
+	"xm_test/internal/events"
+	"xm_test/internal/health"
+	"xm_test/internal/service"
+	xmv1 "xm_test/internal/transport/grpc/pb/xm/v1"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+// healthCheckInterval is the interval between the dependency checks that update the status of the health service
+const healthCheckInterval = 5 * time.Second
+
+type grpcTransport struct {
+	logger  *zap.SugaredLogger
+	monitor health.Monitor
+
+	server       *grpc.Server
+	healthServer *grpchealth.Server
+
+	// event dispatcher of the company service, whose pending dispatches are awaited on shutdown
+	evtDispatcher events.Dispatcher
+	cs            service.CompanyService
+
+	// closed on shutdown to end the long-lived streams and the health checks
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewGrpcTransport returns a new gRPC transport instance. The company, auth and events services share the services
+// and the events dispatcher with the HTTP transport, and the server also exposes the standard health and reflection
+// services.
+func NewGrpcTransport(logger *zap.SugaredLogger, db db.DatabaseAdapter, hub events.Hub, registry events.Registry, monitor health.Monitor) *grpcTransport {
+	t := &grpcTransport{
+		logger:       logger,
+		monitor:      monitor,
+		healthServer: grpchealth.NewServer(),
+		done:         make(chan struct{}),
+	}
+	t.evtDispatcher = events.NewEventsDispatcher(logger, db, registry)
+	t.cs = service.NewCompanyService(logger, db, registry, t.evtDispatcher)
+
+	t.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(t.unaryInterceptor),
+		grpc.ChainStreamInterceptor(t.streamInterceptor),
+	)
+
+	xmv1.RegisterAuthServiceServer(t.server, &authServer{
+		logger: logger,
+		as:     service.NewAuthService(logger, db),
+	})
+	xmv1.RegisterCompanyServiceServer(t.server, &companyServer{
+		logger:         logger,
+		cs:             t.cs,
+		requireVersion: conf.GlobalConfig.RequireIfMatch,
+	})
+	xmv1.RegisterEventsServiceServer(t.server, &eventsServer{
+		logger:      logger,
+		hub:         hub,
+		streamsDone: t.done,
+	})
+	healthpb.RegisterHealthServer(t.server, t.healthServer)
+	reflection.Register(t.server)
+
+	return t
+}
+
+// Serve starts the gRPC server. It listens on the gRPC port specified in the configuration.
+func (t *grpcTransport) Serve() error {
+	t.logger.Debugf("setting up grpc server")
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", conf.GlobalConfig.GrpcPort))
+	if err != nil {
+		return fmt.Errorf("failed to listen on grpc port: %w", err)
+	}
+
+	t.logger.Infof("grpc server listening on port %s", lis.Addr())
+	return t.serve(lis)
+}
+
+// serve accepts the connections of the listener until the server is stopped
+func (t *grpcTransport) serve(lis net.Listener) error {
+	if err := t.server.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// HealthCheck keeps the status of the gRPC health service up to date by running the dependency checks periodically.
+// The server reports itself as not serving while any dependency is down.
+func (t *grpcTransport) HealthCheck() error {
+	t.logger.Debugf("setting up grpc health checks")
+
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		t.updateHealth()
+
+		select {
+		case <-t.done:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// updateHealth runs the dependency checks and sets the serving status of the server
+func (t *grpcTransport) updateHealth() {
+	report := t.monitor.Check(context.Background())
+
+	status := healthpb.HealthCheckResponse_SERVING
+	if report.Status != health.StatusUp {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	t.logger.Debugf("grpc health status: %s", status)
+
+	// the empty service name reports the status of the whole server
+	t.healthServer.SetServingStatus("", status)
+	for _, name := range []string{xmv1.AuthService_ServiceDesc.ServiceName, xmv1.CompanyService_ServiceDesc.ServiceName, xmv1.EventsService_ServiceDesc.ServiceName} {
+		t.healthServer.SetServingStatus(name, status)
+	}
+}
+
+// Close gracefully shuts down the gRPC transport. It reports the server as not serving, ends the long-lived
+// streams, drains the in-flight calls and waits for the pending event dispatches. The in-flight calls are
+// cancelled if they are not completed before the context is done.
+func (t *grpcTransport) Close(ctx context.Context) error {
+	t.logger.Infof("shutting down grpc server")
+	t.healthServer.Shutdown()
+	t.closeOnce.Do(func() {
+		close(t.done)
+	})
+
+	var errs []error
+	stopped := make(chan struct{})
+	go func() {
+		t.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		t.server.Stop()
+		errs = append(errs, fmt.Errorf("failed to drain grpc server: %w", ctx.Err()))
+	}
+	t.logger.Infof("grpc server drained")
+
+	// the dispatches waiting to be retried are dead-lettered instead of holding the shutdown
+	t.evtDispatcher.Close()
+	if err := t.cs.WaitForEvents(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	t.logger.Infof("pending events dispatched")
+
+	return errors.Join(errs...)
+}
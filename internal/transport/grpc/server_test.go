@@ -0,0 +1,276 @@
+package grpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/conf"
+	"xm_test/internal/db"
+	"xm_test/internal/db/models"
+	"xm_test/internal/events"
+	"xm_test/internal/health"
+	"xm_test/internal/helpers"
+	"xm_test/internal/token"
+	xmv1 "xm_test/internal/transport/grpc/pb/xm/v1"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// companyDB stores a single company in memory
+type companyDB struct {
+	db.DatabaseAdapter
+	company *models.CompanyModel
+}
+
+func (d *companyDB) GetCompanyByID(ctx context.Context, id string) (*models.CompanyModel, error) {
+	if d.company == nil || d.company.ID.String() != id {
+		return nil, apierrors.ErrCompanyNotFound
+	}
+	return d.company, nil
+}
+
+func (d *companyDB) UpdateCompany(ctx context.Context, id string, company *models.CompanyModel, version int) (*models.CompanyModel, error) {
+	if _, err := d.GetCompanyByID(ctx, id); err != nil {
+		return nil, err
+	}
+	if version != 0 && version != d.company.Version {
+		return nil, apierrors.ErrPreconditionFailed
+	}
+	company.Version = d.company.Version + 1
+	d.company = company
+	return company, nil
+}
+
+func (d *companyDB) DeleteCompany(ctx context.Context, id string, version int) error {
+	if _, err := d.GetCompanyByID(ctx, id); err != nil {
+		return err
+	}
+	if version != 0 && version != d.company.Version {
+		return apierrors.ErrPreconditionFailed
+	}
+	d.company = nil
+	return nil
+}
+
+func (d *companyDB) CreateEvent(ctx context.Context, event *models.EventModel) error {
+	return nil
+}
+
+type serverSuite struct {
+	suite.Suite
+
+	db        *companyDB
+	hub       events.Hub
+	transport *grpcTransport
+	conn      *grpc.ClientConn
+}
+
+func (s *serverSuite) SetupTest() {
+	conf.NewConfig()
+	conf.GlobalConfig.JwtSecret = "secret"
+	conf.GlobalConfig.RequestTimeout = time.Second
+	s.start()
+}
+
+// start serves the transport with the current configuration and connects a client to it
+func (s *serverSuite) start() {
+	registry, err := events.NewEventsRegistry()
+	s.Require().NoError(err)
+
+	logger := zap.NewNop().Sugar()
+	s.db = &companyDB{company: &models.CompanyModel{ID: uuid.New(), Name: "xm", AmountEmployees: 10, Registered: true, Type: "Corporations", Version: 1}}
+	s.hub = events.NewEventsHub(logger)
+	monitor := health.NewHealthMonitor(logger, time.Second, 0)
+	s.transport = NewGrpcTransport(logger, s.db, s.hub, registry, monitor)
+
+	lis := bufconn.Listen(1024 * 1024)
+	go s.transport.serve(lis)
+
+	s.conn, err = grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	s.Require().NoError(err)
+}
+
+func (s *serverSuite) TearDownTest() {
+	s.conn.Close()
+	s.NoError(s.transport.Close(context.Background()))
+}
+
+// authenticated returns a context carrying a valid access token
+func (s *serverSuite) authenticated() context.Context {
+	accessToken, _, err := token.GenerateToken(uuid.NewString(), "user@xm.com")
+	s.Require().NoError(err)
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+accessToken)
+}
+
+// errorReason returns the code of the API error attached to the status
+func (s *serverSuite) errorReason(err error) string {
+	st := status.Convert(err)
+	s.Require().Len(st.Details(), 1)
+	return st.Details()[0].(*errdetails.ErrorInfo).Reason
+}
+
+func (s *serverSuite) TestGetCompany() {
+	client := xmv1.NewCompanyServiceClient(s.conn)
+
+	s.Run("public", func() {
+		var header metadata.MD
+		ctx := metadata.AppendToOutgoingContext(context.Background(), requestIDHeader, "client-id")
+		resp, err := client.GetCompany(ctx, &xmv1.GetCompanyRequest{Id: s.db.company.ID.String()}, grpc.Header(&header))
+		s.Require().NoError(err)
+		s.Equal(s.db.company.Name, resp.GetCompany().GetName())
+		s.Equal(int32(10), resp.GetCompany().GetAmountEmployees())
+		s.Equal([]string{"client-id"}, header.Get(requestIDHeader))
+	})
+
+	s.Run("not found", func() {
+		_, err := client.GetCompany(context.Background(), &xmv1.GetCompanyRequest{Id: uuid.NewString()})
+		s.Equal(codes.NotFound, status.Code(err))
+		s.Equal(apierrors.ErrCompanyNotFound.Code, s.errorReason(err))
+	})
+
+	s.Run("invalid id", func() {
+		_, err := client.GetCompany(context.Background(), &xmv1.GetCompanyRequest{Id: "not-a-uuid"})
+		s.Equal(apierrors.ErrInvalidUUID.Code, s.errorReason(err))
+	})
+}
+
+func (s *serverSuite) TestAuthentication() {
+	client := xmv1.NewCompanyServiceClient(s.conn)
+
+	s.Run("missing token", func() {
+		_, err := client.DeleteCompany(context.Background(), &xmv1.DeleteCompanyRequest{Id: uuid.NewString()})
+		s.Equal(codes.Unauthenticated, status.Code(err))
+		s.Equal(apierrors.ErrTokenNotFound.Code, s.errorReason(err))
+	})
+
+	s.Run("invalid token", func() {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer invalid")
+		_, err := client.DeleteCompany(ctx, &xmv1.DeleteCompanyRequest{Id: uuid.NewString()})
+		s.Equal(codes.Unauthenticated, status.Code(err))
+		s.Equal(apierrors.ErrInvalidToken.Code, s.errorReason(err))
+	})
+
+	s.Run("missing scheme", func() {
+		accessToken, _, err := token.GenerateToken(uuid.NewString(), "user@xm.com")
+		s.Require().NoError(err)
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", accessToken)
+		_, err = client.DeleteCompany(ctx, &xmv1.DeleteCompanyRequest{Id: uuid.NewString()})
+		s.Equal(codes.Unauthenticated, status.Code(err))
+		s.Equal(apierrors.ErrInvalidToken.Code, s.errorReason(err))
+	})
+
+	s.Run("case-insensitive scheme", func() {
+		accessToken, _, err := token.GenerateToken(uuid.NewString(), "user@xm.com")
+		s.Require().NoError(err)
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "bearer "+accessToken)
+		_, err = client.CreateCompany(ctx, &xmv1.CreateCompanyRequest{Name: "xm", Type: "unknown"})
+		s.Equal(codes.InvalidArgument, status.Code(err))
+	})
+
+	s.Run("validates the request once authenticated", func() {
+		_, err := client.CreateCompany(s.authenticated(), &xmv1.CreateCompanyRequest{Name: "xm", Type: "unknown"})
+		s.Equal(codes.InvalidArgument, status.Code(err))
+		s.Equal(apierrors.ErrInvalidBody.Code, s.errorReason(err))
+	})
+}
+
+func (s *serverSuite) TestVersion() {
+	client := xmv1.NewCompanyServiceClient(s.conn)
+	update := func(version *int32) (*xmv1.UpdateCompanyResponse, error) {
+		return client.UpdateCompany(s.authenticated(), &xmv1.UpdateCompanyRequest{
+			Id: s.db.company.ID.String(), Name: "xm", AmountEmployees: helpers.PointerValue[int32](10), Registered: helpers.PointerValue(true), Type: "Corporations", Version: version,
+		})
+	}
+
+	s.Run("returns the version", func() {
+		resp, err := client.GetCompany(context.Background(), &xmv1.GetCompanyRequest{Id: s.db.company.ID.String()})
+		s.Require().NoError(err)
+		s.Equal(int32(1), resp.GetCompany().GetVersion())
+	})
+
+	s.Run("updates the version", func() {
+		resp, err := update(helpers.PointerValue[int32](1))
+		s.Require().NoError(err)
+		s.Equal(int32(2), resp.GetVersion())
+	})
+
+	s.Run("updates any version", func() {
+		resp, err := update(nil)
+		s.Require().NoError(err)
+		s.Equal(int32(3), resp.GetVersion())
+	})
+
+	s.Run("fails when the version does not match", func() {
+		_, err := update(helpers.PointerValue[int32](1))
+		s.Equal(codes.FailedPrecondition, status.Code(err))
+		s.Equal(apierrors.ErrPreconditionFailed.Code, s.errorReason(err))
+
+		_, err = client.DeleteCompany(s.authenticated(), &xmv1.DeleteCompanyRequest{Id: s.db.company.ID.String(), Version: helpers.PointerValue[int32](1)})
+		s.Equal(apierrors.ErrPreconditionFailed.Code, s.errorReason(err))
+	})
+
+	s.Run("requires the version", func() {
+		s.TearDownTest()
+		conf.GlobalConfig.RequireIfMatch = true
+		s.start()
+		client = xmv1.NewCompanyServiceClient(s.conn)
+
+		_, err := update(nil)
+		s.Equal(codes.FailedPrecondition, status.Code(err))
+		s.Equal(apierrors.ErrPreconditionRequired.Code, s.errorReason(err))
+
+		_, err = client.DeleteCompany(s.authenticated(), &xmv1.DeleteCompanyRequest{Id: s.db.company.ID.String()})
+		s.Equal(apierrors.ErrPreconditionRequired.Code, s.errorReason(err))
+
+		_, err = client.DeleteCompany(s.authenticated(), &xmv1.DeleteCompanyRequest{Id: s.db.company.ID.String(), Version: helpers.PointerValue[int32](1)})
+		s.Require().NoError(err)
+	})
+}
+
+func (s *serverSuite) TestStreamEvents() {
+	client := xmv1.NewEventsServiceClient(s.conn)
+
+	ctx, cancel := context.WithCancel(s.authenticated())
+	defer cancel()
+	stream, err := client.StreamEvents(ctx, &xmv1.StreamEventsRequest{})
+	s.Require().NoError(err)
+
+	// the header is sent once the stream is set up, so the subscription is already registered
+	header, err := stream.Header()
+	s.Require().NoError(err)
+	s.Len(header.Get(requestIDHeader), 1)
+	s.hub.Publish(&events.Event{Type: "delete_company", Version: 1, ID: uuid.New(), EntityID: s.db.company.ID, Timestamp: time.Now(), Payload: []byte(`{"id":"` + s.db.company.ID.String() + `"}`)})
+
+	resp, err := stream.Recv()
+	s.Require().NoError(err)
+	s.Equal("delete_company", resp.GetEvent().GetType())
+	s.Equal(s.db.company.ID.String(), resp.GetEvent().GetPayload().AsMap()["id"])
+}
+
+func (s *serverSuite) TestHealth() {
+	client := healthpb.NewHealthClient(s.conn)
+
+	s.transport.updateHealth()
+	resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: xmv1.CompanyService_ServiceDesc.ServiceName})
+	s.Require().NoError(err)
+	s.Equal(healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+}
+
+func TestServerSuite(t *testing.T) {
+	suite.Run(t, new(serverSuite))
+}
@@ -0,0 +1,155 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/enum"
+	"xm_test/internal/i18n"
+	"xm_test/internal/logging"
+	"xm_test/internal/transport/http/binding"
+	"xm_test/internal/transport/http/schemas"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 50  // amount of items returned when the limit is not set
+	maxPageLimit     = 500 // maximum amount of items returned in a single page
+)
+
+// listDeadLetters lists the events that could not be dispatched
+func (h *handler) listDeadLetters(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("list dead-lettered events endpoint called")
+
+	limit, offset, err := decodePagination(r)
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+
+	deadLetters, err := h.dlq.List(r.Context(), limit, offset)
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+	logger.Infof("%d dead-lettered events listed", len(deadLetters))
+	h.codecs.Render(w, r, deadLetters)
+}
+
+// getDeadLetter retrieves a dead-lettered event by its ID
+func (h *handler) getDeadLetter(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("get dead-lettered event endpoint called")
+
+	id, err := decodeUUIDParam(r)
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+
+	deadLetter, err := h.dlq.Get(r.Context(), id)
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+	logger.Infof("dead-lettered event '%s' retrieved", id)
+	h.codecs.Render(w, r, deadLetter)
+}
+
+// retryDeadLetter dispatches a dead-lettered event again
+func (h *handler) retryDeadLetter(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("retry dead-lettered event endpoint called")
+
+	id, err := decodeUUIDParam(r)
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+
+	if err := h.dlq.Retry(r.Context(), id); err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+	logger.Infof("dead-lettered event '%s' retried", id)
+	h.codecs.Render(w, r, schemas.OkResponse{Message: "event dispatched"})
+}
+
+// discardDeadLetter removes a dead-lettered event without dispatching it
+func (h *handler) discardDeadLetter(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("discard dead-lettered event endpoint called")
+
+	id, err := decodeUUIDParam(r)
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+
+	if err := h.dlq.Discard(r.Context(), id); err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+	logger.Infof("dead-lettered event '%s' discarded", id)
+	h.codecs.Render(w, r, schemas.OkResponse{Message: "event discarded"})
+}
+
+// getLogLevel returns the current log level
+func (h *handler) getLogLevel(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("get log level endpoint called")
+	h.codecs.Render(w, r, schemas.LogLevelResponse{Level: logging.FormatLevel(h.logLevel.Level()).String()})
+}
+
+// setLogLevel changes the log level at runtime. The change is not persisted, so the configured level is restored
+// when the API restarts.
+func (h *handler) setLogLevel(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("set log level endpoint called")
+
+	var body schemas.LogLevelRequest
+	if err := binding.DecodeBody(r, &body); err != nil {
+		h.wrapError(w, r, invalidBody(err))
+		return
+	}
+
+	previous := h.logLevel.Level()
+	h.logLevel.SetLevel(logging.ParseLevel(enum.LogLevel(body.Level)))
+	logger.Warnf("log level changed from '%s' to '%s'", logging.FormatLevel(previous), body.Level)
+	h.codecs.Render(w, r, schemas.LogLevelResponse{Level: body.Level})
+}
+
+// decodeUUIDParam decodes the id URL parameter and checks that it is a valid uuid
+func decodeUUIDParam(r *http.Request) (string, error) {
+	id := chi.URLParam(r, "id")
+	if err := uuid.Validate(id); err != nil {
+		return "", apierrors.ErrInvalidUUID
+	}
+	return id, nil
+}
+
+// decodePagination decodes the limit and offset query parameters
+func decodePagination(r *http.Request) (int, int, error) {
+	limit, offset := defaultPageLimit, 0
+
+	if v := r.URL.Query().Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 1 || l > maxPageLimit {
+			return 0, 0, apierrors.ErrInvalidQuery.WithMessageID("limit", i18n.Params{"max": maxPageLimit})
+		}
+		limit = l
+	}
+
+	if v := r.URL.Query().Get("offset"); v != "" {
+		o, err := strconv.Atoi(v)
+		if err != nil || o < 0 {
+			return 0, 0, apierrors.ErrInvalidQuery.WithMessageID("offset", nil)
+		}
+		offset = o
+	}
+
+	return limit, offset, nil
+}
@@ -0,0 +1,252 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/conf"
+	"xm_test/internal/db"
+	"xm_test/internal/db/models"
+	"xm_test/internal/enum"
+	"xm_test/internal/events"
+	"xm_test/internal/i18n"
+	"xm_test/internal/token"
+	"xm_test/internal/transport/http/schemas"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+// deadLetterDB holds the dead-lettered events in memory
+type deadLetterDB struct {
+	db.DatabaseAdapter
+	deadLetters map[string]*models.DeadLetterEventModel
+}
+
+func (d *deadLetterDB) ListDeadLetterEvents(ctx context.Context, limit int, offset int) ([]*models.DeadLetterEventModel, error) {
+	deadLetters := make([]*models.DeadLetterEventModel, 0, len(d.deadLetters))
+	for _, deadLetter := range d.deadLetters {
+		deadLetters = append(deadLetters, deadLetter)
+	}
+	return deadLetters, nil
+}
+
+func (d *deadLetterDB) GetDeadLetterEvent(ctx context.Context, id string) (*models.DeadLetterEventModel, error) {
+	deadLetter, ok := d.deadLetters[id]
+	if !ok {
+		return nil, apierrors.ErrDeadLetterNotFound.WithMessageID("id", i18n.Params{"id": id})
+	}
+	return deadLetter, nil
+}
+
+func (d *deadLetterDB) DeleteDeadLetterEvent(ctx context.Context, id string) error {
+	if _, ok := d.deadLetters[id]; !ok {
+		return apierrors.ErrDeadLetterNotFound.WithMessageID("id", i18n.Params{"id": id})
+	}
+	delete(d.deadLetters, id)
+	return nil
+}
+
+// recordingDispatcher records the events dispatched, failing the dispatches when err is set
+type recordingDispatcher struct {
+	events []*events.Event
+	err    error
+}
+
+func (d *recordingDispatcher) Dispatch(event *events.Event) error {
+	if d.err != nil {
+		return d.err
+	}
+	d.events = append(d.events, event)
+	return nil
+}
+
+func (d *recordingDispatcher) Close() {}
+
+type adminSuite struct {
+	suite.Suite
+	router     chi.Router
+	db         *deadLetterDB
+	dispatcher *recordingDispatcher
+	deadLetter *models.DeadLetterEventModel
+}
+
+func (s *adminSuite) SetupTest() {
+	conf.NewConfig()
+	conf.GlobalConfig.JwtSecret = "secret"
+	conf.GlobalConfig.AdminEmails = []string{"admin@xm.com"}
+
+	s.deadLetter = &models.DeadLetterEventModel{
+		ID:        uuid.New(),
+		Type:      enum.EventDeleteCompany.String(),
+		Version:   1,
+		EntityID:  uuid.New(),
+		Payload:   json.RawMessage(`{"id": "00000000-0000-0000-0000-000000000001"}`),
+		LastError: "event bus unavailable",
+		Attempts:  5,
+	}
+	s.db = &deadLetterDB{deadLetters: map[string]*models.DeadLetterEventModel{s.deadLetter.ID.String(): s.deadLetter}}
+	s.dispatcher = &recordingDispatcher{}
+
+	logger := zap.NewNop().Sugar()
+	transport := &httpTransport{logger: logger, handler: newHandler(logger, zap.NewAtomicLevel(), nil, nil, nil)}
+	transport.handler.dlq = events.NewDeadLetterQueue(logger, s.db, s.dispatcher)
+	router, err := transport.router()
+	s.Require().NoError(err)
+	s.router = router
+}
+
+func (s *adminSuite) serve(method, path, email, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if email != "" {
+		accessToken, _, err := token.GenerateToken(uuid.NewString(), email)
+		s.Require().NoError(err)
+		r.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, r)
+	return w
+}
+
+func (s *adminSuite) TestAdminRoutes() {
+	s.Run("rejects anonymous users", func() {
+		w := s.serve(http.MethodGet, "/admin/log-level", "", "")
+		s.Equal(apierrors.ErrTokenNotFound.HTTPStatus, w.Code)
+	})
+
+	s.Run("rejects users that are not administrators", func() {
+		for _, method := range []string{http.MethodGet, http.MethodPut} {
+			w := s.serve(method, "/admin/log-level", "user@xm.com", `{"level": "debug"}`)
+			s.Equal(http.StatusForbidden, w.Code, method)
+
+			var body apierrors.APIError
+			s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &body))
+			s.Equal(apierrors.ErrForbidden.Code, body.Code)
+		}
+	})
+
+	s.Run("allows the administrators", func() {
+		w := s.serve(http.MethodPut, "/admin/log-level", "admin@xm.com", `{"level": "debug"}`)
+		s.Equal(http.StatusOK, w.Code)
+
+		w = s.serve(http.MethodGet, "/admin/log-level", "admin@xm.com", "")
+		s.Require().Equal(http.StatusOK, w.Code)
+
+		var body schemas.LogLevelResponse
+		s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &body))
+		s.Equal("debug", body.Level)
+	})
+}
+
+// decodeError returns the API error of the response
+func (s *adminSuite) decodeError(w *httptest.ResponseRecorder) *apierrors.APIError {
+	var body apierrors.APIError
+	s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &body))
+	return &body
+}
+
+func (s *adminSuite) TestListDeadLetters() {
+	s.Run("rejects users that are not administrators", func() {
+		w := s.serve(http.MethodGet, "/admin/events/dead-letters", "user@xm.com", "")
+		s.Equal(http.StatusForbidden, w.Code)
+	})
+
+	s.Run("validates the pagination", func() {
+		w := s.serve(http.MethodGet, "/admin/events/dead-letters?limit=0", "admin@xm.com", "")
+		s.Equal(http.StatusBadRequest, w.Code)
+		s.Equal(apierrors.ErrInvalidQuery.Code, s.decodeError(w).Code)
+	})
+
+	s.Run("ok", func() {
+		w := s.serve(http.MethodGet, "/admin/events/dead-letters?limit=10", "admin@xm.com", "")
+		s.Require().Equal(http.StatusOK, w.Code)
+
+		var body []*models.DeadLetterEventModel
+		s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &body))
+		s.Require().Len(body, 1)
+		s.Equal(s.deadLetter.ID, body[0].ID)
+		s.Equal(s.deadLetter.LastError, body[0].LastError)
+	})
+}
+
+func (s *adminSuite) TestGetDeadLetter() {
+	s.Run("rejects invalid ids", func() {
+		w := s.serve(http.MethodGet, "/admin/events/dead-letters/invalid", "admin@xm.com", "")
+		s.Equal(http.StatusBadRequest, w.Code)
+		s.Equal(apierrors.ErrInvalidUUID.Code, s.decodeError(w).Code)
+	})
+
+	s.Run("not found", func() {
+		w := s.serve(http.MethodGet, "/admin/events/dead-letters/"+uuid.NewString(), "admin@xm.com", "")
+		s.Equal(http.StatusNotFound, w.Code)
+		s.Equal(apierrors.ErrDeadLetterNotFound.Code, s.decodeError(w).Code)
+	})
+
+	s.Run("ok", func() {
+		w := s.serve(http.MethodGet, "/admin/events/dead-letters/"+s.deadLetter.ID.String(), "admin@xm.com", "")
+		s.Require().Equal(http.StatusOK, w.Code)
+
+		var body models.DeadLetterEventModel
+		s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &body))
+		s.Equal(s.deadLetter.ID, body.ID)
+		s.Equal(s.deadLetter.Type, body.Type)
+		s.Equal(s.deadLetter.Attempts, body.Attempts)
+	})
+}
+
+func (s *adminSuite) TestRetryDeadLetter() {
+	path := "/admin/events/dead-letters/" + s.deadLetter.ID.String() + "/retry"
+
+	s.Run("not found", func() {
+		w := s.serve(http.MethodPost, "/admin/events/dead-letters/"+uuid.NewString()+"/retry", "admin@xm.com", "")
+		s.Equal(http.StatusNotFound, w.Code)
+		s.Equal(apierrors.ErrDeadLetterNotFound.Code, s.decodeError(w).Code)
+		s.Empty(s.dispatcher.events)
+	})
+
+	s.Run("keeps the event when the dispatch fails", func() {
+		s.dispatcher.err = errors.New("event bus unavailable")
+		defer func() { s.dispatcher.err = nil }()
+
+		w := s.serve(http.MethodPost, path, "admin@xm.com", "")
+		s.Equal(http.StatusInternalServerError, w.Code)
+		s.Contains(s.db.deadLetters, s.deadLetter.ID.String())
+	})
+
+	s.Run("dispatches the event and removes it", func() {
+		w := s.serve(http.MethodPost, path, "admin@xm.com", "")
+		s.Require().Equal(http.StatusOK, w.Code)
+
+		s.Require().Len(s.dispatcher.events, 1)
+		s.Equal(s.deadLetter.ID, s.dispatcher.events[0].ID)
+		s.Equal(s.deadLetter.EntityID, s.dispatcher.events[0].EntityID)
+		s.JSONEq(string(s.deadLetter.Payload), string(s.dispatcher.events[0].Payload))
+		s.NotContains(s.db.deadLetters, s.deadLetter.ID.String())
+	})
+}
+
+func (s *adminSuite) TestDiscardDeadLetter() {
+	s.Run("not found", func() {
+		w := s.serve(http.MethodDelete, "/admin/events/dead-letters/"+uuid.NewString(), "admin@xm.com", "")
+		s.Equal(http.StatusNotFound, w.Code)
+		s.Equal(apierrors.ErrDeadLetterNotFound.Code, s.decodeError(w).Code)
+	})
+
+	s.Run("removes the event without dispatching it", func() {
+		w := s.serve(http.MethodDelete, "/admin/events/dead-letters/"+s.deadLetter.ID.String(), "admin@xm.com", "")
+		s.Require().Equal(http.StatusOK, w.Code)
+		s.Empty(s.dispatcher.events)
+		s.NotContains(s.db.deadLetters, s.deadLetter.ID.String())
+	})
+}
+
+func TestAdminSuite(t *testing.T) {
+	suite.Run(t, new(adminSuite))
+}
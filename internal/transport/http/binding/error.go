@@ -3,31 +3,71 @@ package binding
 import (
 	"strings"
 	errors "xm_test/internal/api_errors"
+	"xm_test/internal/conf"
 	"xm_test/internal/enum"
+	"xm_test/internal/i18n"
 
 	"github.com/go-playground/validator/v10"
 )
 
+// handleBindingErrors returns an invalid body error describing every field that failed to be validated. The
+// messages are found in the catalogs, so they are translated along with the error.
 func handleBindingErrors(err error) error {
-	validationErrors := err.(validator.ValidationErrors)
-	validationErr := validationErrors[0]
+	validationErrors, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return errors.ErrInvalidBody.WithMessageID("reason", i18n.Params{"reason": err})
+	}
+
+	fieldErrors := make([]errors.FieldError, 0, len(validationErrors))
+	messages := make(i18n.Messages, 0, len(validationErrors))
+	for _, validationErr := range validationErrors {
+		message := fieldMessage(validationErr)
+		fieldErrors = append(fieldErrors, errors.NewFieldError(fieldPointer(validationErr), message))
+		messages = append(messages, message)
+	}
 
-	fieldName := validationErr.Field()
+	return errors.ErrInvalidBody.WithMessageID("fields", i18n.Params{"fields": messages}).WithErrors(fieldErrors)
+}
 
-	apiError := errors.ErrInvalidBody
+// fieldMessage returns the message of the catalogs describing why the field failed to be validated
+func fieldMessage(validationErr validator.FieldError) i18n.Message {
+	params := i18n.Params{"field": validationErr.Field()}
 
 	switch validationErr.Tag() {
-	case "required":
-		apiError.Message = fieldName + " is required and must be a " + validationErr.Type().String()
+	case "required", "required_unless":
+		params["type"] = validationErr.Type().String()
+		return i18n.Message{ID: "validation.required", Params: params}
 	case "oneof":
-		apiError.Message = fieldName + " must be one of: " + strings.Join(strings.Split(validationErr.Param(), " "), ", ")
+		params["values"] = strings.Join(strings.Split(validationErr.Param(), " "), ", ")
+		return i18n.Message{ID: "validation.oneof", Params: params}
 	case "customOneOf":
-		apiError.Message = fieldName + " must be one of: " + strings.Join(enum.AllCompanyTypesString(), ", ")
+		params["values"] = strings.Join(enum.AllCompanyTypesString(), ", ")
+		return i18n.Message{ID: "validation.oneof", Params: params}
 	case "email":
-		apiError.Message = fieldName + " must be a valid email address"
+		return i18n.Message{ID: "validation.email", Params: params}
+	case "min":
+		params["min"] = validationErr.Param()
+		return i18n.Message{ID: "validation.min_items", Params: params}
+	case "maxBulkOperations":
+		params["max"] = conf.GlobalConfig.BulkMaxOperations
+		return i18n.Message{ID: "validation.max_items", Params: params}
 	default:
-		apiError.Message = err.Error()
+		params["tag"] = validationErr.Tag()
+		return i18n.Message{ID: "validation.invalid", Params: params}
 	}
+}
+
+// fieldPointer returns the JSON pointer to the field, e.g. "Request.items[0].name" becomes "/items/0/name"
+func fieldPointer(validationErr validator.FieldError) string {
+	namespace := strings.NewReplacer("[", ".", "]", "").Replace(validationErr.Namespace())
 
-	return apiError
+	// the first segment is the name of the validated struct
+	_, path, _ := strings.Cut(namespace, ".")
+
+	var b strings.Builder
+	for _, segment := range strings.Split(path, ".") {
+		b.WriteByte('/')
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(segment))
+	}
+	return b.String()
 }
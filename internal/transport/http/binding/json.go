@@ -2,28 +2,36 @@ package binding
 
 import (
 	"encoding/json"
+	"io"
 	"net/http"
 	"reflect"
 	"strings"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/conf"
 	"xm_test/internal/enum"
+	"xm_test/internal/i18n"
+	"xm_test/internal/transport/http/codec"
 
 	"github.com/go-playground/validator/v10"
 )
 
+// validate is shared by every request, since the validator caches the validations of each struct
+var validate = newValidator()
+
 func validateCompanyType(fl validator.FieldLevel) bool {
 	companyType := fl.Field().String()
 	return enum.CompanyTypeFromString(companyType) != ""
 }
 
-// DecodeJSONBody decodes the http request body into the given struct and validates it
-func DecodeJSONBody(r *http.Request, v interface{}) error {
-	if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
-		return err
-	}
+// validateBulkOperations checks that a bulk request has at most the configured amount of operations
+func validateBulkOperations(fl validator.FieldLevel) bool {
+	return fl.Field().Len() <= conf.GlobalConfig.BulkMaxOperations
+}
 
-	// validate the decode body
-	validate := validator.New()
-	validate.RegisterTagNameFunc(func(fld reflect.StructField) string {
+// newValidator returns a validator that names the fields after their JSON key and knows the custom validations
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(fld reflect.StructField) string {
 		name := strings.SplitN(fld.Tag.Get("json"), ",", 2)[0]
 
 		if name == "-" {
@@ -33,8 +41,44 @@ func DecodeJSONBody(r *http.Request, v interface{}) error {
 		return name
 	})
 
-	validate.RegisterValidation("customOneOf", validateCompanyType)
+	v.RegisterValidation("customOneOf", validateCompanyType)
+	v.RegisterValidation("maxBulkOperations", validateBulkOperations)
+	return v
+}
+
+// DecodeBody decodes the http request body into the given struct with the codec of its Content-Type, and validates
+// it. The bodies sent without Content-Type are decoded as JSON. It fails with ErrUnsupportedMediaType when no codec
+// is registered for the media type.
+func DecodeBody(r *http.Request, v interface{}) error {
+	contentType := r.Header.Get("Content-Type")
+	decoder, ok := codec.Default.Lookup(contentType)
+	if !ok {
+		return apierrors.ErrUnsupportedMediaType.WithMessageID("type", i18n.Params{
+			"type":      contentType,
+			"supported": strings.Join(codec.Default.MediaTypes(), ", "),
+		})
+	}
+	if err := decoder.Decode(r.Body, v); err != nil {
+		return err
+	}
+
+	// validate the decode body
+	return Validate(v)
+}
+
+// decodeJSON decodes the JSON document read from the reader into the given struct and validates it
+func decodeJSON(r io.Reader, v interface{}) error {
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return err
+	}
+
+	// validate the decode body
+	return Validate(v)
+}
 
+// Validate validates the struct against its validate tags. It is used by the transports that do not decode JSON
+// bodies, so that every transport applies the same rules. Every field that fails to be validated is reported.
+func Validate(v interface{}) error {
 	err := validate.Struct(v)
 	if err != nil {
 		return handleBindingErrors(err)
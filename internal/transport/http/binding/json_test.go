@@ -0,0 +1,126 @@
+package binding
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/conf"
+	"xm_test/internal/transport/http/schemas"
+
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/text/language"
+)
+
+type bindingSuite struct {
+	suite.Suite
+}
+
+func (s *bindingSuite) TestDecodeBody() {
+	s.Run("decodes a valid body", func() {
+		var body schemas.CreateCompanyRequest
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "xm", "amount_employees": 10, "registered": true, "type": "NonProfit"}`))
+		s.Require().NoError(DecodeBody(r, &body))
+		s.Equal("xm", body.Name)
+	})
+
+	s.Run("decodes the body with the codec of its content type", func() {
+		var body schemas.CreateCompanyRequest
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`<request><name>xm</name><amount_employees>10</amount_employees><registered>true</registered><type>NonProfit</type></request>`))
+		r.Header.Set("Content-Type", "application/xml; charset=utf-8")
+		s.Require().NoError(DecodeBody(r, &body))
+		s.Equal("xm", body.Name)
+		s.Equal(10, *body.AmountEmployees)
+		s.True(*body.Registered)
+	})
+
+	s.Run("rejects the unsupported content types", func() {
+		var body schemas.CreateCompanyRequest
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`name=xm`))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		err := DecodeBody(r, &body)
+
+		var apiErr *apierrors.APIError
+		s.Require().ErrorAs(err, &apiErr)
+		s.ErrorIs(err, apierrors.ErrUnsupportedMediaType)
+		s.Contains(apiErr.Message, "application/x-www-form-urlencoded")
+		s.Contains(apiErr.Message, "application/xml")
+	})
+
+	s.Run("reports every invalid field", func() {
+		var body schemas.CreateCompanyRequest
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "xm", "type": "Unknown"}`))
+		err := DecodeBody(r, &body)
+
+		var apiErr *apierrors.APIError
+		s.Require().ErrorAs(err, &apiErr)
+		s.Equal(apierrors.ErrInvalidBody.Code, apiErr.Code)
+		s.Equal(map[string]string{
+			"/amount_employees": "amount_employees is required and must be a *int",
+			"/registered":       "registered is required and must be a *bool",
+			"/type":             "type must be one of: Corporations, NonProfit, Cooperative, Sole Proprietorship",
+		}, fieldMessages(apiErr.Errors))
+		s.Contains(apiErr.Message, "amount_employees is required")
+		s.Contains(apiErr.Message, "type must be one of")
+	})
+
+	s.Run("translates the invalid fields", func() {
+		var body schemas.CreateCompanyRequest
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "xm", "amount_employees": 10, "registered": true, "type": "Unknown"}`))
+		err := DecodeBody(r, &body)
+
+		var apiErr *apierrors.APIError
+		s.Require().ErrorAs(err, &apiErr)
+		localized := apiErr.Localize(language.Spanish)
+		s.Equal(map[string]string{
+			"/type": "type debe ser uno de: Corporations, NonProfit, Cooperative, Sole Proprietorship",
+		}, fieldMessages(localized.Errors))
+		s.Equal("type debe ser uno de: Corporations, NonProfit, Cooperative, Sole Proprietorship", localized.Message)
+		s.Equal("type must be one of: Corporations, NonProfit, Cooperative, Sole Proprietorship", apiErr.Message)
+	})
+
+	s.Run("bounds the operations of the bulk requests", func() {
+		conf.NewConfig()
+		conf.GlobalConfig.BulkMaxOperations = 2
+		operation := `{"op": "delete", "id": "00000000-0000-0000-0000-000000000001"}`
+
+		for operations, message := range map[string]string{
+			"": "operations must have at least 1 items",
+			strings.Repeat(operation+",", 2) + operation: "operations must have at most 2 items",
+		} {
+			var body schemas.BulkCompaniesRequest
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"operations": [`+operations+`]}`))
+			err := DecodeBody(r, &body)
+
+			var apiErr *apierrors.APIError
+			s.Require().ErrorAs(err, &apiErr)
+			s.Equal(map[string]string{"/operations": message}, fieldMessages(apiErr.Errors))
+		}
+
+		var body schemas.BulkCompaniesRequest
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"operations": [`+operation+`,`+operation+`]}`))
+		s.NoError(DecodeBody(r, &body))
+	})
+
+	s.Run("does not modify the sentinel error", func() {
+		var body schemas.LogLevelRequest
+		err := DecodeBody(httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`)), &body)
+		s.Require().Error(err)
+		s.Equal("invalid request body", apierrors.ErrInvalidBody.Message)
+		s.Empty(apierrors.ErrInvalidBody.Errors)
+	})
+}
+
+// fieldMessages returns the message of each invalid field, keyed by pointer
+func fieldMessages(errs []apierrors.FieldError) map[string]string {
+	messages := make(map[string]string, len(errs))
+	for _, e := range errs {
+		messages[e.Pointer] = e.Message
+	}
+	return messages
+}
+
+func TestBindingSuite(t *testing.T) {
+	suite.Run(t, new(bindingSuite))
+}
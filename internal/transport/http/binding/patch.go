@@ -0,0 +1,97 @@
+package binding
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/i18n"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	// MergePatchContentType is the media type of the RFC 7396 merge patches
+	MergePatchContentType = "application/merge-patch+json"
+
+	// JSONPatchContentType is the media type of the RFC 6902 patches
+	JSONPatchContentType = "application/json-patch+json"
+)
+
+// PatchContentTypes lists the media types of the patches, in order of preference
+var PatchContentTypes = []string{MergePatchContentType, JSONPatchContentType}
+
+// Patch modifies a JSON document
+type Patch interface {
+	Apply(doc []byte) ([]byte, error)
+}
+
+// mergePatch is a RFC 7396 merge patch
+type mergePatch []byte
+
+// Apply merges the patch into the document
+func (p mergePatch) Apply(doc []byte) ([]byte, error) {
+	return jsonpatch.MergePatch(doc, p)
+}
+
+// DecodePatch reads the patch of the request body, according to its media type. It fails with
+// ErrUnsupportedMediaType when the body is neither a merge patch nor a JSON patch.
+func DecodePatch(r *http.Request) (Patch, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != MergePatchContentType && mediaType != JSONPatchContentType {
+		return nil, apierrors.ErrUnsupportedMediaType.WithMessageID("type", i18n.Params{
+			"type":      r.Header.Get("Content-Type"),
+			"supported": strings.Join(PatchContentTypes, ", "),
+		})
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, apierrors.ErrInvalidBody.WithMessageID("decode", i18n.Params{"reason": err})
+	}
+
+	if mediaType == JSONPatchContentType {
+		patch, err := jsonpatch.DecodePatch(data)
+		if err != nil {
+			return nil, apierrors.ErrInvalidBody.WithMessageID("patch", i18n.Params{"reason": err})
+		}
+		return patch, nil
+	}
+
+	// the merge patches replace the whole document unless they are objects
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, apierrors.ErrInvalidBody.WithMessageID("patch", i18n.Params{"reason": err})
+	}
+	return mergePatch(data), nil
+}
+
+// ApplyPatch applies the patch to the JSON representation of v, and decodes the result into v, which is validated.
+// The patched document must not have fields that v does not know about. It fails with ErrPatchConflict when the
+// patch cannot be applied to the document, e.g. when a JSON patch test fails.
+func ApplyPatch(patch Patch, v any) error {
+	doc, err := json.Marshal(v)
+	if err != nil {
+		return apierrors.ErrInternalServer.WithMessage("failed to encode the document to patch").Wrap(err)
+	}
+
+	patched, err := patch.Apply(doc)
+	if err != nil {
+		return apierrors.ErrPatchConflict.WithMessageID("reason", i18n.Params{"reason": err})
+	}
+
+	// the result is decoded into a zero value, so the fields removed by the patch are not kept
+	target := reflect.New(reflect.TypeOf(v).Elem())
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(target.Interface()); err != nil {
+		return apierrors.ErrInvalidBody.WithMessageID("decode", i18n.Params{"reason": err})
+	}
+	reflect.ValueOf(v).Elem().Set(target.Elem())
+
+	return Validate(v)
+}
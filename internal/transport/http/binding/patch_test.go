@@ -0,0 +1,126 @@
+package binding
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/helpers"
+	"xm_test/internal/transport/http/schemas"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type patchSuite struct {
+	suite.Suite
+}
+
+// company returns a valid company to be patched
+func (s *patchSuite) company() *schemas.UpdateCompanyRequest {
+	return &schemas.UpdateCompanyRequest{
+		Name:            "xm",
+		Description:     "broker",
+		AmountEmployees: helpers.PointerValue(10),
+		Registered:      helpers.PointerValue(true),
+		Type:            "NonProfit",
+	}
+}
+
+// patch decodes the patch and applies it to a valid company
+func (s *patchSuite) patch(contentType, body string) (*schemas.UpdateCompanyRequest, error) {
+	r := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	patch, err := DecodePatch(r)
+	if err != nil {
+		return nil, err
+	}
+
+	company := s.company()
+	return company, ApplyPatch(patch, company)
+}
+
+func (s *patchSuite) TestMergePatch() {
+	s.Run("updates the fields of the patch", func() {
+		company, err := s.patch(MergePatchContentType, `{"name": "xm group", "amount_employees": 20}`)
+		s.Require().NoError(err)
+
+		expected := s.company()
+		expected.Name, expected.AmountEmployees = "xm group", helpers.PointerValue(20)
+		s.Equal(expected, company)
+	})
+
+	s.Run("removes the null fields", func() {
+		company, err := s.patch(MergePatchContentType+"; charset=utf-8", `{"description": null}`)
+		s.Require().NoError(err)
+		s.Empty(company.Description)
+	})
+
+	s.Run("validates the result", func() {
+		_, err := s.patch(MergePatchContentType, `{"name": null, "type": "Unknown"}`)
+		s.Require().ErrorIs(err, apierrors.ErrInvalidBody)
+
+		var apiErr *apierrors.APIError
+		s.Require().ErrorAs(err, &apiErr)
+		s.Equal(map[string]string{
+			"/name": "name is required and must be a string",
+			"/type": "type must be one of: Corporations, NonProfit, Cooperative, Sole Proprietorship",
+		}, fieldMessages(apiErr.Errors))
+	})
+
+	s.Run("rejects the unknown fields", func() {
+		_, err := s.patch(MergePatchContentType, `{"id": "5f1f3c1e-0b1a-4f6e-9d55-0f0e8a1b2c3d"}`)
+		s.ErrorIs(err, apierrors.ErrInvalidBody)
+	})
+
+	s.Run("rejects the patches that are not objects", func() {
+		_, err := s.patch(MergePatchContentType, `["name"]`)
+		s.ErrorIs(err, apierrors.ErrInvalidBody)
+	})
+}
+
+func (s *patchSuite) TestJSONPatch() {
+	s.Run("applies the operations", func() {
+		company, err := s.patch(JSONPatchContentType, `[
+			{"op": "test", "path": "/name", "value": "xm"},
+			{"op": "replace", "path": "/registered", "value": false},
+			{"op": "copy", "from": "/name", "path": "/description"}
+		]`)
+		s.Require().NoError(err)
+
+		expected := s.company()
+		expected.Registered, expected.Description = helpers.PointerValue(false), "xm"
+		s.Equal(expected, company)
+	})
+
+	s.Run("fails when a test does not pass", func() {
+		_, err := s.patch(JSONPatchContentType, `[{"op": "test", "path": "/name", "value": "other"}, {"op": "remove", "path": "/description"}]`)
+		s.ErrorIs(err, apierrors.ErrPatchConflict)
+	})
+
+	s.Run("fails when the path does not exist", func() {
+		_, err := s.patch(JSONPatchContentType, `[{"op": "replace", "path": "/unknown/field", "value": 1}]`)
+		s.ErrorIs(err, apierrors.ErrPatchConflict)
+	})
+
+	s.Run("validates the result", func() {
+		_, err := s.patch(JSONPatchContentType, `[{"op": "remove", "path": "/amount_employees"}]`)
+		s.ErrorIs(err, apierrors.ErrInvalidBody)
+	})
+
+	s.Run("rejects the invalid operations", func() {
+		_, err := s.patch(JSONPatchContentType, `[{"op": "rename", "path": "/name"}]`)
+		s.ErrorIs(err, apierrors.ErrInvalidBody)
+	})
+}
+
+func (s *patchSuite) TestUnsupportedMediaType() {
+	for _, contentType := range []string{"", "application/json", "text/plain"} {
+		_, err := s.patch(contentType, `{"name": "xm group"}`)
+		s.ErrorIs(err, apierrors.ErrUnsupportedMediaType, contentType)
+	}
+}
+
+func TestPatchSuite(t *testing.T) {
+	suite.Run(t, new(patchSuite))
+}
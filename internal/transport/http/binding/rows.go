@@ -0,0 +1,246 @@
+package binding
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/enum"
+	"xm_test/internal/i18n"
+	"xm_test/internal/service/inputs"
+	"xm_test/internal/transport/http/schemas"
+)
+
+// ImportFields are the fields of the companies read from an imported file, named after their JSON key
+var ImportFields = []string{"name", "description", "amount_employees", "registered", "type"}
+
+// requiredColumns are the fields whose column must be in the header of a CSV file
+var requiredColumns = []string{"name", "amount_employees", "registered", "type"}
+
+// ParseColumnMapping parses the columns of an imported file holding the fields, given as field:column. The fields
+// that are not mapped are read from the column named after them.
+func ParseColumnMapping(mappings []string) (map[string]string, error) {
+	columns := make(map[string]string, len(ImportFields))
+	for _, field := range ImportFields {
+		columns[field] = field
+	}
+
+	for _, mapping := range mappings {
+		field, column, ok := strings.Cut(mapping, ":")
+		field, column = strings.TrimSpace(field), strings.TrimSpace(column)
+		if !ok || column == "" || !slices.Contains(ImportFields, field) {
+			return nil, apierrors.ErrInvalidQuery.WithMessageID("mapping", i18n.Params{
+				"mapping": mapping,
+				"fields":  strings.Join(ImportFields, ", "),
+			})
+		}
+		columns[field] = column
+	}
+	return columns, nil
+}
+
+// NewCompanyRows returns the rows of an imported file in the given format, which hold the fields in the columns of
+// the mapping returned by ParseColumnMapping, or in the columns named after them when the mapping is nil. Each row
+// is validated with the same rules as the body of the request creating a company, and the reason why it is invalid
+// is kept in the row. Only the errors reading the file, and a CSV header missing a required column, are returned.
+func NewCompanyRows(body io.ReadCloser, format enum.ImportFormat, columns map[string]string) (inputs.CompanyRows, error) {
+	if columns == nil {
+		columns, _ = ParseColumnMapping(nil)
+	}
+	rows := &companyRows{body: body, counter: &countingReader{r: body}}
+	switch format {
+	case enum.ImportCSV:
+		next, err := csvRows(rows.counter, columns)
+		if err != nil {
+			return nil, err
+		}
+		rows.next = next
+	case enum.ImportNDJSON:
+		rows.next = ndjsonRows(rows.counter, columns)
+	default:
+		return nil, apierrors.ErrUnsupportedMediaType.WithMessageID("type", i18n.Params{
+			"type":      format,
+			"supported": strings.Join([]string{enum.ImportCSV.String(), enum.ImportNDJSON.String()}, ", "),
+		})
+	}
+	return rows, nil
+}
+
+// companyRows reads the companies of an imported file
+type companyRows struct {
+	body    io.ReadCloser
+	counter *countingReader
+
+	// next returns the line and the fields of the next row. The fields are either decoded JSON values or raw JSON
+	// documents. An API error means that the row is invalid, while any other error means the file cannot be read.
+	next func() (int, map[string]any, error)
+}
+
+func (r *companyRows) Next() (*inputs.ImportRow, error) {
+	line, fields, err := r.next()
+	var apiErr *apierrors.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return &inputs.ImportRow{Line: line, Invalid: err}, nil
+	case err != nil:
+		return nil, err
+	}
+
+	row := &inputs.ImportRow{Line: line}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		row.Invalid = apierrors.ErrInvalidBody.WithMessageID("decode", i18n.Params{"reason": err})
+		return row, nil
+	}
+
+	var request schemas.CreateCompanyRequest
+	if err := decodeJSON(bytes.NewReader(data), &request); err != nil {
+		if !errors.As(err, &apiErr) {
+			err = apierrors.ErrInvalidBody.WithMessageID("decode", i18n.Params{"reason": err})
+		}
+		row.Invalid = err
+		return row, nil
+	}
+	company := inputs.CreateCompanyInput(request)
+	row.Company = &company
+	return row, nil
+}
+
+func (r *companyRows) BytesRead() int64 {
+	return r.counter.n
+}
+
+func (r *companyRows) Close() error {
+	return r.body.Close()
+}
+
+// csvRows reads the header of a CSV file and returns the function reading its rows. The columns are found by
+// their trimmed name regardless of the case, and the values of the numbers and booleans are converted, so they
+// are validated like the JSON values. The empty cells are left out.
+func csvRows(r io.Reader, columns map[string]string) (func() (int, map[string]any, error), error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, apierrors.ErrInvalidBody.WithMessageID("decode", i18n.Params{"reason": err})
+	}
+	indexes := make(map[string]int, len(header))
+	for i, name := range header {
+		// spreadsheets often start the files with a byte order mark
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		indexes[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	fields := make(map[string]int, len(columns))
+	var missing []string
+	for _, field := range ImportFields {
+		i, ok := indexes[strings.ToLower(columns[field])]
+		if ok {
+			fields[field] = i
+		} else if slices.Contains(requiredColumns, field) {
+			missing = append(missing, columns[field])
+		}
+	}
+	if len(missing) > 0 {
+		return nil, apierrors.ErrInvalidBody.WithMessageID("columns", i18n.Params{"columns": strings.Join(missing, ", ")})
+	}
+
+	return func() (int, map[string]any, error) {
+		record, err := reader.Read()
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return parseErr.StartLine, nil, apierrors.ErrInvalidBody.WithMessageID("decode", i18n.Params{"reason": parseErr.Err})
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		values := make(map[string]any, len(fields))
+		for field, i := range fields {
+			if i >= len(record) {
+				continue
+			}
+			if value := strings.TrimSpace(record[i]); value != "" {
+				values[field] = csvValue(field, value)
+			}
+		}
+		return line, values, nil
+	}, nil
+}
+
+// csvValue returns the JSON value of the cell of the field. The cells that are not numbers or booleans where
+// expected are kept as strings, so they fail to be decoded.
+func csvValue(field string, value string) any {
+	switch field {
+	case "amount_employees":
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	case "registered":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
+
+// ndjsonRows returns the function reading the rows of a NDJSON file, which are the objects of its non-empty lines.
+// The fields are found by the key of their column regardless of the case.
+func ndjsonRows(r io.Reader, columns map[string]string) func() (int, map[string]any, error) {
+	reader := bufio.NewReader(r)
+	line := 0
+	return func() (int, map[string]any, error) {
+		for {
+			data, err := reader.ReadBytes('\n')
+			if len(data) == 0 && err != nil {
+				return 0, nil, err
+			}
+			if err != nil && !errors.Is(err, io.EOF) {
+				return 0, nil, err
+			}
+			line++
+			if len(bytes.TrimSpace(data)) == 0 {
+				continue
+			}
+
+			var object map[string]json.RawMessage
+			if err := json.Unmarshal(data, &object); err != nil {
+				return line, nil, apierrors.ErrInvalidBody.WithMessageID("decode", i18n.Params{"reason": err})
+			}
+			keys := make(map[string]json.RawMessage, len(object))
+			for key, value := range object {
+				keys[strings.ToLower(strings.TrimSpace(key))] = value
+			}
+
+			values := make(map[string]any, len(columns))
+			for field, column := range columns {
+				if value, ok := keys[strings.ToLower(column)]; ok {
+					values[field] = value
+				}
+			}
+			return line, values, nil
+		}
+	}
+}
+
+// countingReader counts the bytes read from the underlying reader
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,133 @@
+package binding
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/enum"
+	"xm_test/internal/service/inputs"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type rowsSuite struct {
+	suite.Suite
+}
+
+// readRows reads every row of the file
+func (s *rowsSuite) readRows(format enum.ImportFormat, file string, columns map[string]string) []*inputs.ImportRow {
+	rows, err := NewCompanyRows(io.NopCloser(strings.NewReader(file)), format, columns)
+	s.Require().NoError(err)
+	defer rows.Close()
+
+	var read []*inputs.ImportRow
+	for {
+		row, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			s.Equal(int64(len(file)), rows.BytesRead())
+			return read
+		}
+		s.Require().NoError(err)
+		read = append(read, row)
+	}
+}
+
+func (s *rowsSuite) TestParseColumnMapping() {
+	s.Run("maps the fields to their columns", func() {
+		columns, err := ParseColumnMapping([]string{"name:Company Name", " amount_employees : Employees "})
+		s.Require().NoError(err)
+		s.Equal("Company Name", columns["name"])
+		s.Equal("Employees", columns["amount_employees"])
+		s.Equal("type", columns["type"])
+	})
+
+	for _, mapping := range []string{"name", "name:", "id:ID"} {
+		s.Run("rejects "+mapping, func() {
+			_, err := ParseColumnMapping([]string{mapping})
+			s.ErrorIs(err, apierrors.ErrInvalidQuery)
+		})
+	}
+}
+
+func (s *rowsSuite) TestCSVRows() {
+	s.Run("reads the companies", func() {
+		file := "\ufeffName, Employees ,registered,type,description\n" +
+			"xm,10,true,NonProfit,\n" +
+			"\n" +
+			"\"multi\nline\",5,false,Corporations,broker\n"
+		columns, err := ParseColumnMapping([]string{"amount_employees:employees"})
+		s.Require().NoError(err)
+
+		rows := s.readRows(enum.ImportCSV, file, columns)
+		s.Require().Len(rows, 2)
+		s.Require().NoError(rows[0].Invalid)
+		s.Equal(2, rows[0].Line)
+		s.Equal("xm", rows[0].Company.Name)
+		s.Equal("NonProfit", rows[0].Company.Type)
+		s.Empty(rows[0].Company.Description)
+		s.Equal(10, *rows[0].Company.AmountEmployees)
+		s.True(*rows[0].Company.Registered)
+		s.Require().NoError(rows[1].Invalid)
+		s.Equal(4, rows[1].Line)
+		s.Equal("multi\nline", rows[1].Company.Name)
+		s.Equal("broker", rows[1].Company.Description)
+	})
+
+	s.Run("validates each row", func() {
+		file := "name,amount_employees,registered,type\n" +
+			"xm,ten,true,NonProfit\n" +
+			"xm,10,true,Unknown\n" +
+			",10,true\n" +
+			"xm,\"10,true,NonProfit\n"
+
+		rows := s.readRows(enum.ImportCSV, file, nil)
+		s.Require().Len(rows, 4)
+		for _, row := range rows {
+			s.ErrorIs(row.Invalid, apierrors.ErrInvalidBody)
+			s.Nil(row.Company)
+		}
+		s.Contains(rows[0].Invalid.Error(), "cannot unmarshal string")
+		s.Contains(rows[1].Invalid.Error(), "type must be one of")
+		s.Contains(rows[2].Invalid.Error(), "name is required")
+		s.Equal(5, rows[3].Line)
+	})
+
+	s.Run("requires the columns", func() {
+		_, err := NewCompanyRows(io.NopCloser(strings.NewReader("name,type\n")), enum.ImportCSV, nil)
+		s.Require().ErrorIs(err, apierrors.ErrInvalidBody)
+		s.Contains(err.Error(), "amount_employees, registered")
+	})
+}
+
+func (s *rowsSuite) TestNDJSONRows() {
+	file := `{"Name": "xm", "employees": 10, "registered": true, "type": "NonProfit"}` + "\n" +
+		"\n" +
+		`{"name": "xm", "employees": "10", "registered": true, "type": "NonProfit"}` + "\n" +
+		`not json` + "\n" +
+		`{"name": "last", "employees": 1, "registered": false, "type": "Cooperative"}`
+	columns, err := ParseColumnMapping([]string{"amount_employees:employees"})
+	s.Require().NoError(err)
+
+	rows := s.readRows(enum.ImportNDJSON, file, columns)
+	s.Require().Len(rows, 4)
+	s.Require().NoError(rows[0].Invalid)
+	s.Equal(1, rows[0].Line)
+	s.Equal(10, *rows[0].Company.AmountEmployees)
+	s.ErrorIs(rows[1].Invalid, apierrors.ErrInvalidBody)
+	s.Equal(3, rows[1].Line)
+	s.ErrorIs(rows[2].Invalid, apierrors.ErrInvalidBody)
+	s.Equal(4, rows[2].Line)
+	s.Require().NoError(rows[3].Invalid)
+	s.Equal("last", rows[3].Company.Name)
+}
+
+func (s *rowsSuite) TestUnsupportedFormat() {
+	_, err := NewCompanyRows(io.NopCloser(strings.NewReader("")), enum.ImportFormat("xml"), nil)
+	s.ErrorIs(err, apierrors.ErrUnsupportedMediaType)
+}
+
+func TestRowsSuite(t *testing.T) {
+	suite.Run(t, new(rowsSuite))
+}
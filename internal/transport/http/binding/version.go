@@ -0,0 +1,23 @@
+package binding
+
+import (
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/i18n"
+)
+
+// ExpectedVersion returns the version of the company that a gRPC or GraphQL request modifies according to the
+// version sent by the client, or zero when it is not sent and the request modifies any version. It fails with
+// ErrPreconditionRequired when the version is required but missing, and with ErrPreconditionFailed when it does not
+// match any company, like the If-Match header of the HTTP requests.
+func ExpectedVersion(companyID string, version *int32, required bool) (int, error) {
+	if version == nil {
+		if required {
+			return 0, apierrors.ErrPreconditionRequired.WithMessageID("version", nil)
+		}
+		return 0, nil
+	}
+	if *version <= 0 {
+		return 0, apierrors.ErrPreconditionFailed.WithMessageID("version", i18n.Params{"id": companyID})
+	}
+	return int(*version), nil
+}
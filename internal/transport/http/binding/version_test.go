@@ -0,0 +1,45 @@
+package binding
+
+import (
+	"testing"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/helpers"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type versionSuite struct {
+	suite.Suite
+}
+
+func (s *versionSuite) TestExpectedVersion() {
+	tests := []struct {
+		name     string
+		version  *int32
+		required bool
+		expected int
+		err      error
+	}{
+		{name: "any version", expected: 0},
+		{name: "version", version: helpers.PointerValue[int32](3), expected: 3},
+		{name: "required version", version: helpers.PointerValue[int32](3), required: true, expected: 3},
+		{name: "missing required version", required: true, err: apierrors.ErrPreconditionRequired},
+		{name: "invalid version", version: helpers.PointerValue[int32](0), err: apierrors.ErrPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			version, err := ExpectedVersion("id", tt.version, tt.required)
+			if tt.err != nil {
+				s.ErrorIs(err, tt.err)
+				return
+			}
+			s.Require().NoError(err)
+			s.Equal(tt.expected, version)
+		})
+	}
+}
+
+func TestVersionSuite(t *testing.T) {
+	suite.Run(t, new(versionSuite))
+}
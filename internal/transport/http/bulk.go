@@ -0,0 +1,168 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/db/models"
+	"xm_test/internal/enum"
+	"xm_test/internal/i18n"
+	"xm_test/internal/logging"
+	"xm_test/internal/service/inputs"
+	"xm_test/internal/transport/http/binding"
+	"xm_test/internal/transport/http/schemas"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"golang.org/x/text/language"
+)
+
+// bulkCompanies creates, updates and deletes companies in bulk. Each operation is validated on its own, and the
+// outcome of each of them is returned: the status is 200 when every operation is applied, and 207 otherwise.
+func (h *handler) bulkCompanies(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("bulk companies endpoint called")
+
+	logger.Debugf("decoding request body")
+	var body schemas.BulkCompaniesRequest
+	if err := binding.DecodeBody(r, &body); err != nil {
+		h.wrapError(w, r, invalidBody(err))
+		return
+	}
+	logger.Debugf("request body decoded")
+
+	mode := enum.BulkTransactional
+	if body.Mode != "" {
+		mode = enum.BulkMode(body.Mode)
+	}
+	input := &inputs.BulkCompaniesInput{
+		Operations:    make([]inputs.BulkOperation, len(body.Operations)),
+		Transactional: mode == enum.BulkTransactional,
+	}
+	versions := make([][]int, len(body.Operations))
+	for i, operation := range body.Operations {
+		input.Operations[i], versions[i] = h.bulkOperation(operation)
+	}
+	if err := h.matchBulkVersions(r.Context(), input.Operations, versions); err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+
+	logger.Debugf("writing %d companies in bulk in %s mode", len(input.Operations), mode)
+	ops, err := h.cs.BulkWriteCompanies(r.Context(), input)
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+
+	// the errors of the operations are translated like the error of the request would be
+	tag := i18n.Match(r.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", tag.String())
+	w.Header().Add("Vary", "Accept-Language")
+
+	response := schemas.BulkCompaniesResponse{Mode: mode.String(), Results: make([]schemas.BulkOperationResult, len(ops))}
+	for i, op := range ops {
+		response.Results[i] = bulkResult(i, op, tag)
+		if op.Err != nil {
+			response.Failed++
+			continue
+		}
+		response.Succeeded++
+	}
+
+	logger.Infof("%d companies written in bulk, %d failed", response.Succeeded, response.Failed)
+	if response.Failed > 0 {
+		render.Status(r, http.StatusMultiStatus)
+	}
+	h.codecs.Render(w, r, response)
+}
+
+// bulkOperation returns the input of an operation of a bulk request. The operation is validated like the request
+// applying it alone would be, and the reason why it is invalid is kept in the input. When its If-Match lists several
+// versions, they are returned to be matched against the current company by matchBulkVersions.
+func (h *handler) bulkOperation(operation schemas.BulkOperationRequest) (inputs.BulkOperation, []int) {
+	input := inputs.BulkOperation{Type: enum.BulkOperation(operation.Op), ID: operation.ID}
+	if err := binding.Validate(&operation); err != nil {
+		input.Invalid = err
+		return input, nil
+	}
+	if operation.Company != nil {
+		company := inputs.UpdateCompany(*operation.Company)
+		input.Company = &company
+	}
+	if input.Type == enum.BulkCreate {
+		return input, nil
+	}
+
+	if err := uuid.Validate(operation.ID); err != nil {
+		input.Invalid = apierrors.ErrInvalidUUID
+		return input, nil
+	}
+	versions, err := h.ifMatchCondition(operation.IfMatch, operation.ID)
+	if err != nil || len(versions) > 1 {
+		input.Invalid = err
+		return input, versions
+	}
+	if len(versions) == 1 {
+		input.Version = versions[0]
+	}
+	return input, nil
+}
+
+// matchBulkVersions sets the version of the operations whose If-Match lists several versions to the version of the
+// current company when it is one of them. The companies are read in a single query.
+func (h *handler) matchBulkVersions(ctx context.Context, ops []inputs.BulkOperation, versions [][]int) error {
+	var ids []string
+	for i := range ops {
+		if len(versions[i]) > 1 {
+			ids = append(ids, ops[i].ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	companies, err := h.cs.GetCompaniesByIDs(ctx, ids)
+	if err != nil {
+		return err
+	}
+	current := make(map[uuid.UUID]int, len(companies))
+	for _, company := range companies {
+		current[company.ID] = company.Version
+	}
+
+	for i := range ops {
+		if len(versions[i]) <= 1 {
+			continue
+		}
+		version, ok := current[uuid.MustParse(ops[i].ID)]
+		if !ok {
+			ops[i].Invalid = apierrors.ErrCompanyNotFound.WithMessageID("id", i18n.Params{"id": ops[i].ID})
+			continue
+		}
+		ops[i].Version, ops[i].Invalid = matchingVersion(ops[i].ID, versions[i], version)
+	}
+	return nil
+}
+
+// bulkResult returns the outcome of the operation at the index of a bulk request, with its error translated to the
+// language
+func bulkResult(index int, op *models.CompanyOperation, tag language.Tag) schemas.BulkOperationResult {
+	result := schemas.BulkOperationResult{Index: index, Op: op.Type.String(), Status: http.StatusOK}
+	if op.Err != nil {
+		var apiError *apierrors.APIError
+		if !errors.As(op.Err, &apiError) {
+			apiError = apierrors.ErrInternalServer.Wrap(op.Err)
+		}
+		result.Status = apiError.HTTPStatus
+		result.Error = apiError.Localize(tag)
+		return result
+	}
+
+	result.ID = op.Result.ID.String()
+	if op.Type != enum.BulkDelete {
+		result.ETag = etag(op.Result.Version)
+	}
+	return result
+}
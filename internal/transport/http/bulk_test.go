@@ -0,0 +1,139 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/db/models"
+	"xm_test/internal/enum"
+	"xm_test/internal/helpers"
+	"xm_test/internal/service"
+	"xm_test/internal/service/inputs"
+	"xm_test/internal/transport/http/schemas"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"golang.org/x/text/language"
+)
+
+type bulkSuite struct {
+	suite.Suite
+}
+
+func (s *bulkSuite) TestBulkOperation() {
+	company := &schemas.UpdateCompanyRequest{
+		Name:            "xm",
+		AmountEmployees: helpers.PointerValue(10),
+		Registered:      helpers.PointerValue(true),
+		Type:            enum.Corporation.String(),
+	}
+	id := uuid.NewString()
+
+	tests := []struct {
+		name      string
+		operation schemas.BulkOperationRequest
+		version   int
+		invalid   error
+	}{
+		{name: "creates a company", operation: schemas.BulkOperationRequest{Op: "create", Company: company}},
+		{name: "updates a version", operation: schemas.BulkOperationRequest{Op: "update", ID: id, IfMatch: `"2"`, Company: company}, version: 2},
+		{name: "deletes any version", operation: schemas.BulkOperationRequest{Op: "delete", ID: id}},
+		{name: "requires the company", operation: schemas.BulkOperationRequest{Op: "create"}, invalid: apierrors.ErrInvalidBody},
+		{name: "requires the id", operation: schemas.BulkOperationRequest{Op: "delete"}, invalid: apierrors.ErrInvalidBody},
+		{name: "validates the company", operation: schemas.BulkOperationRequest{Op: "create", Company: &schemas.UpdateCompanyRequest{}}, invalid: apierrors.ErrInvalidBody},
+		{name: "validates the type", operation: schemas.BulkOperationRequest{Op: "upsert"}, invalid: apierrors.ErrInvalidBody},
+		{name: "validates the id", operation: schemas.BulkOperationRequest{Op: "delete", ID: "invalid"}, invalid: apierrors.ErrInvalidUUID},
+		{name: "validates the entity tag", operation: schemas.BulkOperationRequest{Op: "delete", ID: id, IfMatch: "2"}, invalid: apierrors.ErrPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			input, versions := (&handler{}).bulkOperation(tt.operation)
+			s.Equal(enum.BulkOperation(tt.operation.Op), input.Type)
+			s.Empty(versions)
+			if tt.invalid != nil {
+				s.ErrorIs(input.Invalid, tt.invalid)
+				return
+			}
+			s.Require().NoError(input.Invalid)
+			s.Equal(tt.version, input.Version)
+		})
+	}
+
+	s.Run("defers the entity tags listing several versions", func() {
+		input, versions := (&handler{}).bulkOperation(schemas.BulkOperationRequest{Op: "delete", ID: id, IfMatch: `"1", "2"`})
+		s.Require().NoError(input.Invalid)
+		s.Equal([]int{1, 2}, versions)
+	})
+}
+
+// versionsService returns the stored companies and counts the queries reading them
+type versionsService struct {
+	service.CompanyService
+	companies []*models.CompanyModel
+	queries   int
+}
+
+func (v *versionsService) GetCompaniesByIDs(ctx context.Context, ids []string) ([]*models.CompanyModel, error) {
+	v.queries++
+	return v.companies, nil
+}
+
+func (s *bulkSuite) TestMatchBulkVersions() {
+	first, second := &models.CompanyModel{ID: uuid.New(), Version: 2}, &models.CompanyModel{ID: uuid.New(), Version: 5}
+	cs := &versionsService{companies: []*models.CompanyModel{first, second}}
+	h := &handler{cs: cs}
+
+	ops := []inputs.BulkOperation{
+		{Type: enum.BulkUpdate, ID: first.ID.String()},
+		{Type: enum.BulkDelete, ID: second.ID.String()},
+		{Type: enum.BulkDelete, ID: uuid.NewString()},
+		{Type: enum.BulkDelete, ID: first.ID.String(), Version: 2},
+	}
+	s.Require().NoError(h.matchBulkVersions(context.Background(), ops, [][]int{{1, 2}, {3, 4}, {1, 2}, nil}))
+
+	s.Equal(1, cs.queries)
+	s.Require().NoError(ops[0].Invalid)
+	s.Equal(2, ops[0].Version)
+	s.ErrorIs(ops[1].Invalid, apierrors.ErrPreconditionFailed)
+	s.ErrorIs(ops[2].Invalid, apierrors.ErrCompanyNotFound)
+	s.Require().NoError(ops[3].Invalid)
+	s.Equal(2, ops[3].Version)
+
+	s.Run("does not read the companies when no entity tag lists several versions", func() {
+		s.Require().NoError(h.matchBulkVersions(context.Background(), ops[3:], [][]int{nil}))
+		s.Equal(1, cs.queries)
+	})
+}
+
+func (s *bulkSuite) TestBulkResult() {
+	company := &models.CompanyModel{ID: uuid.New(), Version: 3}
+
+	s.Run("applied", func() {
+		result := bulkResult(1, &models.CompanyOperation{Type: enum.BulkUpdate, Result: company}, language.English)
+		s.Equal(schemas.BulkOperationResult{Index: 1, Op: "update", Status: http.StatusOK, ID: company.ID.String(), ETag: `"3"`}, result)
+	})
+
+	s.Run("deleted", func() {
+		result := bulkResult(0, &models.CompanyOperation{Type: enum.BulkDelete, Result: company}, language.English)
+		s.Empty(result.ETag)
+	})
+
+	s.Run("failed", func() {
+		result := bulkResult(0, &models.CompanyOperation{Type: enum.BulkCreate, Err: apierrors.ErrCompanyAlreadyExists}, language.Spanish)
+		s.Equal(http.StatusConflict, result.Status)
+		s.Equal("la empresa ya existe", result.Error.Message)
+	})
+
+	s.Run("hides the internal errors", func() {
+		result := bulkResult(0, &models.CompanyOperation{Type: enum.BulkCreate, Err: errors.New("connection refused")}, language.English)
+		s.Equal(http.StatusInternalServerError, result.Status)
+		s.Equal(apierrors.ErrInternalServer.Message, result.Error.Message)
+	})
+}
+
+func TestBulkSuite(t *testing.T) {
+	suite.Run(t, new(bulkSuite))
+}
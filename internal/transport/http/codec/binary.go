@@ -0,0 +1,69 @@
+package codec
+
+import (
+	"io"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// MessagePack encodes and decodes MessagePack documents with the same structure as the JSON ones
+var MessagePack Codec = msgpackCodec{}
+
+type msgpackCodec struct{}
+
+// Encode writes the MessagePack document of v, with the keys of the maps sorted so that it is deterministic
+func (msgpackCodec) Encode(w io.Writer, v any) error {
+	tree, err := toTree(v)
+	if err != nil {
+		return err
+	}
+	enc := msgpack.NewEncoder(w)
+	enc.SetSortMapKeys(true)
+	return enc.Encode(tree)
+}
+
+func (msgpackCodec) Decode(r io.Reader, v any) error {
+	var tree any
+	if err := msgpack.NewDecoder(r).Decode(&tree); err != nil {
+		return err
+	}
+	return fromTree(tree, v)
+}
+
+// CBOR encodes and decodes CBOR documents with the same structure as the JSON ones
+var CBOR Codec = cborCodec{
+	enc: must(cbor.EncOptions{Sort: cbor.SortBytewiseLexical}.EncMode()),
+	dec: must(cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]any(nil))}.DecMode()),
+}
+
+type cborCodec struct {
+	enc cbor.EncMode
+	dec cbor.DecMode
+}
+
+// Encode writes the CBOR document of v, with the keys of the maps sorted so that it is deterministic
+func (c cborCodec) Encode(w io.Writer, v any) error {
+	tree, err := toTree(v)
+	if err != nil {
+		return err
+	}
+	return c.enc.NewEncoder(w).Encode(tree)
+}
+
+func (c cborCodec) Decode(r io.Reader, v any) error {
+	var tree any
+	if err := c.dec.NewDecoder(r).Decode(&tree); err != nil {
+		return err
+	}
+	return fromTree(tree, v)
+}
+
+// must returns the mode, panicking when its options are not valid
+func must[T any](mode T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return mode
+}
@@ -0,0 +1,157 @@
+package codec
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/http"
+	"xm_test/internal/transport/http/negotiation"
+
+	"github.com/go-chi/render"
+)
+
+const (
+	// JSONMediaType is the media type of the JSON documents, the default one of the API
+	JSONMediaType = "application/json"
+
+	// XMLMediaType is the media type of the XML documents
+	XMLMediaType = "application/xml"
+
+	// MessagePackMediaType is the media type of the MessagePack documents
+	MessagePackMediaType = "application/msgpack"
+
+	// CBORMediaType is the media type of the CBOR (RFC 8949) documents
+	CBORMediaType = "application/cbor"
+)
+
+// Codec encodes the response bodies and decodes the request bodies of a media type
+type Codec interface {
+	Encode(w io.Writer, v any) error // Encode writes v to w
+	Decode(r io.Reader, v any) error // Decode reads the document of r into v, without validating it
+}
+
+// ErrorRenderer renders the failure to encode a response body
+type ErrorRenderer func(w http.ResponseWriter, r *http.Request, err error)
+
+// Option configures a registry
+type Option func(*Registry)
+
+// WithErrorRenderer renders the failures to encode the response bodies with the given renderer, instead of a plain
+// text internal server error
+func WithErrorRenderer(renderer ErrorRenderer) Option {
+	return func(reg *Registry) {
+		reg.encodeError = renderer
+	}
+}
+
+// Registry holds the codecs keyed by media type. The first registered media type is the default one, which is
+// used when the client does not ask for any media type.
+type Registry struct {
+	codecs     map[string]Codec
+	mediaTypes []string // media types by preference, including the aliases
+	primary    []string // first media type of each codec, by preference
+
+	// renders the failures to encode a response body
+	encodeError ErrorRenderer
+}
+
+// NewRegistry returns an empty registry configured with the options
+func NewRegistry(opts ...Option) *Registry {
+	reg := &Registry{codecs: make(map[string]Codec), encodeError: renderPlainError}
+	for _, opt := range opts {
+		opt(reg)
+	}
+	return reg
+}
+
+// Default is the registry of the codecs of the API, with JSON as the default media type. It is meant to decode the
+// requests and negotiate the media types: the responses are rendered with a registry given the error renderer of
+// the API.
+var Default = NewDefaultRegistry()
+
+// NewDefaultRegistry returns a registry of the codecs of the API configured with the options, with JSON as the
+// default media type
+func NewDefaultRegistry(opts ...Option) *Registry {
+	r := NewRegistry(opts...)
+	r.Register(JSON, JSONMediaType)
+	r.Register(XML, XMLMediaType, "text/xml")
+	r.Register(MessagePack, MessagePackMediaType, "application/x-msgpack", "application/vnd.msgpack")
+	r.Register(CBOR, CBORMediaType)
+	return r
+}
+
+// Register registers the codec of the media types, which are aliases of the first one
+func (reg *Registry) Register(codec Codec, mediaTypes ...string) {
+	if len(mediaTypes) == 0 {
+		return
+	}
+	reg.primary = append(reg.primary, mediaTypes[0])
+	for _, mediaType := range mediaTypes {
+		reg.codecs[mediaType] = codec
+		reg.mediaTypes = append(reg.mediaTypes, mediaType)
+	}
+}
+
+// MediaTypes returns the media types of the codecs, by preference, without their aliases
+func (reg *Registry) MediaTypes() []string {
+	return reg.primary
+}
+
+// Lookup returns the codec of the media type of the Content-Type header. An empty header is given the default
+// media type, so the clients that do not set it keep sending JSON.
+func (reg *Registry) Lookup(contentType string) (Codec, bool) {
+	if contentType == "" {
+		return reg.defaultCodec()
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	codec, ok := reg.codecs[mediaType]
+	return codec, ok
+}
+
+// Negotiate returns the media type preferred by the Accept header, along with its codec
+func (reg *Registry) Negotiate(accept string) (string, Codec, bool) {
+	mediaType, ok := negotiation.Negotiate(accept, reg.mediaTypes)
+	if !ok {
+		return "", nil, false
+	}
+	return mediaType, reg.codecs[mediaType], true
+}
+
+// Render writes v to the response, encoded with the codec negotiated with the Accept header of the request, with
+// the status set by render.Status if any. The default media type is used when none is acceptable, so the clients
+// always get a response; the routes rejecting them check the header before handling the request.
+func (reg *Registry) Render(w http.ResponseWriter, r *http.Request, v any) {
+	mediaType, codec, ok := reg.Negotiate(r.Header.Get("Accept"))
+	if !ok {
+		mediaType, codec = reg.primary[0], reg.codecs[reg.primary[0]]
+	}
+
+	// the document is encoded before writing the status, so that an encoding failure is reported
+	var buf bytes.Buffer
+	if err := codec.Encode(&buf, v); err != nil {
+		reg.encodeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Add("Vary", "Accept")
+	if status, ok := r.Context().Value(render.StatusCtxKey).(int); ok {
+		w.WriteHeader(status)
+	}
+	w.Write(buf.Bytes())
+}
+
+func (reg *Registry) defaultCodec() (Codec, bool) {
+	if len(reg.primary) == 0 {
+		return nil, false
+	}
+	return reg.codecs[reg.primary[0]], true
+}
+
+// renderPlainError renders the failure to encode a response body as a plain text internal server error
+func renderPlainError(w http.ResponseWriter, r *http.Request, err error) {
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
@@ -0,0 +1,181 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"xm_test/internal/db/models"
+	"xm_test/internal/helpers"
+	"xm_test/internal/transport/http/schemas"
+
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type codecSuite struct {
+	suite.Suite
+}
+
+func (s *codecSuite) TestRoundTrip() {
+	request := schemas.CreateCompanyRequest{
+		Name:            "xm <&>",
+		Description:     "",
+		AmountEmployees: helpers.PointerValue(10),
+		Registered:      helpers.PointerValue(false),
+		Type:            "NonProfit",
+	}
+
+	for _, mediaType := range Default.MediaTypes() {
+		s.Run(mediaType, func() {
+			codec, ok := Default.Lookup(mediaType + "; charset=utf-8")
+			s.Require().True(ok)
+
+			var buf bytes.Buffer
+			s.Require().NoError(codec.Encode(&buf, request))
+			var decoded schemas.CreateCompanyRequest
+			s.Require().NoError(codec.Decode(&buf, &decoded))
+			s.Equal(request, decoded)
+		})
+	}
+}
+
+func (s *codecSuite) TestXML() {
+	s.Run("encodes the JSON document", func() {
+		id := uuid.New()
+		var buf bytes.Buffer
+		s.Require().NoError(XML.Encode(&buf, map[string]any{
+			"company":  models.CompanyModel{ID: id, Name: "xm", AmountEmployees: 3},
+			"ids":      []any{1, nil, 2.5},
+			"$schema":  "draft",
+			"optional": nil,
+		}))
+		s.Equal(`<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+			`<response>`+
+			`<entry key="$schema">draft</entry>`+
+			`<company><id>`+id.String()+`</id><name>xm</name><description></description><amount_employees>3</amount_employees><registered>false</registered><type></type></company>`+
+			`<ids><item>1</item><item></item><item>2.5</item></ids>`+
+			`</response>`, buf.String())
+	})
+
+	s.Run("decodes following the types of the fields", func() {
+		var body schemas.BulkCompaniesRequest
+		s.Require().NoError(XML.Decode(strings.NewReader(`<response>
+			<mode>best_effort</mode>
+			<operations>
+				<item><op>create</op><company><name>123</name><amount_employees>5</amount_employees><registered>true</registered></company></item>
+				<item><op>delete</op><id>`+uuid.Nil.String()+`</id></item>
+			</operations>
+		</response>`), &body))
+		s.Equal("best_effort", body.Mode)
+		s.Require().Len(body.Operations, 2)
+		s.Equal("123", body.Operations[0].Company.Name)
+		s.Equal(5, *body.Operations[0].Company.AmountEmployees)
+		s.True(*body.Operations[0].Company.Registered)
+		s.Equal(uuid.Nil.String(), body.Operations[1].ID)
+	})
+
+	s.Run("fails on the values of another type", func() {
+		var body schemas.CreateCompanyRequest
+		err := XML.Decode(strings.NewReader(`<response><amount_employees>ten</amount_employees></response>`), &body)
+		var typeErr *json.UnmarshalTypeError
+		s.ErrorAs(err, &typeErr)
+	})
+
+	s.Run("fails on malformed documents", func() {
+		var body schemas.CreateCompanyRequest
+		s.Error(XML.Decode(strings.NewReader(`<response><name>xm</response>`), &body))
+		s.Error(XML.Decode(strings.NewReader(``), &body))
+	})
+}
+
+func (s *codecSuite) TestBinary() {
+	for name, codec := range map[string]Codec{"msgpack": MessagePack, "cbor": CBOR} {
+		s.Run(name, func() {
+			var buf bytes.Buffer
+			s.Require().NoError(codec.Encode(&buf, schemas.OkResponse{Message: "ok"}))
+
+			// the documents have the keys of the JSON documents
+			var decoded map[string]any
+			s.Require().NoError(codec.Decode(bytes.NewReader(buf.Bytes()), &decoded))
+			s.Equal(map[string]any{"message": "ok"}, decoded)
+
+			s.Error(codec.Decode(strings.NewReader("\xc1"), &decoded))
+		})
+	}
+}
+
+func (s *codecSuite) TestLookup() {
+	codec, ok := Default.Lookup("")
+	s.True(ok)
+	s.Equal(JSON, codec)
+
+	codec, ok = Default.Lookup("text/xml")
+	s.True(ok)
+	s.Equal(XML, codec)
+
+	_, ok = Default.Lookup("application/x-www-form-urlencoded")
+	s.False(ok)
+}
+
+func (s *codecSuite) TestRender() {
+	tests := []struct {
+		name        string
+		accept      string
+		contentType string
+	}{
+		{name: "defaults to json", accept: "", contentType: JSONMediaType},
+		{name: "any media type", accept: "*/*", contentType: JSONMediaType},
+		{name: "negotiated media type", accept: "application/json;q=0.5, application/msgpack", contentType: MessagePackMediaType},
+		{name: "alias", accept: "text/xml", contentType: "text/xml"},
+		{name: "falls back to json", accept: "text/csv", contentType: JSONMediaType},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept", tt.accept)
+			render.Status(r, http.StatusCreated)
+			w := httptest.NewRecorder()
+			Default.Render(w, r, schemas.OkResponse{Message: "ok"})
+
+			s.Equal(http.StatusCreated, w.Code)
+			s.Equal(tt.contentType, w.Header().Get("Content-Type"))
+			s.Equal("Accept", w.Header().Get("Vary"))
+
+			codec, ok := Default.Lookup(tt.contentType)
+			s.Require().True(ok)
+			var decoded schemas.OkResponse
+			s.Require().NoError(codec.Decode(w.Body, &decoded))
+			s.Equal("ok", decoded.Message)
+		})
+	}
+}
+
+func (s *codecSuite) TestRenderEncodeError() {
+	s.Run("renders a plain text error by default", func() {
+		w := httptest.NewRecorder()
+		NewDefaultRegistry().Render(w, httptest.NewRequest(http.MethodGet, "/", nil), map[string]any{"callback": func() {}})
+		s.Equal(http.StatusInternalServerError, w.Code)
+		s.Contains(w.Header().Get("Content-Type"), "text/plain")
+	})
+
+	s.Run("renders the error with the renderer of the registry", func() {
+		var rendered error
+		registry := NewDefaultRegistry(WithErrorRenderer(func(w http.ResponseWriter, r *http.Request, err error) {
+			rendered = err
+			w.WriteHeader(http.StatusTeapot)
+		}))
+		w := httptest.NewRecorder()
+		registry.Render(w, httptest.NewRequest(http.MethodGet, "/", nil), map[string]any{"callback": func() {}})
+		s.Equal(http.StatusTeapot, w.Code)
+		s.Error(rendered)
+	})
+}
+
+func TestCodecSuite(t *testing.T) {
+	suite.Run(t, new(codecSuite))
+}
@@ -0,0 +1,70 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// JSON encodes and decodes JSON documents
+var JSON Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+// Encode writes the JSON document of v followed by a newline, escaping the HTML characters as render.JSON does
+func (jsonCodec) Encode(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(true)
+	return enc.Encode(v)
+}
+
+func (jsonCodec) Decode(r io.Reader, v any) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// toTree returns the JSON representation of v as maps, slices and scalars, which the codecs of the other media
+// types encode. Going through JSON keeps the field names, the omitted fields and the custom encodings of the JSON
+// documents, so every media type carries the same document. The integers are kept as such.
+func toTree(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var tree any
+	if err := dec.Decode(&tree); err != nil {
+		return nil, err
+	}
+	return normalize(tree), nil
+}
+
+// fromTree decodes the document decoded by the codec of another media type into v, as if it was sent as JSON
+func fromTree(tree any, v any) error {
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	return JSON.Decode(bytes.NewReader(data), v)
+}
+
+// normalize replaces the JSON numbers of the tree by integers, or by floats when they are not integers
+func normalize(tree any) any {
+	switch value := tree.(type) {
+	case map[string]any:
+		for key, item := range value {
+			value[key] = normalize(item)
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = normalize(item)
+		}
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i
+		}
+		f, _ := value.Float64()
+		return f
+	}
+	return tree
+}
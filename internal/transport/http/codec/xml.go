@@ -0,0 +1,302 @@
+package codec
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+const (
+	xmlRoot  = "response" // element holding the document
+	xmlItem  = "item"     // element of each item of an array
+	xmlEntry = "entry"    // element of the keys that are not valid element names, which are held by its key attribute
+)
+
+var (
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+	rawMessageType      = reflect.TypeFor[json.RawMessage]()
+)
+
+// XML encodes and decodes XML documents mirroring the JSON ones. The document is held by a response element, the
+// keys of the objects are child elements, and the items of the arrays are item elements, e.g. {"ids": [1, 2]} is
+// <response><ids><item>1</item><item>2</item></ids></response>. The keys that are not valid element names are
+// entry elements with a key attribute. The null values of the objects are omitted.
+var XML Codec = xmlCodec{}
+
+type xmlCodec struct{}
+
+// Encode writes the XML document of v, keeping the order of the keys of its JSON document
+func (xmlCodec) Encode(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	if err := encodeXML(enc, dec, xmlRoot); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// encodeXML writes the next value of the JSON document as the element with the name
+func encodeXML(enc *xml.Encoder, dec *json.Decoder, name string) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	start := xmlElement(name)
+
+	// the null items are kept, so the positions of the other items do not change
+	if token == nil {
+		if name != xmlRoot && name != xmlItem {
+			return nil
+		}
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+		return enc.EncodeToken(start.End())
+	}
+
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+	switch token := token.(type) {
+	case json.Delim:
+		for dec.More() {
+			childName := xmlItem
+			if token == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				childName = key.(string)
+			}
+			if err := encodeXML(enc, dec, childName); err != nil {
+				return err
+			}
+		}
+		// the closing delimiter of the object or array
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+	default:
+		if err := enc.EncodeToken(xml.CharData(fmt.Sprint(token))); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// xmlElement returns the element of the key, or an entry element when the key is not a valid element name
+func xmlElement(key string) xml.StartElement {
+	if isXMLName(key) {
+		return xml.StartElement{Name: xml.Name{Local: key}}
+	}
+	return xml.StartElement{Name: xml.Name{Local: xmlEntry}, Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: key}}}
+}
+
+// isXMLName tells whether the key is a valid element name without namespace
+func isXMLName(key string) bool {
+	if key == "" || strings.HasPrefix(strings.ToLower(key), "xml") {
+		return false
+	}
+	for i, r := range key {
+		switch {
+		case unicode.IsLetter(r) || r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Decode reads the XML document into v. Since the elements do not tell the type of their value, the document is
+// converted to JSON following the types of the fields of v, and then decoded as if it was sent as JSON.
+func (xmlCodec) Decode(r io.Reader, v any) error {
+	root, err := parseXML(r)
+	if err != nil {
+		return err
+	}
+	return fromTree(xmlValue(root, reflect.TypeOf(v)), v)
+}
+
+// xmlNode is an element of an XML document
+type xmlNode struct {
+	key      string // name of the element, or key attribute of the entry elements
+	text     strings.Builder
+	children []*xmlNode
+}
+
+// parseXML returns the root element of the XML document
+func parseXML(r io.Reader) (*xmlNode, error) {
+	dec := xml.NewDecoder(r)
+	var root *xmlNode
+	var stack []*xmlNode
+	for {
+		token, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			node := &xmlNode{key: token.Name.Local}
+			if node.key == xmlEntry {
+				for _, attr := range token.Attr {
+					if attr.Name.Local == "key" {
+						node.key = attr.Value
+					}
+				}
+			}
+			switch {
+			case len(stack) > 0:
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			case root != nil:
+				return nil, errors.New("the XML document has more than one root element")
+			default:
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text.Write(token)
+			}
+		}
+	}
+	if root == nil {
+		return nil, errors.New("the XML document is empty")
+	}
+	return root, nil
+}
+
+// xmlValue returns the JSON value of the element, given the type it is decoded into. The scalars that are not valid
+// JSON literals of their type are kept as strings, so that decoding them fails as it does with JSON.
+func xmlValue(node *xmlNode, t reflect.Type) any {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == nil || t == rawMessageType || t.Kind() == reflect.Interface:
+		return inferXMLValue(node)
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		return strings.TrimSpace(node.text.String())
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		fields := jsonFields(t)
+		object := make(map[string]any, len(node.children))
+		for _, child := range node.children {
+			object[child.key] = xmlValue(child, fields[child.key])
+		}
+		return object
+	case reflect.Map:
+		object := make(map[string]any, len(node.children))
+		for _, child := range node.children {
+			object[child.key] = xmlValue(child, t.Elem())
+		}
+		return object
+	case reflect.Slice, reflect.Array:
+		// the byte slices are base64 strings, as in JSON
+		if t.Elem().Kind() == reflect.Uint8 {
+			return strings.TrimSpace(node.text.String())
+		}
+		items := make([]any, len(node.children))
+		for i, child := range node.children {
+			items[i] = xmlValue(child, t.Elem())
+		}
+		return items
+	case reflect.String:
+		return node.text.String()
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+		text := strings.TrimSpace(node.text.String())
+		if json.Valid([]byte(text)) {
+			return json.RawMessage(text)
+		}
+		return text
+	}
+	return inferXMLValue(node)
+}
+
+// inferXMLValue returns the JSON value of an element whose type is unknown: a string when it has no children, an
+// array when they are all items, and an object otherwise
+func inferXMLValue(node *xmlNode) any {
+	if len(node.children) == 0 {
+		return node.text.String()
+	}
+
+	array := true
+	for _, child := range node.children {
+		array = array && child.key == xmlItem
+	}
+	if array {
+		items := make([]any, len(node.children))
+		for i, child := range node.children {
+			items[i] = inferXMLValue(child)
+		}
+		return items
+	}
+
+	object := make(map[string]any, len(node.children))
+	for _, child := range node.children {
+		object[child.key] = inferXMLValue(child)
+	}
+	return object
+}
+
+// jsonFields returns the types of the fields of the struct, keyed by their JSON name, including the fields of the
+// embedded structs
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				// the fields of the outer struct take precedence over the embedded ones
+				for key, typ := range jsonFields(embedded) {
+					if _, ok := fields[key]; !ok {
+						fields[key] = typ
+					}
+				}
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
@@ -0,0 +1,101 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/conf"
+	"xm_test/internal/db"
+	"xm_test/internal/db/models"
+	"xm_test/internal/metrics"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// slowDB blocks the company lookups until their context is done
+type slowDB struct {
+	db.DatabaseAdapter
+	started chan struct{}
+}
+
+func (d *slowDB) GetCompanyByID(ctx context.Context, id string) (*models.CompanyModel, error) {
+	d.started <- struct{}{}
+	<-ctx.Done()
+	return nil, apierrors.ErrInternalServer.WithMessage("failed to get company by id").Wrap(ctx.Err())
+}
+
+type deadlineSuite struct {
+	suite.Suite
+	db    *slowDB
+	logs  *observer.ObservedLogs
+	url   string
+	serve func(*http.Request) *httptest.ResponseRecorder
+}
+
+func (s *deadlineSuite) SetupSuite() {
+	conf.NewConfig()
+	conf.GlobalConfig.RequestTimeout = 50 * time.Millisecond
+
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core).Sugar()
+	s.db = &slowDB{started: make(chan struct{}, 1)}
+	s.logs = logs
+
+	transport := &httpTransport{logger: logger, handler: newHandler(logger, zap.NewAtomicLevel(), s.db, nil, nil)}
+	router, err := transport.router()
+	s.Require().NoError(err)
+	s.url = "/company/" + uuid.NewString()
+	s.serve = func(r *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+}
+
+// accessLogStatus returns the status of the last access log entry
+func (s *deadlineSuite) accessLogStatus() any {
+	entries := s.logs.FilterMessage("request completed").All()
+	s.Require().NotEmpty(entries)
+	return entries[len(entries)-1].ContextMap()["status"]
+}
+
+func (s *deadlineSuite) TestDeadline() {
+	s.Run("times out the slow requests", func() {
+		w := s.serve(httptest.NewRequest(http.MethodGet, s.url, nil))
+		<-s.db.started
+		s.Equal(http.StatusGatewayTimeout, w.Code)
+
+		var body apierrors.APIError
+		s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &body))
+		s.Equal(apierrors.ErrRequestTimeout.Code, body.Code)
+		s.EqualValues(http.StatusGatewayTimeout, s.accessLogStatus())
+	})
+
+	s.Run("reports the requests closed by the client", func() {
+		before := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/company/{id}", "499"))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			<-s.db.started
+			cancel()
+		}()
+		w := s.serve(httptest.NewRequest(http.MethodGet, s.url, nil).WithContext(ctx))
+		s.Equal(apierrors.StatusClientClosedRequest, w.Code)
+		s.EqualValues(apierrors.StatusClientClosedRequest, s.accessLogStatus())
+
+		after := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/company/{id}", "499"))
+		s.Equal(1.0, after-before)
+	})
+}
+
+func TestDeadlineSuite(t *testing.T) {
+	suite.Run(t, new(deadlineSuite))
+}
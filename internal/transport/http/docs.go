@@ -0,0 +1,319 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/db/models"
+	"xm_test/internal/enum"
+	"xm_test/internal/events"
+	"xm_test/internal/service/export"
+	"xm_test/internal/transport/graphql"
+	"xm_test/internal/transport/http/binding"
+	"xm_test/internal/transport/http/codec"
+	"xm_test/internal/transport/http/openapi"
+	"xm_test/internal/transport/http/schemas"
+
+	"github.com/go-chi/chi/v5"
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+// apiInfo is the metadata of the OpenAPI document
+var apiInfo = openapi.Info{
+	Title:       "Company API",
+	Description: "API to manage companies. Changes on the companies are published as events.",
+	Version:     "1.0.0",
+}
+
+var (
+	// ifMatch documents the If-Match header of the requests modifying a company
+	ifMatch = openapi.StringParam("If-Match", "ETag of the company the request modifies. The request fails with 412 when the company was modified since, and with 428 when the header is missing but required.")
+
+	// idempotencyKey documents the Idempotency-Key header of the protected requests modifying the resources
+	idempotencyKey = openapi.StringParam("Idempotency-Key", "Key chosen by the client to retry the request safely. The first response is replayed to the retries with the same key, which fail with 409 when their body differs.")
+
+	// importLocation documents the Location header of the responses creating an import job
+	importLocation = map[string]string{"Location": "URL where the status of the import is polled"}
+
+	// companyETag documents the ETag header of the responses holding a company
+	companyETag = map[string]string{"ETag": "Version of the company, to be sent in the If-Match and If-None-Match headers"}
+)
+
+// endpoints documents every route of the router. Build fails when a route is missing, so a new route cannot be
+// added without its documentation.
+var endpoints = map[string]openapi.Endpoint{
+	// auth routes
+	openapi.Key(http.MethodPost, "/register"): {
+		Summary:   "Register a new user",
+		Tags:      []string{"auth"},
+		Request:   schemas.RegisterRequest{},
+		Responses: []openapi.EndpointResponse{{Status: http.StatusCreated, Description: "The user was registered", Body: schemas.OkResponse{}}},
+	},
+	openapi.Key(http.MethodPost, "/login"): {
+		Summary:   "Log in a user",
+		Tags:      []string{"auth"},
+		Request:   schemas.LoginRequest{},
+		Responses: []openapi.EndpointResponse{{Status: http.StatusOK, Description: "The access token of the user", Body: schemas.LoginResponse{}}},
+	},
+
+	// company routes
+	openapi.Key(http.MethodGet, "/company/{id}"): {
+		Summary: "Get a company",
+		Tags:    []string{"company"},
+		Headers: []*openapi.Parameter{openapi.StringParam("If-None-Match", "ETags of the company known by the client. The company is not sent when it still matches one of them.")},
+		Responses: []openapi.EndpointResponse{
+			{Status: http.StatusOK, Description: "The company", Body: models.CompanyModel{}, Headers: companyETag},
+			{Status: http.StatusNotModified, Description: "The company was not modified since the client read it", Headers: companyETag},
+		},
+	},
+	openapi.Key(http.MethodPost, "/company/create"): {
+		Summary:   "Create a company",
+		Tags:      []string{"company"},
+		Security:  openapi.Bearer,
+		Headers:   []*openapi.Parameter{idempotencyKey},
+		Request:   schemas.CreateCompanyRequest{},
+		Responses: []openapi.EndpointResponse{{Status: http.StatusOK, Description: "The created company", Body: models.CompanyModel{}, Headers: companyETag}},
+	},
+	openapi.Key(http.MethodPost, "/company/bulk"): {
+		Summary:     "Create, update and delete companies in bulk",
+		Description: "Applies the operations in order, and returns the outcome of each of them. In transactional mode, the default, either every operation is applied or none of them, and the operations that did not fail are reported with BULK_ABORTED. In best_effort mode, the failing operations are skipped. The if_match field of an operation works like the If-Match header.",
+		Tags:        []string{"company"},
+		Security:    openapi.Bearer,
+		Headers:     []*openapi.Parameter{idempotencyKey},
+		Request:     schemas.BulkCompaniesRequest{},
+		Responses: []openapi.EndpointResponse{
+			{Status: http.StatusOK, Description: "Every operation was applied", Body: schemas.BulkCompaniesResponse{}},
+			{Status: http.StatusMultiStatus, Description: "Some operations were not applied", Body: schemas.BulkCompaniesResponse{}},
+		},
+	},
+	openapi.Key(http.MethodPost, "/company/imports"): {
+		Summary:     "Import companies from a file",
+		Description: "Creates the companies of the rows of a CSV or NDJSON file. Each row is validated like the body of POST /company/create, and the invalid rows are rejected without failing the import. Small files are imported before responding, while larger ones are imported in the background: the progress is polled at the Location header, and the outcome of every row is downloaded from the report URL.",
+		Tags:        []string{"company"},
+		Security:    openapi.Bearer,
+		Params: []*openapi.Parameter{
+			openapi.StringParam("format", "Format of the file: csv or ndjson. Defaults to the one of the Content-Type header"),
+			openapi.StringParam("map", "Column of the file holding a field, as field:column, e.g. amount_employees:Employees. Repeat it to map several fields. The fields that are not mapped are read from the column named after them"),
+		},
+		Requests: []openapi.EndpointRequest{
+			{Body: "", ContentType: "text/csv"},
+			{Body: "", ContentType: "application/x-ndjson"},
+		},
+		Responses: []openapi.EndpointResponse{
+			{Status: http.StatusCreated, Description: "The file was imported", Body: schemas.ImportJobResponse{}, Headers: importLocation},
+			{Status: http.StatusAccepted, Description: "The file is imported in the background", Body: schemas.ImportJobResponse{}, Headers: importLocation},
+		},
+	},
+	openapi.Key(http.MethodGet, "/company/imports/{id}"): {
+		Summary:   "Get the status of an import",
+		Tags:      []string{"company"},
+		Security:  openapi.Bearer,
+		Responses: []openapi.EndpointResponse{{Status: http.StatusOK, Description: "The status and the progress of the import", Body: schemas.ImportJobResponse{}}},
+	},
+	openapi.Key(http.MethodGet, "/company/imports/{id}/report"): {
+		Summary:     "Download the report of an import",
+		Description: "Streams a CSV file with the line, the status, the ID of the company created and the error of every row processed so far.",
+		Tags:        []string{"company"},
+		Security:    openapi.Bearer,
+		Responses:   []openapi.EndpointResponse{{Status: http.StatusOK, Description: "The report of the import", Body: "", ContentType: "text/csv"}},
+	},
+	openapi.Key(http.MethodGet, "/company/export"): {
+		Summary:     "Export the companies to a file",
+		Description: "Streams the companies matching the filters, sorted by ID, as a CSV, NDJSON, XLSX or Parquet file. The format is chosen by the format query parameter or by the Accept header, and defaults to CSV.",
+		Tags:        []string{"company"},
+		Security:    openapi.Bearer,
+		Params: []*openapi.Parameter{
+			openapi.StringParam("format", "Format of the file: csv, ndjson, xlsx or parquet. Takes precedence over the Accept header"),
+			openapi.StringParam("columns", "Comma-separated columns to export, in their order: id, name, description, amount_employees, registered and type. Defaults to every column"),
+			openapi.StringParam("name", "Case-insensitive substring of the name of the companies"),
+			openapi.StringParam("type", "Type of the companies"),
+			openapi.StringParam("registered", "Whether the companies are registered: true or false"),
+		},
+		Responses: []openapi.EndpointResponse{
+			{Status: http.StatusOK, Description: "The companies in the requested format", Body: "", ContentType: export.MediaType(enum.ExportCSV)},
+			{Status: http.StatusOK, Body: "", ContentType: export.MediaType(enum.ExportNDJSON)},
+			{Status: http.StatusOK, Body: "", ContentType: export.MediaType(enum.ExportXLSX)},
+			{Status: http.StatusOK, Body: "", ContentType: export.MediaType(enum.ExportParquet)},
+		},
+	},
+	openapi.Key(http.MethodPut, "/company/{id}"): {
+		Summary:   "Update a company",
+		Tags:      []string{"company"},
+		Security:  openapi.Bearer,
+		Headers:   []*openapi.Parameter{ifMatch, idempotencyKey},
+		Request:   schemas.UpdateCompanyRequest{},
+		Responses: []openapi.EndpointResponse{{Status: http.StatusOK, Description: "The company was updated", Body: schemas.OkResponse{}, Headers: companyETag}},
+	},
+	openapi.Key(http.MethodPatch, "/company/{id}"): {
+		Summary:     "Patch a company",
+		Description: "Updates the fields of the company changed by a JSON merge patch (RFC 7396) or a JSON patch (RFC 6902). The patched company must be valid.",
+		Tags:        []string{"company"},
+		Security:    openapi.Bearer,
+		Headers:     []*openapi.Parameter{ifMatch, idempotencyKey},
+		Requests: []openapi.EndpointRequest{
+			{Body: schemas.CompanyMergePatch{}, ContentType: binding.MergePatchContentType},
+			{Body: []schemas.PatchOperation{}, ContentType: binding.JSONPatchContentType},
+		},
+		Responses: []openapi.EndpointResponse{{Status: http.StatusOK, Description: "The patched company", Body: models.CompanyModel{}, Headers: companyETag}},
+	},
+	openapi.Key(http.MethodDelete, "/company/{id}"): {
+		Summary:   "Delete a company",
+		Tags:      []string{"company"},
+		Security:  openapi.Bearer,
+		Headers:   []*openapi.Parameter{ifMatch, idempotencyKey},
+		Responses: []openapi.EndpointResponse{{Status: http.StatusOK, Description: "The company was deleted", Body: schemas.OkResponse{}}},
+	},
+
+	// event routes
+	openapi.Key(http.MethodGet, "/events/schemas"): {
+		Summary:   "List the event types",
+		Tags:      []string{"events"},
+		Responses: []openapi.EndpointResponse{{Status: http.StatusOK, Description: "The event types with the JSON schema of their payload", Body: []*events.EventDefinition{}}},
+	},
+	openapi.Key(http.MethodGet, "/events/stream"): {
+		Summary:     "Stream the events",
+		Description: "Streams the events dispatched by any replica as server-sent events.",
+		Tags:        []string{"events"},
+		Security:    openapi.Bearer,
+		Responses:   []openapi.EndpointResponse{{Status: http.StatusOK, Description: "The stream of events", Body: "", ContentType: "text/event-stream"}},
+	},
+
+	// graphql routes
+	openapi.Key(http.MethodPost, "/graphql"): {
+		Summary:     "Run a GraphQL operation",
+		Description: "Queries are public, while mutations and subscriptions require a token. Subscriptions are streamed as server-sent events when the client accepts text/event-stream.",
+		Tags:        []string{"graphql"},
+		Security:    openapi.Optional,
+		Requests:    []openapi.EndpointRequest{{Body: graphql.Request{}, ContentType: openapi.JSONContentType}},
+		Responses: []openapi.EndpointResponse{
+			{Status: http.StatusOK, Description: "The result of the operation", Body: map[string]any{}, ContentType: openapi.JSONContentType},
+		},
+	},
+
+	// admin routes
+	openapi.Key(http.MethodGet, "/admin/events/dead-letters"): {
+		Summary:  "List the dead-lettered events",
+		Tags:     []string{"admin"},
+		Security: openapi.Admin,
+		Params: []*openapi.Parameter{
+			openapi.IntegerParam("limit", fmt.Sprintf("Amount of events to return. Defaults to %d", defaultPageLimit), 1).WithMaximum(maxPageLimit),
+			openapi.IntegerParam("offset", "Amount of events to skip", 0),
+		},
+		Responses: []openapi.EndpointResponse{{Status: http.StatusOK, Description: "The dead-lettered events", Body: []*models.DeadLetterEventModel{}}},
+	},
+	openapi.Key(http.MethodGet, "/admin/events/dead-letters/{id}"): {
+		Summary:   "Get a dead-lettered event",
+		Tags:      []string{"admin"},
+		Security:  openapi.Admin,
+		Responses: []openapi.EndpointResponse{{Status: http.StatusOK, Description: "The dead-lettered event", Body: models.DeadLetterEventModel{}}},
+	},
+	openapi.Key(http.MethodPost, "/admin/events/dead-letters/{id}/retry"): {
+		Summary:   "Dispatch a dead-lettered event again",
+		Tags:      []string{"admin"},
+		Security:  openapi.Admin,
+		Headers:   []*openapi.Parameter{idempotencyKey},
+		Responses: []openapi.EndpointResponse{{Status: http.StatusOK, Description: "The event was dispatched", Body: schemas.OkResponse{}}},
+	},
+	openapi.Key(http.MethodDelete, "/admin/events/dead-letters/{id}"): {
+		Summary:   "Discard a dead-lettered event",
+		Tags:      []string{"admin"},
+		Security:  openapi.Admin,
+		Headers:   []*openapi.Parameter{idempotencyKey},
+		Responses: []openapi.EndpointResponse{{Status: http.StatusOK, Description: "The event was discarded", Body: schemas.OkResponse{}}},
+	},
+	openapi.Key(http.MethodGet, "/admin/log-level"): {
+		Summary:   "Get the log level",
+		Tags:      []string{"admin"},
+		Security:  openapi.Admin,
+		Responses: []openapi.EndpointResponse{{Status: http.StatusOK, Description: "The current log level", Body: schemas.LogLevelResponse{}}},
+	},
+	openapi.Key(http.MethodPut, "/admin/log-level"): {
+		Summary:   "Change the log level",
+		Tags:      []string{"admin"},
+		Security:  openapi.Admin,
+		Headers:   []*openapi.Parameter{idempotencyKey},
+		Request:   schemas.LogLevelRequest{},
+		Responses: []openapi.EndpointResponse{{Status: http.StatusOK, Description: "The new log level", Body: schemas.LogLevelResponse{}}},
+	},
+
+	// documentation routes
+	openapi.Key(http.MethodGet, "/openapi.json"): {
+		Summary:   "Get the OpenAPI document of the API",
+		Tags:      []string{"docs"},
+		Responses: []openapi.EndpointResponse{{Status: http.StatusOK, Description: "The OpenAPI 3.1 document", Body: map[string]any{}, ContentType: openapi.JSONContentType}},
+	},
+	openapi.Key(http.MethodGet, "/docs"): {
+		Summary:   "Browse the interactive documentation of the API",
+		Tags:      []string{"docs"},
+		Responses: []openapi.EndpointResponse{{Status: http.StatusOK, Description: "The Swagger UI page", Body: "", ContentType: "text/html"}},
+	},
+	openapi.Key(http.MethodGet, "/docs/{asset}"): {
+		Summary:    "Get an asset of the interactive documentation",
+		Tags:       []string{"docs"},
+		PathParams: []*openapi.Parameter{openapi.StringParam("asset", "Name of the Swagger UI file, e.g. swagger-ui.css")},
+		Responses:  []openapi.EndpointResponse{{Status: http.StatusOK, Description: "The Swagger UI file", Body: "", ContentType: "application/octet-stream"}},
+	},
+	openapi.Key(http.MethodGet, "/problems"): {
+		Summary:   "List the problem types",
+		Tags:      []string{"docs"},
+		Responses: []openapi.EndpointResponse{{Status: http.StatusOK, Description: "The types of the problems returned by the API", Body: []apierrors.ProblemType{}}},
+	},
+	openapi.Key(http.MethodGet, "/problems/{type}"): {
+		Summary:    "Get a problem type",
+		Tags:       []string{"docs"},
+		PathParams: []*openapi.Parameter{openapi.StringParam("type", "Name of the problem type, e.g. company-not-found")},
+		Responses:  []openapi.EndpointResponse{{Status: http.StatusOK, Description: "The problem type", Body: apierrors.ProblemType{}}},
+	},
+}
+
+// docsPage renders the OpenAPI document with Swagger UI. Its assets are embedded in the binary, so the page does not
+// depend on a CDN serving the expected files.
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Company API</title>
+  <link rel="stylesheet" href="/docs/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="/docs/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = () => { window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" }); };
+  </script>
+</body>
+</html>
+`
+
+// buildDocs generates the OpenAPI document of the routes, and encodes it as JSON. The JSON bodies are also documented
+// in the media types of the other codecs, which follow the default JSON one.
+func buildDocs(routes chi.Routes) (*openapi.Document, []byte, error) {
+	doc, err := openapi.Build(apiInfo, routes, endpoints, codec.Default.MediaTypes()[1:]...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build the OpenAPI document: %w", err)
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode the OpenAPI document: %w", err)
+	}
+	return doc, data, nil
+}
+
+// getOpenAPI returns the OpenAPI document of the API
+func (h *handler) getOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", openapi.JSONContentType)
+	w.Write(h.openapi)
+}
+
+// getDocs returns the interactive documentation page of the API
+func (h *handler) getDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(docsPage))
+}
+
+// getDocsAsset returns a file of the Swagger UI distribution embedded in the binary
+func (h *handler) getDocsAsset(w http.ResponseWriter, r *http.Request) {
+	http.ServeFileFS(w, r, swaggerFiles.FS, chi.URLParam(r, "asset"))
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/conf"
+	"xm_test/internal/transport/http/openapi"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type docsSuite struct {
+	suite.Suite
+	router chi.Router
+}
+
+func (s *docsSuite) SetupSuite() {
+	conf.NewConfig()
+	logger := zap.NewNop().Sugar()
+	transport := &httpTransport{logger: logger, handler: newHandler(logger, zap.NewAtomicLevel(), nil, nil, nil)}
+
+	// the router fails to be built when a route is not documented
+	router, err := transport.router()
+	s.Require().NoError(err)
+	s.router = router
+}
+
+func (s *docsSuite) TestEveryRouteIsDocumented() {
+	err := chi.Walk(s.router, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		s.Contains(endpoints, openapi.Key(method, route), "route is not documented")
+		return nil
+	})
+	s.Require().NoError(err)
+}
+
+func (s *docsSuite) TestServeDocument() {
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	s.Equal(http.StatusOK, w.Code)
+	s.Equal(openapi.JSONContentType, w.Header().Get("Content-Type"))
+
+	var doc openapi.Document
+	s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &doc))
+	s.Equal(openapi.Version, doc.OpenAPI)
+	s.Contains(doc.Paths, "/company/{id}")
+	s.Contains(doc.Paths["/company/{id}"], "get")
+	s.Contains(doc.Components.Schemas, "CreateCompanyRequest")
+	s.Contains(doc.Components.Schemas, "CompanyModel")
+}
+
+func (s *docsSuite) TestServeDocsPage() {
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	s.Equal(http.StatusOK, w.Code)
+	s.Contains(w.Body.String(), "/openapi.json")
+	s.NotContains(w.Body.String(), "https://")
+}
+
+func (s *docsSuite) TestServeDocsAssets() {
+	for _, asset := range []string{"swagger-ui.css", "swagger-ui-bundle.js"} {
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/"+asset, nil))
+		s.Equal(http.StatusOK, w.Code, asset)
+		s.NotEmpty(w.Body.Bytes(), asset)
+	}
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs/missing.js", nil))
+	s.Equal(http.StatusNotFound, w.Code)
+}
+
+func (s *docsSuite) TestServeProblemTypes() {
+	s.Run("documents the problem types", func() {
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, apierrors.TypeURI(apierrors.ErrCompanyNotFound.Code), nil))
+		s.Equal(http.StatusOK, w.Code)
+
+		var problemType apierrors.ProblemType
+		s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &problemType))
+		s.Equal(apierrors.ErrCompanyNotFound.Code, problemType.Code)
+	})
+
+	s.Run("returns a problem for unknown types", func() {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/problems/unknown", nil)
+		r.Header.Set("Accept", apierrors.ProblemContentType)
+		s.router.ServeHTTP(w, r)
+		s.Equal(http.StatusNotFound, w.Code)
+
+		var problem apierrors.Problem
+		s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &problem))
+		s.Equal(apierrors.TypeURI(apierrors.ErrProblemTypeNotFound.Code), problem.Type)
+		s.Equal("/problems/unknown", problem.Instance)
+	})
+}
+
+func TestDocsSuite(t *testing.T) {
+	suite.Run(t, new(docsSuite))
+}
@@ -0,0 +1,103 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/i18n"
+)
+
+// etag returns the strong entity tag of a version of a company, e.g. "3"
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// entityTags splits the comma-separated entity tags of an If-Match or If-None-Match header
+func entityTags(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// notModified tells whether the If-None-Match header of the request matches the entity tag. The tags are compared
+// with the weak comparison, as required for GET requests.
+func notModified(r *http.Request, tag string) bool {
+	for _, t := range entityTags(r.Header.Get("If-None-Match")) {
+		if t == "*" || strings.TrimPrefix(t, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersions returns the versions of the If-Match header, and whether it matches any version ("*"). The
+// header is compared with the strong comparison, so the weak and malformed entity tags do not match any version.
+func ifMatchVersions(header string) (versions []int, anyVersion bool) {
+	for _, tag := range entityTags(header) {
+		if tag == "*" {
+			return nil, true
+		}
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		if version, err := strconv.Atoi(tag[1 : len(tag)-1]); err == nil && version > 0 {
+			versions = append(versions, version)
+		}
+	}
+	return versions, false
+}
+
+// expectedVersion returns the version of the company that a request modifies according to its If-Match header, or
+// zero when the request modifies any version. It fails with ErrPreconditionFailed when the header does not match
+// the company, and with ErrPreconditionRequired when the header is required but missing.
+func (h *handler) expectedVersion(ctx context.Context, header string, companyID string) (int, error) {
+	versions, err := h.ifMatchCondition(header, companyID)
+	if err != nil || len(versions) == 0 {
+		return 0, err
+	}
+	if len(versions) == 1 {
+		return versions[0], nil
+	}
+
+	// the header lists several versions, so the company is modified at its current version if it is one of them
+	company, err := h.cs.GetCompanyByID(ctx, companyID)
+	if err != nil {
+		return 0, err
+	}
+	return matchingVersion(companyID, versions, company.Version)
+}
+
+// ifMatchCondition returns the versions listed by the If-Match header of a request modifying the company, or none
+// when the request modifies any version. It fails like expectedVersion, without reading the company.
+func (h *handler) ifMatchCondition(header string, companyID string) ([]int, error) {
+	if header == "" {
+		if h.requireIfMatch {
+			return nil, apierrors.ErrPreconditionRequired.WithMessageID("if_match", nil)
+		}
+		return nil, nil
+	}
+
+	versions, anyVersion := ifMatchVersions(header)
+	switch {
+	case anyVersion:
+		return nil, nil
+	case len(versions) == 0:
+		return nil, apierrors.ErrPreconditionFailed.WithMessageID("version", i18n.Params{"id": companyID})
+	}
+	return versions, nil
+}
+
+// matchingVersion returns the current version of the company when it is one of the versions of the If-Match header
+func matchingVersion(companyID string, versions []int, current int) (int, error) {
+	if !slices.Contains(versions, current) {
+		return 0, apierrors.ErrPreconditionFailed.WithMessageID("version", i18n.Params{"id": companyID})
+	}
+	return current, nil
+}
@@ -0,0 +1,93 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	apierrors "xm_test/internal/api_errors"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type etagSuite struct {
+	suite.Suite
+}
+
+func (s *etagSuite) TestNotModified() {
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		expected    bool
+	}{
+		{name: "without header", ifNoneMatch: "", expected: false},
+		{name: "matches the tag", ifNoneMatch: `"3"`, expected: true},
+		{name: "matches a weak tag", ifNoneMatch: `W/"3"`, expected: true},
+		{name: "matches any tag of the list", ifNoneMatch: `"1", "3"`, expected: true},
+		{name: "matches any version", ifNoneMatch: "*", expected: true},
+		{name: "does not match other versions", ifNoneMatch: `"1", "2"`, expected: false},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			s.Equal(tt.expected, notModified(r, etag(3)))
+		})
+	}
+}
+
+func (s *etagSuite) TestIfMatchVersions() {
+	tests := []struct {
+		name       string
+		ifMatch    string
+		versions   []int
+		anyVersion bool
+	}{
+		{name: "parses the version", ifMatch: `"3"`, versions: []int{3}},
+		{name: "parses the list of versions", ifMatch: `"1" , "2"`, versions: []int{1, 2}},
+		{name: "matches any version", ifMatch: "*", anyVersion: true},
+		{name: "skips the weak tags", ifMatch: `W/"3"`},
+		{name: "skips the malformed tags", ifMatch: `3, "x", "-1", "`},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			versions, anyVersion := ifMatchVersions(tt.ifMatch)
+			s.Equal(tt.versions, versions)
+			s.Equal(tt.anyVersion, anyVersion)
+		})
+	}
+}
+
+func (s *etagSuite) TestExpectedVersion() {
+	s.Run("modifies any version without header", func() {
+		version, err := (&handler{}).expectedVersion(context.Background(), "", "id")
+		s.Require().NoError(err)
+		s.Zero(version)
+	})
+
+	s.Run("requires the header", func() {
+		_, err := (&handler{requireIfMatch: true}).expectedVersion(context.Background(), "", "id")
+		s.ErrorIs(err, apierrors.ErrPreconditionRequired)
+
+		version, err := (&handler{requireIfMatch: true}).expectedVersion(context.Background(), "*", "id")
+		s.Require().NoError(err)
+		s.Zero(version)
+	})
+
+	s.Run("returns the version of the header", func() {
+		version, err := (&handler{}).expectedVersion(context.Background(), `"4"`, "id")
+		s.Require().NoError(err)
+		s.Equal(4, version)
+	})
+
+	s.Run("fails when the header does not match any version", func() {
+		_, err := (&handler{}).expectedVersion(context.Background(), `W/"4"`, "id")
+		s.ErrorIs(err, apierrors.ErrPreconditionFailed)
+	})
+}
+
+func TestETagSuite(t *testing.T) {
+	suite.Run(t, new(etagSuite))
+}
@@ -0,0 +1,152 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/db/models"
+	"xm_test/internal/enum"
+	"xm_test/internal/i18n"
+	"xm_test/internal/logging"
+	"xm_test/internal/service/export"
+	"xm_test/internal/service/inputs"
+	"xm_test/internal/transport/http/negotiation"
+)
+
+// exportFlushInterval is the amount of companies exported between the flushes of the response
+const exportFlushInterval = 1000
+
+// exportMediaTypes are the media types of the exported files, by preference when the client accepts any of them
+var exportMediaTypes = []string{
+	export.MediaType(enum.ExportCSV),
+	export.MediaType(enum.ExportNDJSON),
+	"application/ndjson",
+	export.MediaType(enum.ExportXLSX),
+	export.MediaType(enum.ExportParquet),
+}
+
+// exportFormats maps the media types of the exported files to their format
+var exportFormats = map[string]enum.ExportFormat{
+	export.MediaType(enum.ExportCSV):     enum.ExportCSV,
+	export.MediaType(enum.ExportNDJSON):  enum.ExportNDJSON,
+	"application/ndjson":                 enum.ExportNDJSON,
+	export.MediaType(enum.ExportXLSX):    enum.ExportXLSX,
+	export.MediaType(enum.ExportParquet): enum.ExportParquet,
+}
+
+// exportCompanies streams the companies matching the filters of the query as a file, in the format chosen by the
+// format query parameter or by the Accept header. The companies are written as they are read from the database, so
+// the response is flushed along the way and the memory used does not depend on the amount of companies.
+func (h *handler) exportCompanies(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("export companies endpoint called")
+
+	format, err := exportFormat(r)
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+	columns, err := export.ParseColumns(r.URL.Query().Get("columns"))
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+	input, err := exportInput(r)
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+
+	body := &responseBody{w: w}
+	writer, err := export.NewWriter(body, format, columns)
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", export.MediaType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="companies.%s"`, format))
+
+	exported := 0
+	err = h.cs.ExportCompanies(r.Context(), input, func(company *models.CompanyModel) error {
+		if err := writer.Write(company); err != nil {
+			return err
+		}
+		exported++
+		if exported%exportFlushInterval == 0 {
+			http.NewResponseController(w).Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = writer.Close()
+	}
+	if err == nil {
+		logger.Infof("exported %d companies as %s", exported, format)
+		return
+	}
+
+	// the error is sent while the response is not started. Otherwise, the connection is aborted, so the client
+	// does not mistake the truncated file for the whole export.
+	if !body.started {
+		w.Header().Del("Content-Disposition")
+		h.wrapError(w, r, err)
+		return
+	}
+	logger.Errorf("failed to export the companies after %d of them: %v", exported, err)
+	panic(http.ErrAbortHandler)
+}
+
+// exportFormat returns the format of the exported file, given by the format query parameter or by the Accept header.
+// An unknown format parameter is an invalid query, while an Accept header matching no format is not acceptable.
+func exportFormat(r *http.Request) (enum.ExportFormat, error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		format := enum.ExportFormat(name)
+		if !format.IsValid() {
+			return "", apierrors.ErrInvalidQuery.WithMessageID("format", i18n.Params{
+				"format":  name,
+				"formats": strings.Join([]string{enum.ExportCSV.String(), enum.ExportNDJSON.String(), enum.ExportXLSX.String(), enum.ExportParquet.String()}, ", "),
+			})
+		}
+		return format, nil
+	}
+
+	mediaType, ok := negotiation.Negotiate(r.Header.Get("Accept"), exportMediaTypes)
+	if !ok {
+		return "", apierrors.ErrNotAcceptable.WithMessageID("type", i18n.Params{
+			"type":      r.Header.Get("Accept"),
+			"supported": strings.Join(exportMediaTypes, ", "),
+		})
+	}
+	return exportFormats[mediaType], nil
+}
+
+// exportInput returns the filters of the exported companies, given by the query parameters
+func exportInput(r *http.Request) (*inputs.ExportCompaniesInput, error) {
+	query := r.URL.Query()
+	input := &inputs.ExportCompaniesInput{
+		Name: query.Get("name"),
+		Type: query.Get("type"),
+	}
+	if v := query.Get("registered"); v != "" {
+		registered, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, apierrors.ErrInvalidQuery.WithMessageID("registered", nil)
+		}
+		input.Registered = &registered
+	}
+	return input, nil
+}
+
+// responseBody writes to the response, recording whether it was started
+type responseBody struct {
+	w       io.Writer
+	started bool
+}
+
+func (b *responseBody) Write(p []byte) (int, error) {
+	b.started = true
+	return b.w.Write(p)
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/enum"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type exportSuite struct {
+	suite.Suite
+}
+
+func (s *exportSuite) TestExportFormat() {
+	tests := []struct {
+		name   string
+		target string
+		accept string
+		format enum.ExportFormat
+		err    error
+	}{
+		{name: "defaults to csv", target: "/", format: enum.ExportCSV},
+		{name: "any media type", target: "/", accept: "*/*", format: enum.ExportCSV},
+		{name: "ndjson media type", target: "/", accept: "application/ndjson", format: enum.ExportNDJSON},
+		{name: "preferred media type", target: "/", accept: "text/csv;q=0.5, application/vnd.apache.parquet", format: enum.ExportParquet},
+		{name: "query parameter", target: "/?format=xlsx", accept: "text/csv", format: enum.ExportXLSX},
+		{name: "unknown media type", target: "/", accept: "application/xml", err: apierrors.ErrNotAcceptable},
+		{name: "unknown format", target: "/?format=xml", accept: "text/csv", err: apierrors.ErrInvalidQuery},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			r.Header.Set("Accept", tt.accept)
+			format, err := exportFormat(r)
+			if tt.err != nil {
+				s.ErrorIs(err, tt.err)
+				return
+			}
+			s.Require().NoError(err)
+			s.Equal(tt.format, format)
+		})
+	}
+}
+
+func (s *exportSuite) TestExportInput() {
+	r := httptest.NewRequest(http.MethodGet, "/?name=acme&type=Cooperative&registered=false", nil)
+	input, err := exportInput(r)
+	s.Require().NoError(err)
+	s.Equal("acme", input.Name)
+	s.Equal("Cooperative", input.Type)
+	s.Require().NotNil(input.Registered)
+	s.False(*input.Registered)
+
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	input, err = exportInput(r)
+	s.Require().NoError(err)
+	s.Nil(input.Registered)
+
+	r = httptest.NewRequest(http.MethodGet, "/?registered=maybe", nil)
+	_, err = exportInput(r)
+	s.ErrorIs(err, apierrors.ErrInvalidQuery)
+}
+
+func TestExportSuite(t *testing.T) {
+	suite.Run(t, new(exportSuite))
+}
@@ -1,18 +1,26 @@
 package http
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"time"
+	"sync"
 	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/conf"
 	"xm_test/internal/db"
-	"xm_test/internal/enum"
 	"xm_test/internal/events"
+	"xm_test/internal/i18n"
+	"xm_test/internal/logging"
 	"xm_test/internal/service"
 	"xm_test/internal/service/inputs"
+	"xm_test/internal/transport/graphql"
 	"xm_test/internal/transport/http/binding"
+	"xm_test/internal/transport/http/codec"
 	"xm_test/internal/transport/http/schemas"
 
+	customMiddlewares "xm_test/internal/transport/http/middleware"
+
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
 	"github.com/google/uuid"
@@ -20,90 +28,138 @@ import (
 )
 
 type handler struct {
-	logger *zap.SugaredLogger
-	db     db.DatabaseAdapter
+	logger   *zap.SugaredLogger
+	logLevel zap.AtomicLevel
+	db       db.DatabaseAdapter
 
 	// services
 	as service.AuthService
 	cs service.CompanyService
+	is service.ImportService
 
-	// event dispatcher
+	// event dispatcher of the services, closed on shutdown
 	evtDispatcher events.Dispatcher
+
+	// local events hub fed by the events of every replica
+	hub events.Hub
+
+	// events schema registry
+	registry events.Registry
+
+	// events that could not be dispatched
+	dlq events.DeadLetterQueue
+
+	// graphql endpoint
+	graphql *graphql.Handler
+
+	// codecs of the responses, rendering the encoding failures as errors of the API
+	codecs *codec.Registry
+
+	// whether the requests modifying a company must have an If-Match header
+	requireIfMatch bool
+
+	// maximum size of the imported files, and of the ones imported within the request
+	importMaxSize     int64
+	importSyncMaxSize int64
+
+	// OpenAPI document of the routes, encoded as JSON
+	openapi []byte
+
+	// closed on shutdown to end the long-lived streams
+	streamsDone      chan struct{}
+	closeStreamsOnce sync.Once
 }
 
 // newHandler creates a new handler.
-func newHandler(logger *zap.SugaredLogger, db db.DatabaseAdapter) *handler {
+func newHandler(logger *zap.SugaredLogger, logLevel zap.AtomicLevel, db db.DatabaseAdapter, hub events.Hub, registry events.Registry) *handler {
 	// initiate services
+	dispatcher := events.NewEventsDispatcher(logger, db, registry)
 	as := service.NewAuthService(logger, db)
-	cs := service.NewCompanyService(logger, db)
-
-	dispatcher := events.NewEventsDispatcher(logger, db)
-	return &handler{logger: logger, db: db, as: as, cs: cs, evtDispatcher: dispatcher}
+	cs := service.NewCompanyService(logger, db, registry, dispatcher)
+
+	dlq := events.NewDeadLetterQueue(logger, db, dispatcher)
+	h := &handler{
+		logger:        logger,
+		logLevel:      logLevel,
+		db:            db,
+		as:            as,
+		cs:            cs,
+		evtDispatcher: dispatcher,
+		hub:           hub,
+		registry:      registry,
+		dlq:           dlq,
+		streamsDone:   make(chan struct{}),
+		codecs:        codec.NewDefaultRegistry(codec.WithErrorRenderer(customMiddlewares.RenderEncodeError)),
+
+		requireIfMatch:    conf.GlobalConfig.RequireIfMatch,
+		importMaxSize:     conf.GlobalConfig.Import.MaxSize,
+		importSyncMaxSize: conf.GlobalConfig.Import.SyncMaxSize,
+	}
+	h.is = service.NewImportService(logger, db, cs, conf.GlobalConfig.Import.BatchSize)
+	h.graphql = graphql.NewHandler(logger, cs, hub, conf.GlobalConfig.RequestTimeout, conf.GlobalConfig.MaxBodySize, h.streamsDone, conf.GlobalConfig.RequireIfMatch)
+	return h
 }
 
 // Register registers a new user
 func (h *handler) register(w http.ResponseWriter, r *http.Request) {
-	h.logger.Infof("registering user endpoint called")
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("registering user endpoint called")
 
-	h.logger.Debugf("decoding request body")
+	logger.Debugf("decoding request body")
 	var body schemas.RegisterRequest
-	if err := binding.DecodeJSONBody(r, &body); err != nil {
-		e := apierrors.ErrInvalidBody
-		e.Message = fmt.Sprintf("failed to decode request body: %v", err)
-		h.wrapError(w, r, e)
+	if err := binding.DecodeBody(r, &body); err != nil {
+		h.wrapError(w, r, invalidBody(err))
 		return
 	}
-	h.logger.Debugf("request body decoded")
+	logger.Debugf("request body decoded")
 
-	h.logger.Debugf("creating account for user with email '%s'", body.Email)
-	if err := h.as.Register(body.Email, body.Password); err != nil {
+	logger.Debugf("creating account for user with email '%s'", body.Email)
+	if err := h.as.Register(r.Context(), body.Email, body.Password); err != nil {
 		h.wrapError(w, r, err)
 		return
 	}
-	h.logger.Infof("user with email '%s' registered", body.Email)
+	logger.Infof("user with email '%s' registered", body.Email)
 	render.Status(r, http.StatusCreated)
-	render.JSON(w, r, schemas.OkResponse{Message: "user registered"})
+	h.codecs.Render(w, r, schemas.OkResponse{Message: "user registered"})
 }
 
 // Login logs in a user
 func (h *handler) login(w http.ResponseWriter, r *http.Request) {
-	h.logger.Infof("login endpoint called")
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("login endpoint called")
 
-	h.logger.Debugf("decoding request body")
+	logger.Debugf("decoding request body")
 	var body schemas.LoginRequest
-	if err := binding.DecodeJSONBody(r, &body); err != nil {
-		e := apierrors.ErrInvalidBody
-		e.Message = fmt.Sprintf("failed to decode request body: %v", err)
-		h.wrapError(w, r, e)
+	if err := binding.DecodeBody(r, &body); err != nil {
+		h.wrapError(w, r, invalidBody(err))
 		return
 	}
-	h.logger.Debugf("request body decoded")
+	logger.Debugf("request body decoded")
 
-	h.logger.Debugf("logging in user with email '%s'", body.Email)
-	token, err := h.as.Login(body.Email, body.Password)
+	logger.Debugf("logging in user with email '%s'", body.Email)
+	token, err := h.as.Login(r.Context(), body.Email, body.Password)
 	if err != nil {
 		h.wrapError(w, r, err)
 		return
 	}
-	h.logger.Infof("user with email '%s' logged in", body.Email)
-	render.JSON(w, r, schemas.LoginResponse{AccessToken: *token})
+	logger.Infof("user with email '%s' logged in", body.Email)
+	h.codecs.Render(w, r, schemas.LoginResponse{AccessToken: *token})
 }
 
 // CreateCompany creates a new company
 func (h *handler) createCompany(w http.ResponseWriter, r *http.Request) {
-	h.logger.Infof("create company endpoint called")
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("create company endpoint called")
 
-	h.logger.Debugf("decoding request body")
+	logger.Debugf("decoding request body")
 	var body schemas.CreateCompanyRequest
-	if err := binding.DecodeJSONBody(r, &body); err != nil {
-		e := apierrors.ErrInvalidBody
-		e.Message = fmt.Sprintf("failed to decode request body: %v", err)
-		h.wrapError(w, r, e)
+	if err := binding.DecodeBody(r, &body); err != nil {
+		h.wrapError(w, r, invalidBody(err))
 		return
 	}
-	h.logger.Debugf("request body decoded")
+	logger.Debugf("request body decoded")
 
-	h.logger.Debugf("creating company with name '%s'", body.Name)
+	logger.Debugf("creating company with name '%s'", body.Name)
 	input := &inputs.CreateCompanyInput{
 		Name:            body.Name,
 		Description:     body.Description,
@@ -111,29 +167,23 @@ func (h *handler) createCompany(w http.ResponseWriter, r *http.Request) {
 		Registered:      body.Registered,
 		Type:            body.Type,
 	}
-	companyModel, err := h.cs.CreateCompany(input)
+	companyModel, err := h.cs.CreateCompany(r.Context(), input)
 	if err != nil {
 		h.wrapError(w, r, err)
 		return
 	}
 
-	// create event
-	go h.createEvent(&events.Event{
-		Type:      enum.EventCreateCompany.String(),
-		Timestamp: time.Now(),
-		ID:        uuid.New(),
-		EntityID:  companyModel.ID,
-	})
-
-	h.logger.Infof("company with name '%s' created", body.Name)
-	render.JSON(w, r, companyModel)
+	logger.Infof("company with name '%s' created", body.Name)
+	w.Header().Set("ETag", etag(companyModel.Version))
+	h.codecs.Render(w, r, companyModel)
 }
 
 // GetCompany retrieves a company by its ID
 func (h *handler) getCompany(w http.ResponseWriter, r *http.Request) {
-	h.logger.Infof("get company endpoint called")
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("get company endpoint called")
 
-	h.logger.Debugf("decoding company id from the request")
+	logger.Debugf("decoding company id from the request")
 	companyID := chi.URLParam(r, "id")
 	if companyID == "" {
 		e := apierrors.ErrCompanyIDRequired
@@ -147,24 +197,33 @@ func (h *handler) getCompany(w http.ResponseWriter, r *http.Request) {
 		h.wrapError(w, r, e)
 		return
 	}
-	h.logger.Debugf("company id decoded: %s", companyID)
+	logger.Debugf("company id decoded: %s", companyID)
 
-	h.logger.Debugf("retrieving company with id '%s'", companyID)
-	company, err := h.cs.GetCompanyByID(companyID)
+	logger.Debugf("retrieving company with id '%s'", companyID)
+	company, err := h.cs.GetCompanyByID(r.Context(), companyID)
 	if err != nil {
 		h.wrapError(w, r, err)
 		return
 	}
-	h.logger.Infof("company with id '%s' retrieved", companyID)
+	logger.Infof("company with id '%s' retrieved", companyID)
+
+	tag := etag(company.Version)
+	w.Header().Set("ETag", tag)
+	if notModified(r, tag) {
+		logger.Debugf("company with id '%s' not modified", companyID)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
 	render.Status(r, http.StatusOK)
-	render.JSON(w, r, company)
+	h.codecs.Render(w, r, company)
 }
 
 // UpdateCompany updates a company
 func (h *handler) updateCompany(w http.ResponseWriter, r *http.Request) {
-	h.logger.Infof("update company endpoint called")
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("update company endpoint called")
 
-	h.logger.Debugf("decoding company id from the request")
+	logger.Debugf("decoding company id from the request")
 	companyID := chi.URLParam(r, "id")
 	if companyID == "" {
 		e := apierrors.ErrCompanyIDRequired
@@ -178,19 +237,23 @@ func (h *handler) updateCompany(w http.ResponseWriter, r *http.Request) {
 		h.wrapError(w, r, e)
 		return
 	}
-	h.logger.Debugf("company id decoded: %s", companyID)
+	logger.Debugf("company id decoded: %s", companyID)
 
-	h.logger.Debugf("decoding request body")
+	version, err := h.expectedVersion(r.Context(), r.Header.Get("If-Match"), companyID)
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+
+	logger.Debugf("decoding request body")
 	var body schemas.UpdateCompanyRequest
-	if err := binding.DecodeJSONBody(r, &body); err != nil {
-		e := apierrors.ErrInvalidBody
-		e.Message = fmt.Sprintf("failed to decode request body: %v", err)
-		h.wrapError(w, r, e)
+	if err := binding.DecodeBody(r, &body); err != nil {
+		h.wrapError(w, r, invalidBody(err))
 		return
 	}
-	h.logger.Debugf("request body decoded")
+	logger.Debugf("request body decoded")
 
-	h.logger.Debugf("updating company with id '%s'", companyID)
+	logger.Debugf("updating company with id '%s'", companyID)
 	input := &inputs.UpdateCompany{
 		Name:            body.Name,
 		Description:     body.Description,
@@ -198,27 +261,77 @@ func (h *handler) updateCompany(w http.ResponseWriter, r *http.Request) {
 		Registered:      body.Registered,
 		Type:            body.Type,
 	}
-	if err := h.cs.UpdateCompany(companyID, input); err != nil {
+	company, err := h.cs.UpdateCompany(r.Context(), companyID, input, version)
+	if err != nil {
 		h.wrapError(w, r, err)
 		return
 	}
 
-	go h.createEvent(&events.Event{
-		Type:      enum.EventUpdateCompany.String(),
-		Timestamp: time.Now(),
-		ID:        uuid.New(),
-		EntityID:  uuid.MustParse(companyID),
+	logger.Infof("company with id '%s' updated", companyID)
+	w.Header().Set("ETag", etag(company.Version))
+	h.codecs.Render(w, r, schemas.OkResponse{Message: "company updated"})
+}
+
+// PatchCompany updates the fields of a company changed by a merge patch or a JSON patch
+func (h *handler) patchCompany(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("patch company endpoint called")
+
+	logger.Debugf("decoding company id from the request")
+	companyID := chi.URLParam(r, "id")
+	if companyID == "" {
+		e := apierrors.ErrCompanyIDRequired
+		h.wrapError(w, r, e)
+		return
+	}
+
+	// check if the company id is a valid uuid
+	if err := uuid.Validate(companyID); err != nil {
+		e := apierrors.ErrInvalidUUID
+		h.wrapError(w, r, e)
+		return
+	}
+	logger.Debugf("company id decoded: %s", companyID)
+
+	version, err := h.expectedVersion(r.Context(), r.Header.Get("If-Match"), companyID)
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+
+	logger.Debugf("decoding request body")
+	patch, err := binding.DecodePatch(r)
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+	logger.Debugf("request body decoded")
+
+	logger.Debugf("patching company with id '%s'", companyID)
+	company, _, err := h.cs.PatchCompany(r.Context(), companyID, version, func(input *inputs.UpdateCompany) error {
+		body := schemas.UpdateCompanyRequest(*input)
+		if err := binding.ApplyPatch(patch, &body); err != nil {
+			return err
+		}
+		*input = inputs.UpdateCompany(body)
+		return nil
 	})
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
 
-	h.logger.Infof("company with id '%s' updated", companyID)
-	render.JSON(w, r, schemas.OkResponse{Message: "company updated"})
+	logger.Infof("company with id '%s' patched", companyID)
+	w.Header().Set("ETag", etag(company.Version))
+	h.codecs.Render(w, r, company)
 }
 
 // DeleteCompany deletes a company
 func (h *handler) deleteCompany(w http.ResponseWriter, r *http.Request) {
-	h.logger.Infof("delete company endpoint called")
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("delete company endpoint called")
 
-	h.logger.Debugf("decoding company id from the request")
+	logger.Debugf("decoding company id from the request")
 	companyID := chi.URLParam(r, "id")
 	if companyID == "" {
 		e := apierrors.ErrCompanyIDRequired
@@ -232,46 +345,111 @@ func (h *handler) deleteCompany(w http.ResponseWriter, r *http.Request) {
 		h.wrapError(w, r, e)
 		return
 	}
-	h.logger.Debugf("company id decoded: %s", companyID)
+	logger.Debugf("company id decoded: %s", companyID)
 
-	h.logger.Debugf("deleting company with id '%s'", companyID)
-	if err := h.cs.DeleteCompany(companyID); err != nil {
+	version, err := h.expectedVersion(r.Context(), r.Header.Get("If-Match"), companyID)
+	if err != nil {
 		h.wrapError(w, r, err)
 		return
 	}
 
-	go h.createEvent(&events.Event{
-		Type:      enum.EventDeleteCompany.String(),
-		Timestamp: time.Now(),
-		ID:        uuid.New(),
-		EntityID:  uuid.MustParse(companyID),
-	})
+	logger.Debugf("deleting company with id '%s'", companyID)
+	if err := h.cs.DeleteCompany(r.Context(), companyID, version); err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
 
-	h.logger.Infof("company with id '%s' deleted", companyID)
-	render.JSON(w, r, schemas.OkResponse{Message: "company deleted"})
+	logger.Infof("company with id '%s' deleted", companyID)
+	h.codecs.Render(w, r, schemas.OkResponse{Message: "company deleted"})
 }
 
-func (h *handler) createEvent(evt *events.Event) {
-	h.logger.Debugf("creating event for company created")
-	if err := h.evtDispatcher.Dispatch(evt); err != nil {
-		h.logger.Errorf("failed to dispatch event: %v", err)
+// streamEvents streams the events dispatched by any replica to the client using server-sent events
+func (h *handler) streamEvents(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("stream events endpoint called")
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		e := apierrors.ErrInternalServer.WithMessage("streaming is not supported")
+		h.wrapError(w, r, e)
 		return
 	}
-	h.logger.Debugf("event for company created")
+
+	evts, unsubscribe := h.hub.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			logger.Infof("events stream closed by the client")
+			return
+		case <-h.streamsDone:
+			logger.Infof("events stream closed by the server")
+			return
+		case evt, ok := <-evts:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(evt)
+			if err != nil {
+				logger.Errorf("failed to encode event '%s': %v", evt.ID, err)
+				continue
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", evt.ID, evt.Type, data)
+			flusher.Flush()
+		}
+	}
 }
 
-// wrapError logs the error and writes it to the response.
+// getEventSchemas returns the registered event types with the JSON schema of their payload
+func (h *handler) getEventSchemas(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("get event schemas endpoint called")
+	h.codecs.Render(w, r, h.registry.Definitions())
+}
+
+// closeStreams ends the long-lived streams, such as the events stream
+func (h *handler) closeStreams() {
+	h.closeStreamsOnce.Do(func() {
+		close(h.streamsDone)
+	})
+}
+
+// invalidBody wraps the error of a request body that could not be decoded or validated, keeping the fields that
+// failed to be validated. The validation errors are translated along with the error wrapping them. The bodies of
+// an unsupported media type are not decoded, so their error is kept.
+func invalidBody(err error) *apierrors.APIError {
+	var bindingErr *apierrors.APIError
+	if errors.As(err, &bindingErr) {
+		if errors.Is(bindingErr, apierrors.ErrUnsupportedMediaType) {
+			return bindingErr
+		}
+		return apierrors.ErrInvalidBody.WithMessageID("decode", i18n.Params{"reason": bindingErr}).WithErrors(bindingErr.Errors)
+	}
+	return apierrors.ErrInvalidBody.WithMessageID("decode", i18n.Params{"reason": err})
+}
+
+// wrapError logs the error and writes it to the response. When the request deadline is exceeded or the client
+// closes the connection, the error of the lower layers is replaced by the one describing the cancellation.
 func (h *handler) wrapError(w http.ResponseWriter, r *http.Request, err error) {
-	apiError, ok := err.(*apierrors.APIError)
-	if !ok {
-		unknownError := apierrors.ErrInternalServer
-		unknownError.Message = err.Error()
-		h.logger.Error(unknownError)
-		render.JSON(w, r, unknownError)
-		return
+	logger := logging.FromContext(r.Context(), h.logger)
+	if ctxErr := apierrors.FromContext(r.Context()); ctxErr != nil {
+		logger.Errorf("request cancelled: %s", err)
+		err = ctxErr
+	}
+
+	// the errors that are not API errors may leak implementation details, so they are only logged
+	var apiError *apierrors.APIError
+	if !errors.As(err, &apiError) {
+		apiError = apierrors.ErrInternalServer.Wrap(err)
 	}
 
-	h.logger.Error(apiError.Message)
-	render.Status(r, apiError.HTTPStatus)
-	render.JSON(w, r, apiError)
+	logger.Error(apiError.Error())
+	customMiddlewares.RenderError(w, r, apiError)
 }
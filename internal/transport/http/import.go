@@ -0,0 +1,241 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/db/models"
+	"xm_test/internal/enum"
+	"xm_test/internal/i18n"
+	"xm_test/internal/logging"
+	"xm_test/internal/service/imports"
+	"xm_test/internal/service/inputs"
+	"xm_test/internal/transport/http/binding"
+	"xm_test/internal/transport/http/schemas"
+
+	customMiddlewares "xm_test/internal/transport/http/middleware"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/google/uuid"
+)
+
+// importFormats maps the media types of the imported files to their format
+var importFormats = map[string]enum.ImportFormat{
+	"text/csv":             enum.ImportCSV,
+	"application/x-ndjson": enum.ImportNDJSON,
+	"application/ndjson":   enum.ImportNDJSON,
+}
+
+// importCompanies imports the companies of the CSV or NDJSON file of the request body. The small files are imported
+// before responding with 201, while the larger ones are imported in the background: the response is sent with 202
+// once the job is created, and its progress is polled at the Location header.
+func (h *handler) importCompanies(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("import companies endpoint called")
+
+	userID, err := authenticatedUser(r)
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+	format, err := importFormat(r)
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+	columns, err := binding.ParseColumnMapping(r.URL.Query()["map"])
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+
+	logger.Debugf("reading the %s file of the request body", format)
+	file, size, err := h.spoolBody(w, r)
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+	rows, err := binding.NewCompanyRows(file, format, columns)
+	if err != nil {
+		file.Close()
+		h.wrapError(w, r, err)
+		return
+	}
+
+	background := size > h.importSyncMaxSize
+	job, err := h.is.ImportCompanies(r.Context(), &inputs.ImportCompaniesInput{
+		UserID:     userID,
+		Format:     format,
+		Size:       size,
+		Rows:       rows,
+		Background: background,
+	})
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+
+	logger.Infof("import job '%s' created for a file of %d bytes", job.ID, size)
+	w.Header().Set("Location", "/company/imports/"+job.ID.String())
+	if background {
+		render.Status(r, http.StatusAccepted)
+	} else {
+		render.Status(r, http.StatusCreated)
+	}
+	h.codecs.Render(w, r, importJobResponse(job))
+}
+
+// getImportJob returns the status and the progress of an import job of the user
+func (h *handler) getImportJob(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("get import job endpoint called")
+
+	userID, err := authenticatedUser(r)
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+	job, err := h.is.GetImportJob(r.Context(), chi.URLParam(r, "id"), userID)
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+	h.codecs.Render(w, r, importJobResponse(job))
+}
+
+// getImportReport streams the report of an import job of the user as a CSV file, with the outcome of every row
+// processed so far
+func (h *handler) getImportReport(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("get import report endpoint called")
+
+	userID, err := authenticatedUser(r)
+	if err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+
+	// the job is checked before streaming, since the errors cannot be sent once the report is being written
+	id := chi.URLParam(r, "id")
+	if _, err := h.is.GetImportJob(r.Context(), id, userID); err != nil {
+		h.wrapError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="import-%s-report.csv"`, id))
+	report := imports.NewReportWriter(w)
+	err = h.is.StreamImportReport(r.Context(), id, userID, report.Write)
+	if err == nil {
+		err = report.Flush()
+	}
+	if err != nil {
+		logger.Errorf("failed to stream the report of import job '%s': %v", id, err)
+	}
+}
+
+// importFormat returns the format of the imported file, given by the format query parameter or by the media type
+// of the request body
+func importFormat(r *http.Request) (enum.ImportFormat, error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		format := enum.ImportFormat(name)
+		if !format.IsValid() {
+			return "", apierrors.ErrUnsupportedMediaType.WithMessageID("type", i18n.Params{
+				"type":      name,
+				"supported": strings.Join([]string{enum.ImportCSV.String(), enum.ImportNDJSON.String()}, ", "),
+			})
+		}
+		return format, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	format, ok := importFormats[mediaType]
+	if !ok {
+		mediaTypes := make([]string, 0, len(importFormats))
+		for mediaType := range importFormats {
+			mediaTypes = append(mediaTypes, mediaType)
+		}
+		slices.Sort(mediaTypes)
+		return "", apierrors.ErrUnsupportedMediaType.WithMessageID("type", i18n.Params{
+			"type":      r.Header.Get("Content-Type"),
+			"supported": strings.Join(mediaTypes, ", "),
+		})
+	}
+	return format, nil
+}
+
+// spoolBody copies the request body to a temporary file, which is removed once closed, and returns it along with
+// its size. The body is spooled because the imports running in the background outlive the request, and so that the
+// progress is known. The body cannot exceed the maximum size of the imports.
+func (h *handler) spoolBody(w http.ResponseWriter, r *http.Request) (io.ReadCloser, int64, error) {
+	body := http.MaxBytesReader(w, r.Body, h.importMaxSize)
+	file, err := os.CreateTemp("", "company-import-*")
+	if err != nil {
+		return nil, 0, apierrors.ErrInternalServer.WithMessage("failed to create the import file").Wrap(err)
+	}
+	spooled := &spooledFile{File: file}
+
+	size, err := io.Copy(file, body)
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		spooled.Close()
+		return nil, 0, apierrors.ErrPayloadTooLarge.WithMessageID("max", i18n.Params{"max": h.importMaxSize})
+	case err != nil:
+		spooled.Close()
+		return nil, 0, apierrors.ErrInvalidBody.WithMessageID("decode", i18n.Params{"reason": err})
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		spooled.Close()
+		return nil, 0, apierrors.ErrInternalServer.WithMessage("failed to read the import file").Wrap(err)
+	}
+	return spooled, size, nil
+}
+
+// spooledFile is a temporary file that is removed once closed
+type spooledFile struct {
+	*os.File
+}
+
+func (f *spooledFile) Close() error {
+	return errors.Join(f.File.Close(), os.Remove(f.Name()))
+}
+
+// authenticatedUser returns the ID of the user authenticated by the request
+func authenticatedUser(r *http.Request) (uuid.UUID, error) {
+	claims, ok := customMiddlewares.ClaimsFromContext(r.Context())
+	if !ok {
+		return uuid.Nil, apierrors.ErrUnauthorized
+	}
+	userID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return uuid.Nil, apierrors.ErrInvalidToken.Wrap(err)
+	}
+	return userID, nil
+}
+
+// importJobResponse returns the response with the status of the import job
+func importJobResponse(job *models.ImportJobModel) schemas.ImportJobResponse {
+	return schemas.ImportJobResponse{
+		ID:        job.ID.String(),
+		Format:    job.Format.String(),
+		Status:    job.Status.String(),
+		Size:      job.Size,
+		BytesRead: job.BytesRead,
+		Progress:  job.Progress(),
+		Processed: job.Processed,
+		Accepted:  job.Accepted,
+		Rejected:  job.Rejected,
+		Error:     job.Error,
+		Report:    "/company/imports/" + job.ID.String() + "/report",
+		CreatedAt: job.CreatedAt,
+		UpdatedAt: job.UpdatedAt,
+	}
+}
@@ -0,0 +1,148 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/conf"
+	"xm_test/internal/db"
+	"xm_test/internal/db/models"
+	"xm_test/internal/enum"
+	"xm_test/internal/token"
+	"xm_test/internal/transport/http/schemas"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+// importDB returns the import job it holds
+type importDB struct {
+	db.DatabaseAdapter
+	job *models.ImportJobModel
+}
+
+func (d *importDB) GetImportJob(ctx context.Context, id string) (*models.ImportJobModel, error) {
+	if id != d.job.ID.String() {
+		return nil, apierrors.ErrImportJobNotFound
+	}
+	return d.job, nil
+}
+
+type importSuite struct {
+	suite.Suite
+}
+
+func (s *importSuite) TestImportFormat() {
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		format      enum.ImportFormat
+	}{
+		{name: "csv media type", target: "/", contentType: "text/csv; charset=utf-8", format: enum.ImportCSV},
+		{name: "ndjson media type", target: "/", contentType: "application/x-ndjson", format: enum.ImportNDJSON},
+		{name: "query parameter", target: "/?format=ndjson", contentType: "text/plain", format: enum.ImportNDJSON},
+		{name: "unknown media type", target: "/", contentType: "application/xml"},
+		{name: "unknown format", target: "/?format=xlsx", contentType: "text/csv"},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			r := httptest.NewRequest(http.MethodPost, tt.target, nil)
+			r.Header.Set("Content-Type", tt.contentType)
+			format, err := importFormat(r)
+			if tt.format == "" {
+				s.ErrorIs(err, apierrors.ErrUnsupportedMediaType)
+				return
+			}
+			s.Require().NoError(err)
+			s.Equal(tt.format, format)
+		})
+	}
+}
+
+func (s *importSuite) TestSpoolBody() {
+	h := &handler{importMaxSize: 9}
+
+	s.Run("copies the body to a temporary file", func() {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name,type"))
+		file, size, err := h.spoolBody(httptest.NewRecorder(), r)
+		s.Require().NoError(err)
+		s.Equal(int64(9), size)
+
+		data, err := io.ReadAll(file)
+		s.Require().NoError(err)
+		s.Equal("name,type", string(data))
+
+		name := file.(*spooledFile).Name()
+		s.Require().NoError(file.Close())
+		_, err = os.Stat(name)
+		s.ErrorIs(err, os.ErrNotExist)
+	})
+
+	s.Run("limits the size", func() {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name,type,description"))
+		_, _, err := h.spoolBody(httptest.NewRecorder(), r)
+		s.ErrorIs(err, apierrors.ErrPayloadTooLarge)
+	})
+}
+
+func (s *importSuite) TestImportJobResponse() {
+	job := &models.ImportJobModel{ID: uuid.New(), Status: enum.ImportRunning, Size: 200, BytesRead: 50}
+	response := importJobResponse(job)
+	s.Equal(25, response.Progress)
+	s.Equal("/company/imports/"+job.ID.String()+"/report", response.Report)
+
+	job.Status = enum.ImportCompleted
+	s.Equal(100, importJobResponse(job).Progress)
+
+	job.Status, job.Size = enum.ImportFailed, 0
+	s.Equal(0, importJobResponse(job).Progress)
+}
+
+func (s *importSuite) TestGetImportJob() {
+	conf.NewConfig()
+	conf.GlobalConfig.JwtSecret = "secret"
+
+	userID := uuid.New()
+	importDB := &importDB{job: &models.ImportJobModel{ID: uuid.New(), UserID: userID, Status: enum.ImportRunning}}
+	logger := zap.NewNop().Sugar()
+	transport := &httpTransport{logger: logger, handler: newHandler(logger, zap.NewAtomicLevel(), importDB, nil, nil)}
+	router, err := transport.router()
+	s.Require().NoError(err)
+
+	serve := func(userID string) *httptest.ResponseRecorder {
+		accessToken, _, err := token.GenerateToken(userID, "user@xm.com")
+		s.Require().NoError(err)
+		r := httptest.NewRequest(http.MethodGet, "/company/imports/"+importDB.job.ID.String(), nil)
+		r.Header.Set("Authorization", "Bearer "+accessToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	s.Run("returns the job of the user", func() {
+		w := serve(userID.String())
+		s.Require().Equal(http.StatusOK, w.Code, w.Body.String())
+
+		var body schemas.ImportJobResponse
+		s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &body))
+		s.Equal(importDB.job.ID.String(), body.ID)
+	})
+
+	s.Run("hides the jobs of the other users", func() {
+		w := serve(uuid.NewString())
+		s.Equal(http.StatusNotFound, w.Code)
+	})
+}
+
+func TestImportSuite(t *testing.T) {
+	suite.Run(t, new(importSuite))
+}
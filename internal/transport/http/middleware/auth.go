@@ -2,12 +2,14 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"slices"
 	"time"
 	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/conf"
+	"xm_test/internal/i18n"
 	"xm_test/internal/token"
-
-	"github.com/go-chi/render"
 )
 
 type contextKey string
@@ -17,30 +19,82 @@ const claimsKey contextKey = "claims"
 // UserMustBeAuthenticated is a middleware that checks if the user is authenticated.
 func UserMustBeAuthenticated(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// check if the user is authenticated
-		// if not, return an error
+		r, err := authenticate(r)
+		if err != nil {
+			RenderError(w, r, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-		// decode the token from the request header
-		claims, err := token.DecodeTokenFromRequest(r)
+// UserMayBeAuthenticated is a middleware that authenticates the user when the request carries an Authorization
+// header, and lets anonymous requests through. Invalid or expired tokens are still rejected. It is used by the
+// endpoints that serve both public and protected operations, such as GraphQL, which check the claims themselves.
+func UserMayBeAuthenticated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		r, err := authenticate(r)
 		if err != nil {
-			render.Status(r, err.(*apierrors.APIError).HTTPStatus)
-			render.JSON(w, r, err)
+			RenderError(w, r, err)
 			return
 		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticate decodes the token of the request header, checks that it is not expired and adds its claims to the
+// request context
+func authenticate(r *http.Request) (*http.Request, *apierrors.APIError) {
+	// decode the token from the request header
+	claims, err := token.DecodeTokenFromRequest(r)
+	if err != nil {
+		var apiError *apierrors.APIError
+		if !errors.As(err, &apiError) {
+			apiError = apierrors.ErrInvalidToken.Wrap(err)
+		}
+		return r, apiError
+	}
+
+	// check whether the token is not expired
+	if time.Now().After(claims.ExpiresAt.Time) {
+		return r, apierrors.ErrTokenExpired.WithMessageID("expired", nil)
+	}
+
+	// add the claims to the request context
+	r = setUserID(r, claims.ID)
+	ctx := r.Context()
+	ctx = context.WithValue(ctx, claimsKey, claims)
+	return r.WithContext(ctx), nil
+}
 
-		// check whether the token is not expired
-		if time.Now().After(claims.ExpiresAt.Time) {
-			e := apierrors.ErrTokenExpired
-			e.Message = "token is expired"
-			render.Status(r, e.HTTPStatus)
-			render.JSON(w, r, e)
+// UserMustBeAdmin is a middleware that checks if the authenticated user is an administrator. It must be used after
+// UserMustBeAuthenticated.
+func UserMustBeAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := ClaimsFromContext(r.Context())
+		if !ok {
+			e := apierrors.ErrUnauthorized
+			RenderError(w, r, e)
+			return
+		}
+
+		if !slices.Contains(conf.GlobalConfig.AdminEmails, claims.Email) {
+			e := apierrors.ErrForbidden.WithMessageID("admin", i18n.Params{"email": claims.Email})
+			RenderError(w, r, e)
 			return
 		}
 
-		// add the claims to the request context
-		ctx := r.Context()
-		ctx = context.WithValue(ctx, claimsKey, claims)
-		r = r.WithContext(ctx)
 		next.ServeHTTP(w, r)
 	})
 }
+
+// ClaimsFromContext returns the claims of the authenticated user stored in the context
+func ClaimsFromContext(ctx context.Context) (*token.Claims, bool) {
+	claims, ok := ctx.Value(claimsKey).(*token.Claims)
+	return claims, ok
+}
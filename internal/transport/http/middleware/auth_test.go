@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/conf"
+	"xm_test/internal/token"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type authSuite struct {
+	suite.Suite
+}
+
+func (s *authSuite) SetupSuite() {
+	conf.NewConfig()
+	conf.GlobalConfig.JwtSecret = "secret"
+}
+
+// TestConcurrentFailures must be run with the race detector: concurrent requests failing with the same error must
+// each receive their own message, without modifying the shared errors
+func (s *authSuite) TestConcurrentFailures() {
+	handler := UserMustBeAuthenticated(UserMustBeAdmin(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	const requests = 60
+	type result struct {
+		expected string
+		body     apierrors.APIError
+	}
+	results := make([]result, requests)
+
+	var wg sync.WaitGroup
+	for i := range requests {
+		r := httptest.NewRequest(http.MethodGet, "/admin/log-level", nil)
+		switch i % 3 {
+		case 0:
+			results[i].expected = "missing Authorization header"
+		case 1:
+			r.Header.Set("Authorization", "Bearer ")
+			results[i].expected = "missing token in Authorization header"
+		case 2:
+			email := fmt.Sprintf("user%d@example.com", i)
+			accessToken, _, err := token.GenerateToken(fmt.Sprint(i), email)
+			s.Require().NoError(err)
+			r.Header.Set("Authorization", "Bearer "+accessToken)
+			results[i].expected = fmt.Sprintf("user '%s' is not an administrator", email)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			json.Unmarshal(w.Body.Bytes(), &results[i].body)
+		}()
+	}
+	wg.Wait()
+
+	for _, result := range results {
+		s.Equal(result.expected, result.body.Message)
+	}
+	s.Equal("token not found", apierrors.ErrTokenNotFound.Message)
+	s.Equal("forbidden", apierrors.ErrForbidden.Message)
+}
+
+func TestAuthSuite(t *testing.T) {
+	suite.Run(t, new(authSuite))
+}
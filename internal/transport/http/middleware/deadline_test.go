@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type deadlineSuite struct {
+	suite.Suite
+}
+
+func (s *deadlineSuite) TestDeadline() {
+	s.Run("sets the deadline", func() {
+		var deadline time.Time
+		var ok bool
+		handler := Deadline(time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			deadline, ok = r.Context().Deadline()
+		}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		s.True(ok)
+		s.WithinDuration(time.Now().Add(time.Minute), deadline, time.Second)
+	})
+
+	s.Run("cancels the context", func() {
+		var err error
+		handler := Deadline(time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-r.Context().Done()
+			err = r.Context().Err()
+		}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		s.ErrorIs(err, context.DeadlineExceeded)
+	})
+
+	s.Run("disabled", func() {
+		var ok bool
+		handler := Deadline(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, ok = r.Context().Deadline()
+		}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		s.False(ok)
+	})
+}
+
+func TestDeadlineSuite(t *testing.T) {
+	suite.Run(t, new(deadlineSuite))
+}
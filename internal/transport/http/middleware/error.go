@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/i18n"
+	"xm_test/internal/logging"
+	"xm_test/internal/transport/http/codec"
+	"xm_test/internal/transport/http/negotiation"
+
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+)
+
+// RenderError writes the error to the response, including the ID of the request. The error is written as an RFC
+// 7807 problem when the client prefers application/problem+json over application/json in its Accept header, and in
+// the legacy shape otherwise, so the existing clients keep working. The legacy shape is encoded with the media type
+// negotiated with the Accept header, like any other response. The messages are translated to the language of the
+// Accept-Language header, falling back to English.
+func RenderError(w http.ResponseWriter, r *http.Request, err *apierrors.APIError) {
+	tag := i18n.Match(r.Header.Get("Accept-Language"))
+	response := *err.Localize(tag)
+	response.RequestID = RequestIDFromContext(r.Context())
+
+	w.Header().Set("Content-Language", tag.String())
+	w.Header().Add("Vary", "Accept-Language")
+
+	// the problems are JSON documents, so they are only sent to the clients that would otherwise get JSON
+	accept := r.Header.Get("Accept")
+	mediaType, _, ok := codec.Default.Negotiate(accept)
+	if (ok && mediaType != codec.JSONMediaType) || !prefersProblem(accept) {
+		render.Status(r, response.HTTPStatus)
+		codec.Default.Render(w, r, &response)
+		return
+	}
+
+	data, marshalErr := json.Marshal(response.Problem(r.URL.Path))
+	if marshalErr != nil {
+		http.Error(w, marshalErr.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", apierrors.ProblemContentType)
+	w.WriteHeader(response.HTTPStatus)
+	w.Write(data)
+}
+
+// RenderEncodeError logs the failure to encode a response body and renders the internal server error instead. The
+// error is rendered as JSON, since the codec negotiated with the Accept header may be the one failing. It is the
+// error renderer of the codec registries of the API.
+func RenderEncodeError(w http.ResponseWriter, r *http.Request, err error) {
+	logger := logging.FromContext(r.Context(), zap.S())
+	logger.Errorf("failed to encode the response: %v", err)
+
+	if mediaType, _, ok := codec.Default.Negotiate(r.Header.Get("Accept")); ok && mediaType != codec.JSONMediaType {
+		r = r.Clone(r.Context())
+		r.Header.Set("Accept", codec.JSONMediaType)
+	}
+	RenderError(w, r, apierrors.ErrInternalServer.Wrap(err))
+}
+
+// prefersProblem tells whether the Accept header prefers the problem media type over plain JSON. Wildcards are not
+// taken into account, so the problems are only sent to the clients asking for them.
+func prefersProblem(accept string) bool {
+	problemQ := negotiation.ExplicitQuality(accept, apierrors.ProblemContentType)
+	return problemQ > 0 && problemQ >= negotiation.ExplicitQuality(accept, codec.JSONMediaType)
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/i18n"
+	"xm_test/internal/logging"
+	"xm_test/internal/transport/http/codec"
+
+	"github.com/go-chi/render"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type errorSuite struct {
+	suite.Suite
+}
+
+func (s *errorSuite) render(accept string) *httptest.ResponseRecorder {
+	e := apierrors.NewAPIError(apierrors.ErrInvalidBody.Code, "name is required", apierrors.ErrInvalidBody.HTTPStatus)
+	e.Errors = []apierrors.FieldError{{Pointer: "/name", Message: "name is required"}}
+
+	r := httptest.NewRequest(http.MethodPost, "/company/create", nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	RenderError(w, r, e)
+	return w
+}
+
+func (s *errorSuite) TestRenderError() {
+	s.Run("renders the legacy shape by default", func() {
+		for _, accept := range []string{"", "*/*", "application/json", "application/json, application/problem+json;q=0.5"} {
+			w := s.render(accept)
+			s.Equal(http.StatusBadRequest, w.Code, accept)
+			s.Contains(w.Header().Get("Content-Type"), "application/json", accept)
+
+			var body apierrors.APIError
+			s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &body))
+			s.Equal(apierrors.ErrInvalidBody.Code, body.Code)
+			s.Equal("name is required", body.Message)
+			s.Len(body.Errors, 1)
+		}
+	})
+
+	s.Run("renders the legacy shape in the negotiated media type", func() {
+		for _, accept := range []string{"application/xml", "application/xml, application/problem+json;q=0.5"} {
+			w := s.render(accept)
+			s.Equal(http.StatusBadRequest, w.Code, accept)
+			s.Equal("application/xml", w.Header().Get("Content-Type"), accept)
+
+			var body struct {
+				Code    string `xml:"code"`
+				Message string `xml:"message"`
+			}
+			s.Require().NoError(xml.Unmarshal(w.Body.Bytes(), &body))
+			s.Equal(apierrors.ErrInvalidBody.Code, body.Code)
+			s.Equal("name is required", body.Message)
+		}
+	})
+
+	s.Run("renders a problem when the client prefers it", func() {
+		for _, accept := range []string{"application/problem+json", "application/json;q=0.9, application/problem+json"} {
+			w := s.render(accept)
+			s.Equal(http.StatusBadRequest, w.Code, accept)
+			s.Equal(apierrors.ProblemContentType, w.Header().Get("Content-Type"), accept)
+
+			var problem apierrors.Problem
+			s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &problem))
+			s.Equal(apierrors.Problem{
+				Type:     "/problems/invalid-body",
+				Title:    "Invalid request body",
+				Status:   http.StatusBadRequest,
+				Detail:   "name is required",
+				Instance: "/company/create",
+				Code:     apierrors.ErrInvalidBody.Code,
+				Errors:   []apierrors.FieldError{{Pointer: "/name", Message: "name is required"}},
+			}, problem)
+		}
+	})
+}
+
+func (s *errorSuite) TestRenderEncodeError() {
+	registry := codec.NewDefaultRegistry(codec.WithErrorRenderer(RenderEncodeError))
+	for _, accept := range []string{"", "application/xml", "application/msgpack"} {
+		core, logs := observer.New(zap.ErrorLevel)
+		r := httptest.NewRequest(http.MethodGet, "/company/export", nil)
+		r = r.WithContext(logging.WithLogger(r.Context(), zap.New(core).Sugar()))
+		r.Header.Set("Accept", accept)
+		render.Status(r, http.StatusCreated)
+		w := httptest.NewRecorder()
+		registry.Render(w, r, map[string]any{"callback": func() {}})
+
+		s.Equal(http.StatusInternalServerError, w.Code, accept)
+		s.Equal(codec.JSONMediaType, w.Header().Get("Content-Type"), accept)
+		var body apierrors.APIError
+		s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &body), accept)
+		s.Equal(apierrors.ErrInternalServer.Code, body.Code, accept)
+		s.Equal(1, logs.FilterMessageSnippet("failed to encode the response").Len(), accept)
+	}
+}
+
+func (s *errorSuite) TestLocalizeError() {
+	render := func(acceptLanguage string) (*httptest.ResponseRecorder, apierrors.APIError) {
+		e := apierrors.ErrUserAlreadyExists.WithMessageID("email", i18n.Params{"email": "jane@xm.com"})
+		r := httptest.NewRequest(http.MethodPost, "/auth/register", nil)
+		r.Header.Set("Accept-Language", acceptLanguage)
+		w := httptest.NewRecorder()
+		RenderError(w, r, e)
+
+		var body apierrors.APIError
+		s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &body))
+		return w, body
+	}
+
+	s.Run("translates the message to the language of the client", func() {
+		w, body := render("fr-FR, en;q=0.5")
+		s.Equal("fr", w.Header().Get("Content-Language"))
+		s.Contains(w.Header().Values("Vary"), "Accept-Language")
+		s.Equal("un utilisateur avec l'email 'jane@xm.com' existe déjà", body.Message)
+	})
+
+	s.Run("falls back to English", func() {
+		w, body := render("pl")
+		s.Equal("en", w.Header().Get("Content-Language"))
+		s.Equal("user with email 'jane@xm.com' already exists", body.Message)
+	})
+}
+
+func TestErrorSuite(t *testing.T) {
+	suite.Run(t, new(errorSuite))
+}
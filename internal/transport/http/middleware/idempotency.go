@@ -0,0 +1,232 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"time"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/db/models"
+	"xm_test/internal/i18n"
+	"xm_test/internal/logging"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// IdempotencyKeyHeader is the header holding the key chosen by the client to identify the retries of a request
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// IdempotentReplayedHeader is set on the responses replayed to the retries of a request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// maxIdempotencyKeyLength is the maximum length of an idempotency key
+	maxIdempotencyKeyLength = 255
+)
+
+// idempotencyPollInterval is the delay between the checks of a key reserved by a concurrent request
+var idempotencyPollInterval = 50 * time.Millisecond
+
+// IdempotencyStore keeps the responses of the requests sent with an idempotency key. It is implemented by the
+// database adapter.
+type IdempotencyStore interface {
+	ReserveIdempotencyKey(ctx context.Context, key *models.IdempotencyKeyModel) (*models.IdempotencyKeyModel, error)
+	CompleteIdempotencyKey(ctx context.Context, key *models.IdempotencyKeyModel) error
+	ReleaseIdempotencyKey(ctx context.Context, userID string, key string) error
+}
+
+// Idempotency is a middleware that processes only once the POST, PUT, PATCH and DELETE requests sent by a user with
+// the same Idempotency-Key header. The first response is stored for the TTL and replayed to the retries, which fail
+// with ErrIdempotencyKeyReused when their method, path or body differ. The retries sent while the first request is
+// processed wait for its response. The server errors are not stored, so the request can be retried with the same key,
+// and a request holding a key for longer than the lock timeout is considered abandoned. The bodies are read in memory
+// to fingerprint the requests, so the ones larger than maxBodySize are rejected with ErrPayloadTooLarge. It must be
+// used after UserMustBeAuthenticated.
+func Idempotency(logger *zap.SugaredLogger, store IdempotencyStore, ttl, lockTimeout time.Duration, maxBodySize int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			claims, ok := ClaimsFromContext(r.Context())
+			if key == "" || !ok || !slices.Contains([]string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}, r.Method) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			logger := logging.FromContext(r.Context(), logger)
+
+			if len(key) > maxIdempotencyKeyLength {
+				RenderError(w, r, apierrors.ErrInvalidIdempotencyKey.WithMessageID("length", i18n.Params{"max": maxIdempotencyKeyLength}))
+				return
+			}
+			userID, err := uuid.Parse(claims.ID)
+			if err != nil {
+				RenderError(w, r, apierrors.ErrInvalidToken.Wrap(err))
+				return
+			}
+
+			// the body is read to fingerprint the request, and restored for the handler
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				RenderError(w, r, apierrors.ErrPayloadTooLarge.WithMessageID("max", i18n.Params{"max": maxBytesErr.Limit}))
+				return
+			}
+			if err != nil {
+				RenderError(w, r, apierrors.ErrInvalidBody.WithMessageID("decode", i18n.Params{"reason": err}))
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			now := time.Now()
+			reservation := &models.IdempotencyKeyModel{
+				UserID:      userID,
+				Key:         key,
+				RequestHash: requestHash(r, body),
+				LockedUntil: now.Add(lockTimeout),
+				ExpiresAt:   now.Add(ttl),
+			}
+			stored, apiErr := reserve(r.Context(), store, reservation)
+			if apiErr != nil {
+				logger.Errorf("failed to reserve idempotency key '%s': %s", key, apiErr)
+				RenderError(w, r, apiErr)
+				return
+			}
+			if stored != nil {
+				logger.Infof("replaying the response of idempotency key '%s'", key)
+				for name, values := range stored.Headers {
+					w.Header()[name] = values
+				}
+				w.Header().Set(IdempotentReplayedHeader, "true")
+				w.WriteHeader(stored.Status)
+				w.Write(stored.Body)
+				return
+			}
+
+			// the response is stored once it is sent. A retry arriving meanwhile waits until it is stored.
+			headers := w.Header().Clone()
+			rw := &recordingResponseWriter{ResponseWriter: w}
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				if err := store.ReleaseIdempotencyKey(context.WithoutCancel(r.Context()), userID.String(), key); err != nil {
+					logger.Errorf("failed to release idempotency key '%s': %s", key, err)
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+
+			status := rw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if rw.streaming || status >= http.StatusInternalServerError || status == apierrors.StatusClientClosedRequest {
+				logger.Debugf("the response of idempotency key '%s' is not stored", key)
+				return
+			}
+
+			reservation.Status = status
+			reservation.Headers = make(map[string][]string)
+			for name, values := range w.Header() {
+				// the headers set by the outer middlewares, such as the request ID, are set again on replay
+				if !slices.Equal(headers[name], values) {
+					reservation.Headers[name] = values
+				}
+			}
+			reservation.Body = rw.body.Bytes()
+			if err := store.CompleteIdempotencyKey(context.WithoutCancel(r.Context()), reservation); err != nil {
+				logger.Errorf("failed to store the response of idempotency key '%s': %s", key, err)
+				return
+			}
+			completed = true
+		})
+	}
+}
+
+// reserve reserves the key of the request and returns nil, or returns the stored response to the same request. It
+// waits while a concurrent request holds the key.
+func reserve(ctx context.Context, store IdempotencyStore, reservation *models.IdempotencyKeyModel) (*models.IdempotencyKeyModel, *apierrors.APIError) {
+	for {
+		stored, err := store.ReserveIdempotencyKey(ctx, reservation)
+		if err != nil {
+			if ctxErr := apierrors.FromContext(ctx); ctxErr != nil {
+				return nil, ctxErr
+			}
+			var apiError *apierrors.APIError
+			if !errors.As(err, &apiError) {
+				apiError = apierrors.ErrInternalServer.Wrap(err)
+			}
+			return nil, apiError
+		}
+		if stored == nil {
+			return nil, nil
+		}
+		if stored.RequestHash != reservation.RequestHash {
+			return nil, apierrors.ErrIdempotencyKeyReused.WithMessageID("key", i18n.Params{"key": reservation.Key})
+		}
+		if stored.Completed {
+			return stored, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, apierrors.FromContext(ctx)
+		case <-time.After(idempotencyPollInterval):
+		}
+	}
+}
+
+// requestHash fingerprints the method, path and body of the request
+func requestHash(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// recordingResponseWriter sends the response while recording it, so it can be stored. The streamed responses are
+// not recorded.
+type recordingResponseWriter struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	streaming bool
+}
+
+// WriteHeader records the status of the response
+func (w *recordingResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the body of the response
+func (w *recordingResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.streaming {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush sends the response written so far, which is then considered streamed
+func (w *recordingResponseWriter) Flush() {
+	w.streaming = true
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the original response writer, so http.ResponseController can reach it
+func (w *recordingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
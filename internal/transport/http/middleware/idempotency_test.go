@@ -0,0 +1,214 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/conf"
+	"xm_test/internal/db/models"
+	"xm_test/internal/token"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+// memoryIdempotencyStore keeps the idempotency keys in memory, like the database does
+type memoryIdempotencyStore struct {
+	mu   sync.Mutex
+	keys map[string]models.IdempotencyKeyModel
+}
+
+func (m *memoryIdempotencyStore) ReserveIdempotencyKey(_ context.Context, key *models.IdempotencyKeyModel) (*models.IdempotencyKeyModel, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	id := key.UserID.String() + "/" + key.Key
+	if stored, ok := m.keys[id]; ok && stored.ExpiresAt.After(time.Now()) {
+		return &stored, nil
+	}
+	m.keys[id] = *key
+	return nil, nil
+}
+
+func (m *memoryIdempotencyStore) CompleteIdempotencyKey(_ context.Context, key *models.IdempotencyKeyModel) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key.Completed = true
+	m.keys[key.UserID.String()+"/"+key.Key] = *key
+	return nil
+}
+
+func (m *memoryIdempotencyStore) ReleaseIdempotencyKey(_ context.Context, userID string, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.keys, userID+"/"+key)
+	return nil
+}
+
+type idempotencySuite struct {
+	suite.Suite
+	store  *memoryIdempotencyStore
+	claims *token.Claims
+}
+
+func (s *idempotencySuite) SetupSuite() {
+	conf.NewConfig()
+	conf.GlobalConfig.JwtSecret = "secret"
+}
+
+func (s *idempotencySuite) SetupTest() {
+	s.store = &memoryIdempotencyStore{keys: make(map[string]models.IdempotencyKeyModel)}
+	idempotencyPollInterval = time.Millisecond
+
+	// the claims are parsed from a real token, so the user is read from the same claim as in the requests
+	accessToken, _, err := token.GenerateToken(uuid.NewString(), "user@xm.com")
+	s.Require().NoError(err)
+	s.claims, err = token.ValidateAndParseToken(accessToken)
+	s.Require().NoError(err)
+}
+
+// handler returns the idempotency middleware wrapping the handler, which counts its calls
+func (s *idempotencySuite) handler(calls *atomic.Int32, handler http.HandlerFunc) http.Handler {
+	return Idempotency(zap.NewNop().Sugar(), s.store, time.Hour, time.Minute, 1024)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		handler(w, r)
+	}))
+}
+
+// request sends a request of the user with the idempotency key
+func (s *idempotencySuite) request(handler http.Handler, method, key, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/company/create", strings.NewReader(body))
+	if key != "" {
+		r.Header.Set(IdempotencyKeyHeader, key)
+	}
+	r = r.WithContext(context.WithValue(r.Context(), claimsKey, s.claims))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+// created echoes the body of the request
+func created(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Location", "/company/1")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(body)
+}
+
+func (s *idempotencySuite) TestReplay() {
+	s.Run("replays the first response", func() {
+		var calls atomic.Int32
+		handler := s.handler(&calls, created)
+
+		first := s.request(handler, http.MethodPost, "key", `{"name": "xm"}`)
+		s.Equal(http.StatusCreated, first.Code)
+		s.Empty(first.Header().Get(IdempotentReplayedHeader))
+
+		retry := s.request(handler, http.MethodPost, "key", `{"name": "xm"}`)
+		s.Equal(http.StatusCreated, retry.Code)
+		s.Equal(`{"name": "xm"}`, retry.Body.String())
+		s.Equal("/company/1", retry.Header().Get("Location"))
+		s.Equal("true", retry.Header().Get(IdempotentReplayedHeader))
+		s.Equal(int32(1), calls.Load())
+
+		// the key is stored for the user of the token
+		s.Contains(s.store.keys, s.claims.ID+"/key")
+	})
+
+	s.Run("fails when the key is reused with another body", func() {
+		var calls atomic.Int32
+		handler := s.handler(&calls, created)
+
+		s.request(handler, http.MethodPost, "reused", `{"name": "xm"}`)
+		w := s.request(handler, http.MethodPost, "reused", `{"name": "other"}`)
+		s.Equal(http.StatusConflict, w.Code)
+
+		var body apierrors.APIError
+		s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &body))
+		s.Equal(apierrors.ErrIdempotencyKeyReused.Code, body.Code)
+		s.Equal(int32(1), calls.Load())
+	})
+
+	s.Run("does not store the server errors", func() {
+		var calls atomic.Int32
+		handler := s.handler(&calls, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+
+		s.Equal(http.StatusInternalServerError, s.request(handler, http.MethodPost, "failed", "").Code)
+		s.Equal(http.StatusInternalServerError, s.request(handler, http.MethodPost, "failed", "").Code)
+		s.Equal(int32(2), calls.Load())
+	})
+
+	s.Run("ignores the requests without key or that do not modify resources", func() {
+		var calls atomic.Int32
+		handler := s.handler(&calls, created)
+
+		s.request(handler, http.MethodPost, "", `{}`)
+		s.request(handler, http.MethodPost, "", `{}`)
+		s.request(handler, http.MethodGet, "get", "")
+		s.request(handler, http.MethodGet, "get", "")
+		s.Equal(int32(4), calls.Load())
+	})
+
+	s.Run("rejects the bodies larger than the maximum size", func() {
+		var calls atomic.Int32
+		w := s.request(s.handler(&calls, created), http.MethodPost, "large", strings.Repeat("a", 1025))
+		s.Equal(http.StatusRequestEntityTooLarge, w.Code)
+		s.Contains(w.Body.String(), apierrors.ErrPayloadTooLarge.Code)
+		s.Zero(calls.Load())
+	})
+
+	s.Run("rejects the keys that are too long", func() {
+		var calls atomic.Int32
+		w := s.request(s.handler(&calls, created), http.MethodPost, strings.Repeat("k", maxIdempotencyKeyLength+1), "")
+		s.Equal(http.StatusBadRequest, w.Code)
+		s.Zero(calls.Load())
+	})
+}
+
+// TestConcurrentRequests must be run with the race detector: the duplicates sent while the first request is
+// processed wait for its response instead of being processed
+func (s *idempotencySuite) TestConcurrentRequests() {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	handler := s.handler(&calls, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		created(w, r)
+	})
+
+	const requests = 10
+	responses := make([]*httptest.ResponseRecorder, requests)
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i] = s.request(handler, http.MethodPost, "concurrent", `{"name": "xm"}`)
+		}()
+	}
+
+	// the first request is processed while the others wait
+	s.Eventually(func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	s.Equal(int32(1), calls.Load())
+	for _, w := range responses {
+		s.Equal(http.StatusCreated, w.Code)
+		s.Equal(`{"name": "xm"}`, w.Body.String())
+	}
+}
+
+func TestIdempotencySuite(t *testing.T) {
+	suite.Run(t, new(idempotencySuite))
+}
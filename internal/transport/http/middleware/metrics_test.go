@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"xm_test/internal/metrics"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type metricsSuite struct {
+	suite.Suite
+}
+
+func (s *metricsSuite) TestMetrics() {
+	r := chi.NewRouter()
+	r.Use(Metrics)
+	r.Get("/company/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	r.Get("/ok", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	s.Run("labels by route pattern", func() {
+		before := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/company/{id}", "404"))
+		for _, id := range []string{"a", "b"} {
+			r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/company/"+id, nil))
+		}
+		after := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/company/{id}", "404"))
+		s.Equal(2.0, after-before)
+	})
+
+	s.Run("implicit status", func() {
+		before := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/ok", "200"))
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+		after := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/ok", "200"))
+		s.Equal(1.0, after-before)
+	})
+
+	s.Run("unmatched route", func() {
+		before := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404"))
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/does/not/exist", nil))
+		after := testutil.ToFloat64(metrics.HTTPRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404"))
+		s.Equal(1.0, after-before)
+		s.Equal(0.0, testutil.ToFloat64(metrics.HTTPRequestsInFlight))
+	})
+}
+
+func TestMetricsSuite(t *testing.T) {
+	suite.Run(t, new(metricsSuite))
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/i18n"
+	"xm_test/internal/transport/http/codec"
+	"xm_test/internal/transport/http/negotiation"
+)
+
+// Negotiate is a middleware that rejects with the NOT_ACCEPTABLE error the requests whose Accept header allows none
+// of the media types of the registry, before they are handled. The clients only accepting problems are let through,
+// since they get their errors as problems and the rest of the responses in the default media type.
+func Negotiate(registry *codec.Registry) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accept := r.Header.Get("Accept")
+			if _, _, ok := registry.Negotiate(accept); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if _, ok := negotiation.Negotiate(accept, []string{apierrors.ProblemContentType}); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			RenderError(w, r, apierrors.ErrNotAcceptable.WithMessageID("type", i18n.Params{
+				"type":      accept,
+				"supported": strings.Join(registry.MediaTypes(), ", "),
+			}))
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/transport/http/codec"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type negotiateSuite struct {
+	suite.Suite
+}
+
+func (s *negotiateSuite) serve(accept string) (*httptest.ResponseRecorder, bool) {
+	var called bool
+	handler := Negotiate(codec.Default)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/company/1", nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w, called
+}
+
+func (s *negotiateSuite) TestNegotiate() {
+	s.Run("accepts the supported media types", func() {
+		for _, accept := range []string{
+			"", "*/*", "application/json", "application/xml", "text/xml", "application/msgpack",
+			"application/x-msgpack", "application/cbor", "text/html, application/*;q=0.1", "application/problem+json",
+		} {
+			_, called := s.serve(accept)
+			s.True(called, accept)
+		}
+	})
+
+	s.Run("rejects the unsupported media types", func() {
+		for _, accept := range []string{"text/html", "application/json;q=0, text/csv"} {
+			w, called := s.serve(accept)
+			s.False(called, accept)
+			s.Equal(http.StatusNotAcceptable, w.Code, accept)
+
+			var body apierrors.APIError
+			s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &body))
+			s.Equal(apierrors.ErrNotAcceptable.Code, body.Code)
+			s.Contains(body.Message, "application/xml")
+		}
+	})
+}
+
+func TestNegotiateSuite(t *testing.T) {
+	suite.Run(t, new(negotiateSuite))
+}
@@ -0,0 +1,134 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/i18n"
+	"xm_test/internal/logging"
+	"xm_test/internal/transport/http/openapi"
+
+	"go.uber.org/zap"
+)
+
+// ValidateOpenAPI is a middleware that validates the messages against the OpenAPI document. When requests is set,
+// the requests that do not match the document are rejected with every violation found. When responses is set, the
+// responses that do not match the document are replaced by an error. Since the responses are buffered to be validated, it
+// is meant for tests. Streamed responses are sent as soon as they are flushed and are not validated.
+func ValidateOpenAPI(logger *zap.SugaredLogger, validator *openapi.Validator, requests, responses bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := logging.FromContext(r.Context(), logger)
+
+			if requests {
+				violations, err := validator.ValidateRequest(w, r)
+				if err != nil {
+					e := apierrors.ErrInvalidBody.WithMessageID("decode", i18n.Params{"reason": err})
+					var maxBytesErr *http.MaxBytesError
+					if errors.As(err, &maxBytesErr) {
+						e = apierrors.ErrPayloadTooLarge.WithMessageID("max", i18n.Params{"max": maxBytesErr.Limit})
+					}
+					logger.Error(e.Message)
+					RenderError(w, r, e)
+					return
+				}
+				if len(violations) > 0 {
+					e := withViolations(apierrors.ErrInvalidRequest, violations)
+					logger.Error(e.Message)
+					RenderError(w, r, e)
+					return
+				}
+			}
+
+			if !responses {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			bw := &bufferedResponseWriter{ResponseWriter: w}
+			next.ServeHTTP(bw, r)
+			if bw.streaming {
+				return
+			}
+
+			status := bw.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			violations, err := validator.ValidateResponse(r.Method, r.URL.Path, status, w.Header().Get("Content-Type"), bw.body.Bytes())
+			if err != nil {
+				violations = []apierrors.FieldError{{Message: err.Error()}}
+			}
+			if len(violations) > 0 {
+				e := withViolations(apierrors.ErrInvalidResponse, violations)
+				logger.Errorw(e.Message, "status", status, "violations", violations)
+
+				w.Header().Del("Content-Length")
+				RenderError(w, r, e)
+				return
+			}
+
+			w.WriteHeader(status)
+			w.Write(bw.body.Bytes())
+		})
+	}
+}
+
+// withViolations returns a copy of the error with the violations of the message, summarized by its message
+func withViolations(e *apierrors.APIError, violations []apierrors.FieldError) *apierrors.APIError {
+	if len(violations) == 1 {
+		return e.WithMessageID("violation", nil).WithErrors(violations)
+	}
+	return e.WithMessageID("violations", i18n.Params{"count": len(violations)}).WithErrors(violations)
+}
+
+// bufferedResponseWriter holds the response until the handler returns, so it can be validated. The response is
+// streamed as is once the handler flushes it.
+type bufferedResponseWriter struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	streaming bool
+}
+
+// WriteHeader records the status of the response
+func (w *bufferedResponseWriter) WriteHeader(status int) {
+	if w.streaming {
+		w.ResponseWriter.WriteHeader(status)
+		return
+	}
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+// Write buffers the body of the response
+func (w *bufferedResponseWriter) Write(b []byte) (int, error) {
+	if w.streaming {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(b)
+}
+
+// Flush sends the buffered response and streams the rest of it
+func (w *bufferedResponseWriter) Flush() {
+	if !w.streaming {
+		w.streaming = true
+		if w.status != 0 {
+			w.ResponseWriter.WriteHeader(w.status)
+		}
+		w.ResponseWriter.Write(w.body.Bytes())
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the original response writer, so http.ResponseController can reach it
+func (w *bufferedResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
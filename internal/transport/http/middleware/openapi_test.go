@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/transport/http/openapi"
+	"xm_test/internal/transport/http/schemas"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+type openAPISuite struct {
+	suite.Suite
+}
+
+// newRouter returns a router serving the handler on the documented routes, validated against their document
+func (s *openAPISuite) newRouter(handler http.HandlerFunc, requests, responses bool) *chi.Mux {
+	validator := openapi.NewValidator(1024)
+	r := chi.NewRouter()
+	r.Use(ValidateOpenAPI(zap.NewNop().Sugar(), validator, requests, responses))
+	r.Post("/company/create", handler)
+	r.Get("/events/stream", handler)
+
+	doc, err := openapi.Build(openapi.Info{Title: "test", Version: "1"}, r, map[string]openapi.Endpoint{
+		openapi.Key(http.MethodPost, "/company/create"): {
+			Request:   schemas.CreateCompanyRequest{},
+			Responses: []openapi.EndpointResponse{{Status: http.StatusOK, Description: "ok", Body: schemas.OkResponse{}}},
+		},
+		openapi.Key(http.MethodGet, "/events/stream"): {
+			Responses: []openapi.EndpointResponse{{Status: http.StatusOK, Description: "ok", Body: "", ContentType: "text/event-stream"}},
+		},
+	})
+	s.Require().NoError(err)
+	s.Require().NoError(validator.Load(doc))
+	return r
+}
+
+func (s *openAPISuite) TestValidateRequests() {
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, schemas.OkResponse{Message: "ok"})
+	}
+
+	s.Run("rejects the invalid requests with every violation", func() {
+		r := s.newRouter(ok, true, false)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/company/create", strings.NewReader(`{"name": "xm", "type": "Unknown"}`)))
+		s.Equal(http.StatusBadRequest, w.Code)
+
+		var body apierrors.APIError
+		s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &body))
+		s.Equal(apierrors.ErrInvalidRequest.Code, body.Code)
+		s.Equal("the request has 3 invalid fields", body.Message)
+		s.Len(body.Errors, 3)
+		var pointers []string
+		for _, e := range body.Errors {
+			pointers = append(pointers, e.Pointer)
+		}
+		s.Equal([]string{"/amount_employees", "/registered", "/type"}, pointers)
+	})
+
+	s.Run("rejects the malformed bodies", func() {
+		r := s.newRouter(ok, true, false)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/company/create", strings.NewReader(`{`)))
+		s.Equal(http.StatusBadRequest, w.Code)
+		s.Contains(w.Body.String(), apierrors.ErrInvalidBody.Code)
+	})
+
+	s.Run("rejects the bodies larger than the maximum size", func() {
+		r := s.newRouter(ok, true, false)
+		w := httptest.NewRecorder()
+		body := fmt.Sprintf(`{"name": %q, "amount_employees": 10, "registered": true, "type": "NonProfit"}`, strings.Repeat("a", 1024))
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/company/create", strings.NewReader(body)))
+		s.Equal(http.StatusRequestEntityTooLarge, w.Code)
+		s.Contains(w.Body.String(), apierrors.ErrPayloadTooLarge.Code)
+	})
+
+	s.Run("passes the valid requests", func() {
+		r := s.newRouter(ok, true, false)
+		w := httptest.NewRecorder()
+		body := `{"name": "xm", "amount_employees": 10, "registered": true, "type": "NonProfit"}`
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/company/create", strings.NewReader(body)))
+		s.Equal(http.StatusOK, w.Code)
+	})
+}
+
+func (s *openAPISuite) TestValidateResponses() {
+	valid := `{"name": "xm", "amount_employees": 10, "registered": true, "type": "NonProfit"}`
+
+	s.Run("replaces the invalid responses", func() {
+		r := s.newRouter(func(w http.ResponseWriter, r *http.Request) {
+			render.JSON(w, r, map[string]int{"message": 1})
+		}, false, true)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/company/create", strings.NewReader(valid)))
+		s.Equal(http.StatusInternalServerError, w.Code)
+
+		var body apierrors.APIError
+		s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &body))
+		s.Equal(apierrors.ErrInvalidResponse.Code, body.Code)
+		s.Require().Len(body.Errors, 1)
+		s.Equal("/message", body.Errors[0].Pointer)
+	})
+
+	s.Run("sends the valid responses", func() {
+		r := s.newRouter(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Test", "true")
+			render.Status(r, http.StatusOK)
+			render.JSON(w, r, schemas.OkResponse{Message: "ok"})
+		}, false, true)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/company/create", strings.NewReader(valid)))
+		s.Equal(http.StatusOK, w.Code)
+		s.Equal("true", w.Header().Get("X-Test"))
+		s.JSONEq(`{"message": "ok"}`, w.Body.String())
+	})
+
+	s.Run("streams the flushed responses", func() {
+		r := s.newRouter(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			w.(http.Flusher).Flush()
+			fmt.Fprint(w, "data: {}\n\n")
+		}, false, true)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/events/stream", nil))
+		s.Equal(http.StatusOK, w.Code)
+		s.True(w.Flushed)
+		s.Equal("data: {}\n\n", w.Body.String())
+	})
+}
+
+func TestOpenAPISuite(t *testing.T) {
+	suite.Run(t, new(openAPISuite))
+}
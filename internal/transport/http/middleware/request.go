@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"xm_test/internal/logging"
+	"xm_test/internal/requestid"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader is the header used to receive and return the ID of the request
+const RequestIDHeader = "X-Request-ID"
+
+const requestKey contextKey = "request"
+
+// requestInfo holds the data of the request that is only known by the inner middlewares, such as the
+// authenticated user, so that it can be read by the outer ones
+type requestInfo struct {
+	id     string
+	userID string
+}
+
+// RequestContext is a middleware that assigns an ID to the request, honouring the X-Request-ID header sent by the
+// client, and stores a logger with the request and trace IDs in the request context. The ID is returned in the
+// X-Request-ID header of the response.
+func RequestContext(logger *zap.SugaredLogger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if !requestid.IsValid(id) {
+				id = uuid.NewString()
+			}
+			w.Header().Set(RequestIDHeader, id)
+
+			fields := []any{"request_id", id}
+			if span := trace.SpanContextFromContext(r.Context()); span.IsValid() {
+				fields = append(fields, "trace_id", span.TraceID().String())
+			}
+
+			ctx := context.WithValue(r.Context(), requestKey, &requestInfo{id: id})
+			ctx = logging.WithLogger(ctx, logger.With(fields...))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// AccessLog is a middleware that logs every request once it is completed, with its method, route, status,
+// latency, user and request IDs. It must be used after RequestContext.
+func AccessLog(logger *zap.SugaredLogger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			route := unmatchedRoute
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+				route = rctx.RoutePattern()
+			}
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			fields := []any{
+				"method", r.Method,
+				"route", route,
+				"path", r.URL.Path,
+				"status", status,
+				"latency", time.Since(start),
+				"bytes", ww.BytesWritten(),
+			}
+			if info, ok := r.Context().Value(requestKey).(*requestInfo); ok {
+				fields = append(fields, "request_id", info.id)
+				if info.userID != "" {
+					fields = append(fields, "user_id", info.userID)
+				}
+			}
+
+			switch {
+			case status >= http.StatusInternalServerError:
+				logger.Errorw("request completed", fields...)
+			case status >= http.StatusBadRequest:
+				logger.Warnw("request completed", fields...)
+			default:
+				logger.Infow("request completed", fields...)
+			}
+		})
+	}
+}
+
+// RequestIDFromContext returns the ID of the request, or an empty string if the context does not belong to a request
+func RequestIDFromContext(ctx context.Context) string {
+	if info, ok := ctx.Value(requestKey).(*requestInfo); ok {
+		return info.id
+	}
+	return ""
+}
+
+// setUserID records the authenticated user in the request and adds it to the request-scoped logger
+func setUserID(r *http.Request, userID string) *http.Request {
+	if info, ok := r.Context().Value(requestKey).(*requestInfo); ok {
+		info.userID = userID
+	}
+	return r.WithContext(logging.With(r.Context(), "user_id", userID))
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/logging"
+	"xm_test/internal/requestid"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type requestSuite struct {
+	suite.Suite
+}
+
+// newRouter returns a router with the request middlewares whose logs are recorded in the returned observer
+func (s *requestSuite) newRouter(handler http.HandlerFunc) (*chi.Mux, *observer.ObservedLogs) {
+	core, logs := observer.New(zap.DebugLevel)
+	logger := zap.New(core).Sugar()
+
+	r := chi.NewRouter()
+	r.Use(RequestContext(logger))
+	r.Use(AccessLog(logger))
+	r.Get("/company/{id}", handler)
+	return r, logs
+}
+
+func (s *requestSuite) TestRequestID() {
+	s.Run("honours the header", func() {
+		var id string
+		r, _ := s.newRouter(func(w http.ResponseWriter, r *http.Request) {
+			id = RequestIDFromContext(r.Context())
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/company/1", nil)
+		req.Header.Set(RequestIDHeader, "client-id")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+
+		s.Equal("client-id", id)
+		s.Equal("client-id", rec.Header().Get(RequestIDHeader))
+	})
+
+	s.Run("generates an ID", func() {
+		for _, header := range []string{"", strings.Repeat("a", requestid.MaxLength+1), "bad\nid"} {
+			r, _ := s.newRouter(func(w http.ResponseWriter, r *http.Request) {})
+
+			req := httptest.NewRequest(http.MethodGet, "/company/1", nil)
+			req.Header.Set(RequestIDHeader, header)
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			s.NoError(uuid.Validate(rec.Header().Get(RequestIDHeader)))
+		}
+	})
+}
+
+func (s *requestSuite) TestAccessLog() {
+	r, logs := s.newRouter(func(w http.ResponseWriter, r *http.Request) {
+		r = setUserID(r, "user-1")
+		logging.FromContext(r.Context(), nil).Infof("handling request")
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/company/1", nil)
+	req.Header.Set(RequestIDHeader, "request-1")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	entries := logs.AllUntimed()
+	s.Require().Len(entries, 2)
+
+	// request-scoped logger
+	handlerLog := entries[0].ContextMap()
+	s.Equal("request-1", handlerLog["request_id"])
+	s.Equal("user-1", handlerLog["user_id"])
+
+	// access log
+	s.Equal(zap.WarnLevel, entries[1].Level)
+	accessLog := entries[1].ContextMap()
+	s.Equal(http.MethodGet, accessLog["method"])
+	s.Equal("/company/{id}", accessLog["route"])
+	s.EqualValues(http.StatusNotFound, accessLog["status"])
+	s.Equal("request-1", accessLog["request_id"])
+	s.Equal("user-1", accessLog["user_id"])
+	s.Contains(accessLog, "latency")
+}
+
+func (s *requestSuite) TestRenderError() {
+	r, _ := s.newRouter(func(w http.ResponseWriter, r *http.Request) {
+		RenderError(w, r, apierrors.ErrCompanyNotFound)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/company/1", nil)
+	req.Header.Set(RequestIDHeader, "request-1")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+
+	var body apierrors.APIError
+	s.Require().NoError(json.NewDecoder(rec.Body).Decode(&body))
+	s.Equal(apierrors.ErrCompanyNotFound.HTTPStatus, rec.Code)
+	s.Equal(apierrors.ErrCompanyNotFound.Code, body.Code)
+	s.Equal("request-1", body.RequestID)
+
+	// the shared error is not modified
+	s.Empty(apierrors.ErrCompanyNotFound.RequestID)
+}
+
+func TestRequestSuite(t *testing.T) {
+	suite.Run(t, new(requestSuite))
+}
@@ -0,0 +1,95 @@
+package negotiation
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// mediaRange is a media range of an Accept header, such as text/*, along with its quality
+type mediaRange struct {
+	mediaType string
+	subtype   string
+	q         float64
+}
+
+// Negotiate returns the offered media type that the Accept header prefers. Each offer takes the quality of the most
+// specific range matching it, and the ties are won by the first offer, so the offers are given by preference. The
+// first offer is returned when the header is empty, and none when no offer is acceptable.
+func Negotiate(accept string, offers []string) (string, bool) {
+	if len(offers) == 0 {
+		return "", false
+	}
+	if strings.TrimSpace(accept) == "" {
+		return offers[0], true
+	}
+
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		if q := quality(ranges, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best, bestQ > 0
+}
+
+// ExplicitQuality returns the quality that the Accept header gives to the media type in the ranges naming it, or
+// zero when none names it. The wildcards are not taken into account, so the media types are only given a quality
+// when the client asks for them.
+func ExplicitQuality(accept, mediaType string) float64 {
+	var named []mediaRange
+	for _, r := range parseAccept(accept) {
+		if r.mediaType != "*" && r.subtype != "*" {
+			named = append(named, r)
+		}
+	}
+	return quality(named, mediaType)
+}
+
+// parseAccept returns the media ranges of the Accept header, skipping the ones that are not valid
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, value := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: typ, subtype: subtype, q: q})
+	}
+	return ranges
+}
+
+// quality returns the quality of the most specific range matching the media type, or zero when none matches it
+func quality(ranges []mediaRange, mediaType string) float64 {
+	typ, subtype, _ := strings.Cut(mediaType, "/")
+	q, specificity := 0.0, -1
+	for _, r := range ranges {
+		var s int
+		switch {
+		case r.mediaType == typ && r.subtype == subtype:
+			s = 2
+		case r.mediaType == typ && r.subtype == "*":
+			s = 1
+		case r.mediaType == "*" && r.subtype == "*":
+			s = 0
+		default:
+			continue
+		}
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
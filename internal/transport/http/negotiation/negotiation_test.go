@@ -0,0 +1,61 @@
+package negotiation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type negotiationSuite struct {
+	suite.Suite
+}
+
+func (s *negotiationSuite) TestNegotiate() {
+	offers := []string{"text/csv", "application/x-ndjson", "application/vnd.apache.parquet"}
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+	}{
+		{name: "empty header", accept: "", want: "text/csv"},
+		{name: "any media type", accept: "*/*", want: "text/csv"},
+		{name: "exact media type", accept: "application/vnd.apache.parquet", want: "application/vnd.apache.parquet"},
+		{name: "highest quality", accept: "text/csv;q=0.5, application/x-ndjson;q=0.8", want: "application/x-ndjson"},
+		{name: "type wildcard", accept: "application/*", want: "application/x-ndjson"},
+		{name: "most specific range", accept: "application/*;q=0.9, application/x-ndjson;q=0, */*;q=0.1", want: "application/vnd.apache.parquet"},
+		{name: "invalid ranges are skipped", accept: "text, text/csv;q=x, application/x-ndjson", want: "application/x-ndjson"},
+		{name: "not acceptable", accept: "application/xml, text/csv;q=0"},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			got, ok := Negotiate(tt.accept, offers)
+			s.Equal(tt.want != "", ok)
+			s.Equal(tt.want, got)
+		})
+	}
+}
+
+func (s *negotiationSuite) TestExplicitQuality() {
+	tests := []struct {
+		name   string
+		accept string
+		want   float64
+	}{
+		{name: "empty header", accept: ""},
+		{name: "wildcards are ignored", accept: "*/*, application/*"},
+		{name: "named media type", accept: "application/json, application/problem+json;q=0.5", want: 0.5},
+		{name: "named over wildcards", accept: "application/*;q=0.9, application/problem+json;q=0.2", want: 0.2},
+		{name: "invalid ranges are skipped", accept: "application/problem+json;q=x, text/csv", want: 0},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			s.Equal(tt.want, ExplicitQuality(tt.accept, "application/problem+json"))
+		})
+	}
+}
+
+func TestNegotiationSuite(t *testing.T) {
+	suite.Run(t, new(negotiationSuite))
+}
@@ -0,0 +1,265 @@
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	apierrors "xm_test/internal/api_errors"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// JSONContentType is the media type of the request and response bodies unless stated otherwise
+	JSONContentType = "application/json"
+
+	// bearerScheme is the name of the security scheme of the authenticated operations
+	bearerScheme = "bearerAuth"
+)
+
+// Security tells how an operation is authenticated
+type Security int
+
+const (
+	Public   Security = iota // the operation does not require a token
+	Optional                 // the operation accepts a token, but does not require it
+	Bearer                   // the operation requires a token
+	Admin                    // the operation requires the token of an admin
+)
+
+// Endpoint documents an operation of the router. The path parameters are taken from the route itself, and are
+// documented as UUIDs since every resource of the API is identified by one.
+type Endpoint struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Security    Security
+	Params      []*Parameter       // query parameters
+	PathParams  []*Parameter       // path parameters that are not UUIDs
+	Headers     []*Parameter       // header parameters
+	Request     any                // value of the type of the JSON request body, if any
+	Requests    []EndpointRequest  // request bodies of other media types, when the body is not only sent as JSON
+	Responses   []EndpointResponse // successful responses. The errors are documented by default
+}
+
+// EndpointRequest documents a request body of an operation
+type EndpointRequest struct {
+	Body        any               // value of the type of the request body
+	ContentType string            // defaults to JSONContentType, along with the alternate media types
+	Headers     map[string]string // headers of the response, keyed by name, with their description
+}
+
+// EndpointResponse documents a response of an operation
+type EndpointResponse struct {
+	Status      int
+	Description string
+	Body        any               // value of the type of the response body, if any
+	ContentType string            // defaults to JSONContentType, along with the alternate media types
+	Headers     map[string]string // headers of the response, keyed by name, with their description
+}
+
+// Key returns the key of the endpoint of the method and route pattern
+func Key(method, pattern string) string {
+	return method + " " + pattern
+}
+
+// pathParamRegex matches the parameters of a chi route pattern, dropping their regular expression if any
+var pathParamRegex = regexp.MustCompile(`\{([^}:]+)(:[^}]+)?\}`)
+
+// Build generates the document of the routes. Every route must be documented by an endpoint keyed by Key, and every
+// endpoint must match a route, so the document never drifts from the router. The bodies whose content type is not
+// set are documented as JSON and as each of the alternate media types, in which they can also be sent.
+func Build(info Info, routes chi.Routes, endpoints map[string]Endpoint, alternates ...string) (*Document, error) {
+	g := newGenerator()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   make(map[string]PathItem),
+	}
+
+	var errs []error
+	documented := make(map[string]bool, len(endpoints))
+	walkFn := func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		key := Key(method, route)
+		endpoint, ok := endpoints[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("route '%s' is not documented", key))
+			return nil
+		}
+		documented[key] = true
+
+		path := pathParamRegex.ReplaceAllString(route, "{$1}")
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(PathItem)
+		}
+		doc.Paths[path][strings.ToLower(method)] = g.operation(method, route, endpoint, alternates)
+		return nil
+	}
+	if err := chi.Walk(routes, walkFn); err != nil {
+		return nil, fmt.Errorf("failed to walk the routes: %w", err)
+	}
+
+	for key := range endpoints {
+		if !documented[key] {
+			errs = append(errs, fmt.Errorf("endpoint '%s' does not match any route", key))
+		}
+	}
+	if len(errs) > 0 {
+		slices.SortFunc(errs, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+		return nil, errors.Join(errs...)
+	}
+
+	doc.Components = Components{
+		Schemas: g.schemas,
+		SecuritySchemes: map[string]*SecurityScheme{
+			bearerScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+		},
+	}
+	return doc, nil
+}
+
+// operation generates the operation of the endpoint
+func (g *generator) operation(method, route string, endpoint Endpoint, alternates []string) *Operation {
+	op := &Operation{
+		OperationID: operationID(method, route),
+		Summary:     endpoint.Summary,
+		Description: endpoint.Description,
+		Tags:        endpoint.Tags,
+		Responses:   make(map[string]*Response),
+	}
+
+	for _, match := range pathParamRegex.FindAllStringSubmatch(route, -1) {
+		param := &Parameter{Name: match[1], Schema: &Schema{Type: "string", Format: "uuid"}}
+		if i := slices.IndexFunc(endpoint.PathParams, func(p *Parameter) bool { return p.Name == match[1] }); i >= 0 {
+			param = endpoint.PathParams[i]
+		}
+
+		p := *param
+		p.In, p.Required = "path", true
+		op.Parameters = append(op.Parameters, &p)
+	}
+	for _, param := range endpoint.Params {
+		p := *param
+		p.In = "query"
+		op.Parameters = append(op.Parameters, &p)
+	}
+	for _, param := range endpoint.Headers {
+		p := *param
+		p.In = "header"
+		op.Parameters = append(op.Parameters, &p)
+	}
+
+	requests := endpoint.Requests
+	if endpoint.Request != nil {
+		requests = append([]EndpointRequest{{Body: endpoint.Request}}, requests...)
+	}
+	if len(requests) > 0 {
+		op.RequestBody = &RequestBody{Required: true, Content: make(map[string]*MediaType, len(requests))}
+		for _, request := range requests {
+			addContent(op.RequestBody.Content, request.ContentType, g.schemaOf(request.Body), alternates)
+		}
+	}
+
+	// the responses with the same status are sent in different media types, so their content is merged, keeping
+	// the description of the first one
+	for _, response := range endpoint.Responses {
+		status := strconv.Itoa(response.Status)
+		r, ok := op.Responses[status]
+		if !ok {
+			r = &Response{Description: response.Description}
+			op.Responses[status] = r
+		}
+		if response.Body != nil {
+			if r.Content == nil {
+				r.Content = make(map[string]*MediaType)
+			}
+			addContent(r.Content, response.ContentType, g.schemaOf(response.Body), alternates)
+		}
+		for name, description := range response.Headers {
+			if r.Headers == nil {
+				r.Headers = make(map[string]*Header, len(response.Headers))
+			}
+			r.Headers[name] = &Header{Description: description, Schema: &Schema{Type: "string"}}
+		}
+	}
+
+	// every operation may fail, and the errors share the same body. The errors are sent as RFC 7807 problems to the
+	// clients asking for them.
+	op.Responses["default"] = &Response{
+		Description: "The request failed",
+		Content: map[string]*MediaType{
+			apierrors.ProblemContentType: {Schema: g.schemaOf(apierrors.Problem{})},
+		},
+	}
+	addContent(op.Responses["default"].Content, "", g.schemaOf(apierrors.APIError{}), alternates)
+
+	switch endpoint.Security {
+	case Bearer, Admin:
+		op.Security = []map[string][]string{{bearerScheme: {}}}
+	case Optional:
+		op.Security = []map[string][]string{{}, {bearerScheme: {}}}
+	}
+	return op
+}
+
+// operationID derives a stable identifier from the method and the route, e.g. "GET /company/{id}" becomes
+// "getCompanyById"
+func operationID(method, route string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(route, "/") {
+		if segment == "" {
+			continue
+		}
+		if match := pathParamRegex.FindStringSubmatch(segment); match != nil {
+			b.WriteString("By")
+			segment = match[1]
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r == '-' || r == '_' || r == '.' }) {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	return b.String()
+}
+
+// StringParam returns a parameter holding a string
+func StringParam(name, description string) *Parameter {
+	return &Parameter{
+		Name:        name,
+		Description: description,
+		Schema:      &Schema{Type: "string"},
+	}
+}
+
+// IntegerParam returns a query parameter holding an integer not lower than the minimum
+func IntegerParam(name, description string, minimum float64) *Parameter {
+	return &Parameter{
+		Name:        name,
+		Description: description,
+		Schema:      &Schema{Type: "integer", Minimum: &minimum},
+	}
+}
+
+// WithMaximum sets the maximum value of an integer parameter
+func (p *Parameter) WithMaximum(maximum float64) *Parameter {
+	p.Schema.Maximum = &maximum
+	return p
+}
+
+// addContent adds the schema to the content under its content type. An empty content type stands for JSON and the
+// alternate media types.
+func addContent(content map[string]*MediaType, contentType string, schema *Schema, alternates []string) {
+	if contentType != "" {
+		content[contentType] = &MediaType{Schema: schema}
+		return
+	}
+
+	content[JSONContentType] = &MediaType{Schema: schema}
+	for _, alternate := range alternates {
+		content[alternate] = &MediaType{Schema: schema}
+	}
+}
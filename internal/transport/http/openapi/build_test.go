@@ -0,0 +1,149 @@
+package openapi
+
+import (
+	"net/http"
+	"testing"
+	"xm_test/internal/enum"
+	"xm_test/internal/transport/http/schemas"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/suite"
+)
+
+type buildSuite struct {
+	suite.Suite
+}
+
+func noop(http.ResponseWriter, *http.Request) {}
+
+func (s *buildSuite) TestBuild() {
+	s.Run("documents the routes", func() {
+		r := chi.NewRouter()
+		r.Put("/company/{id}", noop)
+		doc, err := Build(Info{Title: "test", Version: "1"}, r, map[string]Endpoint{
+			Key(http.MethodPut, "/company/{id}"): {
+				Security:  Bearer,
+				Headers:   []*Parameter{StringParam("If-Match", "version")},
+				Request:   schemas.UpdateCompanyRequest{},
+				Responses: []EndpointResponse{{Status: http.StatusOK, Description: "ok", Body: schemas.OkResponse{}, Headers: map[string]string{"ETag": "version"}}},
+			},
+		})
+		s.Require().NoError(err)
+		s.Equal(Version, doc.OpenAPI)
+
+		op := doc.Paths["/company/{id}"]["put"]
+		s.Require().NotNil(op)
+		s.Equal("putCompanyById", op.OperationID)
+		s.Require().Len(op.Parameters, 2)
+		s.Equal("path", op.Parameters[0].In)
+		s.True(op.Parameters[0].Required)
+		s.Equal("header", op.Parameters[1].In)
+		s.Equal("If-Match", op.Parameters[1].Name)
+		s.Equal([]map[string][]string{{bearerScheme: {}}}, op.Security)
+		s.Equal("#/components/schemas/UpdateCompanyRequest", op.RequestBody.Content[JSONContentType].Schema.Ref)
+		s.Equal("#/components/schemas/OkResponse", op.Responses["200"].Content[JSONContentType].Schema.Ref)
+		s.Equal("version", op.Responses["200"].Headers["ETag"].Description)
+		s.Equal("#/components/schemas/APIError", op.Responses["default"].Content[JSONContentType].Schema.Ref)
+	})
+
+	s.Run("merges the media types of a status", func() {
+		r := chi.NewRouter()
+		r.Get("/company/export", noop)
+		doc, err := Build(Info{Title: "test", Version: "1"}, r, map[string]Endpoint{
+			Key(http.MethodGet, "/company/export"): {
+				Responses: []EndpointResponse{
+					{Status: http.StatusOK, Description: "csv", Body: "", ContentType: "text/csv"},
+					{Status: http.StatusOK, Description: "ndjson", Body: "", ContentType: "application/x-ndjson"},
+				},
+			},
+		})
+		s.Require().NoError(err)
+
+		response := doc.Paths["/company/export"]["get"].Responses["200"]
+		s.Equal("csv", response.Description)
+		s.Len(response.Content, 2)
+		s.Contains(response.Content, "application/x-ndjson")
+	})
+
+	s.Run("documents the alternate media types of the JSON bodies", func() {
+		r := chi.NewRouter()
+		r.Post("/company/create", noop)
+		r.Get("/openapi.json", noop)
+		doc, err := Build(Info{Title: "test", Version: "1"}, r, map[string]Endpoint{
+			Key(http.MethodPost, "/company/create"): {
+				Request:   struct{}{},
+				Responses: []EndpointResponse{{Status: http.StatusCreated, Body: struct{}{}}},
+			},
+			Key(http.MethodGet, "/openapi.json"): {
+				Responses: []EndpointResponse{{Status: http.StatusOK, Body: map[string]any{}, ContentType: JSONContentType}},
+			},
+		}, "application/xml")
+		s.Require().NoError(err)
+
+		op := doc.Paths["/company/create"]["post"]
+		s.Contains(op.RequestBody.Content, "application/xml")
+		s.Contains(op.Responses["201"].Content, "application/xml")
+		s.Contains(op.Responses["default"].Content, "application/xml")
+		s.Len(op.Responses["default"].Content, 3)
+		s.NotContains(doc.Paths["/openapi.json"]["get"].Responses["200"].Content, "application/xml")
+	})
+
+	s.Run("fails when a route is not documented", func() {
+		r := chi.NewRouter()
+		r.Get("/company/{id}", noop)
+		r.Delete("/company/{id}", noop)
+		_, err := Build(Info{}, r, map[string]Endpoint{Key(http.MethodGet, "/company/{id}"): {}})
+		s.ErrorContains(err, "route 'DELETE /company/{id}' is not documented")
+	})
+
+	s.Run("fails when an endpoint does not match any route", func() {
+		r := chi.NewRouter()
+		r.Get("/company/{id}", noop)
+		_, err := Build(Info{}, r, map[string]Endpoint{
+			Key(http.MethodGet, "/company/{id}"): {},
+			Key(http.MethodGet, "/companies"):    {},
+		})
+		s.ErrorContains(err, "endpoint 'GET /companies' does not match any route")
+	})
+}
+
+func (s *buildSuite) TestSchema() {
+	s.Run("applies the validations", func() {
+		g := newGenerator()
+		g.schemaOf(schemas.CreateCompanyRequest{})
+		schema := g.schemas["CreateCompanyRequest"]
+		s.Require().NotNil(schema)
+		s.ElementsMatch([]string{"name", "amount_employees", "registered", "type"}, schema.Required)
+		s.Equal("integer", schema.Properties["amount_employees"].Type)
+		s.Equal("boolean", schema.Properties["registered"].Type)
+
+		var types []any
+		for _, t := range enum.AllCompanyTypesString() {
+			types = append(types, t)
+		}
+		s.Equal(types, schema.Properties["type"].Enum)
+	})
+
+	s.Run("bounds the items of the arrays", func() {
+		g := newGenerator()
+		g.schemaOf(schemas.BulkCompaniesRequest{})
+		schema := g.schemas["BulkCompaniesRequest"]
+		s.Require().NotNil(schema)
+		s.Equal([]string{"operations"}, schema.Required)
+		s.Require().NotNil(schema.Properties["operations"].MinItems)
+		s.Equal(1, *schema.Properties["operations"].MinItems)
+	})
+
+	s.Run("describes the formats", func() {
+		g := newGenerator()
+		g.schemaOf(schemas.RegisterRequest{})
+		s.Equal("email", g.schemas["RegisterRequest"].Properties["email"].Format)
+
+		g.schemaOf(schemas.LogLevelRequest{})
+		s.Equal([]any{"debug", "info", "warn", "error"}, g.schemas["LogLevelRequest"].Properties["level"].Enum)
+	})
+}
+
+func TestBuildSuite(t *testing.T) {
+	suite.Run(t, new(buildSuite))
+}
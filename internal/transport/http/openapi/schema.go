@@ -0,0 +1,182 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"xm_test/internal/enum"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// customEnums holds the values accepted by the custom validations registered in the binding package
+var customEnums = map[string][]string{
+	"customOneOf": enum.AllCompanyTypesString(),
+}
+
+// formats maps the types that are encoded as strings to their format
+var formats = map[reflect.Type]string{
+	reflect.TypeOf(time.Time{}):          "date-time",
+	reflect.TypeOf(pgtype.Timestamptz{}): "date-time",
+	reflect.TypeOf(uuid.UUID{}):          "uuid",
+}
+
+// rawMessageType is encoded as is, so any JSON value is accepted
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// generator builds the schemas of the Go types. Named structs are stored as components and referenced, so every
+// struct is described only once.
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newGenerator() *generator {
+	return &generator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// schemaOf returns the schema of the type of the value
+func (g *generator) schemaOf(v any) *Schema {
+	return g.schema(reflect.TypeOf(v))
+}
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if format, ok := formats[t]; ok {
+		return &Schema{Type: "string", Format: format}
+	}
+	if t == rawMessageType {
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + g.component(t)}
+	default:
+		return &Schema{}
+	}
+}
+
+// component stores the schema of the named struct and returns its name. The name of the package is prepended when
+// two structs share the same name.
+func (g *generator) component(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	name := t.Name()
+	if _, taken := g.schemas[name]; taken {
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+
+	// the name is reserved before describing the fields, so recursive types reference themselves
+	g.names[t] = name
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.structSchema(t)
+	return name
+}
+
+// structSchema describes the exported fields of the struct as they are encoded by encoding/json, applying the
+// constraints of their validate tags
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := g.schema(field.Type)
+		if applyValidations(property, field.Tag.Get("validate")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+	return schema
+}
+
+// applyValidations adds the constraints of the validate tag to the schema and returns whether the field is required
+func applyValidations(schema *Schema, tag string) bool {
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			schema.Format = "email"
+		case "uuid":
+			schema.Format = "uuid"
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, value)
+			}
+		case "min", "gte":
+			setBound(schema, param, &schema.Minimum, &schema.MinLength, &schema.MinItems)
+		case "max", "lte":
+			setBound(schema, param, &schema.Maximum, &schema.MaxLength, &schema.MaxItems)
+		default:
+			if values, ok := customEnums[name]; ok {
+				for _, value := range values {
+					schema.Enum = append(schema.Enum, value)
+				}
+			}
+		}
+	}
+	return required
+}
+
+// setBound sets the numeric bound for numbers, the length bound for strings, or the items bound for arrays
+func setBound(schema *Schema, param string, number **float64, length **int, items **int) {
+	switch schema.Type {
+	case "integer", "number":
+		if v, err := strconv.ParseFloat(param, 64); err == nil {
+			*number = &v
+		}
+	case "string":
+		if v, err := strconv.Atoi(param); err == nil {
+			*length = &v
+		}
+	case "array":
+		if v, err := strconv.Atoi(param); err == nil {
+			*items = &v
+		}
+	}
+}
@@ -0,0 +1,324 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	apierrors "xm_test/internal/api_errors"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+// documentURL is the location under which the document is added to the schema compiler
+const documentURL = "openapi.json"
+
+// printer formats the messages of the violations
+var printer = message.NewPrinter(language.English)
+
+// Validator validates the requests and responses against the schemas of an OpenAPI document
+type Validator struct {
+	// matches the request paths with the paths of the document
+	router *chi.Mux
+
+	// compiled schemas of the operations, keyed by Key
+	operations map[string]*operationSchemas
+
+	// maximum size in bytes of the request bodies read to be validated
+	maxBodySize int64
+}
+
+// operationSchemas holds the compiled schemas of an operation
+type operationSchemas struct {
+	requests  map[string]*jsonschema.Schema // keyed by JSON media type
+	params    []*paramSchema
+	responses map[string]map[string]*jsonschema.Schema // keyed by status, or "default", and by JSON media type
+}
+
+// paramSchema holds the compiled schema of a query parameter
+type paramSchema struct {
+	name     string
+	required bool
+	typ      string
+	schema   *jsonschema.Schema
+}
+
+// NewValidator returns a validator without any operation, which reads request bodies of up to maxBodySize bytes. The
+// schemas are compiled by Load.
+func NewValidator(maxBodySize int64) *Validator {
+	return &Validator{router: chi.NewRouter(), operations: make(map[string]*operationSchemas), maxBodySize: maxBodySize}
+}
+
+// Load compiles the schemas of the operations of the document. It must be called before the validator is used.
+func (v *Validator) Load(doc *Document) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("failed to encode the OpenAPI document: %w", err)
+	}
+	resource, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to decode the OpenAPI document: %w", err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.DefaultDraft(jsonschema.Draft2020)
+	compiler.AssertFormat()
+	if err := compiler.AddResource(documentURL, resource); err != nil {
+		return fmt.Errorf("failed to add the OpenAPI document: %w", err)
+	}
+
+	for path, item := range doc.Paths {
+		for method, op := range item {
+			location := "/paths/" + escapePointer(path) + "/" + method
+			schemas, err := compileOperation(compiler, location, op)
+			if err != nil {
+				return fmt.Errorf("failed to compile the schemas of '%s %s': %w", strings.ToUpper(method), path, err)
+			}
+
+			key := Key(strings.ToUpper(method), path)
+			v.operations[key] = schemas
+			v.router.MethodFunc(strings.ToUpper(method), path, func(http.ResponseWriter, *http.Request) {})
+		}
+	}
+	return nil
+}
+
+// compileOperation compiles the schemas of the operation found at the location of the document
+func compileOperation(compiler *jsonschema.Compiler, location string, op *Operation) (*operationSchemas, error) {
+	compile := func(pointer string) (*jsonschema.Schema, error) {
+		return compiler.Compile(documentURL + "#" + location + pointer)
+	}
+
+	schemas := &operationSchemas{
+		requests:  make(map[string]*jsonschema.Schema),
+		responses: make(map[string]map[string]*jsonschema.Schema),
+	}
+	if op.RequestBody != nil {
+		for mediaType := range op.RequestBody.Content {
+			if !isJSON(mediaType) {
+				continue
+			}
+			schema, err := compile("/requestBody/content/" + escapePointer(mediaType) + "/schema")
+			if err != nil {
+				return nil, err
+			}
+			schemas.requests[mediaType] = schema
+		}
+	}
+
+	for i, param := range op.Parameters {
+		if param.In != "query" {
+			continue
+		}
+		schema, err := compile(fmt.Sprintf("/parameters/%d/schema", i))
+		if err != nil {
+			return nil, err
+		}
+		schemas.params = append(schemas.params, &paramSchema{name: param.Name, required: param.Required, typ: param.Schema.Type, schema: schema})
+	}
+
+	for status, response := range op.Responses {
+		for mediaType := range response.Content {
+			if !isJSON(mediaType) {
+				continue
+			}
+			schema, err := compile("/responses/" + status + "/content/" + escapePointer(mediaType) + "/schema")
+			if err != nil {
+				return nil, err
+			}
+			if schemas.responses[status] == nil {
+				schemas.responses[status] = make(map[string]*jsonschema.Schema)
+			}
+			schemas.responses[status][mediaType] = schema
+		}
+	}
+	return schemas, nil
+}
+
+// operation returns the schemas of the operation matching the method and path, if any
+func (v *Validator) operation(method, path string) *operationSchemas {
+	rctx := chi.NewRouteContext()
+	if !v.router.Match(rctx, method, path) {
+		return nil
+	}
+	return v.operations[Key(method, rctx.RoutePattern())]
+}
+
+// ValidateRequest validates the query parameters and the JSON body of the request against the operation matching
+// it, and returns every violation. The body is validated against the schema of its media type, and is restored so
+// it can be decoded again by the handler. The requests that do not match any operation are not validated, and
+// neither are the bodies whose media type is not documented, which are rejected by the handlers. The bodies larger
+// than the maximum size fail with an *http.MaxBytesError.
+func (v *Validator) ValidateRequest(w http.ResponseWriter, r *http.Request) ([]apierrors.FieldError, error) {
+	op := v.operation(r.Method, r.URL.Path)
+	if op == nil {
+		return nil, nil
+	}
+
+	var violations []apierrors.FieldError
+	query := r.URL.Query()
+	for _, param := range op.params {
+		if !query.Has(param.name) {
+			if param.required {
+				violations = append(violations, apierrors.FieldError{Parameter: param.name, Message: "parameter is required"})
+			}
+			continue
+		}
+
+		value, err := param.decode(query.Get(param.name))
+		if err != nil {
+			violations = append(violations, apierrors.FieldError{Parameter: param.name, Message: err.Error()})
+			continue
+		}
+		for _, violation := range validate(param.schema, value) {
+			violation.Parameter, violation.Pointer = param.name, ""
+			violations = append(violations, violation)
+		}
+	}
+
+	request, ok := op.requests[mediaType(r.Header.Get("Content-Type"))]
+	if !ok {
+		return violations, nil
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, v.maxBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the request body: %w", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return append(violations, apierrors.FieldError{Pointer: "", Message: "request body is required"}), nil
+	}
+	body, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the request body: %w", err)
+	}
+	return append(violations, validate(request, body)...), nil
+}
+
+// ValidateResponse validates the JSON body of a response against the schema of its status and media type, or the
+// default one, and returns every violation
+func (v *Validator) ValidateResponse(method, path string, status int, contentType string, data []byte) ([]apierrors.FieldError, error) {
+	op := v.operation(method, path)
+	if op == nil || !isJSON(contentType) || len(data) == 0 {
+		return nil, nil
+	}
+
+	schemas, ok := op.responses[strconv.Itoa(status)]
+	if !ok {
+		schemas, ok = op.responses["default"]
+	}
+	schema, found := schemas[mediaType(contentType)]
+	if !ok || !found {
+		return nil, nil
+	}
+
+	body, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the response body: %w", err)
+	}
+	return validate(schema, body), nil
+}
+
+// decode converts the value of the query parameter to the type of its schema
+func (p *paramSchema) decode(value string) (any, error) {
+	switch p.typ {
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return nil, errors.New("must be an integer")
+		}
+		return json.Number(value), nil
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return nil, errors.New("must be a number")
+		}
+		return json.Number(value), nil
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New("must be a boolean")
+		}
+		return b, nil
+	default:
+		return value, nil
+	}
+}
+
+// validate validates the value against the schema and returns a violation for each failing keyword. A violation
+// is reported for each missing property, pointing to the property itself.
+func validate(schema *jsonschema.Schema, value any) []apierrors.FieldError {
+	err := schema.Validate(value)
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return nil
+	}
+
+	var violations []apierrors.FieldError
+	var walk func(e *jsonschema.ValidationError)
+	walk = func(e *jsonschema.ValidationError) {
+		if len(e.Causes) > 0 {
+			for _, cause := range e.Causes {
+				walk(cause)
+			}
+			return
+		}
+
+		pointer := toPointer(e.InstanceLocation)
+		if required, ok := e.ErrorKind.(*kind.Required); ok {
+			for _, property := range required.Missing {
+				violations = append(violations, apierrors.FieldError{Pointer: pointer + "/" + escapePointer(property), Message: "property is required"})
+			}
+			return
+		}
+		violations = append(violations, apierrors.FieldError{Pointer: pointer, Message: e.ErrorKind.LocalizedString(printer)})
+	}
+	walk(validationErr)
+
+	slices.SortStableFunc(violations, func(a, b apierrors.FieldError) int { return strings.Compare(a.Pointer, b.Pointer) })
+	return violations
+}
+
+// toPointer returns the JSON pointer of the location
+func toPointer(location []string) string {
+	var b strings.Builder
+	for _, token := range location {
+		b.WriteByte('/')
+		b.WriteString(escapePointer(token))
+	}
+	return b.String()
+}
+
+// escapePointer escapes a token of a JSON pointer
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+// mediaType returns the media type of the content type, which defaults to JSON
+func mediaType(contentType string) string {
+	if contentType == "" {
+		return JSONContentType
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType
+}
+
+// isJSON tells whether the content type is JSON. An empty content type is considered JSON, since it is the default
+// media type of the API.
+func isJSON(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == JSONContentType || strings.HasSuffix(mediaType, "+json"))
+}
@@ -0,0 +1,164 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/transport/http/schemas"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/suite"
+)
+
+type validatorSuite struct {
+	suite.Suite
+	validator *Validator
+}
+
+func (s *validatorSuite) SetupSuite() {
+	r := chi.NewRouter()
+	r.Post("/company/create", noop)
+	r.Get("/company/{id}", noop)
+	r.Patch("/company/{id}", noop)
+	r.Get("/admin/events/dead-letters", noop)
+
+	doc, err := Build(Info{Title: "test", Version: "1"}, r, map[string]Endpoint{
+		Key(http.MethodPost, "/company/create"): {
+			Request:   schemas.CreateCompanyRequest{},
+			Responses: []EndpointResponse{{Status: http.StatusOK, Description: "ok", Body: schemas.OkResponse{}}},
+		},
+		Key(http.MethodGet, "/company/{id}"): {
+			Responses: []EndpointResponse{{Status: http.StatusOK, Description: "ok", Body: schemas.LogLevelResponse{}}},
+		},
+		Key(http.MethodPatch, "/company/{id}"): {
+			Requests: []EndpointRequest{
+				{Body: schemas.CompanyMergePatch{}, ContentType: "application/merge-patch+json"},
+				{Body: []schemas.PatchOperation{}, ContentType: "application/json-patch+json"},
+			},
+		},
+		Key(http.MethodGet, "/admin/events/dead-letters"): {
+			Params: []*Parameter{IntegerParam("limit", "", 1).WithMaximum(500)},
+		},
+	})
+	s.Require().NoError(err)
+
+	s.validator = NewValidator(1024)
+	s.Require().NoError(s.validator.Load(doc))
+}
+
+func (s *validatorSuite) TestValidateRequest() {
+	s.Run("accepts a valid body", func() {
+		body := `{"name": "xm", "amount_employees": 10, "registered": true, "type": "NonProfit"}`
+		violations, err := s.validator.ValidateRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/company/create", strings.NewReader(body)))
+		s.Require().NoError(err)
+		s.Empty(violations)
+	})
+
+	s.Run("reports every violation", func() {
+		body := `{"name": 1, "registered": true, "type": "Unknown"}`
+		r := httptest.NewRequest(http.MethodPost, "/company/create", strings.NewReader(body))
+		violations, err := s.validator.ValidateRequest(httptest.NewRecorder(), r)
+		s.Require().NoError(err)
+		s.Require().Len(violations, 3)
+		s.Equal("/amount_employees", violations[0].Pointer)
+		s.Equal("property is required", violations[0].Message)
+		s.Equal("/name", violations[1].Pointer)
+		s.Equal("/type", violations[2].Pointer)
+
+		// the body can be decoded again
+		s.Require().NotNil(r.Body)
+		data := make([]byte, len(body))
+		n, _ := r.Body.Read(data)
+		s.Equal(body, string(data[:n]))
+	})
+
+	s.Run("requires the body", func() {
+		violations, err := s.validator.ValidateRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/company/create", nil))
+		s.Require().NoError(err)
+		s.Equal([]apierrors.FieldError{{Message: "request body is required"}}, violations)
+	})
+
+	s.Run("fails when the body is malformed", func() {
+		_, err := s.validator.ValidateRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/company/create", strings.NewReader("{")))
+		s.Error(err)
+	})
+
+	s.Run("limits the size of the body", func() {
+		body := `{"name": "` + strings.Repeat("a", 1024) + `"}`
+		_, err := s.validator.ValidateRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/company/create", strings.NewReader(body)))
+		var maxBytesErr *http.MaxBytesError
+		s.Require().ErrorAs(err, &maxBytesErr)
+		s.Equal(int64(1024), maxBytesErr.Limit)
+	})
+
+	s.Run("validates the query parameters", func() {
+		violations, err := s.validator.ValidateRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/admin/events/dead-letters?limit=1000", nil))
+		s.Require().NoError(err)
+		s.Require().Len(violations, 1)
+		s.Equal("limit", violations[0].Parameter)
+
+		violations, err = s.validator.ValidateRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/admin/events/dead-letters?limit=ten", nil))
+		s.Require().NoError(err)
+		s.Equal([]apierrors.FieldError{{Parameter: "limit", Message: "must be an integer"}}, violations)
+
+		violations, err = s.validator.ValidateRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/admin/events/dead-letters?limit=10", nil))
+		s.Require().NoError(err)
+		s.Empty(violations)
+	})
+
+	s.Run("validates the body against the schema of its media type", func() {
+		patch := func(contentType, body string) []apierrors.FieldError {
+			r := httptest.NewRequest(http.MethodPatch, "/company/5f1f3c1e-0b1a-4f6e-9d55-0f0e8a1b2c3d", strings.NewReader(body))
+			r.Header.Set("Content-Type", contentType)
+			violations, err := s.validator.ValidateRequest(httptest.NewRecorder(), r)
+			s.Require().NoError(err)
+			return violations
+		}
+
+		s.Empty(patch("application/merge-patch+json", `{"name": "xm"}`))
+		s.Len(patch("application/merge-patch+json", `{"type": "Unknown"}`), 1)
+		s.Empty(patch("application/json-patch+json", `[{"op": "replace", "path": "/name", "value": "xm"}]`))
+		s.Len(patch("application/json-patch+json", `[{"op": "rename", "path": "/name"}]`), 1)
+		s.Empty(patch("application/json", `{"type": "Unknown"}`))
+	})
+
+	s.Run("ignores the unknown routes", func() {
+		violations, err := s.validator.ValidateRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/unknown", strings.NewReader("{")))
+		s.Require().NoError(err)
+		s.Empty(violations)
+	})
+}
+
+func (s *validatorSuite) TestValidateResponse() {
+	s.Run("accepts a valid response", func() {
+		violations, err := s.validator.ValidateResponse(http.MethodGet, "/company/5f1f3c1e-0b1a-4f6e-9d55-0f0e8a1b2c3d", http.StatusOK, JSONContentType, []byte(`{"level": "info"}`))
+		s.Require().NoError(err)
+		s.Empty(violations)
+	})
+
+	s.Run("reports the violations", func() {
+		violations, err := s.validator.ValidateResponse(http.MethodGet, "/company/5f1f3c1e-0b1a-4f6e-9d55-0f0e8a1b2c3d", http.StatusOK, JSONContentType, []byte(`{"level": 1}`))
+		s.Require().NoError(err)
+		s.Require().Len(violations, 1)
+		s.Equal("/level", violations[0].Pointer)
+	})
+
+	s.Run("validates the errors against the default response", func() {
+		violations, err := s.validator.ValidateResponse(http.MethodGet, "/company/invalid", http.StatusBadRequest, JSONContentType, []byte(`{"code": 1, "message": "invalid UUID"}`))
+		s.Require().NoError(err)
+		s.Require().Len(violations, 1)
+		s.Equal("/code", violations[0].Pointer)
+	})
+
+	s.Run("ignores the responses that are not JSON", func() {
+		violations, err := s.validator.ValidateResponse(http.MethodGet, "/company/invalid", http.StatusOK, "text/plain", []byte("ok"))
+		s.Require().NoError(err)
+		s.Empty(violations)
+	})
+}
+
+func TestValidatorSuite(t *testing.T) {
+	suite.Run(t, new(validatorSuite))
+}
@@ -0,0 +1,29 @@
+package http
+
+import (
+	"net/http"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/logging"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// listProblemTypes returns the problem types that can be returned by the API
+func (h *handler) listProblemTypes(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("list problem types endpoint called")
+	h.codecs.Render(w, r, apierrors.ProblemTypes())
+}
+
+// getProblemType returns the problem type referenced by the type of the problems
+func (h *handler) getProblemType(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context(), h.logger)
+	logger.Infof("get problem type endpoint called")
+
+	problemType, ok := apierrors.LookupProblemType(chi.URLParam(r, "type"))
+	if !ok {
+		h.wrapError(w, r, apierrors.ErrProblemTypeNotFound)
+		return
+	}
+	h.codecs.Render(w, r, problemType)
+}
@@ -1,12 +1,17 @@
 package http
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"sync/atomic"
 	"xm_test/internal/conf"
 	"xm_test/internal/db"
 	"xm_test/internal/events"
+	"xm_test/internal/health"
 	"xm_test/internal/helpers"
+	"xm_test/internal/transport/http/openapi"
 	"xm_test/internal/transport/http/schemas"
 
 	customMiddlewares "xm_test/internal/transport/http/middleware"
@@ -14,6 +19,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 )
 
@@ -22,77 +28,267 @@ type httpTransport struct {
 	db     db.DatabaseAdapter
 
 	evtDispatcher events.Dispatcher
+	hub           events.Hub
+	registry      events.Registry
+	monitor       health.Monitor
+
+	handler      *handler
+	server       *http.Server
+	healthServer *http.Server
+	shuttingDown atomic.Bool
 }
 
 // NewHttpTransport returns a new http transport instance
-func NewHttpTransport(logger *zap.SugaredLogger, db db.DatabaseAdapter) *httpTransport {
-	return &httpTransport{logger: logger, db: db}
+func NewHttpTransport(logger *zap.SugaredLogger, logLevel zap.AtomicLevel, db db.DatabaseAdapter, hub events.Hub, registry events.Registry, monitor health.Monitor) *httpTransport {
+	return &httpTransport{
+		logger:       logger,
+		db:           db,
+		hub:          hub,
+		registry:     registry,
+		monitor:      monitor,
+		handler:      newHandler(logger, logLevel, db, hub, registry),
+		server:       &http.Server{Addr: fmt.Sprintf(":%s", conf.GlobalConfig.Port)},
+		healthServer: &http.Server{Addr: fmt.Sprintf(":%s", conf.GlobalConfig.HealthPort)},
+	}
 }
 
 // Serve is a function that sets up the http server. It listens on the port specified in the configuration.
-func (h httpTransport) Serve() error {
+func (h *httpTransport) Serve() error {
 	h.logger.Debugf("setting up http server")
+	r, err := h.router()
+	if err != nil {
+		return h.wrapError(err)
+	}
+
+	h.server.Handler = r
+	h.logger.Infof("http server listening on port %s", h.server.Addr)
+	if err := h.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return h.wrapError(err)
+	}
+
+	return nil
+}
+
+// router sets up the routes of the http server and generates their OpenAPI document. It fails when a route is
+// not documented.
+func (h *httpTransport) router() (chi.Router, error) {
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
+	r.Use(customMiddlewares.Tracing)
+	r.Use(customMiddlewares.RequestContext(h.logger))
+	r.Use(customMiddlewares.Metrics)
+	r.Use(customMiddlewares.AccessLog(h.logger))
 	r.Use(middleware.Recoverer)
 
+	// the schemas of the validator are compiled once every route is registered and documented. The requests are
+	// validated once authenticated, so the anonymous clients cannot learn the document from the violations.
+	validation := conf.GlobalConfig.OpenAPIValidation
+	validator := openapi.NewValidator(conf.GlobalConfig.MaxBodySize)
+	validate := func(next http.Handler) http.Handler { return next }
+	if validation.Requests || validation.Responses {
+		validate = customMiddlewares.ValidateOpenAPI(h.logger, validator, validation.Requests, validation.Responses)
+	}
+
 	// setup the routes here
-	handler := newHandler(h.logger, h.db)
+	handler := h.handler
+
+	// every route but the long-lived streams must be completed within the request deadline
+	deadlineRoutes := r.With(customMiddlewares.Deadline(conf.GlobalConfig.RequestTimeout))
+	publicRoutes := deadlineRoutes.With(validate)
 
-	protectedRoutes := r.Group(func(r chi.Router) {
+	// the mutating requests of a user sent with the same Idempotency-Key header are processed once. A request that
+	// holds a key for longer than its deadline was abandoned.
+	lockTimeout := conf.GlobalConfig.RequestTimeout
+	if lockTimeout <= 0 {
+		lockTimeout = conf.GlobalConfig.IdempotencyKeyTTL
+	}
+	idempotency := customMiddlewares.Idempotency(h.logger, handler.db, conf.GlobalConfig.IdempotencyKeyTTL, lockTimeout, conf.GlobalConfig.MaxBodySize)
+
+	// the routes rendering documents reject the clients that accept none of the media types of the API up front.
+	// The documentation is always JSON or HTML, and the streams fall back to their own media type.
+	negotiatedRoutes := deadlineRoutes.With(customMiddlewares.Negotiate(handler.codecs))
+	apiRoutes := negotiatedRoutes.With(validate)
+
+	protectedRoutes := negotiatedRoutes.Group(func(r chi.Router) {
+		r.Use(customMiddlewares.UserMustBeAuthenticated)
+		r.Use(validate)
+		r.Use(idempotency)
+	})
+
+	adminRoutes := negotiatedRoutes.Group(func(r chi.Router) {
+		r.Use(customMiddlewares.UserMustBeAuthenticated)
+		r.Use(customMiddlewares.UserMustBeAdmin)
+		r.Use(validate)
+		r.Use(idempotency)
+	})
+
+	// the long-lived streams, the uploads and the downloads, which may outlast the request deadline and are too
+	// large to be buffered by the idempotency middleware, are only authenticated
+	streamRoutes := r.Group(func(r chi.Router) {
 		r.Use(customMiddlewares.UserMustBeAuthenticated)
+		r.Use(validate)
 	})
 
 	// auth routes
-	r.Post("/register", handler.register)
-	r.Post("/login", handler.login)
+	apiRoutes.Post("/register", handler.register)
+	apiRoutes.Post("/login", handler.login)
 
 	// company routes
-	r.Get("/company/{id}", handler.getCompany)
+	apiRoutes.Get("/company/{id}", handler.getCompany)
 	protectedRoutes.Post("/company/create", handler.createCompany)
+	protectedRoutes.Post("/company/bulk", handler.bulkCompanies)
 	protectedRoutes.Put("/company/{id}", handler.updateCompany)
+	protectedRoutes.Patch("/company/{id}", handler.patchCompany)
 	protectedRoutes.Delete("/company/{id}", handler.deleteCompany)
 
-	port := fmt.Sprintf(":%s", conf.GlobalConfig.Port)
-	h.logger.Infof("http server listening on port %s", port)
-	if err := http.ListenAndServe(port, r); err != nil {
-		return h.wrapError(err)
+	// company import and export routes
+	streamRoutes.Post("/company/imports", handler.importCompanies)
+	protectedRoutes.Get("/company/imports/{id}", handler.getImportJob)
+	streamRoutes.Get("/company/imports/{id}/report", handler.getImportReport)
+	streamRoutes.Get("/company/export", handler.exportCompanies)
+
+	// event routes
+	apiRoutes.Get("/events/schemas", handler.getEventSchemas)
+	streamRoutes.Get("/events/stream", handler.streamEvents)
+
+	// graphql routes. Queries are public, while mutations and subscriptions check the claims themselves. The
+	// handler applies the request deadline to every operation but the subscriptions.
+	r.With(customMiddlewares.UserMayBeAuthenticated, validate).Post("/graphql", handler.graphql.ServeHTTP)
+
+	// admin routes
+	adminRoutes.Get("/admin/events/dead-letters", handler.listDeadLetters)
+	adminRoutes.Get("/admin/events/dead-letters/{id}", handler.getDeadLetter)
+	adminRoutes.Post("/admin/events/dead-letters/{id}/retry", handler.retryDeadLetter)
+	adminRoutes.Delete("/admin/events/dead-letters/{id}", handler.discardDeadLetter)
+	adminRoutes.Get("/admin/log-level", handler.getLogLevel)
+	adminRoutes.Put("/admin/log-level", handler.setLogLevel)
+
+	// documentation routes
+	publicRoutes.Get("/openapi.json", handler.getOpenAPI)
+	publicRoutes.Get("/docs", handler.getDocs)
+	publicRoutes.Get("/docs/{asset}", handler.getDocsAsset)
+	apiRoutes.Get("/problems", handler.listProblemTypes)
+	apiRoutes.Get("/problems/{type}", handler.getProblemType)
+
+	doc, data, err := buildDocs(r)
+	if err != nil {
+		return nil, err
 	}
+	handler.openapi = data
 
-	return nil
+	if err := validator.Load(doc); err != nil {
+		return nil, err
+	}
+
+	return r, nil
 }
 
 // HealthCheck is a function that sets up the health check endpoint. It listens on the health port specified in the configuration,
 // which is different from the main port to allow for easier monitoring of the application.
-func (h httpTransport) HealthCheck() error {
+func (h *httpTransport) HealthCheck() error {
 	h.logger.Debugf("setting up health check endpoint")
 
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
+	r.Use(customMiddlewares.RequestContext(h.logger))
+	r.Use(customMiddlewares.AccessLog(h.logger))
 	r.Use(middleware.Recoverer)
 
 	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
 		h.logger.Debug("health check endpoint called")
+
+		// report the service as unavailable as soon as the shutdown starts, so no new traffic is routed to it
+		if h.shuttingDown.Load() {
+			render.Status(r, http.StatusServiceUnavailable)
+			h.handler.codecs.Render(w, r, schemas.HealthResponse{Message: "shutting down"})
+			return
+		}
+
 		response := schemas.HealthResponse{Message: "OK"}
 		h.logger.Debugf("got health check response: %s", helpers.PrettyPrintStructResponse(response))
-		render.JSON(w, r, response)
+		h.handler.codecs.Render(w, r, response)
 	})
+	r.Get("/livez", h.livez)
+	r.Get("/readyz", h.readyz)
+	r.Handle("/metrics", promhttp.Handler())
 
-	port := fmt.Sprintf(":%s", conf.GlobalConfig.HealthPort)
-	h.logger.Infof("health check server listening on port %s", port)
-	if err := http.ListenAndServe(port, r); err != nil {
+	h.healthServer.Handler = r
+	h.logger.Infof("health check server listening on port %s", h.healthServer.Addr)
+	if err := h.healthServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return h.wrapError(err)
 	}
 
 	return nil
 }
 
-func (h httpTransport) Close() error {
-	return nil
+// livez reports whether the process is alive. It does not check any dependency, so a failing dependency never
+// causes the service to be restarted.
+func (h *httpTransport) livez(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("liveness endpoint called")
+	h.handler.codecs.Render(w, r, health.Report{Status: health.StatusUp})
+}
+
+// readyz reports whether the service is ready to receive traffic by running the dependency checks. The result of
+// each check is included when the verbose query parameter is set.
+func (h *httpTransport) readyz(w http.ResponseWriter, r *http.Request) {
+	h.logger.Debug("readiness endpoint called")
+
+	// report the service as unavailable as soon as the shutdown starts, so no new traffic is routed to it
+	if h.shuttingDown.Load() {
+		render.Status(r, http.StatusServiceUnavailable)
+		h.handler.codecs.Render(w, r, health.Report{Status: health.StatusDown})
+		return
+	}
+
+	report := h.monitor.Check(r.Context())
+	h.logger.Debugf("got readiness report: %s", helpers.PrettyPrintStructResponse(report))
+	if !r.URL.Query().Has("verbose") {
+		report.Checks = nil
+	}
+
+	if report.Status != health.StatusUp {
+		render.Status(r, http.StatusServiceUnavailable)
+	}
+	h.handler.codecs.Render(w, r, report)
+}
+
+// Close gracefully shuts down the http transport. It flips the health check to failing, stops accepting new
+// connections, drains the in-flight requests and waits for the pending event dispatches. The health server is
+// stopped last so it keeps reporting the shutdown while the requests are drained.
+func (h *httpTransport) Close(ctx context.Context) error {
+	h.logger.Infof("shutting down http server")
+	h.shuttingDown.Store(true)
+
+	// long-lived streams never become idle, so they are closed before draining the server
+	h.handler.closeStreams()
+
+	var errs []error
+	if err := h.server.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to drain http server: %w", err))
+	}
+	h.logger.Infof("http server drained")
+
+	// the imports running in the background dispatch events, so they are interrupted first
+	if err := h.handler.is.Close(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	h.logger.Infof("import jobs interrupted")
+
+	// the dispatches waiting to be retried are dead-lettered instead of holding the shutdown
+	h.handler.evtDispatcher.Close()
+	if err := h.handler.cs.WaitForEvents(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	h.logger.Infof("pending events dispatched")
+
+	if err := h.healthServer.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to shut down health check server: %w", err))
+	}
+	h.logger.Infof("health check server stopped")
+
+	return errors.Join(errs...)
 }
 
 // wrapError is a helper function that logs the error and returns it
-func (h httpTransport) wrapError(err error) error {
+func (h *httpTransport) wrapError(err error) error {
 	h.logger.Error("error")
 	return err
 }
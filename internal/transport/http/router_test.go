@@ -0,0 +1,132 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	apierrors "xm_test/internal/api_errors"
+	"xm_test/internal/conf"
+	"xm_test/internal/db"
+	"xm_test/internal/events"
+	"xm_test/internal/service"
+	"xm_test/internal/token"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/zap"
+)
+
+// retryingDispatcher fails every dispatch and waits to retry it until it is closed
+type retryingDispatcher struct {
+	closed chan struct{}
+}
+
+func (d *retryingDispatcher) Dispatch(event *events.Event) error {
+	<-d.closed
+	return errors.New("event bus unavailable")
+}
+
+func (d *retryingDispatcher) Close() {
+	close(d.closed)
+}
+
+// companyDB deletes the companies without storing them
+type companyDB struct {
+	db.DatabaseAdapter
+}
+
+func (companyDB) DeleteCompany(ctx context.Context, id string, version int) error {
+	return nil
+}
+
+type shutdownSuite struct {
+	suite.Suite
+}
+
+func (s *shutdownSuite) newTransport() *httpTransport {
+	conf.NewConfig()
+	logger := zap.NewNop().Sugar()
+	return &httpTransport{
+		logger:       logger,
+		handler:      newHandler(logger, zap.NewAtomicLevel(), nil, nil, nil),
+		server:       &http.Server{},
+		healthServer: &http.Server{},
+	}
+}
+
+func (s *shutdownSuite) TestClose() {
+	s.Run("interrupts the dispatches waiting to be retried", func() {
+		transport := s.newTransport()
+		registry, err := events.NewEventsRegistry()
+		s.Require().NoError(err)
+		dispatcher := &retryingDispatcher{closed: make(chan struct{})}
+		transport.handler.evtDispatcher = dispatcher
+		transport.handler.cs = service.NewCompanyService(zap.NewNop().Sugar(), companyDB{}, registry, dispatcher)
+
+		s.Require().NoError(transport.handler.cs.DeleteCompany(context.Background(), uuid.NewString(), 0))
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		start := time.Now()
+		s.Require().NoError(transport.Close(ctx))
+		s.Less(time.Since(start), 500*time.Millisecond)
+		s.True(transport.shuttingDown.Load())
+	})
+}
+
+func TestShutdownSuite(t *testing.T) {
+	suite.Run(t, new(shutdownSuite))
+}
+
+type validationSuite struct {
+	suite.Suite
+}
+
+func (s *validationSuite) TestValidateRequests() {
+	conf.NewConfig()
+	conf.GlobalConfig.JwtSecret = "secret"
+	conf.GlobalConfig.OpenAPIValidation.Requests = true
+	conf.GlobalConfig.MaxBodySize = 1024
+
+	logger := zap.NewNop().Sugar()
+	transport := &httpTransport{logger: logger, handler: newHandler(logger, zap.NewAtomicLevel(), nil, nil, nil)}
+	router, err := transport.router()
+	s.Require().NoError(err)
+
+	serve := func(path string, authenticated bool) *apierrors.APIError {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"name": 5}`))
+		r.Header.Set("Content-Type", "application/json")
+		if authenticated {
+			accessToken, _, err := token.GenerateToken(uuid.NewString(), "user@xm.com")
+			s.Require().NoError(err)
+			r.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		var body apierrors.APIError
+		s.Require().NoError(json.Unmarshal(w.Body.Bytes(), &body))
+		return &body
+	}
+
+	s.Run("authenticates the requests before validating them", func() {
+		s.Equal(apierrors.ErrTokenNotFound.Code, serve("/company/create", false).Code)
+	})
+
+	s.Run("validates the authenticated requests", func() {
+		s.Equal(apierrors.ErrInvalidRequest.Code, serve("/company/create", true).Code)
+	})
+
+	s.Run("validates the public requests", func() {
+		s.Equal(apierrors.ErrInvalidRequest.Code, serve("/login", false).Code)
+	})
+}
+
+func TestValidationSuite(t *testing.T) {
+	suite.Run(t, new(validationSuite))
+}
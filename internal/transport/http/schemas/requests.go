@@ -1,9 +1,11 @@
 package schemas
 
+import "encoding/json"
+
 // RegisterRequest is the request schema for registering a new user
 type RegisterRequest struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required"`
+	Email    string `json:"email" validate:"required,email" redact:"email"`
+	Password string `json:"password" validate:"required" redact:"true"`
 }
 
 // LoginRequest is the request schema for logging in a user
@@ -12,7 +14,7 @@ type LoginRequest RegisterRequest
 // CreateCompanyRequest is the request schema for creating a company
 type CreateCompanyRequest struct {
 	Name            string `json:"name" validate:"required"`
-	Description     string `json:"description,omitempty"`
+	Description     string `json:"description"`
 	AmountEmployees *int   `json:"amount_employees" validate:"required"`
 	Registered      *bool  `json:"registered" validate:"required"`
 	Type            string `json:"type" validate:"required,customOneOf"`
@@ -20,3 +22,42 @@ type CreateCompanyRequest struct {
 
 // UpdateCompanyRequest is the request schema for updating a company
 type UpdateCompanyRequest CreateCompanyRequest
+
+// BulkCompaniesRequest is the request schema for creating, updating and deleting companies in bulk. The operations
+// are applied in order, and the mode defaults to transactional. A request has at most BULK_MAX_OPERATIONS operations.
+type BulkCompaniesRequest struct {
+	Mode       string                 `json:"mode" validate:"omitempty,oneof=transactional best_effort"`
+	Operations []BulkOperationRequest `json:"operations" validate:"required,min=1,maxBulkOperations"`
+}
+
+// BulkOperationRequest is an operation of a bulk request. The ID is required to update and delete a company, and
+// the company to create and update it.
+type BulkOperationRequest struct {
+	Op      string                `json:"op" validate:"required,oneof=create update delete"`
+	ID      string                `json:"id,omitempty" validate:"required_unless=Op create"`
+	IfMatch string                `json:"if_match,omitempty"` // entity tag the company must match, like the If-Match header
+	Company *UpdateCompanyRequest `json:"company,omitempty" validate:"required_unless=Op delete"`
+}
+
+// CompanyMergePatch is the RFC 7396 merge patch of a company. The fields that are not sent are kept, and the
+// patched company must be valid.
+type CompanyMergePatch struct {
+	Name            string `json:"name"`
+	Description     string `json:"description"`
+	AmountEmployees int    `json:"amount_employees"`
+	Registered      bool   `json:"registered"`
+	Type            string `json:"type" validate:"customOneOf"`
+}
+
+// PatchOperation is an operation of a RFC 6902 JSON patch
+type PatchOperation struct {
+	Op    string          `json:"op" validate:"required,oneof=add remove replace move copy test"`
+	Path  string          `json:"path" validate:"required"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// LogLevelRequest is the request schema for changing the log level at runtime
+type LogLevelRequest struct {
+	Level string `json:"level" validate:"required,oneof=debug info warn error"`
+}
@@ -1,5 +1,10 @@
 package schemas
 
+import (
+	"time"
+	apierrors "xm_test/internal/api_errors"
+)
+
 // HealthResponse is the response for the health check endpoint
 type HealthResponse struct {
 	Message string `json:"message"`
@@ -12,5 +17,45 @@ type OkResponse struct {
 
 // LoginResponse is the response for a successful login
 type LoginResponse struct {
-	AccessToken string `json:"access_token"`
+	AccessToken string `json:"access_token" redact:"true"`
+}
+
+// LogLevelResponse is the response with the current log level
+type LogLevelResponse struct {
+	Level string `json:"level"`
+}
+
+// BulkCompaniesResponse is the response with the outcome of every operation of a bulk request
+type BulkCompaniesResponse struct {
+	Mode      string                `json:"mode"`
+	Succeeded int                   `json:"succeeded"` // amount of operations applied
+	Failed    int                   `json:"failed"`    // amount of operations not applied
+	Results   []BulkOperationResult `json:"results"`   // outcome of each operation, in the order of the request
+}
+
+// BulkOperationResult is the outcome of an operation of a bulk request
+type BulkOperationResult struct {
+	Index  int                 `json:"index"`           // position of the operation in the request
+	Op     string              `json:"op"`              // type of the operation
+	Status int                 `json:"status"`          // http status the operation would get if it was sent alone
+	ID     string              `json:"id,omitempty"`    // ID of the company written, including the ones created
+	ETag   string              `json:"etag,omitempty"`  // entity tag of the company written, unless it was deleted
+	Error  *apierrors.APIError `json:"error,omitempty"` // why the operation was not applied
+}
+
+// ImportJobResponse is the response with the status of an import job
+type ImportJobResponse struct {
+	ID        string    `json:"id"`
+	Format    string    `json:"format"`
+	Status    string    `json:"status"`          // running, completed or failed
+	Size      int64     `json:"size"`            // size of the file in bytes
+	BytesRead int64     `json:"bytes_read"`      // bytes of the file processed so far
+	Progress  int       `json:"progress"`        // percentage of the file processed
+	Processed int       `json:"processed"`       // amount of rows processed
+	Accepted  int       `json:"accepted"`        // amount of companies created
+	Rejected  int       `json:"rejected"`        // amount of rows rejected
+	Error     string    `json:"error,omitempty"` // why the job failed before the end of the file
+	Report    string    `json:"report"`          // URL of the report with the outcome of every row processed
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -1,7 +1,13 @@
 package transport
 
 import (
+	"context"
+	"xm_test/internal/conf"
 	"xm_test/internal/db"
+	"xm_test/internal/enum"
+	"xm_test/internal/events"
+	"xm_test/internal/health"
+	"xm_test/internal/transport/grpc"
 	"xm_test/internal/transport/http"
 
 	"go.uber.org/zap"
@@ -10,12 +16,26 @@ import (
 // Transporter is an interface for the transport layer. It defines the Serve method that
 // will be run by any transport layer implementation (HTTP, gRPC, GraphQL, etc.).
 type Transporter interface {
-	Serve() error       // starts the transport layer
-	HealthCheck() error // starts a health check endpoint that verifies the service is up and running
-	Close() error       // handles the graceful shutdown of the transport layer
+	Serve() error                    // starts the transport layer
+	HealthCheck() error              // starts the liveness and readiness endpoints that verify the service is up and running
+	Close(ctx context.Context) error // handles the graceful shutdown of the transport layer
 }
 
-// NewTransporter creates a new transport layer based on the provided type.
-func NewTransporter(logger *zap.SugaredLogger, db db.DatabaseAdapter) Transporter {
-	return http.NewHttpTransport(logger, db)
+// NewTransporter creates the transport layers listed in the configuration. When several transports are
+// configured, they are served side by side and share the same lifecycle.
+func NewTransporter(logger *zap.SugaredLogger, logLevel zap.AtomicLevel, db db.DatabaseAdapter, hub events.Hub, registry events.Registry, monitor health.Monitor) Transporter {
+	var transports multiTransport
+	for _, transportType := range conf.GlobalConfig.Transports {
+		switch transportType {
+		case enum.HTTPTransport:
+			transports = append(transports, http.NewHttpTransport(logger, logLevel, db, hub, registry, monitor))
+		case enum.GRPCTransport:
+			transports = append(transports, grpc.NewGrpcTransport(logger, db, hub, registry, monitor))
+		}
+	}
+
+	if len(transports) == 1 {
+		return transports[0]
+	}
+	return transports
 }
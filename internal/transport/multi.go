@@ -0,0 +1,53 @@
+package transport
+
+import (
+	"context"
+	"errors"
+)
+
+// multiTransport serves several transport layers side by side
+type multiTransport []Transporter
+
+// Serve starts every transport. It returns as soon as one of them fails, or once all of them are stopped.
+func (m multiTransport) Serve() error {
+	return m.run(Transporter.Serve)
+}
+
+// HealthCheck starts the health checks of every transport. It returns as soon as one of them fails, or once all
+// of them are stopped.
+func (m multiTransport) HealthCheck() error {
+	return m.run(Transporter.HealthCheck)
+}
+
+// Close shuts down every transport concurrently, so they all drain within the same deadline
+func (m multiTransport) Close(ctx context.Context) error {
+	errs := make([]error, len(m))
+	done := make(chan struct{}, len(m))
+	for i, t := range m {
+		go func() {
+			errs[i] = t.Close(ctx)
+			done <- struct{}{}
+		}()
+	}
+	for range m {
+		<-done
+	}
+	return errors.Join(errs...)
+}
+
+// run calls fn on every transport concurrently and returns the first error
+func (m multiTransport) run(fn func(Transporter) error) error {
+	errs := make(chan error, len(m))
+	for _, t := range m {
+		go func() {
+			errs <- fn(t)
+		}()
+	}
+
+	for range m {
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"net/http"
 	"strings"
@@ -18,11 +19,13 @@ import (
 	"xm_test/internal/transport/http/schemas"
 
 	"github.com/docker/go-connections/nat"
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/suite"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/wait"
+	"github.com/vmihailenco/msgpack/v5"
 )
 
 type integrationSuite struct {
@@ -385,6 +388,125 @@ func (s *integrationSuite) TestUpdateCompany() {
 	})
 }
 
+func (s *integrationSuite) TestPatchCompany() {
+	// login
+	bodyLogin := `{"email":"` + s.email + `","password":"` + s.pasword + `"}`
+	loginResp, err := http.Post(s.apiURL+"/login", "application/json", strings.NewReader(bodyLogin))
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusOK, loginResp.StatusCode)
+
+	var loginResponse schemas.LoginResponse
+	err = json.NewDecoder(loginResp.Body).Decode(&loginResponse)
+	s.Require().NoError(err)
+	loginResp.Body.Close()
+
+	// create a company
+	company := &schemas.CreateCompanyRequest{
+		Name:            "testPatch",
+		Description:     "test",
+		AmountEmployees: helpers.PointerValue(10),
+		Registered:      helpers.PointerValue(true),
+		Type:            enum.Corporation.String(),
+	}
+	companyJSON, err := json.Marshal(company)
+	s.Require().NoError(err)
+
+	client := &http.Client{}
+	req, err := http.NewRequest("POST", s.apiURL+"/company/create", bytes.NewBuffer(companyJSON))
+	s.Require().NoError(err)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+loginResponse.AccessToken)
+
+	resp, err := client.Do(req)
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	var createdCompany models.CompanyModel
+	err = json.NewDecoder(resp.Body).Decode(&createdCompany)
+	s.Require().NoError(err)
+	resp.Body.Close()
+
+	s.Run("ok", func() {
+		input := []struct {
+			name               string
+			id                 string
+			contentType        string
+			body               string
+			expectedStatusCode int
+		}{
+			{
+				name:               "merge patch",
+				id:                 createdCompany.ID.String(),
+				contentType:        "application/merge-patch+json",
+				body:               `{"name": "testPatch2"}`,
+				expectedStatusCode: http.StatusOK,
+			},
+			{
+				name:               "json patch",
+				id:                 createdCompany.ID.String(),
+				contentType:        "application/json-patch+json",
+				body:               `[{"op": "test", "path": "/name", "value": "testPatch2"}, {"op": "replace", "path": "/amount_employees", "value": 20}]`,
+				expectedStatusCode: http.StatusOK,
+			},
+			{
+				name:               "invalid result",
+				id:                 createdCompany.ID.String(),
+				contentType:        "application/merge-patch+json",
+				body:               `{"type": "Unknown"}`,
+				expectedStatusCode: http.StatusBadRequest,
+			},
+			{
+				name:               "failed test",
+				id:                 createdCompany.ID.String(),
+				contentType:        "application/json-patch+json",
+				body:               `[{"op": "test", "path": "/name", "value": "testPatch"}]`,
+				expectedStatusCode: http.StatusConflict,
+			},
+			{
+				name:               "unsupported media type",
+				id:                 createdCompany.ID.String(),
+				contentType:        "application/json",
+				body:               `{"name": "testPatch3"}`,
+				expectedStatusCode: http.StatusUnsupportedMediaType,
+			},
+			{
+				name:               "company not found",
+				id:                 "5f1f3c1e-0b1a-4f6e-9d55-0f0e8a1b2c3d",
+				contentType:        "application/merge-patch+json",
+				body:               `{"name": "testPatch3"}`,
+				expectedStatusCode: http.StatusNotFound,
+			},
+		}
+
+		for _, tt := range input {
+			uri := fmt.Sprintf("%s/company/%s", s.apiURL, tt.id)
+			req, err := http.NewRequest("PATCH", uri, strings.NewReader(tt.body))
+			s.Require().NoError(err)
+
+			req.Header.Set("Content-Type", tt.contentType)
+			req.Header.Set("Authorization", "Bearer "+loginResponse.AccessToken)
+
+			resp, err := client.Do(req)
+			s.Require().NoError(err)
+			s.Equal(tt.expectedStatusCode, resp.StatusCode, tt.name)
+			resp.Body.Close()
+		}
+
+		// the fields that were not patched are kept
+		resp, err := http.Get(fmt.Sprintf("%s/company/%s", s.apiURL, createdCompany.ID.String()))
+		s.Require().NoError(err)
+		defer resp.Body.Close()
+
+		var patchedCompany models.CompanyModel
+		s.Require().NoError(json.NewDecoder(resp.Body).Decode(&patchedCompany))
+		s.Equal("testPatch2", patchedCompany.Name)
+		s.Equal(20, patchedCompany.AmountEmployees)
+		s.Equal(company.Description, patchedCompany.Description)
+		s.Equal(company.Type, patchedCompany.Type)
+	})
+}
+
 func (s *integrationSuite) TestDeleteCompany() {
 	// login
 	bodyLogin := `{"email":"` + s.email + `","password":"` + s.pasword + `"}`
@@ -437,6 +559,457 @@ func (s *integrationSuite) TestDeleteCompany() {
 	})
 }
 
+func (s *integrationSuite) TestConditionalRequests() {
+	// login
+	bodyLogin := `{"email":"` + s.email + `","password":"` + s.pasword + `"}`
+	loginResp, err := http.Post(s.apiURL+"/login", "application/json", strings.NewReader(bodyLogin))
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusOK, loginResp.StatusCode)
+
+	var loginResponse schemas.LoginResponse
+	err = json.NewDecoder(loginResp.Body).Decode(&loginResponse)
+	s.Require().NoError(err)
+	loginResp.Body.Close()
+
+	// create a company
+	company := &schemas.CreateCompanyRequest{
+		Name:            "testETag",
+		Description:     "test",
+		AmountEmployees: helpers.PointerValue(10),
+		Registered:      helpers.PointerValue(true),
+		Type:            enum.Corporation.String(),
+	}
+	companyJSON, err := json.Marshal(company)
+	s.Require().NoError(err)
+
+	client := &http.Client{}
+	req, err := http.NewRequest("POST", s.apiURL+"/company/create", bytes.NewBuffer(companyJSON))
+	s.Require().NoError(err)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+loginResponse.AccessToken)
+
+	resp, err := client.Do(req)
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+	s.Equal(`"1"`, resp.Header.Get("ETag"))
+
+	var createdCompany models.CompanyModel
+	err = json.NewDecoder(resp.Body).Decode(&createdCompany)
+	s.Require().NoError(err)
+	resp.Body.Close()
+
+	uri := fmt.Sprintf("%s/company/%s", s.apiURL, createdCompany.ID.String())
+	updateJSON, err := json.Marshal(&schemas.UpdateCompanyRequest{
+		Name:            "testETag2",
+		Description:     "test2",
+		AmountEmployees: helpers.PointerValue(20),
+		Registered:      helpers.PointerValue(false),
+		Type:            enum.Cooperative.String(),
+	})
+	s.Require().NoError(err)
+
+	input := []struct {
+		name               string
+		method             string
+		header             string
+		value              string
+		contentType        string
+		body               string
+		expectedStatusCode int
+		expectedETag       string
+	}{
+		{name: "not modified", method: "GET", header: "If-None-Match", value: `"1"`, expectedStatusCode: http.StatusNotModified, expectedETag: `"1"`},
+		{name: "update", method: "PUT", header: "If-Match", value: `"1"`, contentType: "application/json", body: string(updateJSON), expectedStatusCode: http.StatusOK, expectedETag: `"2"`},
+		{name: "stale update", method: "PUT", header: "If-Match", value: `"1"`, contentType: "application/json", body: string(updateJSON), expectedStatusCode: http.StatusPreconditionFailed},
+		{name: "patch", method: "PATCH", header: "If-Match", value: `"1", "2"`, contentType: "application/merge-patch+json", body: `{"name": "testETag3"}`, expectedStatusCode: http.StatusOK, expectedETag: `"3"`},
+		{name: "modified", method: "GET", header: "If-None-Match", value: `"2"`, expectedStatusCode: http.StatusOK, expectedETag: `"3"`},
+		{name: "stale delete", method: "DELETE", header: "If-Match", value: `"2"`, expectedStatusCode: http.StatusPreconditionFailed},
+		{name: "delete", method: "DELETE", header: "If-Match", value: `"3"`, expectedStatusCode: http.StatusOK},
+		{name: "company not found", method: "DELETE", header: "If-Match", value: `"3"`, expectedStatusCode: http.StatusNotFound},
+	}
+
+	for _, tt := range input {
+		req, err := http.NewRequest(tt.method, uri, strings.NewReader(tt.body))
+		s.Require().NoError(err)
+
+		req.Header.Set(tt.header, tt.value)
+		req.Header.Set("Authorization", "Bearer "+loginResponse.AccessToken)
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+
+		resp, err := client.Do(req)
+		s.Require().NoError(err)
+		s.Equal(tt.expectedStatusCode, resp.StatusCode, tt.name)
+		if tt.expectedETag != "" {
+			s.Equal(tt.expectedETag, resp.Header.Get("ETag"), tt.name)
+		}
+		resp.Body.Close()
+	}
+}
+
+func (s *integrationSuite) TestIdempotencyKey() {
+	// login
+	bodyLogin := `{"email":"` + s.email + `","password":"` + s.pasword + `"}`
+	loginResp, err := http.Post(s.apiURL+"/login", "application/json", strings.NewReader(bodyLogin))
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusOK, loginResp.StatusCode)
+
+	var loginResponse schemas.LoginResponse
+	err = json.NewDecoder(loginResp.Body).Decode(&loginResponse)
+	s.Require().NoError(err)
+	loginResp.Body.Close()
+
+	client := &http.Client{}
+	create := func(body string) (*http.Response, models.CompanyModel) {
+		req, err := http.NewRequest("POST", s.apiURL+"/company/create", strings.NewReader(body))
+		s.Require().NoError(err)
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+loginResponse.AccessToken)
+		req.Header.Set("Idempotency-Key", "create-testIdempotent")
+
+		resp, err := client.Do(req)
+		s.Require().NoError(err)
+		defer resp.Body.Close()
+
+		var company models.CompanyModel
+		json.NewDecoder(resp.Body).Decode(&company)
+		return resp, company
+	}
+
+	body := `{"name": "testIdem", "description": "test", "amount_employees": 10, "registered": true, "type": "Corporations"}`
+	resp, createdCompany := create(body)
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+	s.Run("replays the response", func() {
+		resp, company := create(body)
+		s.Equal(http.StatusOK, resp.StatusCode)
+		s.Equal("true", resp.Header.Get("Idempotent-Replayed"))
+		s.Equal(createdCompany.ID, company.ID)
+	})
+
+	s.Run("fails when the body differs", func() {
+		resp, _ := create(`{"name": "testIdem2", "amount_employees": 10, "registered": true, "type": "Corporations"}`)
+		s.Equal(http.StatusConflict, resp.StatusCode)
+	})
+}
+
+func (s *integrationSuite) TestBulkCompanies() {
+	// login
+	bodyLogin := `{"email":"` + s.email + `","password":"` + s.pasword + `"}`
+	loginResp, err := http.Post(s.apiURL+"/login", "application/json", strings.NewReader(bodyLogin))
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusOK, loginResp.StatusCode)
+
+	var loginResponse schemas.LoginResponse
+	err = json.NewDecoder(loginResp.Body).Decode(&loginResponse)
+	s.Require().NoError(err)
+	loginResp.Body.Close()
+
+	client := &http.Client{}
+	bulk := func(body string) (*http.Response, schemas.BulkCompaniesResponse) {
+		req, err := http.NewRequest("POST", s.apiURL+"/company/bulk", strings.NewReader(body))
+		s.Require().NoError(err)
+
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+loginResponse.AccessToken)
+
+		resp, err := client.Do(req)
+		s.Require().NoError(err)
+		defer resp.Body.Close()
+
+		var response schemas.BulkCompaniesResponse
+		json.NewDecoder(resp.Body).Decode(&response)
+		return resp, response
+	}
+
+	resp, created := bulk(`{"operations": [
+		{"op": "create", "company": {"name": "bulkA", "amount_employees": 10, "registered": true, "type": "Corporations"}},
+		{"op": "create", "company": {"name": "bulkB", "amount_employees": 20, "registered": false, "type": "NonProfit"}}
+	]}`)
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+	s.Require().Len(created.Results, 2)
+	s.Equal(2, created.Succeeded)
+	for _, result := range created.Results {
+		s.Equal(http.StatusOK, result.Status)
+		s.NotEmpty(result.ID)
+		s.Equal(`"1"`, result.ETag)
+	}
+
+	s.Run("aborts the transactional operations", func() {
+		resp, response := bulk(`{"operations": [
+			{"op": "update", "id": "` + created.Results[0].ID + `", "company": {"name": "bulkA2", "amount_employees": 10, "registered": true, "type": "Corporations"}},
+			{"op": "create", "company": {"name": "bulkB", "amount_employees": 20, "registered": false, "type": "NonProfit"}}
+		]}`)
+		s.Require().Equal(http.StatusMultiStatus, resp.StatusCode)
+		s.Equal(2, response.Failed)
+		s.Equal(apierrors.ErrBulkAborted.Code, response.Results[0].Error.Code)
+		s.Equal(http.StatusConflict, response.Results[1].Status)
+		s.Equal(apierrors.ErrCompanyAlreadyExists.Code, response.Results[1].Error.Code)
+	})
+
+	s.Run("skips the failing operations in best effort", func() {
+		resp, response := bulk(`{"mode": "best_effort", "operations": [
+			{"op": "delete", "id": "` + created.Results[0].ID + `", "if_match": "\"1\""},
+			{"op": "delete", "id": "` + uuid.NewString() + `"},
+			{"op": "update", "id": "not-a-uuid", "company": {"name": "bulkC", "amount_employees": 1, "registered": true, "type": "Corporations"}},
+			{"op": "create", "company": {"name": "bulkC"}}
+		]}`)
+		s.Require().Equal(http.StatusMultiStatus, resp.StatusCode)
+		s.Equal(1, response.Succeeded)
+		s.Equal(http.StatusOK, response.Results[0].Status)
+		s.Equal(apierrors.ErrCompanyNotFound.Code, response.Results[1].Error.Code)
+		s.Equal(apierrors.ErrInvalidUUID.Code, response.Results[2].Error.Code)
+		s.Equal(apierrors.ErrInvalidBody.Code, response.Results[3].Error.Code)
+		s.NotEmpty(response.Results[3].Error.Errors)
+	})
+}
+
+func (s *integrationSuite) TestImportCompanies() {
+	// login
+	bodyLogin := `{"email":"` + s.email + `","password":"` + s.pasword + `"}`
+	loginResp, err := http.Post(s.apiURL+"/login", "application/json", strings.NewReader(bodyLogin))
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusOK, loginResp.StatusCode)
+
+	var loginResponse schemas.LoginResponse
+	err = json.NewDecoder(loginResp.Body).Decode(&loginResponse)
+	s.Require().NoError(err)
+	loginResp.Body.Close()
+
+	client := &http.Client{}
+	get := func(path string) *http.Response {
+		req, err := http.NewRequest("GET", s.apiURL+path, nil)
+		s.Require().NoError(err)
+		req.Header.Set("Authorization", "Bearer "+loginResponse.AccessToken)
+
+		resp, err := client.Do(req)
+		s.Require().NoError(err)
+		return resp
+	}
+
+	file := "Company,employees,registered,type,description\n" +
+		"importA,10,true,Corporations,first\n" +
+		"importB,ten,true,Corporations,\n" +
+		"importC,5,false,NonProfit,\n"
+	req, err := http.NewRequest("POST", s.apiURL+"/company/imports?map=name:company&map=amount_employees:employees", strings.NewReader(file))
+	s.Require().NoError(err)
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("Authorization", "Bearer "+loginResponse.AccessToken)
+
+	resp, err := client.Do(req)
+	s.Require().NoError(err)
+	defer resp.Body.Close()
+	s.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+	var job schemas.ImportJobResponse
+	s.Require().NoError(json.NewDecoder(resp.Body).Decode(&job))
+	s.Equal(enum.ImportCompleted.String(), job.Status)
+	s.Equal(100, job.Progress)
+	s.Equal(2, job.Accepted)
+	s.Equal(1, job.Rejected)
+
+	s.Run("polls the status", func() {
+		resp := get(resp.Header.Get("Location"))
+		defer resp.Body.Close()
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+
+		var polled schemas.ImportJobResponse
+		s.Require().NoError(json.NewDecoder(resp.Body).Decode(&polled))
+		s.Equal(job.ID, polled.ID)
+		s.Equal(job.Accepted, polled.Accepted)
+	})
+
+	s.Run("downloads the report", func() {
+		resp := get(job.Report)
+		defer resp.Body.Close()
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Equal("text/csv", resp.Header.Get("Content-Type"))
+
+		var report bytes.Buffer
+		_, err := report.ReadFrom(resp.Body)
+		s.Require().NoError(err)
+		lines := strings.Split(strings.TrimSpace(report.String()), "\n")
+		s.Require().Len(lines, 4)
+		s.Equal("line,status,company_id,error_code,error_message", lines[0])
+		s.True(strings.HasPrefix(lines[1], "2,accepted,"))
+		s.True(strings.HasPrefix(lines[2], "3,rejected,,"+apierrors.ErrInvalidBody.Code))
+		s.True(strings.HasPrefix(lines[3], "4,accepted,"))
+	})
+
+	s.Run("rejects the files missing columns", func() {
+		req, err := http.NewRequest("POST", s.apiURL+"/company/imports?format=csv", strings.NewReader("name,type\n"))
+		s.Require().NoError(err)
+		req.Header.Set("Authorization", "Bearer "+loginResponse.AccessToken)
+
+		resp, err := client.Do(req)
+		s.Require().NoError(err)
+		defer resp.Body.Close()
+		s.Equal(http.StatusBadRequest, resp.StatusCode)
+	})
+
+	s.Run("not found", func() {
+		resp := get("/company/imports/" + uuid.NewString())
+		defer resp.Body.Close()
+		s.Equal(http.StatusNotFound, resp.StatusCode)
+	})
+}
+
+func (s *integrationSuite) TestExportCompanies() {
+	// login
+	bodyLogin := `{"email":"` + s.email + `","password":"` + s.pasword + `"}`
+	loginResp, err := http.Post(s.apiURL+"/login", "application/json", strings.NewReader(bodyLogin))
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusOK, loginResp.StatusCode)
+
+	var loginResponse schemas.LoginResponse
+	err = json.NewDecoder(loginResp.Body).Decode(&loginResponse)
+	s.Require().NoError(err)
+	loginResp.Body.Close()
+
+	client := &http.Client{}
+	get := func(path string, accept string) *http.Response {
+		req, err := http.NewRequest("GET", s.apiURL+path, nil)
+		s.Require().NoError(err)
+		req.Header.Set("Authorization", "Bearer "+loginResponse.AccessToken)
+		req.Header.Set("Accept", accept)
+
+		resp, err := client.Do(req)
+		s.Require().NoError(err)
+		return resp
+	}
+
+	// create the companies
+	file := `{"name":"exportA","amount_employees":10,"registered":true,"type":"Corporations"}` + "\n" +
+		`{"name":"exportB","amount_employees":5,"registered":false,"type":"NonProfit"}` + "\n"
+	req, err := http.NewRequest("POST", s.apiURL+"/company/imports", strings.NewReader(file))
+	s.Require().NoError(err)
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Authorization", "Bearer "+loginResponse.AccessToken)
+	resp, err := client.Do(req)
+	s.Require().NoError(err)
+	resp.Body.Close()
+	s.Require().Equal(http.StatusCreated, resp.StatusCode)
+
+	s.Run("csv", func() {
+		resp := get("/company/export?name=export&columns=name,registered", "")
+		defer resp.Body.Close()
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Equal("text/csv", resp.Header.Get("Content-Type"))
+		s.Equal(`attachment; filename="companies.csv"`, resp.Header.Get("Content-Disposition"))
+
+		var body bytes.Buffer
+		_, err := body.ReadFrom(resp.Body)
+		s.Require().NoError(err)
+		lines := strings.Split(strings.TrimSpace(body.String()), "\n")
+		s.Require().Len(lines, 3)
+		s.Equal("name,registered", lines[0])
+		s.ElementsMatch([]string{"exportA,true", "exportB,false"}, lines[1:])
+	})
+
+	s.Run("ndjson with filters", func() {
+		resp := get("/company/export?name=export&registered=false", "application/x-ndjson")
+		defer resp.Body.Close()
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Equal("application/x-ndjson", resp.Header.Get("Content-Type"))
+
+		var company models.CompanyModel
+		decoder := json.NewDecoder(resp.Body)
+		s.Require().NoError(decoder.Decode(&company))
+		s.Equal("exportB", company.Name)
+		s.False(decoder.More())
+	})
+
+	s.Run("not acceptable", func() {
+		resp := get("/company/export", "application/xml")
+		defer resp.Body.Close()
+		s.Equal(http.StatusNotAcceptable, resp.StatusCode)
+	})
+
+	s.Run("invalid type", func() {
+		resp := get("/company/export?type=unknown", "")
+		defer resp.Body.Close()
+		s.Equal(http.StatusBadRequest, resp.StatusCode)
+		s.Empty(resp.Header.Get("Content-Disposition"))
+	})
+}
+
+func (s *integrationSuite) TestContentNegotiation() {
+	// login
+	bodyLogin := `{"email":"` + s.email + `","password":"` + s.pasword + `"}`
+	loginResp, err := http.Post(s.apiURL+"/login", "application/json", strings.NewReader(bodyLogin))
+	s.Require().NoError(err)
+	s.Require().Equal(http.StatusOK, loginResp.StatusCode)
+
+	var loginResponse schemas.LoginResponse
+	err = json.NewDecoder(loginResp.Body).Decode(&loginResponse)
+	s.Require().NoError(err)
+	loginResp.Body.Close()
+
+	client := &http.Client{}
+	do := func(method, path, contentType, accept, body string) *http.Response {
+		req, err := http.NewRequest(method, s.apiURL+path, strings.NewReader(body))
+		s.Require().NoError(err)
+		req.Header.Set("Authorization", "Bearer "+loginResponse.AccessToken)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Accept", accept)
+
+		resp, err := client.Do(req)
+		s.Require().NoError(err)
+		return resp
+	}
+
+	// create a company sending XML and receiving MessagePack
+	body := `<company><name>negotiated</name><amount_employees>10</amount_employees><registered>true</registered><type>Corporations</type></company>`
+	resp := do("POST", "/company/create", "application/xml", "application/msgpack", body)
+	s.Require().Equal(http.StatusOK, resp.StatusCode)
+	s.Equal("application/msgpack", resp.Header.Get("Content-Type"))
+
+	var created map[string]any
+	s.Require().NoError(msgpack.NewDecoder(resp.Body).Decode(&created))
+	resp.Body.Close()
+	s.Equal("negotiated", created["name"])
+	s.EqualValues(10, created["amount_employees"])
+
+	s.Run("xml", func() {
+		resp := do("GET", fmt.Sprintf("/company/%s", created["id"]), "", "application/xml", "")
+		defer resp.Body.Close()
+		s.Require().Equal(http.StatusOK, resp.StatusCode)
+		s.Equal("application/xml", resp.Header.Get("Content-Type"))
+
+		var company struct {
+			Name            string `xml:"name"`
+			AmountEmployees int    `xml:"amount_employees"`
+			Registered      bool   `xml:"registered"`
+		}
+		s.Require().NoError(xml.NewDecoder(resp.Body).Decode(&company))
+		s.Equal("negotiated", company.Name)
+		s.Equal(10, company.AmountEmployees)
+		s.True(company.Registered)
+	})
+
+	s.Run("not acceptable", func() {
+		resp := do("GET", fmt.Sprintf("/company/%s", created["id"]), "", "text/html", "")
+		defer resp.Body.Close()
+		s.Equal(http.StatusNotAcceptable, resp.StatusCode)
+	})
+
+	s.Run("unsupported media type", func() {
+		resp := do("POST", "/company/create", "application/x-www-form-urlencoded", "application/xml", "name=negotiated")
+		defer resp.Body.Close()
+		s.Require().Equal(http.StatusUnsupportedMediaType, resp.StatusCode)
+		s.Equal("application/xml", resp.Header.Get("Content-Type"))
+
+		var apiErr struct {
+			Code string `xml:"code"`
+		}
+		s.Require().NoError(xml.NewDecoder(resp.Body).Decode(&apiErr))
+		s.Equal(apierrors.ErrUnsupportedMediaType.Code, apiErr.Code)
+	})
+}
+
 func TestIntegrationSuite(t *testing.T) {
 	suite.Run(t, new(integrationSuite))
 }
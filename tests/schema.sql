@@ -13,7 +13,8 @@ CREATE TABLE IF NOT EXISTS "company" (
     "description" VARCHAR(3000),
     "amount_employees" INT NOT NULL,
     "registered" BOOLEAN NOT NULL,
-    "type" ORG_TYPE NOT NULL
+    "type" ORG_TYPE NOT NULL,
+    "version" INT NOT NULL DEFAULT 1
 );
 
 CREATE UNIQUE INDEX company_id_idx ON "company"("id");
@@ -39,9 +40,71 @@ CREATE TYPE EVENT_TYPE AS ENUM (
 CREATE TABLE IF NOT EXISTS "events" (
     "id" UUID PRIMARY KEY,
     "type" EVENT_TYPE NOT NULL,
+    "version" INT NOT NULL DEFAULT 1,
     "timestamp" TIMESTAMP NOT NULL,
-    "entity_id" UUID NOT NULL
+    "entity_id" UUID NOT NULL,
+    "payload" JSONB,
+    "trace_parent" VARCHAR(55) NOT NULL DEFAULT '',
+    "trace_state" TEXT NOT NULL DEFAULT ''
 );
 
 CREATE UNIQUE INDEX events_id_idx ON "events"("id");
-CREATE INDEX entity_id ON "events"("entity_id");
\ No newline at end of file
+CREATE INDEX entity_id ON "events"("entity_id");
+
+
+CREATE TABLE IF NOT EXISTS "dead_letter_events" (
+    "id" UUID PRIMARY KEY,
+    "type" VARCHAR(50) NOT NULL,
+    "version" INT NOT NULL,
+    "timestamp" TIMESTAMPTZ NOT NULL,
+    "entity_id" UUID NOT NULL,
+    "payload" JSONB,
+    "last_error" TEXT NOT NULL,
+    "attempts" INT NOT NULL,
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    "updated_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    "trace_parent" VARCHAR(55) NOT NULL DEFAULT '',
+    "trace_state" TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX dead_letter_events_created_at_idx ON "dead_letter_events"("created_at");
+
+
+CREATE TABLE IF NOT EXISTS "idempotency_keys" (
+    "user_id" UUID NOT NULL,
+    "key" VARCHAR(255) NOT NULL,
+    "request_hash" VARCHAR(64) NOT NULL,
+    "completed" BOOLEAN NOT NULL DEFAULT FALSE,
+    "status" INT NOT NULL DEFAULT 0,
+    "headers" JSONB NOT NULL DEFAULT '{}',
+    "body" BYTEA NOT NULL DEFAULT '',
+    "locked_until" TIMESTAMPTZ NOT NULL,
+    "expires_at" TIMESTAMPTZ NOT NULL,
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY ("user_id", "key")
+);
+
+CREATE TABLE IF NOT EXISTS "import_jobs" (
+    "id" UUID PRIMARY KEY,
+    "user_id" UUID NOT NULL,
+    "format" VARCHAR(16) NOT NULL,
+    "status" VARCHAR(16) NOT NULL,
+    "size" BIGINT NOT NULL DEFAULT 0,
+    "bytes_read" BIGINT NOT NULL DEFAULT 0,
+    "processed" INT NOT NULL DEFAULT 0,
+    "accepted" INT NOT NULL DEFAULT 0,
+    "rejected" INT NOT NULL DEFAULT 0,
+    "error" TEXT NOT NULL DEFAULT '',
+    "created_at" TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    "updated_at" TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS "import_rows" (
+    "job_id" UUID NOT NULL REFERENCES "import_jobs"("id") ON DELETE CASCADE,
+    "line" INT NOT NULL,
+    "accepted" BOOLEAN NOT NULL,
+    "company_id" UUID,
+    "error_code" VARCHAR(64) NOT NULL DEFAULT '',
+    "error_message" TEXT NOT NULL DEFAULT '',
+    PRIMARY KEY ("job_id", "line")
+);